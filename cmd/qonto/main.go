@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -17,8 +18,18 @@ import (
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/api"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/app"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/dispatch"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/grpcapi"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/health"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/metrics"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/screening"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/mysql"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/postgres"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/tracing"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/utils"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -33,48 +44,191 @@ func run(args []string) error {
 	appCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	appLogger := qonto.NewInstanceLogger(os.Stdout, "Qonto")
+	// "statement" subcommand writes the statement and exits instead of serving API
+	var statement *statementCommand
+	if len(args) > 0 && args[0] == "statement" {
+		var err error
+		if statement, err = parseStatementCommand(args[1:]); err != nil {
+			return err
+		}
+	}
+
+	config, err := app.ConfigurationFromEnv(os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	logLevel, err := qonto.ParseLevel(config.Log.Level)
+	if err != nil {
+		return err
+	}
+	logFormat, err := qonto.ParseFormat(config.Log.Format)
+	if err != nil {
+		return err
+	}
+	logOutput := os.Stdout
+	if statement != nil {
+		// stdout is for the statement itself
+		logOutput = os.Stderr
+	}
+	appLogger := qonto.NewInstanceLogger(logOutput, "Qonto").WithLevel(logLevel).WithFormat(logFormat)
 	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt, syscall.SIGQUIT, syscall.SIGHUP, syscall.SIGTERM)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM)
 	go func() {
 		<-signalChan
 		appLogger.Info("received interruption request, closing the app")
 		cancel()
 	}()
 
-	config, err := app.ConfigurationFromEnv(os.Getenv)
+	registry := metrics.NewRegistry()
+	db, err := newDatabase(config, registry)
 	if err != nil {
 		return err
 	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			appLogger.Error("could not close database connection", "error", err)
+		}
+	}()
+	if err := dbConnect(appCtx, db, appLogger); err != nil {
+		return err
+	}
+	if err := db.migrate(db.migrationsSource, db.DB()); err != nil {
+		return fmt.Errorf("migrations failed: %v", err)
+	}
+	appLogger.Info("migration completed")
 
-	mysqlConfig := storage.NewMysqlConfig()
-	mysqlConfig.User = config.DB.User
-	mysqlConfig.Passwd = config.DB.Password
-	mysqlConfig.DBName = config.DB.Name
-	mysqlConfig.Net = "tcp"
-	mysqlConfig.Addr = config.DB.Address
+	if statement != nil {
+		return statement.run(appCtx, db)
+	}
 
-	mysqlStorage, err := storage.NewMysqlStorage(mysqlConfig)
+	tracerProvider, err := tracing.NewProvider(tracing.Output(config.TracingOutput), "qonto")
 	if err != nil {
-		return err
+		return fmt.Errorf("could not set up tracing: %v", err)
 	}
 	defer func() {
-		if err := mysqlStorage.Close(); err != nil {
-			appLogger.Error("could not close database connection: %v", err)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+			appLogger.Error("could not export remaining spans", "error", err)
 		}
 	}()
-	if err := dbConnect(appCtx, mysqlStorage, appLogger); err != nil {
+	tracedStorage := tracing.NewStorage(db, tracerProvider, config.DB.Driver)
+
+	rules := []core.Rule{}
+	if config.RulesFile != "" {
+		rulesConfig, err := core.LoadRulesConfig(config.RulesFile)
+		if err != nil {
+			return err
+		}
+		if rules, err = rulesConfig.Rules(); err != nil {
+			return fmt.Errorf("invalid rules config: %v", err)
+		}
+		appLogger.Info("loaded risk rules", "count", len(rules), "file", config.RulesFile)
+	}
+
+	duplicatesMode, err := core.ParseDuplicatesMode(config.Duplicates.Mode)
+	if err != nil {
 		return err
 	}
-	projectRoot := utils.ProjectRootDir()
-	if err := storage.Migrate("file://"+projectRoot+"/migrations/", mysqlStorage.DB()); err != nil {
-		return fmt.Errorf("migrations failed: %v", err)
+
+	transferManager := core.NewQontoTransferManager(tracedStorage).
+		WithDuplicatesPolicy(core.DuplicatesPolicy{Mode: duplicatesMode, Window: config.Duplicates.Window})
+	// without rules every request would cost an account lookup and a stored "allow" decision for nothing
+	if len(rules) > 0 {
+		transferManager.WithRuleEngine(core.NewRuleEngine(rules...))
+	}
+	var dispatchedManager core.TransferManager = transferManager
+	if config.Dispatcher.Workers > 0 {
+		dispatcher := dispatch.NewDispatcher(transferManager, config.Dispatcher.Workers, config.Dispatcher.QueueSize)
+		dispatcher.WithObserver(metrics.NewDispatcherMetrics(registry, dispatcher.Stats)).
+			WithIdleTimeout(config.Dispatcher.IdleTimeout)
+		// servers are stopped by now, so queued requests of finished calls are drained before the database is closed
+		defer dispatcher.Close()
+		dispatchedManager = dispatcher
+	}
+	instrumentedManager := metrics.NewTransferManager(tracing.NewTransferManager(dispatchedManager, tracerProvider), registry, api.ErrorCode)
+	historyManager := core.NewQontoHistoryManager(tracedStorage)
+
+	throttleMetrics := metrics.NewThrottleMetrics(registry)
+	clientLimiter := ratelimit.NewLimiter(ratelimit.Limits{
+		Rate:        config.ClientRateLimit.Rate,
+		Burst:       config.ClientRateLimit.Burst,
+		MaxInFlight: config.ClientRateLimit.MaxInFlight,
+	}).WithObserver(throttleMetrics.Scope(ratelimit.SCOPE_CLIENT))
+	limiter := ratelimit.NewLimiter(ratelimit.Limits{
+		Rate:        config.RateLimit.Rate,
+		Burst:       config.RateLimit.Burst,
+		MaxInFlight: config.RateLimit.MaxInFlight,
+	}).WithObserver(throttleMetrics.Scope(ratelimit.SCOPE_ACCOUNT))
+	overrides, err := ratelimit.ParseOverrides(config.RateLimit.Overrides)
+	if err != nil {
+		return fmt.Errorf("invalid QONTO_RATE_LIMIT_OVERRIDES: %v", err)
+	}
+	for iban, limits := range overrides {
+		limiter.WithOverride(ratelimit.AccountKey(iban, ""), limits)
+	}
+	qontoAPI := api.NewAPI(instrumentedManager).
+		WithReportManager(core.NewQontoReportManager(tracedStorage)).
+		WithStatementManager(core.NewQontoStatementManager(tracedStorage)).
+		WithHistoryManager(historyManager).
+		WithClientLimiter(clientLimiter).
+		WithLimiter(limiter)
+
+	var screener *screening.Screener
+	if config.Screening.ListFile != "" {
+		if screener, err = screening.NewScreener(config.Screening.ListFile, config.Screening.Threshold); err != nil {
+			return err
+		}
+		appLogger.Info("loaded sanctions list", "entries", screener.Len(), "file", config.Screening.ListFile)
+		transferManager.WithScreener(screener)
+		qontoAPI.WithScreeningManager(core.NewQontoScreeningManager(tracedStorage, screener))
 	}
-	appLogger.Info("migration completed")
 
-	qontoAPI := api.NewAPI(core.NewQontoTransferManager(mysqlStorage))
 	router := chi.NewRouter()
-	router.Post("/v1/transfers", qontoAPI.HandleTransfers)
+	router.Use(api.RequestIDMiddleware)
+	router.Use(tracing.Middleware(tracerProvider))
+	router.Use(api.LoggerMiddleware(appLogger.SubLogger("http")))
+	router.Use(api.AccessLogMiddleware)
+	router.Use(metrics.NewHTTPMetrics(registry).Middleware)
+	// panics are recovered first, so outer middlewares observe internal error response
+	router.Use(api.RecoverMiddleware)
+	qontoAPI.Routes(router)
+	router.Method(http.MethodGet, "/metrics", registry)
+
+	// SIGHUP reloads sanctions list if screening is enabled, otherwise it stops the app
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func(logger qonto.Logger) {
+		for {
+			select {
+			case <-appCtx.Done():
+				return
+			case <-hupChan:
+				if screener == nil {
+					logger.Info("received interruption request, closing the app")
+					cancel()
+					return
+				}
+				if err := screener.Reload(); err != nil {
+					logger.Error("could not reload sanctions list", "error", err)
+					continue
+				}
+				logger.Info("reloaded sanctions list", "entries", screener.Len())
+			}
+		}
+	}(appLogger)
+
+	latestMigration, err := storage.LatestMigration(db.migrationsSource)
+	if err != nil {
+		return fmt.Errorf("could not read migrations: %v", err)
+	}
+	probe := health.NewProbe(2*time.Second).
+		WithCheck("database", health.PingCheck(db)).
+		WithCheck("migrations", health.MigrationsCheck(db, latestMigration))
+	router.Get("/healthz", probe.HandleLive)
+	router.Get("/readyz", probe.HandleReady)
+	router.Get("/startupz", probe.HandleStartup)
 
 	server := http.Server{
 		Addr:         config.ListenAddress,
@@ -83,14 +237,45 @@ func run(args []string) error {
 		Handler:      router,
 	}
 
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(tracing.UnaryServerInterceptor(tracerProvider)),
+		grpc.StreamInterceptor(tracing.StreamServerInterceptor(tracerProvider)),
+	)
+	grpcapi.NewServer(instrumentedManager).
+		WithAccountManager(core.NewQontoAccountManager(tracedStorage)).
+		WithHistoryManager(historyManager).
+		WithClientLimiter(clientLimiter).
+		WithLimiter(limiter).
+		Register(grpcServer)
+	grpcListener, err := net.Listen("tcp", config.GRPCListenAddress)
+	if err != nil {
+		return fmt.Errorf("could not listen gRPC address: %v", err)
+	}
+
+	httpListener, err := net.Listen("tcp", config.ListenAddress)
+	if err != nil {
+		return fmt.Errorf("could not listen HTTP address: %v", err)
+	}
+
+	// readiness fails as soon as shutdown is requested, servers are stopped once load balancers stop sending requests
+	drainCtx, drained := context.WithCancel(context.Background())
+	defer drained()
+	go func(logger qonto.Logger) {
+		<-appCtx.Done()
+		probe.SetShuttingDown()
+		logger.Info("readiness probe is failing, waiting before shutdown", "delay", config.ShutdownDelay)
+		time.Sleep(config.ShutdownDelay)
+		drained()
+	}(appLogger)
+
 	wg := sync.WaitGroup{}
 
 	// starting HTTP server in background
 	wg.Add(1)
 	go func(srv *http.Server, logger qonto.Logger) {
 		defer wg.Done()
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error(err.Error())
+		if err := srv.Serve(httpListener); err != nil && err != http.ErrServerClosed {
+			logger.Error("server failed", "error", err)
 		}
 
 	}(&server, appLogger.SubLogger("http server"))
@@ -105,12 +290,46 @@ func run(args []string) error {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
-		logger.Info("trying gracefully shutdown in %v", timeout)
+		logger.Info("trying gracefully shutdown", "timeout", timeout)
 		if err := srv.Shutdown(shutdownCtx); err != nil {
-			logger.Error(err.Error())
+			logger.Error("graceful shutdown failed", "error", err)
 		}
 		logger.Info("done")
-	}(appCtx, &server, appLogger.SubLogger("http server"))
+	}(drainCtx, &server, appLogger.SubLogger("http server"))
+
+	// starting gRPC server in background
+	wg.Add(1)
+	go func(srv *grpc.Server, logger qonto.Logger) {
+		defer wg.Done()
+		if err := srv.Serve(grpcListener); err != nil {
+			logger.Error("server failed", "error", err)
+		}
+	}(grpcServer, appLogger.SubLogger("grpc server"))
+
+	// app shutdown watcher that will stop gRPC server
+	wg.Add(1)
+	go func(ctx context.Context, srv *grpc.Server, logger qonto.Logger) {
+		defer wg.Done()
+		<-ctx.Done()
+
+		timeout := 10 * time.Second
+		logger.Info("trying gracefully shutdown", "timeout", timeout)
+		stopped := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(timeout):
+			// streams that are still running are cancelled
+			srv.Stop()
+		}
+		logger.Info("done")
+	}(drainCtx, grpcServer, appLogger.SubLogger("grpc server"))
+
+	probe.SetStarted()
+	appLogger.Info("started", "http_address", config.ListenAddress, "grpc_address", config.GRPCListenAddress)
 
 	wg.Wait()
 
@@ -119,27 +338,75 @@ func run(args []string) error {
 	return nil
 }
 
-func dbConnect(ctx context.Context, mysqlStorage storage.Storage, appLogger qonto.Logger) error {
+func dbConnect(ctx context.Context, s storage.Health, appLogger qonto.Logger) error {
 	dbPingCtx, dbPingCancel := context.WithTimeout(ctx, 30*time.Second)
 	defer dbPingCancel()
 
-	dbUpWaitFunc := func(db *sql.DB) (bool, error) {
-		for {
-			select {
-			case <-dbPingCtx.Done():
-				return false, dbPingCtx.Err()
-			case <-time.After(1 * time.Second):
-				if err := db.PingContext(dbPingCtx); err != nil {
-					appLogger.Info("db ping failed: %v", err)
-					return true, err
-				}
-				return false, nil
-			}
+	return storage.WaitAvailable(dbPingCtx, s, time.Second, func(err error) {
+		appLogger.Info("db ping failed", "error", err)
+	})
+}
+
+// database is the storage of the configured driver along with its migrations
+type database struct {
+	sqlStorage
+	migrationsSource string
+	migrate          func(source string, db *sql.DB) error
+}
+
+// sqlStorage is implemented by storages of all supported drivers
+type sqlStorage interface {
+	storage.Storage
+	storage.Health
+	DB() *sql.DB
+	Close() error
+}
+
+// newDatabase creates storage of the configured driver reporting its transactions to the registry
+func newDatabase(config *app.Configuration, registry *metrics.Registry) (*database, error) {
+	retryPolicy := storage.RetryPolicy{
+		MaxAttempts: config.DB.Retry.MaxAttempts,
+		BaseDelay:   config.DB.Retry.BaseDelay,
+		MaxDelay:    config.DB.Retry.MaxDelay,
+	}
+	migrationsDir := "file://" + utils.ProjectRootDir() + "/migrations/"
+
+	if config.DB.Driver == app.DB_DRIVER_POSTGRES {
+		postgresStorage, err := postgres.NewStorage(postgres.NewDSN(config.DB.Address, config.DB.User, config.DB.Password, config.DB.Name))
+		if err != nil {
+			return nil, err
 		}
+		dbMetrics := metrics.NewDBMetrics(registry, postgresStorage.DB().Stats)
+		postgresStorage.WithTransactionObserver(dbMetrics).
+			WithRetryObserver(dbMetrics).
+			WithRetryPolicy(retryPolicy)
+
+		return &database{
+			sqlStorage:       postgresStorage,
+			migrationsSource: migrationsDir + "postgres/",
+			migrate:          postgres.Migrate,
+		}, nil
 	}
-	if err := mysqlStorage.Wait(dbUpWaitFunc); err != nil {
-		return err
+
+	mysqlConfig := mysql.NewConfig()
+	mysqlConfig.User = config.DB.User
+	mysqlConfig.Passwd = config.DB.Password
+	mysqlConfig.DBName = config.DB.Name
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = config.DB.Address
+
+	mysqlStorage, err := mysql.NewStorage(mysqlConfig)
+	if err != nil {
+		return nil, err
 	}
+	dbMetrics := metrics.NewDBMetrics(registry, mysqlStorage.DB().Stats)
+	mysqlStorage.WithTransactionObserver(dbMetrics).
+		WithRetryObserver(dbMetrics).
+		WithRetryPolicy(retryPolicy)
 
-	return nil
+	return &database{
+		sqlStorage:       mysqlStorage,
+		migrationsSource: migrationsDir,
+		migrate:          mysql.Migrate,
+	}, nil
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/iso20022"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+// statementCommand writes ISO 20022 statement of the account into a file or stdout
+type statementCommand struct {
+	iban        string
+	messageType string
+	from        string
+	to          string
+	output      string
+}
+
+func parseStatementCommand(args []string) (*statementCommand, error) {
+	cmd := &statementCommand{}
+	flags := flag.NewFlagSet("statement", flag.ContinueOnError)
+	flags.StringVar(&cmd.iban, "iban", "", "IBAN of the account, required")
+	flags.StringVar(&cmd.messageType, "type", iso20022.MESSAGE_CAMT053, "message type: camt.053 (end of day statements) or camt.052 (intraday report)")
+	flags.StringVar(&cmd.from, "from", "", "period start, date (2006-01-02) or RFC 3339 time; camt.053 defaults to the previous day, camt.052 to the current one")
+	flags.StringVar(&cmd.to, "to", "", "period end, date is included into the period")
+	flags.StringVar(&cmd.output, "output", "", "output file, stdout by default")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if cmd.iban == "" {
+		return nil, fmt.Errorf("statement: -iban is required")
+	}
+	if _, _, err := iso20022.StatementPeriod(cmd.messageType, cmd.from, cmd.to, time.Now()); err != nil {
+		return nil, fmt.Errorf("statement: %w", err)
+	}
+
+	return cmd, nil
+}
+
+func (cmd *statementCommand) run(ctx context.Context, s storage.Storage) error {
+	now := time.Now()
+	from, to, err := iso20022.StatementPeriod(cmd.messageType, cmd.from, cmd.to, now)
+	if err != nil {
+		return err
+	}
+	statement, err := core.NewQontoStatementManager(s).Statement(ctx, cmd.iban, from, to)
+	if err != nil {
+		return err
+	}
+
+	messageID, err := iso20022.NewMessageID("STM", now)
+	if err != nil {
+		return err
+	}
+	message, err := iso20022.NewStatementMessage(cmd.messageType, statement, messageID, now)
+	if err != nil {
+		return err
+	}
+	content, err := message.Marshal()
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if cmd.output != "" {
+		f, err := os.Create(cmd.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	_, err = out.Write(content)
+	return err
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
 )
 
 func NewAPI(transferManager core.TransferManager) *qontoAPI {
@@ -9,3 +10,39 @@ func NewAPI(transferManager core.TransferManager) *qontoAPI {
 		manager: transferManager,
 	}
 }
+
+// WithScreeningManager enables sanctions screening endpoints
+func (qapi *qontoAPI) WithScreeningManager(screening core.ScreeningManager) *qontoAPI {
+	qapi.screening = screening
+	return qapi
+}
+
+// WithHistoryManager enables export of transaction history
+func (qapi *qontoAPI) WithHistoryManager(history core.HistoryManager) *qontoAPI {
+	qapi.history = history
+	return qapi
+}
+
+// WithStatementManager enables ISO 20022 account statements
+func (qapi *qontoAPI) WithStatementManager(statements core.StatementManager) *qontoAPI {
+	qapi.statements = statements
+	return qapi
+}
+
+// WithLimiter throttles bulk transfer requests per debited account
+func (qapi *qontoAPI) WithLimiter(limiter *ratelimit.Limiter) *qontoAPI {
+	qapi.limiter = limiter
+	return qapi
+}
+
+// WithClientLimiter throttles bulk transfer requests per client address before they are decoded
+func (qapi *qontoAPI) WithClientLimiter(limiter *ratelimit.Limiter) *qontoAPI {
+	qapi.clientLimiter = limiter
+	return qapi
+}
+
+// WithReportManager enables pain.002 status reports of processed pain.001 messages
+func (qapi *qontoAPI) WithReportManager(reports core.ReportManager) *qontoAPI {
+	qapi.reports = reports
+	return qapi
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/spool"
+)
+
+// columns of CSV upload, description is optional
+const (
+	csvColumnName        = "counterparty_name"
+	csvColumnIBAN        = "counterparty_iban"
+	csvColumnBIC         = "counterparty_bic"
+	csvColumnAmount      = "amount"
+	csvColumnCurrency    = "currency"
+	csvColumnDescription = "description"
+)
+
+// maxCSVErrors stops validation of hopelessly broken files
+const maxCSVErrors = 100
+
+var (
+	csvRequiredColumns = []string{csvColumnName, csvColumnIBAN, csvColumnBIC, csvColumnAmount, csvColumnCurrency}
+	csvKnownColumns    = []string{csvColumnName, csvColumnIBAN, csvColumnBIC, csvColumnAmount, csvColumnCurrency, csvColumnDescription}
+)
+
+type (
+	// CSVRowError refers to the row of uploaded file, header is row 1
+	CSVRowError struct {
+		Row     int
+		Message string
+	}
+
+	// CSVErrors holds all errors found in the file
+	CSVErrors []CSVRowError
+)
+
+func (e CSVRowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+func (e CSVErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+
+	return ErrInvalidCSV.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Is makes CSV errors match ErrInvalidCSV
+func (e CSVErrors) Is(target error) bool {
+	return target == ErrInvalidCSV
+}
+
+// parseCSVTransfers reads transfers row by row into the spool, so rows are validated without loading the whole file.
+// Either comma or semicolon (spreadsheets in some locales) separates columns
+func parseCSVTransfers(r io.Reader, transfers *spool.Transfers) error {
+	buffered := bufio.NewReader(r)
+	// spreadsheets may start UTF-8 files with byte order mark
+	if bom, _ := buffered.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		_, _ = buffered.Discard(3)
+	}
+	reader := csv.NewReader(buffered)
+	headerLine, _ := buffered.Peek(buffered.Size())
+	if i := bytes.IndexByte(headerLine, '\n'); i >= 0 {
+		headerLine = headerLine[:i]
+	}
+	if bytes.Count(headerLine, []byte(";")) > bytes.Count(headerLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return CSVErrors{{Row: 1, Message: "header is required"}}
+	}
+	if err != nil {
+		return CSVErrors{{Row: 1, Message: err.Error()}}
+	}
+	columns, err := csvColumns(header)
+	if err != nil {
+		return CSVErrors{{Row: 1, Message: err.Error()}}
+	}
+
+	errs := CSVErrors{}
+	for row := 2; len(errs) < maxCSVErrors; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			errs = append(errs, CSVRowError{Row: row, Message: fmt.Sprintf("expected %d columns, got %d", len(header), len(record))})
+			continue
+		}
+		if err != nil {
+			// the rest of the file cannot be read reliably
+			errs = append(errs, CSVRowError{Row: row, Message: err.Error()})
+			break
+		}
+
+		transfer, rowErrs := csvTransfer(record, columns)
+		for _, message := range rowErrs {
+			errs = append(errs, CSVRowError{Row: row, Message: message})
+		}
+		if len(errs) > 0 {
+			// the file is rejected, there is no need to keep transfers any more
+			continue
+		}
+		if err := transfers.Append(transfer); err != nil {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	if transfers.Len() == 0 {
+		return CSVErrors{{Row: 2, Message: "at least one transfer is required"}}
+	}
+
+	return nil
+}
+
+// csvColumns maps known column names to their positions
+func csvColumns(header []string) (map[string]int, error) {
+	columns := map[string]int{}
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !contains(csvKnownColumns, column) {
+			return nil, fmt.Errorf("unknown column %q, expected columns are %s", column, strings.Join(csvKnownColumns, ", "))
+		}
+		if _, ok := columns[column]; ok {
+			return nil, fmt.Errorf("duplicate column %q", column)
+		}
+		columns[column] = i
+	}
+	missing := []string{}
+	for _, column := range csvRequiredColumns {
+		if _, ok := columns[column]; !ok {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing columns: %s", strings.Join(missing, ", "))
+	}
+
+	return columns, nil
+}
+
+// csvTransfer maps record into transfer, all problems of the record are reported
+func csvTransfer(record []string, columns map[string]int) (core.Transfer, []string) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	errs := []string{}
+	for _, column := range []string{csvColumnName, csvColumnIBAN, csvColumnCurrency} {
+		if field(column) == "" {
+			errs = append(errs, column+" is required")
+		}
+	}
+	amount, err := core.ParseAmount(field(csvColumnAmount))
+	if err != nil {
+		errs = append(errs, fmt.Sprintf("invalid amount %s: %v", strconv.Quote(field(csvColumnAmount)), err))
+	} else if amount.Cents <= 0 {
+		errs = append(errs, fmt.Sprintf("amount must be positive, got %s", strconv.Quote(field(csvColumnAmount))))
+	}
+
+	return core.Transfer{
+		Amount:      amount,
+		Currency:    core.Currency(strings.ToUpper(field(csvColumnCurrency))),
+		Description: field(csvColumnDescription),
+		CounterParty: core.Party{
+			Name: field(csvColumnName),
+			BIC:  field(csvColumnBIC),
+			IBAN: field(csvColumnIBAN),
+		},
+	}, errs
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/spool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCSVTransfers(t *testing.T) {
+	cases := []struct {
+		name              string
+		content           string
+		expectedTransfers []core.Transfer
+		expectedErrors    []CSVRowError
+	}{
+		{
+			name: "happy",
+			content: "counterparty_name,counterparty_iban,counterparty_bic,amount,currency,description\n" +
+				"Bip Bip,EE383680981021245685,CRLYFRPPTOU,14.5,EUR,Wonderland/4410\n" +
+				"\"Coyote, Wile E\",DE9935420810036209081725212,ZDRPLBQI,61238,eur,\n",
+			expectedTransfers: []core.Transfer{
+				{
+					Amount:       core.Amount{Cents: 1450},
+					Currency:     core.CURRENCY_EURO,
+					Description:  "Wonderland/4410",
+					CounterParty: core.Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+				},
+				{
+					Amount:       core.Amount{Cents: 6123800},
+					Currency:     core.CURRENCY_EURO,
+					CounterParty: core.Party{Name: "Coyote, Wile E", BIC: "ZDRPLBQI", IBAN: "DE9935420810036209081725212"},
+				},
+			},
+		},
+		{
+			name: "spreadsheet export with semicolons, byte order mark and another column order",
+			content: "\xef\xbb\xbfAmount;Currency;Counterparty_Name;Counterparty_IBAN;Counterparty_BIC\r\n" +
+				"14.50;EUR;Bip Bip;EE383680981021245685;CRLYFRPPTOU\r\n",
+			expectedTransfers: []core.Transfer{
+				{
+					Amount:       core.Amount{Cents: 1450},
+					Currency:     core.CURRENCY_EURO,
+					CounterParty: core.Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+				},
+			},
+		},
+		{
+			name: "row errors are numbered",
+			content: "counterparty_name,counterparty_iban,counterparty_bic,amount,currency,description\n" +
+				"Bip Bip,EE383680981021245685,CRLYFRPPTOU,14.5,EUR,\n" +
+				",EE383680981021245685,CRLYFRPPTOU,14.555,EUR,\n" +
+				"Bip Bip,EE383680981021245685,CRLYFRPPTOU,14.5\n" +
+				"Bip Bip,,CRLYFRPPTOU,-1,EUR,\n",
+			expectedErrors: []CSVRowError{
+				{Row: 3, Message: "counterparty_name is required"},
+				{Row: 3, Message: `invalid amount "14.555": amount must contain at most 2 decimals after period`},
+				{Row: 4, Message: "expected 6 columns, got 4"},
+				{Row: 5, Message: "counterparty_iban is required"},
+				{Row: 5, Message: `amount must be positive, got "-1"`},
+			},
+		},
+		{
+			name:           "unknown column",
+			content:        "counterparty_name,counterparty_iban,counterparty_bic,amount,currency,reference\n",
+			expectedErrors: []CSVRowError{{Row: 1, Message: `unknown column "reference", expected columns are counterparty_name, counterparty_iban, counterparty_bic, amount, currency, description`}},
+		},
+		{
+			name:           "missing column",
+			content:        "counterparty_name,counterparty_iban,amount\n",
+			expectedErrors: []CSVRowError{{Row: 1, Message: "missing columns: counterparty_bic, currency"}},
+		},
+		{
+			name:           "empty file",
+			content:        "",
+			expectedErrors: []CSVRowError{{Row: 1, Message: "header is required"}},
+		},
+		{
+			name:           "no transfers",
+			content:        "counterparty_name,counterparty_iban,counterparty_bic,amount,currency\n",
+			expectedErrors: []CSVRowError{{Row: 2, Message: "at least one transfer is required"}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			transfers := spool.New("")
+			defer transfers.Close()
+			err := parseCSVTransfers(strings.NewReader(tc.content), transfers)
+			if tc.expectedErrors == nil {
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedTransfers, readTransfers(t, transfers))
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidCSV)
+			var csvErrs CSVErrors
+			require.True(t, errors.As(err, &csvErrs))
+			assert.Equal(t, tc.expectedErrors, []CSVRowError(csvErrs))
+		})
+	}
+}
+
+func TestParseCSVTransfersLimitsErrors(t *testing.T) {
+	content := "counterparty_name,counterparty_iban,counterparty_bic,amount,currency\n" +
+		strings.Repeat("Bip Bip,EE383680981021245685,CRLYFRPPTOU,abc,EUR\n", 1000)
+	transfers := spool.New("")
+	defer transfers.Close()
+	err := parseCSVTransfers(strings.NewReader(content), transfers)
+	var csvErrs CSVErrors
+	require.True(t, errors.As(err, &csvErrs))
+	assert.Len(t, csvErrs, maxCSVErrors)
+}
+
+func TestHandleTransfersCSV(t *testing.T) {
+	content := "counterparty_name,counterparty_iban,counterparty_bic,amount,currency,description\n" +
+		"Bip Bip,EE383680981021245685,CRLYFRPPTOU,14.5,EUR,Wonderland/4410\n"
+
+	testCases := []struct {
+		name            string
+		manager         *mockManager
+		query           string
+		body            string
+		expectedStatus  int
+		expectedCode    string
+		expectedDetails []ErrorDetail
+		expectedRequest *core.Request
+	}{
+		{
+			name:           "happy",
+			manager:        newMockManager(),
+			query:          "?organization_name=ACME+Corp&organization_bic=OIVUSCLQXXX&organization_iban=FR10474608000002006107XXXXX&allow_duplicates=true",
+			body:           content,
+			expectedStatus: http.StatusCreated,
+			expectedRequest: &core.Request{
+				Party: core.Party{Name: "ACME Corp", BIC: "OIVUSCLQXXX", IBAN: "FR10474608000002006107XXXXX"},
+				CreditTransfers: core.TransferList{
+					{
+						Amount:       core.Amount{Cents: 1450},
+						Currency:     core.CURRENCY_EURO,
+						Description:  "Wonderland/4410",
+						CounterParty: core.Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+					},
+				},
+				AllowDuplicates: true,
+				Mode:            core.MODE_ALL_OR_NOTHING,
+			},
+		},
+		{
+			name:           "best effort",
+			manager:        newMockManager().WithResult(core.ResultOf(core.TransferResult{Status: core.TRANSFER_ACCEPTED})),
+			query:          "?organization_iban=FR10474608000002006107XXXXX&mode=best_effort",
+			body:           content,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "missing debtor",
+			manager:        newMockManager(),
+			body:           content,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeMalformedInput,
+		},
+		{
+			name:           "invalid rows",
+			manager:        newMockManager(),
+			query:          "?organization_iban=FR10474608000002006107XXXXX",
+			body:           content + "Bip Bip,EE383680981021245685,CRLYFRPPTOU,1.001,EUR,\n",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidCSV,
+			expectedDetails: []ErrorDetail{
+				{Row: 3, Error: `invalid amount "1.001": amount must contain at most 2 decimals after period`},
+			},
+		},
+		{
+			name:           "business error",
+			manager:        newMockManager().WithError(core.ErrNotEnoughFunds),
+			query:          "?organization_iban=FR10474608000002006107XXXXX",
+			body:           content,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   CodeNotEnoughFunds,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			qapi := NewAPI(tc.manager)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "http://localhost/v1/transfers"+tc.query, strings.NewReader(tc.body))
+			r.Header.Set(HeaderContentType, "text/csv; charset=utf-8")
+			qapi.HandleTransfers(w, r)
+
+			body, _ := ioutil.ReadAll(w.Result().Body)
+			if !assert.Equal(t, tc.expectedStatus, w.Result().StatusCode) {
+				t.Error(string(body))
+			}
+			if tc.expectedCode != "" {
+				response := errorResponse{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, tc.expectedCode, response.Code)
+				if tc.expectedDetails != nil {
+					assert.Equal(t, tc.expectedDetails, response.Details)
+				}
+			}
+			if tc.expectedRequest != nil {
+				assert.Equal(t, tc.expectedRequest, tc.manager.request)
+			}
+		})
+	}
+}
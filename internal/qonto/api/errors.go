@@ -1,5 +1,7 @@
 package api
 
+import "github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+
 type Error string
 
 func (e Error) Error() string {
@@ -7,15 +9,77 @@ func (e Error) Error() string {
 }
 
 const (
-	ErrMalformedInput = Error("malformed input data")
+	ErrMalformedInput       = Error("malformed input data")
+	ErrUnsupportedMediaType = Error("unsupported media type")
+	ErrInvalidCSV           = Error("invalid CSV file")
 )
 
+// error codes returned to customers, so they can distinguish failures without parsing messages
+const (
+	CodeMalformedInput              = "malformed_input"
+	CodeInvalidMessage              = "invalid_message"
+	CodeInvalidCSV                  = "invalid_csv"
+	CodeUnsupportedMediaType        = "unsupported_media_type"
+	CodeInvalidCurrency             = "invalid_currency"
+	CodeInvalidAmount               = "invalid_amount"
+	CodeNotEnoughFunds              = "not_enough_funds"
+	CodeSingleTransferLimitExceeded = "single_transfer_limit_exceeded"
+	CodeDailyLimitExceeded          = "daily_limit_exceeded"
+	CodeMonthlyLimitExceeded        = "monthly_limit_exceeded"
+	CodeBatchSizeLimitExceeded      = "batch_size_limit_exceeded"
+	CodeTransferDenied              = "transfer_denied"
+	CodeNewBeneficiaryLargeAmount   = "denied_new_beneficiary_large_amount"
+	CodeRoundAmountBurst            = "denied_round_amount_burst"
+	CodeBlockedCountry              = "denied_blocked_country"
+	CodeDuplicateTransfer           = "duplicate_transfer"
+	CodeScreeningHit                = "screening_hit"
+	CodeScreeningHitNotFound        = "screening_hit_not_found"
+	CodeStatusReportNotFound        = "status_report_not_found"
+	CodeAccountNotFound             = "account_not_found"
+	CodeDuplicateIBAN               = "duplicate_iban"
+	CodeConstraintViolation         = "constraint_violation"
+	CodeConflict                    = "conflict"
+	CodeInvalidPeriod               = "invalid_period"
+	CodeUnsupportedMessage          = "unsupported_message"
+	CodeRateLimited                 = "rate_limited"
+	CodeTooManyRequestsInFlight     = "too_many_requests_in_flight"
+	CodeAccountQueueFull            = "account_queue_full"
+	CodeServiceUnavailable          = "service_unavailable"
+	CodeInternalError               = "internal_error"
+)
+
+// PROBLEM_TYPE_DEFAULT tells the problem has no semantics beyond its HTTP status, see RFC 7807 section 4.2
+const PROBLEM_TYPE_DEFAULT = "about:blank"
+
+// problemResponse is RFC 7807 problem details object, code is an extension member shared with errorResponse
+type problemResponse struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code,omitempty"`
+}
+
 type errorResponse struct {
-	Error string `json:"error,omitempty"`
+	Code    string        `json:"code,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Details []ErrorDetail `json:"details,omitempty"`
+	// RequestID lets customers refer to the failed request when contacting support
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ErrorDetail points to the part of ISO 20022 message the error concerns
+type ErrorDetail struct {
+	Row        int    `json:"row,omitempty"`
+	PmtInfId   string `json:"pmt_inf_id,omitempty"`
+	EndToEndId string `json:"end_to_end_id,omitempty"`
+	Error      string `json:"error"`
 }
 
-func wrapError(err error) *errorResponse {
+// wrapError hides IBANs and known names of the error message, nil redactor hides IBANs only
+func wrapError(err error, code string, redactor *qonto.Redactor) *errorResponse {
 	return &errorResponse{
-		Error: err.Error(),
+		Code:  code,
+		Error: redactor.Text(err.Error()),
 	}
 }
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+// supported export formats
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+
+	MediaTypeNDJSON = "application/x-ndjson"
+)
+
+type (
+	// exportColumn defines how transaction field is written in every format
+	exportColumn struct {
+		name string
+		text func(core.Transaction) string
+		json func(core.Transaction) interface{}
+	}
+
+	// exportWriter writes transactions in the export format
+	exportWriter interface {
+		WriteHeader() error
+		Write(core.Transaction) error
+		Flush() error
+	}
+
+	csvExportWriter struct {
+		writer  *csv.Writer
+		columns []exportColumn
+		record  []string
+	}
+
+	ndjsonExportWriter struct {
+		writer  io.Writer
+		columns []exportColumn
+		buf     []byte
+	}
+)
+
+// exportColumns lists all columns in default order, amounts are formatted by core.Amount in every format
+var exportColumns = []exportColumn{
+	{
+		name: "id",
+		text: func(tx core.Transaction) string { return strconv.FormatInt(tx.ID, 10) },
+		json: func(tx core.Transaction) interface{} { return tx.ID },
+	},
+	{
+		name: "created_at",
+		text: func(tx core.Transaction) string { return tx.CreatedAt.UTC().Format(time.RFC3339Nano) },
+		json: func(tx core.Transaction) interface{} { return tx.CreatedAt.UTC().Format(time.RFC3339Nano) },
+	},
+	{
+		name: "counterparty_name",
+		text: func(tx core.Transaction) string { return tx.CounterParty.Name },
+		json: func(tx core.Transaction) interface{} { return tx.CounterParty.Name },
+	},
+	{
+		name: "counterparty_iban",
+		text: func(tx core.Transaction) string { return tx.CounterParty.IBAN },
+		json: func(tx core.Transaction) interface{} { return tx.CounterParty.IBAN },
+	},
+	{
+		name: "counterparty_bic",
+		text: func(tx core.Transaction) string { return tx.CounterParty.BIC },
+		json: func(tx core.Transaction) interface{} { return tx.CounterParty.BIC },
+	},
+	{
+		name: "amount",
+		text: func(tx core.Transaction) string { return tx.Amount.String() },
+		json: func(tx core.Transaction) interface{} { return tx.Amount.String() },
+	},
+	{
+		name: "currency",
+		text: func(tx core.Transaction) string { return string(tx.Currency) },
+		json: func(tx core.Transaction) interface{} { return tx.Currency },
+	},
+	{
+		name: "description",
+		text: func(tx core.Transaction) string { return tx.Description },
+		json: func(tx core.Transaction) interface{} { return tx.Description },
+	},
+	{
+		name: "flagged_duplicate",
+		text: func(tx core.Transaction) string { return strconv.FormatBool(tx.FlaggedDuplicate) },
+		json: func(tx core.Transaction) interface{} { return tx.FlaggedDuplicate },
+	},
+}
+
+// parseExportColumns selects columns by comma separated names, all columns are selected by default
+func parseExportColumns(s string) ([]exportColumn, error) {
+	if strings.TrimSpace(s) == "" {
+		return exportColumns, nil
+	}
+
+	columns := []exportColumn{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, column := range exportColumns {
+			if column.name == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, 0, len(exportColumns))
+			for _, column := range exportColumns {
+				names = append(names, column.name)
+			}
+			return nil, fmt.Errorf("%w: unknown column %q, known columns are %s", ErrMalformedInput, name, strings.Join(names, ", "))
+		}
+	}
+
+	return columns, nil
+}
+
+func newExportWriter(format string, w io.Writer, columns []exportColumn) (exportWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return &csvExportWriter{writer: csv.NewWriter(w), columns: columns, record: make([]string, len(columns))}, nil
+	case ExportFormatNDJSON:
+		return &ndjsonExportWriter{writer: w, columns: columns}, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown export format %q, %s or %s expected", ErrMalformedInput, format, ExportFormatCSV, ExportFormatNDJSON)
+}
+
+func (cw *csvExportWriter) WriteHeader() error {
+	for i, column := range cw.columns {
+		cw.record[i] = column.name
+	}
+	return cw.writer.Write(cw.record)
+}
+
+func (cw *csvExportWriter) Write(tx core.Transaction) error {
+	for i, column := range cw.columns {
+		cw.record[i] = column.text(tx)
+	}
+	return cw.writer.Write(cw.record)
+}
+
+func (cw *csvExportWriter) Flush() error {
+	cw.writer.Flush()
+	return cw.writer.Error()
+}
+
+// WriteHeader does nothing, every line is self-describing
+func (nw *ndjsonExportWriter) WriteHeader() error {
+	return nil
+}
+
+// Write writes transaction as JSON object with keys in order of columns
+func (nw *ndjsonExportWriter) Write(tx core.Transaction) error {
+	nw.buf = append(nw.buf[:0], '{')
+	for i, column := range nw.columns {
+		if i > 0 {
+			nw.buf = append(nw.buf, ',')
+		}
+		value, err := json.Marshal(column.json(tx))
+		if err != nil {
+			return err
+		}
+		nw.buf = strconv.AppendQuote(nw.buf, column.name)
+		nw.buf = append(nw.buf, ':')
+		nw.buf = append(nw.buf, value...)
+	}
+	nw.buf = append(nw.buf, '}', '\n')
+
+	_, err := nw.writer.Write(nw.buf)
+	return err
+}
+
+func (nw *ndjsonExportWriter) Flush() error {
+	return nil
+}
@@ -1,51 +1,169 @@
 package api
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/spool"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/tracing"
 )
 
+// HandleTransfers processes bulk transfers request in format defined by Content-Type header:
+// JSON (default), ISO 20022 pain.001 XML or CSV
 func (qapi *qontoAPI) HandleTransfers(w http.ResponseWriter, r *http.Request) {
-	var request Request
-	err := json.NewDecoder(r.Body).Decode(&request)
+	mediaType, err := requestMediaType(r)
 	if err != nil {
-		handleErrors(w, r, fmt.Errorf("error decoding request: %w: %v", ErrMalformedInput, err))
+		handleErrors(w, r, err)
+		return
+	}
+	release, ok := qapi.admitClient(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	switch mediaType {
+	case MediaTypeJSON:
+		qapi.handleJSONTransfers(w, r)
+	case MediaTypeXML, MediaTypeTextXML:
+		qapi.handlePain001Transfers(w, r)
+	case MediaTypeCSV:
+		qapi.handleCSVTransfers(w, r)
+	default:
+		handleErrors(w, r, fmt.Errorf("%w: %s", ErrUnsupportedMediaType, mediaType))
+	}
+}
+
+func (qapi *qontoAPI) handleJSONTransfers(w http.ResponseWriter, r *http.Request) {
+	transfers := spool.New("")
+	defer closeSpool(r, transfers)
+
+	_, span := tracing.StartSpan(r.Context(), "decode request")
+	request, err := decodeJSONRequest(r.Body, transfers)
+	tracing.End(r.Context(), span, err)
+	if err != nil {
+		if !errors.Is(err, ErrMalformedInput) {
+			err = fmt.Errorf("error decoding request: %w: %v", ErrMalformedInput, err)
+		}
+		handleErrors(w, r, err)
+		return
+	}
+
+	qapi.processTransfers(w, r, request)
+}
+
+// handleCSVTransfers processes CSV file with a transfer per row, the debtor and processing options
+// are given by query parameters named as fields of JSON request
+func (qapi *qontoAPI) handleCSVTransfers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("organization_iban") == "" {
+		handleErrors(w, r, fmt.Errorf("%w: organization_iban query parameter is required", ErrMalformedInput))
+		return
+	}
+	mode, err := core.ParseMode(query.Get("mode"))
+	if err != nil {
+		handleErrors(w, r, fmt.Errorf("%w: %v", ErrMalformedInput, err))
+		return
+	}
+	allowDuplicates := false
+	if value := query.Get("allow_duplicates"); value != "" {
+		if allowDuplicates, err = strconv.ParseBool(value); err != nil {
+			handleErrors(w, r, fmt.Errorf("invalid allow_duplicates: %w: %v", ErrMalformedInput, err))
+			return
+		}
+	}
+
+	transfers := spool.New("")
+	defer closeSpool(r, transfers)
+
+	_, span := tracing.StartSpan(r.Context(), "decode request")
+	err = parseCSVTransfers(r.Body, transfers)
+	tracing.End(r.Context(), span, err)
+	if err != nil {
+		handleErrors(w, r, err)
 		return
 	}
 
-	coreRequest := core.Request{
+	qapi.processTransfers(w, r, &core.Request{
 		Party: core.Party{
-			Name: request.OrganizationName,
-			BIC:  request.OrganizationBIC,
-			IBAN: request.OrganizationIBAN,
+			Name: query.Get("organization_name"),
+			BIC:  query.Get("organization_bic"),
+			IBAN: query.Get("organization_iban"),
 		},
-		CreditTransfers: make([]core.Transfer, 0, len(request.CreditTransfers)),
+		CreditTransfers: transfers,
+		AllowDuplicates: allowDuplicates,
+		Mode:            mode,
+	})
+}
+
+// closeSpool removes transfers of the request spilled to disk once it is answered
+func closeSpool(r *http.Request, transfers io.Closer) {
+	if err := transfers.Close(); err != nil {
+		qonto.LoggerFromContext(r.Context()).Error("error removing spooled transfers", "error", err)
 	}
+}
 
-	for _, transfer := range request.CreditTransfers {
-		if transfer.Currency != string(core.CURRENCY_EURO) {
-			handleErrors(w, r, core.ErrInvalidCurrency)
-		}
-		coreRequest.CreditTransfers = append(coreRequest.CreditTransfers,
-			core.Transfer{
-				Amount:   transfer.Amount,
-				Currency: core.Currency(transfer.Currency),
-				CounterParty: core.Party{
-					Name: transfer.CounterpartyName,
-					BIC:  transfer.CounterpartyBIC,
-					IBAN: transfer.CounterpartyIBAN,
-				},
-				Description: transfer.Description,
-			})
-	}
-	if err := qapi.manager.ProcessTransfers(r.Context(), &coreRequest); err != nil {
+// processTransfers executes the request and responds with its outcome
+func (qapi *qontoAPI) processTransfers(w http.ResponseWriter, r *http.Request, request *core.Request) {
+	// neither spans nor responses of the request may repeat names of its parties
+	redactor := request.Redactor()
+	r = r.WithContext(qonto.ContextWithRedactor(r.Context(), redactor))
+
+	release, ok := qapi.throttle(w, r, request.Party.IBAN)
+	if !ok {
+		return
+	}
+	defer release()
+
+	result, err := qapi.manager.ProcessTransfers(r.Context(), request)
+	if err != nil {
 		handleErrors(w, r, err)
 		return
 	}
 
+	if request.Mode == core.MODE_BEST_EFFORT {
+		Respond(w, r, bulkResponse(request.Mode, result, redactor))
+		return
+	}
+	// the other transfers are executed, held ones wait for screening hits to be cleared
+	if result.Held() > 0 {
+		RespondCode(w, r, http.StatusAccepted, bulkResponse(request.Mode, result, redactor))
+		return
+	}
+
 	RespondCode(w, r, http.StatusCreated, "operation succeeded")
+}
+
+func bulkResponse(mode core.Mode, result *core.Result, redactor *qonto.Redactor) *BulkResponse {
+	response := &BulkResponse{
+		Mode:    string(mode),
+		Results: make([]TransferResult, 0, result.Len()),
+	}
+	for i := 0; i < result.Len(); i++ {
+		transfer := result.Transfer(i)
+		item := TransferResult{
+			Index:  i,
+			Status: string(transfer.Status),
+		}
+		if transfer.Err != nil {
+			_, item.Code = errorStatus(transfer.Err)
+			item.Error = redactor.Text(transfer.Err.Error())
+		}
+		switch transfer.Status {
+		case core.TRANSFER_ACCEPTED:
+			response.Accepted++
+		case core.TRANSFER_HELD:
+			response.Held++
+		default:
+			response.Rejected++
+		}
+		response.Results = append(response.Results, item)
+	}
 
+	return response
 }
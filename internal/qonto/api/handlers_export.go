@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+// exportFlushRows is the number of rows sent to the client at once
+const exportFlushRows = 100
+
+// HandleExportTransactions streams transactions of the account identified by iban URL parameter.
+// Query parameters: format is either csv (default) or ndjson, columns is a comma separated list of columns,
+// from and to are dates or RFC 3339 times limiting the period
+func (qapi *qontoAPI) HandleExportTransactions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = ExportFormatCSV
+	}
+	columns, err := parseExportColumns(query.Get("columns"))
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+	writer, err := newExportWriter(format, w, columns)
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+	var from, to time.Time
+	if value := query.Get("from"); value != "" {
+		if from, err = core.ParsePeriodBoundary(value, false); err != nil {
+			handleErrors(w, r, err)
+			return
+		}
+	}
+	if value := query.Get("to"); value != "" {
+		if to, err = core.ParsePeriodBoundary(value, true); err != nil {
+			handleErrors(w, r, err)
+			return
+		}
+	}
+
+	iban := chi.URLParam(r, "iban")
+	// response starts with the first transaction, so errors found before it are reported as usual
+	started := false
+	start := func() error {
+		started = true
+		mediaType := MediaTypeCSV
+		if format == ExportFormatNDJSON {
+			mediaType = MediaTypeNDJSON
+		}
+		w.Header().Set(HeaderContentType, mediaType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "transactions-"+iban+"."+format))
+		w.WriteHeader(http.StatusOK)
+		return writer.WriteHeader()
+	}
+	flush := func() error {
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	rows := 0
+	err = qapi.history.EachTransaction(r.Context(), iban, from, to, func(tx core.Transaction) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(tx); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushRows == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		handleErrors(w, r, err)
+		return
+	}
+	if err != nil {
+		// status is already sent, so the client only sees truncated response
+		qonto.LoggerFromContext(r.Context()).Error("error exporting transactions", "rows", rows, "error", err)
+		_ = flush()
+		return
+	}
+
+	if !started {
+		if err := start(); err != nil {
+			qonto.LoggerFromContext(r.Context()).Error("error writing response", "error", err)
+			return
+		}
+	}
+	if err := flush(); err != nil {
+		qonto.LoggerFromContext(r.Context()).Error("error writing response", "error", err)
+	}
+}
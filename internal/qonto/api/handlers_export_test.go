@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleExportTransactions(t *testing.T) {
+	createdAt := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	transactions := []core.Transaction{
+		{
+			ID:           1,
+			Amount:       core.Amount{Cents: 1450},
+			Currency:     core.CURRENCY_EURO,
+			Description:  "Wonderland/4410",
+			CounterParty: core.Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+			CreatedAt:    createdAt,
+		},
+		{
+			ID:               2,
+			Amount:           core.Amount{Cents: 6123800},
+			Currency:         core.CURRENCY_EURO,
+			Description:      `Invoice "12", June`,
+			CounterParty:     core.Party{Name: "Wile E Coyote", BIC: "ZDRPLBQI", IBAN: "DE9935420810036209081725212"},
+			CreatedAt:        createdAt.Add(time.Hour),
+			FlaggedDuplicate: true,
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		manager        *mockHistoryManager
+		url            string
+		expectedStatus int
+		expectedCode   string
+		expectedType   string
+		expectedBody   string
+		expectedFrom   time.Time
+		expectedTo     time.Time
+	}{
+		{
+			name:           "csv with all columns",
+			manager:        newMockHistoryManager(transactions...),
+			url:            "/v1/accounts/FR10474608000002006107XXXXX/transactions/export",
+			expectedStatus: http.StatusOK,
+			expectedType:   MediaTypeCSV,
+			expectedBody: "id,created_at,counterparty_name,counterparty_iban,counterparty_bic,amount,currency,description,flagged_duplicate\n" +
+				"1,2022-06-01T10:00:00Z,Bip Bip,EE383680981021245685,CRLYFRPPTOU,14.50,EUR,Wonderland/4410,false\n" +
+				"2,2022-06-01T11:00:00Z,Wile E Coyote,DE9935420810036209081725212,ZDRPLBQI,61238.00,EUR,\"Invoice \"\"12\"\", June\",true\n",
+		},
+		{
+			name:           "ndjson with selected columns and period",
+			manager:        newMockHistoryManager(transactions...),
+			url:            "/v1/accounts/FR10474608000002006107XXXXX/transactions/export?format=ndjson&columns=amount,id,description,flagged_duplicate&from=2022-06-01&to=2022-06-30",
+			expectedStatus: http.StatusOK,
+			expectedType:   MediaTypeNDJSON,
+			expectedBody: `{"amount":"14.50","id":1,"description":"Wonderland/4410","flagged_duplicate":false}` + "\n" +
+				`{"amount":"61238.00","id":2,"description":"Invoice \"12\", June","flagged_duplicate":true}` + "\n",
+			expectedFrom: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "empty history",
+			manager:        newMockHistoryManager(),
+			url:            "/v1/accounts/FR10474608000002006107XXXXX/transactions/export?columns=id,amount",
+			expectedStatus: http.StatusOK,
+			expectedType:   MediaTypeCSV,
+			expectedBody:   "id,amount\n",
+		},
+		{
+			name:           "failure after first rows truncates response",
+			manager:        newMockHistoryManager(transactions[0]).WithError(errors.New("connection reset")),
+			url:            "/v1/accounts/FR10474608000002006107XXXXX/transactions/export?columns=id",
+			expectedStatus: http.StatusOK,
+			expectedType:   MediaTypeCSV,
+			expectedBody:   "id\n1\n",
+		},
+		{
+			name:           "unknown account",
+			manager:        newMockHistoryManager().WithError(core.ErrAccountNotFound),
+			url:            "/v1/accounts/FR00/transactions/export",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeAccountNotFound,
+		},
+		{
+			name:           "unknown column",
+			manager:        newMockHistoryManager(transactions...),
+			url:            "/v1/accounts/FR10474608000002006107XXXXX/transactions/export?columns=id,balance",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeMalformedInput,
+		},
+		{
+			name:           "unknown format",
+			manager:        newMockHistoryManager(transactions...),
+			url:            "/v1/accounts/FR10474608000002006107XXXXX/transactions/export?format=xlsx",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeMalformedInput,
+		},
+		{
+			name:           "invalid period",
+			manager:        newMockHistoryManager(transactions...),
+			url:            "/v1/accounts/FR10474608000002006107XXXXX/transactions/export?from=yesterday",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidPeriod,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			qapi := NewAPI(newMockManager()).WithHistoryManager(tc.manager)
+			router := chi.NewRouter()
+			router.Get("/v1/accounts/{iban}/transactions/export", qapi.HandleExportTransactions)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			body, _ := ioutil.ReadAll(w.Result().Body)
+			if !assert.Equal(t, tc.expectedStatus, w.Result().StatusCode) {
+				t.Error(string(body))
+			}
+			if tc.expectedCode != "" {
+				response := errorResponse{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, tc.expectedCode, response.Code)
+				return
+			}
+			assert.Equal(t, tc.expectedType, w.Result().Header.Get(HeaderContentType))
+			assert.Equal(t, tc.expectedBody, string(body))
+			assert.Equal(t, tc.expectedFrom, tc.manager.from)
+			assert.Equal(t, tc.expectedTo, tc.manager.to)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/iso20022"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/tracing"
+)
+
+// handlePain001Transfers processes pain.001 message.
+// Every payment information block is processed as a separate all-or-nothing request
+func (qapi *qontoAPI) handlePain001Transfers(w http.ResponseWriter, r *http.Request) {
+	_, span := tracing.StartSpan(r.Context(), "decode request")
+	message, err := iso20022.ParsePain001(r.Body)
+	tracing.End(r.Context(), span, err)
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+	defer closeSpool(r, message)
+	if err := message.Validate(); err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+	requests := message.Requests()
+	// payment information blocks may debit different accounts, the message is admitted by all of them
+	ibans := make([]string, 0, len(requests))
+	for _, request := range requests {
+		ibans = append(ibans, request.Party.IBAN)
+	}
+	release, ok := qapi.throttle(w, r, ibans...)
+	if !ok {
+		return
+	}
+	defer release()
+
+	response := Pain001Response{
+		MessageId: message.GrpHdr.MsgId,
+		Payments:  make([]PaymentResult, 0, len(requests)),
+	}
+	outcomes := make([]iso20022.PaymentOutcome, len(requests))
+	for i, request := range requests {
+		payment := PaymentResult{
+			PmtInfId: message.PmtInf[i].PmtInfId,
+			Status:   string(core.TRANSFER_ACCEPTED),
+		}
+		redactor := request.Redactor()
+		ctx := qonto.ContextWithRedactor(r.Context(), redactor)
+		result, err := qapi.manager.ProcessTransfers(ctx, request)
+		outcomes[i] = iso20022.PaymentOutcome{Err: err, Result: result}
+		switch {
+		case err != nil:
+			_, payment.Code = errorStatus(err)
+			payment.Status = string(core.TRANSFER_REJECTED)
+			payment.Error = redactor.Text(err.Error())
+			response.Rejected++
+		case result.Held() > 0:
+			// the block is not executed completely until screening hits of held transfers are cleared
+			for j := 0; j < result.Len(); j++ {
+				if transfer := result.Transfer(j); transfer.Status == core.TRANSFER_HELD {
+					_, payment.Code = errorStatus(transfer.Err)
+					payment.Error = redactor.Text(transfer.Err.Error())
+					break
+				}
+			}
+			payment.Status = string(core.TRANSFER_HELD)
+			response.Held++
+		default:
+			response.Accepted++
+		}
+		response.Payments = append(response.Payments, payment)
+	}
+
+	if qapi.reports != nil {
+		// transfers are already executed, so failed report must not hide their outcomes
+		if response.StatusReportId, err = qapi.saveStatusReport(r.Context(), message, outcomes); err != nil {
+			qonto.LoggerFromContext(r.Context()).Error("error saving status report", "message_id", message.GrpHdr.MsgId, "error", err)
+		}
+	}
+
+	status := http.StatusCreated
+	switch {
+	case response.Rejected == len(requests):
+		status = http.StatusUnprocessableEntity
+	case response.Rejected > 0:
+		status = http.StatusOK
+	case response.Held > 0:
+		status = http.StatusAccepted
+	}
+	RespondCode(w, r, status, response)
+}
+
+// saveStatusReport generates pain.002 report of the processed message and stores it
+func (qapi *qontoAPI) saveStatusReport(ctx context.Context, message *iso20022.Pain001, outcomes []iso20022.PaymentOutcome) (int64, error) {
+	now := time.Now()
+	messageID, err := iso20022.NewMessageID("STS", now)
+	if err != nil {
+		return 0, err
+	}
+
+	pain002, err := iso20022.NewPain002(message, outcomes, messageID, now)
+	if err != nil {
+		return 0, err
+	}
+	content, err := pain002.Marshal()
+	if err != nil {
+		return 0, err
+	}
+
+	report := &core.StatusReport{
+		MessageID:         messageID,
+		OriginalMessageID: message.GrpHdr.MsgId,
+		GroupStatus:       pain002.GroupStatus(),
+		Content:           content,
+	}
+	if err := qapi.reports.SaveStatusReport(ctx, report); err != nil {
+		return 0, err
+	}
+
+	return report.ID, nil
+}
+
+// HandleStatusReport downloads pain.002 status report identified by id URL parameter
+func (qapi *qontoAPI) HandleStatusReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		handleErrors(w, r, fmt.Errorf("invalid report id: %w: %v", ErrMalformedInput, err))
+		return
+	}
+
+	report, err := qapi.reports.FindStatusReport(r.Context(), id)
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+
+	w.Header().Set(HeaderContentType, MediaTypeXML)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", report.MessageID+".xml"))
+	if _, err := w.Write(report.Content); err != nil {
+		qonto.LoggerFromContext(r.Context()).Error("error writing response", "error", err)
+	}
+}
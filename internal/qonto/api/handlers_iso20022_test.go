@@ -0,0 +1,241 @@
+package api
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/iso20022"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleTransfersPain001(t *testing.T) {
+	sample, err := os.ReadFile("../iso20022/testdata/pain001.xml")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name             string
+		api              *qontoAPI
+		contentType      string
+		body             string
+		expectedStatus   int
+		expectedCode     string
+		expectedDetails  []ErrorDetail
+		expectedPayments []PaymentResult
+	}{
+		{
+			name:           "happy",
+			api:            NewAPI(newMockManager()),
+			contentType:    "application/xml; charset=utf-8",
+			body:           string(sample),
+			expectedStatus: http.StatusCreated,
+			expectedPayments: []PaymentResult{
+				{PmtInfId: "PMT-1", Status: "accepted"},
+				{PmtInfId: "PMT-2", Status: "accepted"},
+			},
+		},
+		{
+			name:           "all payments rejected",
+			api:            NewAPI(newMockManager().WithError(core.ErrNotEnoughFunds)),
+			contentType:    "text/xml",
+			body:           string(sample),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedPayments: []PaymentResult{
+				{PmtInfId: "PMT-1", Status: "rejected", Code: CodeNotEnoughFunds, Error: core.ErrNotEnoughFunds.Error()},
+				{PmtInfId: "PMT-2", Status: "rejected", Code: CodeNotEnoughFunds, Error: core.ErrNotEnoughFunds.Error()},
+			},
+		},
+		{
+			name: "payment held by screening hit",
+			api: NewAPI(newMockManager().WithResult(core.ResultOf(
+				core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+				core.TransferResult{Status: core.TRANSFER_HELD, Err: core.ErrScreeningHit},
+			))),
+			contentType:    "application/xml",
+			body:           string(sample),
+			expectedStatus: http.StatusAccepted,
+			expectedPayments: []PaymentResult{
+				{PmtInfId: "PMT-1", Status: "held", Code: CodeScreeningHit, Error: core.ErrScreeningHit.Error()},
+				{PmtInfId: "PMT-2", Status: "held", Code: CodeScreeningHit, Error: core.ErrScreeningHit.Error()},
+			},
+		},
+		{
+			name:           "unknown elements are ignored",
+			api:            NewAPI(newMockManager()),
+			contentType:    "application/xml",
+			body:           strings.Replace(string(sample), "<EndToEndId>E2E-2</EndToEndId>", "<EndToEndId>E2E-2</EndToEndId><Unknown/>", 1),
+			expectedStatus: http.StatusCreated,
+			expectedPayments: []PaymentResult{
+				{PmtInfId: "PMT-1", Status: "accepted"},
+				{PmtInfId: "PMT-2", Status: "accepted"},
+			},
+		},
+		{
+			name:           "control sum mismatch",
+			api:            NewAPI(newMockManager()),
+			contentType:    "application/xml",
+			body:           strings.Replace(string(sample), `<InstdAmt Ccy="EUR">999</InstdAmt>`, `<InstdAmt Ccy="EUR">998</InstdAmt>`, 1),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidMessage,
+			expectedDetails: []ErrorDetail{
+				{Error: "GrpHdr/CtrlSum is 62251.5, but sum of transactions is 62250.50"},
+			},
+		},
+		{
+			name:           "missing end to end id",
+			api:            NewAPI(newMockManager()),
+			contentType:    "application/xml",
+			body:           strings.Replace(string(sample), "<EndToEndId>E2E-3</EndToEndId>", "<EndToEndId></EndToEndId>", 1),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidMessage,
+			expectedDetails: []ErrorDetail{
+				{PmtInfId: "PMT-2", Error: "PmtId/EndToEndId is required"},
+			},
+		},
+		{
+			name:           "malformed XML",
+			api:            NewAPI(newMockManager()),
+			contentType:    "application/xml",
+			body:           "<Document>",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidMessage,
+		},
+		{
+			name:           "unsupported media type",
+			api:            NewAPI(newMockManager()),
+			contentType:    "application/pdf",
+			body:           "%PDF",
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   CodeUnsupportedMediaType,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(tc.body))
+			r.Header.Set(HeaderContentType, tc.contentType)
+			tc.api.HandleTransfers(w, r)
+
+			body, _ := ioutil.ReadAll(w.Result().Body)
+			if !assert.Equal(t, tc.expectedStatus, w.Result().StatusCode) {
+				t.Error(string(body))
+			}
+			if tc.expectedCode != "" {
+				response := errorResponse{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, tc.expectedCode, response.Code)
+				if tc.expectedDetails != nil {
+					assert.Equal(t, tc.expectedDetails, response.Details)
+				}
+			}
+			if tc.expectedPayments != nil {
+				response := Pain001Response{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, "MSG-2022-06-001", response.MessageId)
+				assert.Equal(t, tc.expectedPayments, response.Payments)
+			}
+		})
+	}
+}
+
+func TestHandleStatusReport(t *testing.T) {
+	sample, err := os.ReadFile("../iso20022/testdata/pain001.xml")
+	require.NoError(t, err)
+
+	reports := newMockReportManager()
+	qapi := NewAPI(newMockManager().WithError(core.ErrNotEnoughFunds)).WithReportManager(reports)
+	router := chi.NewRouter()
+	router.Post("/v1/transfers", qapi.HandleTransfers)
+	router.Get("/v1/status-reports/{id}", qapi.HandleStatusReport)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/v1/transfers", strings.NewReader(string(sample)))
+	r.Header.Set(HeaderContentType, MediaTypeXML)
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Result().StatusCode)
+	upload := Pain001Response{}
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&upload))
+	require.Equal(t, int64(1), upload.StatusReportId)
+	assert.Equal(t, "MSG-2022-06-001", reports.reports[0].OriginalMessageID)
+	assert.Equal(t, iso20022.STATUS_REJECTED, reports.reports[0].GroupStatus)
+
+	testCases := []struct {
+		name           string
+		url            string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "existing report",
+			url:            "/v1/status-reports/1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unknown report",
+			url:            "/v1/status-reports/2",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeStatusReportNotFound,
+		},
+		{
+			name:           "invalid id",
+			url:            "/v1/status-reports/abc",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeMalformedInput,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			body, _ := ioutil.ReadAll(w.Result().Body)
+			if !assert.Equal(t, tc.expectedStatus, w.Result().StatusCode) {
+				t.Error(string(body))
+			}
+			if tc.expectedCode != "" {
+				response := errorResponse{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, tc.expectedCode, response.Code)
+				return
+			}
+
+			assert.Equal(t, MediaTypeXML, w.Result().Header.Get(HeaderContentType))
+			report := struct {
+				OrgnlMsgId string   `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>OrgnlMsgId"`
+				GrpSts     string   `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>GrpSts"`
+				Reasons    []string `xml:"CstmrPmtStsRpt>OrgnlPmtInfAndSts>StsRsnInf>Rsn>Cd"`
+			}{}
+			require.NoError(t, xml.Unmarshal(body, &report))
+			assert.Equal(t, "MSG-2022-06-001", report.OrgnlMsgId)
+			assert.Equal(t, iso20022.STATUS_REJECTED, report.GrpSts)
+			assert.Equal(t, []string{iso20022.REASON_INSUFFICIENT_FUNDS, iso20022.REASON_INSUFFICIENT_FUNDS}, report.Reasons)
+		})
+	}
+}
+
+func TestHandleTransfersPain001ReportFailure(t *testing.T) {
+	sample, err := os.ReadFile("../iso20022/testdata/pain001.xml")
+	require.NoError(t, err)
+
+	qapi := NewAPI(newMockManager()).WithReportManager(newMockReportManager().WithError(errors.New("connection refused")))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(string(sample)))
+	r.Header.Set(HeaderContentType, MediaTypeXML)
+	qapi.HandleTransfers(w, r)
+
+	// transfers are executed, so their outcomes are reported anyway
+	require.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	response := Pain001Response{}
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&response))
+	assert.Equal(t, 2, response.Accepted)
+	assert.Zero(t, response.StatusReportId)
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+// HandleListScreeningHits lists screening hits, optionally filtered by status query parameter
+func (qapi *qontoAPI) HandleListScreeningHits(w http.ResponseWriter, r *http.Request) {
+	hits, err := qapi.screening.ListHits(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+
+	response := make([]ScreeningHit, 0, len(hits))
+	for _, hit := range hits {
+		item := ScreeningHit{
+			ID:               hit.ID,
+			CounterpartyName: hit.CounterParty.Name,
+			CounterpartyBIC:  hit.CounterParty.BIC,
+			CounterpartyIBAN: hit.CounterParty.IBAN,
+			EntryID:          hit.EntryID,
+			EntryName:        hit.EntryName,
+			MatchedField:     hit.MatchedField,
+			Score:            hit.Score,
+			Status:           hit.Status,
+			CreatedAt:        hit.CreatedAt,
+		}
+		if !hit.ClearedAt.IsZero() {
+			clearedAt := hit.ClearedAt
+			item.ClearedAt = &clearedAt
+		}
+		response = append(response, item)
+	}
+
+	Respond(w, r, response)
+}
+
+// HandleClearScreeningHit clears open screening hit identified by id URL parameter
+func (qapi *qontoAPI) HandleClearScreeningHit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		handleErrors(w, r, fmt.Errorf("invalid hit id: %w: %v", ErrMalformedInput, err))
+		return
+	}
+
+	if err := qapi.screening.ClearHit(r.Context(), id); err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+
+	Respond(w, r, "hit cleared")
+}
+
+// HandleReloadScreeningList reloads sanctions list from its source
+func (qapi *qontoAPI) HandleReloadScreeningList(w http.ResponseWriter, r *http.Request) {
+	if err := qapi.screening.ReloadList(); err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+
+	Respond(w, r, "list reloaded")
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleScreeningHits(t *testing.T) {
+	hits := []core.ScreeningHit{
+		{
+			ID:           1,
+			CounterParty: core.Party{Name: "Bugs Bunny", IBAN: "FR0010009380540930414023042"},
+			EntryID:      "EU.1",
+			EntryName:    "Bugs Bunny",
+			MatchedField: "name",
+			Score:        1,
+			Status:       "open",
+			CreatedAt:    time.Now(),
+		},
+		{
+			ID:           2,
+			CounterParty: core.Party{Name: "Wile E Coyote", IBAN: "DE9935420810036209081725212"},
+			EntryID:      "EU.2",
+			EntryName:    "Coyote",
+			MatchedField: "name",
+			Score:        0.9,
+			Status:       "cleared",
+			CreatedAt:    time.Now(),
+			ClearedAt:    time.Now(),
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		manager        *mockScreeningManager
+		method         string
+		url            string
+		expectedStatus int
+		expectedCode   string
+		expectedHits   int
+	}{
+		{
+			name:           "list all hits",
+			manager:        newMockScreeningManager(hits...),
+			method:         http.MethodGet,
+			url:            "/v1/screening/hits",
+			expectedStatus: http.StatusOK,
+			expectedHits:   2,
+		},
+		{
+			name:           "list open hits",
+			manager:        newMockScreeningManager(hits...),
+			method:         http.MethodGet,
+			url:            "/v1/screening/hits?status=open",
+			expectedStatus: http.StatusOK,
+			expectedHits:   1,
+		},
+		{
+			name:           "clear open hit",
+			manager:        newMockScreeningManager(hits...),
+			method:         http.MethodPost,
+			url:            "/v1/screening/hits/1/clear",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "clear already cleared hit",
+			manager:        newMockScreeningManager(hits...),
+			method:         http.MethodPost,
+			url:            "/v1/screening/hits/2/clear",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeScreeningHitNotFound,
+		},
+		{
+			name:           "clear hit with invalid id",
+			manager:        newMockScreeningManager(hits...),
+			method:         http.MethodPost,
+			url:            "/v1/screening/hits/abc/clear",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeMalformedInput,
+		},
+		{
+			name:           "reload list",
+			manager:        newMockScreeningManager(),
+			method:         http.MethodPost,
+			url:            "/v1/screening/list/reload",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "reload broken list",
+			manager:        newMockScreeningManager().WithError(errors.New("cannot load sanctions list")),
+			method:         http.MethodPost,
+			url:            "/v1/screening/list/reload",
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   CodeInternalError,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			qapi := NewAPI(newMockManager()).WithScreeningManager(tc.manager)
+			router := chi.NewRouter()
+			router.Get("/v1/screening/hits", qapi.HandleListScreeningHits)
+			router.Post("/v1/screening/hits/{id}/clear", qapi.HandleClearScreeningHit)
+			router.Post("/v1/screening/list/reload", qapi.HandleReloadScreeningList)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, tc.url, nil)
+			router.ServeHTTP(w, r)
+
+			body, _ := ioutil.ReadAll(w.Result().Body)
+			if !assert.Equal(t, tc.expectedStatus, w.Result().StatusCode) {
+				t.Error(string(body))
+			}
+			if tc.expectedCode != "" {
+				response := errorResponse{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, tc.expectedCode, response.Code)
+			}
+			if tc.expectedHits > 0 {
+				response := []ScreeningHit{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Len(t, response, tc.expectedHits)
+			}
+		})
+	}
+}
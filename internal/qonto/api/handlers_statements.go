@@ -0,0 +1,56 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/iso20022"
+)
+
+// HandleStatement generates ISO 20022 statement of the account identified by iban URL parameter.
+// Query parameters: type is either camt.053 (default) or camt.052, from and to are dates or RFC 3339 times
+func (qapi *qontoAPI) HandleStatement(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	messageType := query.Get("type")
+	if messageType == "" {
+		messageType = iso20022.MESSAGE_CAMT053
+	}
+
+	now := time.Now()
+	from, to, err := iso20022.StatementPeriod(messageType, query.Get("from"), query.Get("to"), now)
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+
+	statement, err := qapi.statements.Statement(r.Context(), chi.URLParam(r, "iban"), from, to)
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+
+	messageID, err := iso20022.NewMessageID("STM", now)
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+	message, err := iso20022.NewStatementMessage(messageType, statement, messageID, now)
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+	content, err := message.Marshal()
+	if err != nil {
+		handleErrors(w, r, err)
+		return
+	}
+
+	w.Header().Set(HeaderContentType, MediaTypeXML)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", messageID+".xml"))
+	if _, err := w.Write(content); err != nil {
+		qonto.LoggerFromContext(r.Context()).Error("error writing response", "error", err)
+	}
+}
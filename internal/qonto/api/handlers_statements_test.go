@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleStatement(t *testing.T) {
+	testCases := []struct {
+		name             string
+		manager          *mockStatementManager
+		url              string
+		expectedStatus   int
+		expectedCode     string
+		expectedDocument string
+		expectedFrom     time.Time
+		expectedTo       time.Time
+	}{
+		{
+			name:             "end of day statements",
+			manager:          newMockStatementManager(),
+			url:              "/v1/accounts/FR10474608000002006107XXXXX/statements?from=2022-06-01&to=2022-06-02",
+			expectedStatus:   http.StatusOK,
+			expectedDocument: "BkToCstmrStmt",
+			expectedFrom:     time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:       time.Date(2022, 6, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:             "intraday report",
+			manager:          newMockStatementManager(),
+			url:              "/v1/accounts/FR10474608000002006107XXXXX/statements?type=camt.052&from=2022-06-01T08:00:00Z&to=2022-06-01T12:00:00Z",
+			expectedStatus:   http.StatusOK,
+			expectedDocument: "BkToCstmrAcctRpt",
+			expectedFrom:     time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC),
+			expectedTo:       time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:           "unknown account",
+			manager:        newMockStatementManager().WithError(core.ErrAccountNotFound),
+			url:            "/v1/accounts/FR00/statements",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   CodeAccountNotFound,
+		},
+		{
+			name:           "invalid period",
+			manager:        newMockStatementManager(),
+			url:            "/v1/accounts/FR10474608000002006107XXXXX/statements?from=2022-06-02&to=2022-06-01",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeInvalidPeriod,
+		},
+		{
+			name:           "unsupported message type",
+			manager:        newMockStatementManager(),
+			url:            "/v1/accounts/FR10474608000002006107XXXXX/statements?type=camt.054",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeUnsupportedMessage,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			qapi := NewAPI(newMockManager()).WithStatementManager(tc.manager)
+			router := chi.NewRouter()
+			router.Get("/v1/accounts/{iban}/statements", qapi.HandleStatement)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			body, _ := ioutil.ReadAll(w.Result().Body)
+			if !assert.Equal(t, tc.expectedStatus, w.Result().StatusCode) {
+				t.Error(string(body))
+			}
+			if tc.expectedCode != "" {
+				response := errorResponse{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, tc.expectedCode, response.Code)
+				return
+			}
+
+			assert.Equal(t, MediaTypeXML, w.Result().Header.Get(HeaderContentType))
+			assert.Equal(t, "FR10474608000002006107XXXXX", tc.manager.iban)
+			assert.Equal(t, tc.expectedFrom, tc.manager.from)
+			assert.Equal(t, tc.expectedTo, tc.manager.to)
+			document := struct {
+				Content []struct {
+					XMLName xml.Name
+				} `xml:",any"`
+			}{}
+			require.NoError(t, xml.Unmarshal(body, &document))
+			require.Len(t, document.Content, 1)
+			assert.Equal(t, tc.expectedDocument, document.Content[0].XMLName.Local)
+		})
+	}
+}
@@ -1,14 +1,27 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/iso20022"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandleTransfers(t *testing.T) {
@@ -17,6 +30,7 @@ func TestHandleTransfers(t *testing.T) {
 		body           string
 		api            *qontoAPI
 		expectedStatus int
+		expectedCode   string
 	}{
 		{
 			name: "happy",
@@ -93,6 +107,30 @@ func TestHandleTransfers(t *testing.T) {
 			  }
 			`,
 			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   CodeNotEnoughFunds,
+		},
+		{
+			name: "manager returns daily limit error",
+			api:  NewAPI(newMockManager().WithError(fmt.Errorf("%w: organization", core.ErrDailyLimitExceeded))),
+			body: `
+			{
+				"organization_name": "ACME Corp",
+				"organization_bic": "OIVUSCLQXXX",
+				"organization_iban": "FR10474608000002006107XXXXX",
+				"credit_transfers": [
+				  {
+					"amount": "14.5",
+					"currency": "EUR",
+					"counterparty_name": "Bip Bip",
+					"counterparty_bic": "CRLYFRPPTOU",
+					"counterparty_iban": "EE383680981021245685",
+					"description": "Wonderland/4410"
+				  }
+				]
+			  }
+			`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   CodeDailyLimitExceeded,
 		},
 		{
 			name: "empty input is not valid",
@@ -100,6 +138,7 @@ func TestHandleTransfers(t *testing.T) {
 			body: `
 			`,
 			expectedStatus: http.StatusBadRequest,
+			expectedCode:   CodeMalformedInput,
 		},
 	}
 	for _, tc := range testCases {
@@ -108,10 +147,331 @@ func TestHandleTransfers(t *testing.T) {
 			r := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(tc.body))
 			tc.api.HandleTransfers(w, r)
 
+			body, _ := ioutil.ReadAll(w.Result().Body)
 			if !assert.Equal(t, tc.expectedStatus, w.Result().StatusCode) {
-				body, _ := ioutil.ReadAll(w.Result().Body)
 				t.Error(string(body))
 			}
+			if tc.expectedCode != "" {
+				response := errorResponse{}
+				require.NoError(t, json.Unmarshal(body, &response))
+				assert.Equal(t, tc.expectedCode, response.Code)
+			}
+		})
+	}
+}
+
+func TestHandleTransfersMapsRequest(t *testing.T) {
+	manager := newMockManager()
+	body := `
+	{
+		"organization_name": "ACME Corp",
+		"organization_bic": "OIVUSCLQXXX",
+		"organization_iban": "FR10474608000002006107XXXXX",
+		"allow_duplicates": true,
+		"credit_transfers": [
+		  {
+			"amount": "14.5",
+			"currency": "EUR",
+			"counterparty_name": "Bip Bip",
+			"counterparty_bic": "CRLYFRPPTOU",
+			"counterparty_iban": "EE383680981021245685",
+			"description": "Wonderland/4410"
+		  }
+		]
+	}
+	`
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+	NewAPI(manager).HandleTransfers(w, r)
+
+	require.Equal(t, http.StatusCreated, w.Result().StatusCode)
+	require.NotNil(t, manager.request)
+	assert.True(t, manager.request.AllowDuplicates)
+	assert.Equal(t, "FR10474608000002006107XXXXX", manager.request.Party.IBAN)
+	transfers := manager.request.CreditTransfers.(core.TransferList)
+	require.Len(t, transfers, 1)
+	assert.Equal(t, core.Amount{Cents: 1450}, transfers[0].Amount)
+	assert.Equal(t, "Wonderland/4410", transfers[0].Description)
+}
+
+func TestHandleTransfersBestEffort(t *testing.T) {
+	body := `
+	{
+		"organization_name": "ACME Corp",
+		"organization_bic": "OIVUSCLQXXX",
+		"organization_iban": "FR10474608000002006107XXXXX",
+		"mode": "best_effort",
+		"credit_transfers": [
+		  {
+			"amount": "14.5",
+			"currency": "EUR",
+			"counterparty_name": "Bip Bip",
+			"counterparty_bic": "CRLYFRPPTOU",
+			"counterparty_iban": "EE383680981021245685",
+			"description": "Wonderland/4410"
+		  },
+		  {
+			"amount": "61238",
+			"currency": "EUR",
+			"counterparty_name": "Wile E Coyote",
+			"counterparty_bic": "ZDRPLBQI",
+			"counterparty_iban": "DE9935420810036209081725212",
+			"description": "//TeslaMotors/Invoice/12"
+		  }
+		]
+	}
+	`
+	manager := newMockManager().WithResult(core.ResultOf(
+		core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+		core.TransferResult{Status: core.TRANSFER_REJECTED, Err: core.ErrNotEnoughFunds},
+	))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+	NewAPI(manager).HandleTransfers(w, r)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, core.MODE_BEST_EFFORT, manager.request.Mode)
+
+	response := BulkResponse{}
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&response))
+	assert.Equal(t, BulkResponse{
+		Mode:     "best_effort",
+		Accepted: 1,
+		Rejected: 1,
+		Results: []TransferResult{
+			{Index: 0, Status: "accepted"},
+			{Index: 1, Status: "rejected", Code: CodeNotEnoughFunds, Error: core.ErrNotEnoughFunds.Error()},
+		},
+	}, response)
+}
+
+func TestHandleTransfersHeld(t *testing.T) {
+	body := `{
+		"organization_iban": "FR10474608000002006107XXXXX",
+		"credit_transfers": [
+			{"amount": "14.5", "currency": "EUR", "counterparty_name": "Bip Bip", "counterparty_iban": "EE383680981021245685"},
+			{"amount": "1", "currency": "EUR", "counterparty_name": "Bugs Bunny", "counterparty_iban": "DE9935420810036209081725212"}
+		]
+	}`
+	manager := newMockManager().WithResult(core.ResultOf(
+		core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+		core.TransferResult{Status: core.TRANSFER_HELD, Err: core.ErrScreeningHit},
+	))
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+	NewAPI(manager).HandleTransfers(w, r)
+
+	// all or nothing request is executed except held transfers, so they are reported
+	require.Equal(t, http.StatusAccepted, w.Result().StatusCode)
+	response := BulkResponse{}
+	require.NoError(t, json.NewDecoder(w.Result().Body).Decode(&response))
+	assert.Equal(t, BulkResponse{
+		Mode:     "all_or_nothing",
+		Accepted: 1,
+		Held:     1,
+		Results: []TransferResult{
+			{Index: 0, Status: "accepted"},
+			{Index: 1, Status: "held", Code: CodeScreeningHit, Error: core.ErrScreeningHit.Error()},
+		},
+	}, response)
+}
+
+func TestHandleTransfersUnknownMode(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(`{"mode": "some_effort"}`))
+	NewAPI(newMockManager()).HandleTransfers(w, r)
+
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+}
+
+func TestHandleTransfersRedactsErrors(t *testing.T) {
+	sensitive := []string{"FR10474608000002006107XXXXX", "EE383680981021245685", "ACME Corp", "Bip Bip"}
+	body := `{
+		"organization_name": "ACME Corp",
+		"organization_iban": "FR10474608000002006107XXXXX",
+		"mode": "best_effort",
+		"credit_transfers": [{"amount": "14.5", "currency": "EUR", "counterparty_name": "Bip Bip", "counterparty_iban": "EE383680981021245685"}]
+	}`
+	testCases := []struct {
+		name           string
+		manager        *mockManager
+		expectedStatus int
+	}{
+		{
+			name:           "internal error",
+			manager:        newMockManager().WithError(fmt.Errorf("could not lock account FR10474608000002006107XXXXX of ACME Corp: %w", fmt.Errorf("deadlock while paying EE383680981021245685"))),
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name: "rejected transfer",
+			manager: newMockManager().WithResult(core.ResultOf(
+				core.TransferResult{Status: core.TRANSFER_REJECTED, Err: fmt.Errorf("%w: amount 14.50 to Bip Bip EE383680981021245685", core.ErrDuplicateTransfer)},
+			)),
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := &bytes.Buffer{}
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "http://localhost", strings.NewReader(body))
+			r = r.WithContext(qonto.ContextWithLogger(r.Context(), qonto.NewInstanceLogger(logs, "Qonto")))
+			NewAPI(tc.manager).HandleTransfers(w, r)
+
+			require.Equal(t, tc.expectedStatus, w.Result().StatusCode)
+			response := w.Body.String()
+			assert.Contains(t, response, "****")
+			assert.Contains(t, response, "sha256:")
+			for _, value := range sensitive {
+				assert.NotContains(t, response, value)
+				assert.NotContains(t, logs.String(), value)
+			}
+		})
+	}
+}
+
+// heapPeak tracks the peak of live heap, every sample collects garbage first
+type heapPeak struct {
+	mu   sync.Mutex
+	peak uint64
+}
+
+func (hp *heapPeak) sample() {
+	runtime.GC()
+	stats := runtime.MemStats{}
+	runtime.ReadMemStats(&stats)
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+	if stats.HeapAlloc > hp.peak {
+		hp.peak = stats.HeapAlloc
+	}
+}
+
+// heapStorage executes transfers of a single account without keeping them and samples heap on every insert,
+// other storage methods are not used
+type heapStorage struct {
+	storage.Storage
+	account  storage.Account
+	executed int
+	heap     *heapPeak
+}
+
+func (hs *heapStorage) WithTransactionStorage(ctx context.Context, f func(context.Context, storage.Storage) error) error {
+	return f(ctx, hs)
+}
+
+func (hs *heapStorage) FindAccountByIBAN(ctx context.Context, iban string) (storage.Account, error) {
+	return hs.account, nil
+}
+
+func (hs *heapStorage) FindTransferLimits(ctx context.Context, accountID int64) ([]storage.TransferLimit, error) {
+	return nil, nil
+}
+
+func (hs *heapStorage) FindTransactionFingerprints(ctx context.Context, accountID int64, fingerprints []string, since time.Time) ([]string, error) {
+	return nil, nil
+}
+
+func (hs *heapStorage) AppendAccountTransactions(ctx context.Context, transactions []*storage.Transaction) error {
+	hs.executed += len(transactions)
+	hs.heap.sample()
+	return nil
+}
+
+func (hs *heapStorage) UpdateAccountBalance(ctx context.Context, id, balance int64) error {
+	hs.account.BalanceCents = balance
+	return nil
+}
+
+// requestBody generates all-or-nothing request of the given number of transfers of 0.01 while it is read,
+// so the body itself does not take memory, heap is sampled after every chunk of transfers
+func requestBody(mediaType string, transfers int, heap *heapPeak) io.Reader {
+	r, w := io.Pipe()
+	go func() {
+		buf := bufio.NewWriter(w)
+		if mediaType == MediaTypeXML {
+			fmt.Fprintf(buf, `<Document xmlns="%s"><CstmrCdtTrfInitn>`, iso20022.NamespacePain001)
+			fmt.Fprintf(buf, `<GrpHdr><MsgId>MSG-1</MsgId><NbOfTxs>%d</NbOfTxs></GrpHdr>`, transfers)
+			buf.WriteString(`<PmtInf><PmtInfId>PMT-1</PmtInfId><PmtMtd>TRF</PmtMtd><Dbtr><Nm>ACME Corp</Nm></Dbtr>`)
+			buf.WriteString(`<DbtrAcct><Id><IBAN>FR10474608000002006107XXXXX</IBAN></Id></DbtrAcct>`)
+		} else {
+			buf.WriteString(`{"organization_name": "ACME Corp", "organization_bic": "OIVUSCLQXXX", "organization_iban": "FR10474608000002006107XXXXX", "credit_transfers": [`)
+		}
+		for i := 0; i < transfers; i++ {
+			if mediaType == MediaTypeXML {
+				fmt.Fprintf(buf, `<CdtTrfTxInf><PmtId><EndToEndId>E2E-%d</EndToEndId></PmtId><Amt><InstdAmt Ccy="EUR">0.01</InstdAmt></Amt>`, i)
+				fmt.Fprintf(buf, `<Cdtr><Nm>Bip Bip %d</Nm></Cdtr><CdtrAcct><Id><IBAN>EE383680981021245685</IBAN></Id></CdtrAcct></CdtTrfTxInf>`, i)
+			} else {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				fmt.Fprintf(buf, `{"amount": "0.01", "currency": "EUR", "counterparty_name": "Bip Bip %d", "counterparty_bic": "CRLYFRPPTOU", "counterparty_iban": "EE383680981021245685", "description": "Wonderland/%d"}`, i, i)
+			}
+			if i%core.CHUNK_SIZE == 0 {
+				heap.sample()
+			}
+		}
+		if mediaType == MediaTypeXML {
+			buf.WriteString(`</PmtInf></CstmrCdtTrfInitn></Document>`)
+		} else {
+			buf.WriteString(`]}`)
+		}
+		w.CloseWithError(buf.Flush())
+	}()
+	return r
+}
+
+// handleLargeRequest processes request of the given number of transfers and returns peak of live heap above
+// the one before the request
+func handleLargeRequest(tb testing.TB, mediaType string, transfers int) uint64 {
+	heap := &heapPeak{}
+	stub := &heapStorage{account: storage.Account{ID: 1, BalanceCents: int64(transfers)}, heap: heap}
+	qapi := NewAPI(core.NewQontoTransferManager(stub))
+	heap.sample()
+	base := heap.peak
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost/v1/transfers", requestBody(mediaType, transfers, heap))
+	r.Header.Set(HeaderContentType, mediaType)
+	qapi.HandleTransfers(w, r)
+	require.Equal(tb, http.StatusCreated, w.Code, w.Body.String())
+	require.Equal(tb, transfers, stub.executed)
+	require.Zero(tb, stub.account.BalanceCents)
+
+	return heap.peak - base
+}
+
+// BenchmarkHandleLargeRequest executes requests of all sizes, peak-heap-B is expected to be flat
+func BenchmarkHandleLargeRequest(b *testing.B) {
+	for _, mediaType := range []string{MediaTypeJSON, MediaTypeXML} {
+		for _, transfers := range []int{10000, 100000} {
+			b.Run(fmt.Sprintf("%s/%d", mediaType, transfers), func(b *testing.B) {
+				b.ReportAllocs()
+				var peak uint64
+				for i := 0; i < b.N; i++ {
+					if p := handleLargeRequest(b, mediaType, transfers); p > peak {
+						peak = p
+					}
+				}
+				b.ReportMetric(float64(peak), "peak-heap-B")
+			})
+		}
+	}
+}
+
+func TestHandleLargeRequestMemoryIsBounded(t *testing.T) {
+	if testing.Short() {
+		t.Skip("executes 100000 transfers")
+	}
+
+	for _, mediaType := range []string{MediaTypeJSON, MediaTypeXML} {
+		t.Run(mediaType, func(t *testing.T) {
+			base := handleLargeRequest(t, mediaType, 10000)
+			peak := handleLargeRequest(t, mediaType, 100000)
+			t.Logf("peak of live heap: %d B for 10000 transfers, %d B for 100000 transfers", base, peak)
+			assert.LessOrEqualf(t, float64(peak), 1.5*float64(base)+1<<20,
+				"100000 transfers took %d B of heap, 10000 transfers took %d B", peak, base)
 		})
 	}
 }
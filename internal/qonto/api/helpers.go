@@ -3,14 +3,25 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"mime"
 	"net/http"
 
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/dispatch"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/iso20022"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
 )
 
 const (
 	HeaderContentType string = "Content-Type"
+
+	MediaTypeJSON        = "application/json"
+	MediaTypeProblemJSON = "application/problem+json"
+	MediaTypeXML         = "application/xml"
+	MediaTypeTextXML     = "text/xml"
+	MediaTypeCSV         = "text/csv"
 )
 
 func Respond(w http.ResponseWriter, r *http.Request, content interface{}) {
@@ -18,34 +29,145 @@ func Respond(w http.ResponseWriter, r *http.Request, content interface{}) {
 }
 
 func RespondCode(w http.ResponseWriter, r *http.Request, code int, content interface{}) {
+	// headers set after WriteHeader are not sent
+	w.Header().Set(HeaderContentType, MediaTypeJSON)
 	w.WriteHeader(code)
-	w.Header().Set(HeaderContentType, "application/json")
 
 	b, err := json.Marshal(content)
 	if err != nil {
-		log.Printf("error marshalling response: %v", err)
+		qonto.LoggerFromContext(r.Context()).Error("error marshalling response", "error", err)
 		return
 	}
 	_, err = w.Write(b)
 	if err != nil {
-		log.Printf("error writing response: %v", err)
+		qonto.LoggerFromContext(r.Context()).Error("error writing response", "error", err)
+		return
+	}
+}
+
+// RespondProblem responds with RFC 7807 problem details of the status, the request ID is the problem instance
+func RespondProblem(w http.ResponseWriter, r *http.Request, status int, code string) {
+	w.Header().Set(HeaderContentType, MediaTypeProblemJSON)
+	w.WriteHeader(status)
+
+	b, err := json.Marshal(&problemResponse{
+		Type:     PROBLEM_TYPE_DEFAULT,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: RequestIDFromContext(r.Context()),
+		Code:     code,
+	})
+	if err != nil {
+		qonto.LoggerFromContext(r.Context()).Error("error marshalling response", "error", err)
 		return
 	}
+	if _, err := w.Write(b); err != nil {
+		qonto.LoggerFromContext(r.Context()).Error("error writing response", "error", err)
+	}
+}
+
+// errorMappings defines HTTP status and code of known errors, first match wins
+var errorMappings = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{core.ErrNotEnoughFunds, http.StatusUnprocessableEntity, CodeNotEnoughFunds},
+	{core.ErrSingleTransferLimitExceeded, http.StatusUnprocessableEntity, CodeSingleTransferLimitExceeded},
+	{core.ErrDailyLimitExceeded, http.StatusUnprocessableEntity, CodeDailyLimitExceeded},
+	{core.ErrMonthlyLimitExceeded, http.StatusUnprocessableEntity, CodeMonthlyLimitExceeded},
+	{core.ErrBatchSizeLimitExceeded, http.StatusUnprocessableEntity, CodeBatchSizeLimitExceeded},
+	{core.ErrNewBeneficiaryLargeAmount, http.StatusUnprocessableEntity, CodeNewBeneficiaryLargeAmount},
+	{core.ErrRoundAmountBurst, http.StatusUnprocessableEntity, CodeRoundAmountBurst},
+	{core.ErrBlockedCountry, http.StatusUnprocessableEntity, CodeBlockedCountry},
+	{core.ErrTransferDenied, http.StatusUnprocessableEntity, CodeTransferDenied},
+	{core.ErrDuplicateTransfer, http.StatusConflict, CodeDuplicateTransfer},
+	{core.ErrScreeningHit, http.StatusUnprocessableEntity, CodeScreeningHit},
+	{core.ErrScreeningHitNotFound, http.StatusNotFound, CodeScreeningHitNotFound},
+	{core.ErrStatusReportNotFound, http.StatusNotFound, CodeStatusReportNotFound},
+	{core.ErrAccountNotFound, http.StatusNotFound, CodeAccountNotFound},
+	{core.ErrDuplicateIBAN, http.StatusConflict, CodeDuplicateIBAN},
+	{core.ErrConstraintViolation, http.StatusUnprocessableEntity, CodeConstraintViolation},
+	{core.ErrConflict, http.StatusConflict, CodeConflict},
+	{core.ErrInvalidPeriod, http.StatusBadRequest, CodeInvalidPeriod},
+	{iso20022.ErrUnsupportedMessage, http.StatusBadRequest, CodeUnsupportedMessage},
+	{core.ErrInvalidCurrency, http.StatusBadRequest, CodeInvalidCurrency},
+	{core.ErrInvalidAmount, http.StatusBadRequest, CodeInvalidAmount},
+	{iso20022.ErrInvalidMessage, http.StatusBadRequest, CodeInvalidMessage},
+	{ErrInvalidCSV, http.StatusBadRequest, CodeInvalidCSV},
+	{ErrMalformedInput, http.StatusBadRequest, CodeMalformedInput},
+	{ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType},
+	{ratelimit.ErrRateLimited, http.StatusTooManyRequests, CodeRateLimited},
+	{ratelimit.ErrConcurrencyLimited, http.StatusTooManyRequests, CodeTooManyRequestsInFlight},
+	{dispatch.ErrQueueFull, http.StatusServiceUnavailable, CodeAccountQueueFull},
+	{dispatch.ErrClosed, http.StatusServiceUnavailable, CodeServiceUnavailable},
+}
+
+// errorStatus returns HTTP status and code of the error
+func errorStatus(err error) (int, string) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping.status, mapping.code
+		}
+	}
+
+	// you should never expose wild errors in production,
+	// ideally, even previous errors must be wrapped in abstract errors
+	// without details, unless they are properly handled
+	return http.StatusInternalServerError, CodeInternalError
 }
 
+// handleErrors responds with status and code of the error, its messages are redacted by the redactor
+// of the request context, so names of the parties are hidden once the request is decoded
 func handleErrors(w http.ResponseWriter, r *http.Request, err error) {
-	wrappedErr := wrapError(err)
-	switch {
-	case errors.Is(err, core.ErrNotEnoughFunds):
-		RespondCode(w, r, http.StatusUnprocessableEntity, wrappedErr)
-	case errors.Is(err, core.ErrInvalidCurrency):
-		RespondCode(w, r, http.StatusBadRequest, wrappedErr)
-	case errors.Is(err, ErrMalformedInput):
-		RespondCode(w, r, http.StatusBadRequest, wrappedErr)
-	default:
-		// you should never expose wild errors in production,
-		// ideally, even previous errors must be wrapped in abstract errors
-		// without details, unless they are properly handled
-		RespondCode(w, r, http.StatusInternalServerError, wrappedErr)
+	status, code := errorStatus(err)
+	redactor := qonto.RedactorFromContext(r.Context())
+	if status >= http.StatusInternalServerError {
+		qonto.LoggerFromContext(r.Context()).Error("request failed", "error", redactor.Text(err.Error()))
 	}
+	response := wrapError(err, code, redactor)
+	response.RequestID = RequestIDFromContext(r.Context())
+
+	var validationErrs iso20022.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		for _, ve := range validationErrs {
+			response.Details = append(response.Details, ErrorDetail{
+				PmtInfId:   ve.PmtInfId,
+				EndToEndId: ve.EndToEndId,
+				Error:      redactor.Text(ve.Message),
+			})
+		}
+	}
+
+	var csvErrs CSVErrors
+	if errors.As(err, &csvErrs) {
+		for _, ce := range csvErrs {
+			response.Details = append(response.Details, ErrorDetail{
+				Row:   ce.Row,
+				Error: redactor.Text(ce.Message),
+			})
+		}
+	}
+
+	RespondCode(w, r, status, response)
+}
+
+// requestMediaType returns media type of request body, JSON is assumed if not set
+func requestMediaType(r *http.Request) (string, error) {
+	contentType := r.Header.Get(HeaderContentType)
+	if contentType == "" {
+		return MediaTypeJSON, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupportedMediaType, err)
+	}
+
+	return mediaType, nil
+}
+
+// ErrorCode returns code the error is reported with to customers
+func ErrorCode(err error) string {
+	_, code := errorStatus(err)
+	return code
 }
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/spool"
+)
+
+// decodeJSONRequest reads the request token by token, so that credit transfers are converted
+// one by one into the spool and the body is never held in memory as a whole. It accepts the same documents
+// as decoding into Request: field names are case insensitive and unknown fields are ignored
+func decodeJSONRequest(r io.Reader, transfers *spool.Transfers) (*core.Request, error) {
+	decoder := json.NewDecoder(r)
+	request := &core.Request{CreditTransfers: transfers}
+	mode := ""
+	decoded := false
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return nil, err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected token %v", token)
+		}
+
+		switch {
+		case strings.EqualFold(key, "organization_name"):
+			err = decoder.Decode(&request.Party.Name)
+		case strings.EqualFold(key, "organization_bic"):
+			err = decoder.Decode(&request.Party.BIC)
+		case strings.EqualFold(key, "organization_iban"):
+			err = decoder.Decode(&request.Party.IBAN)
+		case strings.EqualFold(key, "allow_duplicates"):
+			err = decoder.Decode(&request.AllowDuplicates)
+		case strings.EqualFold(key, "mode"):
+			err = decoder.Decode(&mode)
+		case strings.EqualFold(key, "credit_transfers"):
+			if decoded {
+				return nil, fmt.Errorf("%w: credit_transfers is repeated", ErrMalformedInput)
+			}
+			decoded = true
+			err = decodeCreditTransfers(decoder, transfers)
+		default:
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := expectDelim(decoder, '}'); err != nil {
+		return nil, err
+	}
+
+	parsedMode, err := core.ParseMode(mode)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedInput, err)
+	}
+	request.Mode = parsedMode
+
+	return request, nil
+}
+
+// decodeCreditTransfers decodes array of transfers element by element into the spool, null is an empty list
+func decodeCreditTransfers(decoder *json.Decoder, transfers *spool.Transfers) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("credit_transfers must be an array, got %v", token)
+	}
+
+	for decoder.More() {
+		var transfer Transfer
+		if err := decoder.Decode(&transfer); err != nil {
+			return err
+		}
+		err := transfers.Append(core.Transfer{
+			Amount:   transfer.Amount,
+			Currency: core.Currency(transfer.Currency),
+			CounterParty: core.Party{
+				Name: transfer.CounterpartyName,
+				BIC:  transfer.CounterpartyBIC,
+				IBAN: transfer.CounterpartyIBAN,
+			},
+			Description: transfer.Description,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return expectDelim(decoder, ']')
+}
+
+func expectDelim(decoder *json.Decoder, expected json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != expected {
+		return fmt.Errorf("expected %v, got %v", expected, token)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/spool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeJSONRequest(t *testing.T) {
+	transfer := core.Transfer{
+		Amount:   core.Amount{Cents: 1450},
+		Currency: core.CURRENCY_EURO,
+		CounterParty: core.Party{
+			Name: "Bip Bip",
+			BIC:  "CRLYFRPPTOU",
+			IBAN: "EE383680981021245685",
+		},
+		Description: "Wonderland/4410",
+	}
+	transferJSON := `{
+		"amount": "14.5",
+		"currency": "EUR",
+		"counterparty_name": "Bip Bip",
+		"counterparty_bic": "CRLYFRPPTOU",
+		"counterparty_iban": "EE383680981021245685",
+		"description": "Wonderland/4410"
+	}`
+
+	testCases := []struct {
+		name          string
+		body          string
+		expected      *core.Request
+		expectedError bool
+	}{
+		{
+			name: "all fields",
+			body: `{
+				"organization_name": "ACME Corp",
+				"organization_bic": "OIVUSCLQXXX",
+				"organization_iban": "FR10474608000002006107XXXXX",
+				"allow_duplicates": true,
+				"mode": "best_effort",
+				"credit_transfers": [` + transferJSON + `,` + transferJSON + `]
+			}`,
+			expected: &core.Request{
+				Party:           core.Party{Name: "ACME Corp", BIC: "OIVUSCLQXXX", IBAN: "FR10474608000002006107XXXXX"},
+				CreditTransfers: core.TransferList{transfer, transfer},
+				AllowDuplicates: true,
+				Mode:            core.MODE_BEST_EFFORT,
+			},
+		},
+		{
+			name: "fields after transfers, case insensitive names and unknown fields",
+			body: `{
+				"credit_transfers": [` + transferJSON + `],
+				"unknown": {"nested": [1, 2, {"deeper": null}]},
+				"Organization_IBAN": "FR10474608000002006107XXXXX",
+				"MODE": "all_or_nothing"
+			}`,
+			expected: &core.Request{
+				Party:           core.Party{IBAN: "FR10474608000002006107XXXXX"},
+				CreditTransfers: core.TransferList{transfer},
+				Mode:            core.MODE_ALL_OR_NOTHING,
+			},
+		},
+		{
+			name:     "null transfers",
+			body:     `{"organization_iban": "FR10474608000002006107XXXXX", "credit_transfers": null}`,
+			expected: &core.Request{Party: core.Party{IBAN: "FR10474608000002006107XXXXX"}, CreditTransfers: core.TransferList{}, Mode: core.MODE_ALL_OR_NOTHING},
+		},
+		{
+			name:          "transfers are not an array",
+			body:          `{"credit_transfers": {"amount": "1"}}`,
+			expectedError: true,
+		},
+		{
+			name:          "not an object",
+			body:          `[]`,
+			expectedError: true,
+		},
+		{
+			name:          "truncated body",
+			body:          `{"credit_transfers": [` + transferJSON,
+			expectedError: true,
+		},
+		{
+			name:          "invalid amount",
+			body:          `{"credit_transfers": [{"amount": "1.234"}]}`,
+			expectedError: true,
+		},
+		{
+			name:          "repeated transfers",
+			body:          `{"credit_transfers": [` + transferJSON + `], "credit_transfers": [` + transferJSON + `]}`,
+			expectedError: true,
+		},
+		{
+			name:          "invalid mode",
+			body:          `{"mode": "some_effort"}`,
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			transfers := spool.New("")
+			defer transfers.Close()
+			request, err := decodeJSONRequest(strings.NewReader(tc.body), transfers)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			decoded := *request
+			decoded.CreditTransfers = core.TransferList(readTransfers(t, transfers))
+			assert.Equal(t, tc.expected, &decoded)
+		})
+	}
+}
+
+// readTransfers reads all transfers into memory
+func readTransfers(t *testing.T, transfers core.Transfers) []core.Transfer {
+	list := []core.Transfer{}
+	require.NoError(t, transfers.Chunks(core.CHUNK_SIZE, func(offset int, chunk []core.Transfer) error {
+		list = append(list, chunk...)
+		return nil
+	}))
+	return list
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// HeaderOrganization identifies organization the request is made on behalf of
+	HeaderOrganization = "X-Qonto-Organization"
+	// HeaderRequestID identifies the request in logs, traces and error responses
+	HeaderRequestID = "X-Request-ID"
+
+	// maxRequestIDLength limits length of request ID given by the caller
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns ID of the request being handled, it is empty outside of RequestIDMiddleware
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// RequestIDMiddleware propagates request ID given by X-Request-ID header or assigns a new one,
+// the ID is sent back in the header of response
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(HeaderRequestID)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(HeaderRequestID, requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, requestID)))
+	})
+}
+
+// validRequestID accepts IDs of printable ASCII characters, so they are safe to log and echo
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if requestID[i] < 0x21 || requestID[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// IDs are only used for correlation, so time based one is good enough
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// LoggerMiddleware binds the logger with request ID, organization and trace ID to the request context,
+// handlers get it by qonto.LoggerFromContext. It must run after request ID and tracing middlewares
+func LoggerMiddleware(logger qonto.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			fields := []interface{}{}
+			if requestID := RequestIDFromContext(ctx); requestID != "" {
+				fields = append(fields, "request_id", requestID)
+			}
+			if organization := r.Header.Get(HeaderOrganization); organization != "" {
+				fields = append(fields, "organization", organization)
+			}
+			if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+				fields = append(fields, "trace_id", spanContext.TraceID().String())
+			}
+
+			next.ServeHTTP(w, r.WithContext(qonto.ContextWithLogger(ctx, logger.With(fields...))))
+		})
+	}
+}
+
+// AccessLogMiddleware logs every handled request with the logger bound by LoggerMiddleware,
+// failed requests are logged as errors
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			// nothing is written, so net/http responds with 200
+			status = http.StatusOK
+		}
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		logger := qonto.LoggerFromContext(r.Context())
+		log := logger.Info
+		if status >= http.StatusInternalServerError {
+			log = logger.Error
+		}
+		log("request handled",
+			"method", r.Method,
+			"route", route,
+			"status", status,
+			"bytes", ww.BytesWritten(),
+			"duration", time.Since(start),
+		)
+	})
+}
+
+// RecoverMiddleware converts panics of handlers into internal error responses with RFC 7807 problem details,
+// so a failed request does not close the connection without response
+func RecoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				// the handler aborts the response on purpose
+				panic(recovered)
+			}
+
+			qonto.LoggerFromContext(r.Context()).Error("handler panicked", "panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+			if ww.Status() != 0 {
+				// status is already sent, the client sees truncated response
+				return
+			}
+			RespondProblem(ww, r, http.StatusInternalServerError, CodeInternalError)
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
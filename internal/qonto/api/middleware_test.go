@@ -0,0 +1,235 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoggerMiddleware(t *testing.T) {
+	buf := &bytes.Buffer{}
+	provider, err := tracing.NewProvider("", "qonto")
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(RequestIDMiddleware)
+	router.Use(tracing.Middleware(provider))
+	router.Use(LoggerMiddleware(qonto.NewInstanceLogger(buf, "Qonto").WithFormat(qonto.FORMAT_JSON)))
+	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		qonto.LoggerFromContext(r.Context()).Info("handled")
+	})
+
+	testCases := []struct {
+		name     string
+		headers  map[string]string
+		expected map[string]string
+	}{
+		{
+			name: "all fields",
+			headers: map[string]string{
+				HeaderRequestID:    "req-1",
+				HeaderOrganization: "ACME Corp",
+				"traceparent":      "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			},
+			expected: map[string]string{
+				"request_id":   "req-1",
+				"organization": qonto.RedactName("ACME Corp"),
+				"trace_id":     "4bf92f3577b34da6a3ce929d0e0e4736",
+			},
+		},
+		{
+			name:     "no organization and trace",
+			headers:  map[string]string{HeaderRequestID: "req-2"},
+			expected: map[string]string{"request_id": "req-2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			request := httptest.NewRequest(http.MethodGet, "http://localhost/", nil)
+			for key, value := range tc.headers {
+				request.Header.Set(key, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), request)
+
+			entry := map[string]string{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+			for _, key := range []string{"request_id", "organization", "trace_id"} {
+				assert.Equal(t, tc.expected[key], entry[key], key)
+			}
+			assert.Equal(t, "handled", entry["msg"])
+		})
+	}
+}
+
+// newMiddlewareRouter chains middlewares in the order main does
+func newMiddlewareRouter(logs *bytes.Buffer) chi.Router {
+	router := chi.NewRouter()
+	router.Use(RequestIDMiddleware)
+	router.Use(LoggerMiddleware(qonto.NewInstanceLogger(logs, "Qonto").WithFormat(qonto.FORMAT_JSON)))
+	router.Use(AccessLogMiddleware)
+	router.Use(RecoverMiddleware)
+	return router
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	router := newMiddlewareRouter(&bytes.Buffer{})
+	router.Get("/v1/accounts/{iban}/statements", func(w http.ResponseWriter, r *http.Request) {
+		handleErrors(w, r, core.ErrAccountNotFound)
+	})
+
+	testCases := []struct {
+		name       string
+		requestID  string
+		expectedID string
+	}{
+		{name: "propagated", requestID: "caller-42", expectedID: "caller-42"},
+		{name: "assigned"},
+		{name: "invalid is replaced", requestID: "line\nbreak"},
+		{name: "too long is replaced", requestID: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/v1/accounts/FR10474608000002006107XXXXX/statements", nil)
+			if tc.requestID != "" {
+				r.Header.Set(HeaderRequestID, tc.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			requestID := w.Header().Get(HeaderRequestID)
+			if tc.expectedID != "" {
+				assert.Equal(t, tc.expectedID, requestID)
+			} else {
+				assert.Regexp(t, "^[0-9a-f]{32}$", requestID)
+			}
+
+			response := errorResponse{}
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+			assert.Equal(t, CodeAccountNotFound, response.Code)
+			assert.Equal(t, requestID, response.RequestID)
+		})
+	}
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	logs := &bytes.Buffer{}
+	router := newMiddlewareRouter(logs)
+	router.Post("/v1/transfers", func(w http.ResponseWriter, r *http.Request) {
+		RespondCode(w, r, http.StatusCreated, "operation succeeded")
+	})
+
+	testCases := []struct {
+		name     string
+		method   string
+		url      string
+		expected map[string]interface{}
+	}{
+		{
+			name:   "matched route",
+			method: http.MethodPost,
+			url:    "http://localhost/v1/transfers",
+			expected: map[string]interface{}{
+				"level":        "info",
+				"msg":          "request handled",
+				"request_id":   "req-1",
+				"organization": qonto.RedactName("ACME Corp"),
+				"method":       http.MethodPost,
+				"route":        "/v1/transfers",
+				"status":       float64(http.StatusCreated),
+				"bytes":        float64(len(`"operation succeeded"`)),
+			},
+		},
+		{
+			name:   "unmatched route",
+			method: http.MethodGet,
+			url:    "http://localhost/unknown",
+			expected: map[string]interface{}{
+				"level":  "info",
+				"route":  "unmatched",
+				"status": float64(http.StatusNotFound),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+			r := httptest.NewRequest(tc.method, tc.url, nil)
+			r.Header.Set(HeaderRequestID, "req-1")
+			r.Header.Set(HeaderOrganization, "ACME Corp")
+			router.ServeHTTP(httptest.NewRecorder(), r)
+
+			entry := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+			for key, value := range tc.expected {
+				assert.Equal(t, value, entry[key], key)
+			}
+			assert.NotEmpty(t, entry["duration"])
+		})
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	logs := &bytes.Buffer{}
+	router := newMiddlewareRouter(logs)
+	router.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("nil map")
+	})
+	router.Get("/panic-after-write", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id\n"))
+		panic("nil map")
+	})
+	router.Get("/abort", func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})
+
+	t.Run("internal error response", func(t *testing.T) {
+		logs.Reset()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost/panic", nil)
+		r.Header.Set(HeaderRequestID, "req-1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, MediaTypeProblemJSON, w.Header().Get(HeaderContentType))
+		response := map[string]interface{}{}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, map[string]interface{}{
+			"type":     "about:blank",
+			"title":    "Internal Server Error",
+			"status":   float64(http.StatusInternalServerError),
+			"instance": "req-1",
+			"code":     CodeInternalError,
+		}, response)
+
+		assert.Contains(t, logs.String(), `"msg":"handler panicked","request_id":"req-1","panic":"nil map"`)
+		assert.Contains(t, logs.String(), `"msg":"request handled","request_id":"req-1","method":"GET","route":"/panic","status":500`)
+	})
+
+	t.Run("response is already started", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost/panic-after-write", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "id\n", w.Body.String())
+	})
+
+	t.Run("aborted handler", func(t *testing.T) {
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost/abort", nil))
+		})
+	})
+}
@@ -2,23 +2,192 @@ package api
 
 import (
 	"context"
+	"time"
 
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
 )
 
 type mockManager struct {
-	err error
+	err     error
+	result  *core.Result
+	request *core.Request
 }
 
 func newMockManager() *mockManager {
 	return &mockManager{}
 }
 
-func (mm *mockManager) ProcessTransfers(ctx context.Context, request *core.Request) error {
-	return mm.err
+func (mm *mockManager) ProcessTransfers(ctx context.Context, request *core.Request) (*core.Result, error) {
+	// transfers may be spooled and removed once the request is answered, so a copy read into memory is recorded
+	recorded := *request
+	transfers := core.TransferList{}
+	if err := request.Each(func(i int, transfer core.Transfer) error {
+		transfers = append(transfers, transfer)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	recorded.CreditTransfers = transfers
+	mm.request = &recorded
+	if mm.err != nil {
+		return nil, mm.err
+	}
+	if mm.result != nil {
+		return mm.result, nil
+	}
+
+	outcomes := make([]core.TransferResult, len(transfers))
+	for i := range outcomes {
+		outcomes[i].Status = core.TRANSFER_ACCEPTED
+	}
+	return core.ResultOf(outcomes...), nil
+}
+
+func (mm *mockManager) WithResult(result *core.Result) *mockManager {
+	mm.result = result
+	return mm
 }
 
 func (mm *mockManager) WithError(err error) *mockManager {
 	mm.err = err
 	return mm
 }
+
+type mockScreeningManager struct {
+	hits    []core.ScreeningHit
+	cleared []int64
+	err     error
+}
+
+func newMockScreeningManager(hits ...core.ScreeningHit) *mockScreeningManager {
+	return &mockScreeningManager{
+		hits: hits,
+	}
+}
+
+func (msm *mockScreeningManager) ListHits(ctx context.Context, status string) ([]core.ScreeningHit, error) {
+	result := []core.ScreeningHit{}
+	for _, hit := range msm.hits {
+		if status == "" || hit.Status == status {
+			result = append(result, hit)
+		}
+	}
+	return result, msm.err
+}
+
+func (msm *mockScreeningManager) ClearHit(ctx context.Context, id int64) error {
+	if msm.err != nil {
+		return msm.err
+	}
+	for _, hit := range msm.hits {
+		if hit.ID == id && hit.Status == "open" {
+			msm.cleared = append(msm.cleared, id)
+			return nil
+		}
+	}
+	return core.ErrScreeningHitNotFound
+}
+
+func (msm *mockScreeningManager) ReloadList() error {
+	return msm.err
+}
+
+func (msm *mockScreeningManager) WithError(err error) *mockScreeningManager {
+	msm.err = err
+	return msm
+}
+
+type mockReportManager struct {
+	reports []core.StatusReport
+	err     error
+}
+
+func newMockReportManager() *mockReportManager {
+	return &mockReportManager{}
+}
+
+func (mrm *mockReportManager) WithError(err error) *mockReportManager {
+	mrm.err = err
+	return mrm
+}
+
+func (mrm *mockReportManager) SaveStatusReport(ctx context.Context, report *core.StatusReport) error {
+	if mrm.err != nil {
+		return mrm.err
+	}
+	report.ID = int64(len(mrm.reports) + 1)
+	report.CreatedAt = time.Now()
+	mrm.reports = append(mrm.reports, *report)
+	return nil
+}
+
+func (mrm *mockReportManager) FindStatusReport(ctx context.Context, id int64) (core.StatusReport, error) {
+	if mrm.err != nil {
+		return core.StatusReport{}, mrm.err
+	}
+	for _, report := range mrm.reports {
+		if report.ID == id {
+			return report, nil
+		}
+	}
+	return core.StatusReport{}, core.ErrStatusReportNotFound
+}
+
+type mockStatementManager struct {
+	err      error
+	iban     string
+	from, to time.Time
+}
+
+func newMockStatementManager() *mockStatementManager {
+	return &mockStatementManager{}
+}
+
+func (msm *mockStatementManager) WithError(err error) *mockStatementManager {
+	msm.err = err
+	return msm
+}
+
+func (msm *mockStatementManager) Statement(ctx context.Context, iban string, from, to time.Time) (*core.Statement, error) {
+	msm.iban, msm.from, msm.to = iban, from, to
+	if msm.err != nil {
+		return nil, msm.err
+	}
+	return &core.Statement{
+		Account:        core.Party{Name: "ACME Corp", IBAN: iban},
+		Currency:       core.CURRENCY_EURO,
+		From:           from,
+		To:             to,
+		OpeningBalance: core.Amount{Cents: 1000},
+		ClosingBalance: core.Amount{Cents: 1000},
+		Entries:        []core.StatementEntry{},
+	}, nil
+}
+
+type mockHistoryManager struct {
+	transactions []core.Transaction
+	// err is returned after all transactions are passed
+	err      error
+	from, to time.Time
+}
+
+func newMockHistoryManager(transactions ...core.Transaction) *mockHistoryManager {
+	return &mockHistoryManager{
+		transactions: transactions,
+	}
+}
+
+func (mhm *mockHistoryManager) WithError(err error) *mockHistoryManager {
+	mhm.err = err
+	return mhm
+}
+
+func (mhm *mockHistoryManager) EachTransaction(ctx context.Context, iban string, from, to time.Time, f func(core.Transaction) error) error {
+	mhm.from, mhm.to = from, to
+	for _, tx := range mhm.transactions {
+		if err := f(tx); err != nil {
+			return err
+		}
+	}
+	return mhm.err
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+)
+
+// openAPISpec is OpenAPI 3 specification of the API, contract tests validate handlers against it
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// HandleOpenAPI serves OpenAPI specification of the API
+func HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(HeaderContentType, MediaTypeJSON)
+	if _, err := w.Write(openAPISpec); err != nil {
+		qonto.LoggerFromContext(r.Context()).Error("error writing response", "error", err)
+	}
+}
@@ -0,0 +1,393 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/dispatch"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDocument is a minimal subset of OpenAPI 3 used by contract tests: operations, their request bodies
+// and responses described by JSON schemas with type, properties, required, additionalProperties, items,
+// enum, pattern, date-time format, oneOf and local $ref
+type openAPIDocument map[string]interface{}
+
+func loadOpenAPIDocument(t *testing.T) openAPIDocument {
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(openAPISpec, &doc))
+	require.Equal(t, "3.0.3", doc["openapi"])
+	return doc
+}
+
+// resolve follows local $ref of the node
+func (doc openAPIDocument) resolve(node map[string]interface{}) map[string]interface{} {
+	for {
+		ref, ok := node["$ref"].(string)
+		if !ok {
+			return node
+		}
+		var current interface{} = map[string]interface{}(doc)
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			current = current.(map[string]interface{})[part]
+		}
+		node = current.(map[string]interface{})
+	}
+}
+
+// child returns resolved object at the path of keys, nil if any of them is missing
+func (doc openAPIDocument) child(node map[string]interface{}, keys ...string) map[string]interface{} {
+	for _, key := range keys {
+		next, ok := node[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = doc.resolve(next)
+	}
+	return node
+}
+
+// operations returns "METHOD /path" of all documented operations
+func (doc openAPIDocument) operations() []string {
+	result := []string{}
+	for path, item := range doc["paths"].(map[string]interface{}) {
+		for method := range item.(map[string]interface{}) {
+			result = append(result, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (doc openAPIDocument) operation(method, path string) map[string]interface{} {
+	return doc.child(map[string]interface{}(doc), "paths", path, strings.ToLower(method))
+}
+
+// validate returns violations of the schema by the value decoded from JSON
+func (doc openAPIDocument) validate(schema map[string]interface{}, value interface{}, at string) []string {
+	schema = doc.resolve(schema)
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, option := range oneOf {
+			if len(doc.validate(option.(map[string]interface{}), value, at)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return []string{fmt.Sprintf("%s: matches %d of oneOf schemas", at, matched)}
+		}
+		return nil
+	}
+
+	violations := []string{}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if allowed == value {
+				found = true
+			}
+		}
+		if !found {
+			violations = append(violations, fmt.Sprintf("%s: %v is not one of %v", at, value, enum))
+		}
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%s: expected object, got %T", at, value))
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := object[name.(string)]; !ok {
+					violations = append(violations, fmt.Sprintf("%s: missing required property %s", at, name))
+				}
+			}
+		}
+		for name, property := range object {
+			propertySchema, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if schema["additionalProperties"] == false {
+					violations = append(violations, fmt.Sprintf("%s: unknown property %s", at, name))
+				}
+				continue
+			}
+			violations = append(violations, doc.validate(propertySchema, property, at+"."+name)...)
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%s: expected array, got %T", at, value))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range array {
+				violations = append(violations, doc.validate(items, item, fmt.Sprintf("%s[%d]", at, i))...)
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return append(violations, fmt.Sprintf("%s: expected string, got %T", at, value))
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			violations = append(violations, fmt.Sprintf("%s: %q does not match %s", at, s, pattern))
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				violations = append(violations, fmt.Sprintf("%s: %q is not date-time", at, s))
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			violations = append(violations, fmt.Sprintf("%s: expected integer, got %v", at, value))
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			violations = append(violations, fmt.Sprintf("%s: expected number, got %T", at, value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			violations = append(violations, fmt.Sprintf("%s: expected boolean, got %T", at, value))
+		}
+	}
+
+	return violations
+}
+
+func TestOpenAPIValidator(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+	schema := map[string]interface{}{"$ref": "#/components/schemas/BulkResponse"}
+
+	valid := `{"mode": "best_effort", "accepted": 1, "rejected": 0, "held": 0, "results": [{"index": 0, "status": "accepted"}]}`
+	var value interface{}
+	require.NoError(t, json.Unmarshal([]byte(valid), &value))
+	assert.Empty(t, doc.validate(schema, value, "$"))
+
+	invalid := `{"mode": "some_effort", "accepted": 1.5, "results": [{"index": "0", "status": "accepted", "extra": true}]}`
+	require.NoError(t, json.Unmarshal([]byte(invalid), &value))
+	assert.ElementsMatch(t, []string{
+		`$: missing required property rejected`,
+		`$: missing required property held`,
+		`$.mode: some_effort is not one of [all_or_nothing best_effort]`,
+		`$.accepted: expected integer, got 1.5`,
+		`$.results[0]: unknown property extra`,
+		`$.results[0].index: expected integer, got 0`,
+	}, doc.validate(schema, value, "$"))
+}
+
+// TestOpenAPIRoutes makes sure that every registered route is documented and every documented operation is served
+func TestOpenAPIRoutes(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+	router := chi.NewRouter()
+	newContractAPI(newMockManager()).Routes(router)
+
+	routes := []string{}
+	require.NoError(t, chi.Walk(router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routes = append(routes, method+" "+route)
+		return nil
+	}))
+	sort.Strings(routes)
+
+	assert.Equal(t, doc.operations(), routes)
+}
+
+func TestOpenAPIContract(t *testing.T) {
+	doc := loadOpenAPIDocument(t)
+	pain001, err := os.ReadFile("../iso20022/testdata/pain001.xml")
+	require.NoError(t, err)
+	transfersJSON := `{
+		"organization_name": "ACME Corp",
+		"organization_bic": "OIVUSCLQXXX",
+		"organization_iban": "FR10474608000002006107XXXXX",
+		"credit_transfers": [
+			{
+				"amount": "14.5",
+				"currency": "EUR",
+				"counterparty_name": "Bip Bip",
+				"counterparty_bic": "CRLYFRPPTOU",
+				"counterparty_iban": "EE383680981021245685",
+				"description": "Wonderland/4410"
+			}
+		]
+	}`
+	bestEffortJSON := strings.Replace(transfersJSON, `"organization_name"`, `"mode": "best_effort", "organization_name"`, 1)
+	csvBody := "counterparty_name,counterparty_iban,counterparty_bic,amount,currency\nBip Bip,EE383680981021245685,CRLYFRPPTOU,14.50,EUR\n"
+	createdAt := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	hits := []core.ScreeningHit{
+		{ID: 1, CounterParty: core.Party{Name: "Bip Bip"}, EntryID: "1", EntryName: "Bip", MatchedField: "name", Score: 0.9, Status: "open", CreatedAt: createdAt},
+		{ID: 2, CounterParty: core.Party{Name: "Wile E Coyote"}, EntryID: "2", EntryName: "Coyote", MatchedField: "name", Score: 1, Status: "cleared", CreatedAt: createdAt, ClearedAt: createdAt.Add(time.Hour)},
+	}
+	bestEffortResult := core.ResultOf(
+		core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+		core.TransferResult{Status: core.TRANSFER_REJECTED, Err: core.ErrNotEnoughFunds},
+	)
+	heldResult := core.ResultOf(
+		core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+		core.TransferResult{Status: core.TRANSFER_HELD, Err: core.ErrScreeningHit},
+	)
+	reports := newMockReportManager()
+	require.NoError(t, reports.SaveStatusReport(context.Background(), &core.StatusReport{MessageID: "STS-1", Content: []byte("<Document/>")}))
+	// the only request allowed in flight of test client is never released
+	exhausted := ratelimit.NewLimiter(ratelimit.Limits{MaxInFlight: 1})
+	_, err = exhausted.Acquire("FR10474608000002006107XXXXX")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name        string
+		api         *qontoAPI
+		method      string
+		url         string
+		contentType string
+		body        string
+		// invalidRequest skips validation of request body which is known to violate the specification
+		invalidRequest bool
+		expectedStatus int
+	}{
+		{name: "specification", method: http.MethodGet, url: "/openapi.json", expectedStatus: http.StatusOK},
+
+		{name: "json transfers", method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusCreated},
+		{name: "json transfers with content type", method: http.MethodPost, url: "/v1/transfers", contentType: "application/json; charset=utf-8", body: transfersJSON, expectedStatus: http.StatusCreated},
+		{name: "json best effort", api: newContractAPI(newMockManager().WithResult(bestEffortResult)), method: http.MethodPost, url: "/v1/transfers", body: bestEffortJSON, expectedStatus: http.StatusOK},
+		{name: "json held", api: newContractAPI(newMockManager().WithResult(heldResult)), method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusAccepted},
+		{name: "json malformed", method: http.MethodPost, url: "/v1/transfers", body: `{"credit_transfers": {}}`, invalidRequest: true, expectedStatus: http.StatusBadRequest},
+		{name: "json invalid mode", method: http.MethodPost, url: "/v1/transfers", body: `{"mode": "some_effort"}`, invalidRequest: true, expectedStatus: http.StatusBadRequest},
+		{name: "json unknown account", api: newContractAPI(newMockManager().WithError(core.ErrAccountNotFound)), method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusNotFound},
+		{name: "json duplicate", api: newContractAPI(newMockManager().WithError(core.ErrDuplicateTransfer)), method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusConflict},
+		{name: "json not enough funds", api: newContractAPI(newMockManager().WithError(core.ErrNotEnoughFunds)), method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "json conflict", api: newContractAPI(newMockManager().WithError(core.ErrConflict)), method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusConflict},
+		{name: "json unknown account", api: newContractAPI(newMockManager().WithError(core.ErrAccountNotFound)), method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusNotFound},
+		{name: "json internal error", api: newContractAPI(newMockManager().WithError(errors.New("db is down"))), method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusInternalServerError},
+		{name: "json queue full", api: newContractAPI(newMockManager().WithError(dispatch.ErrQueueFull)), method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusServiceUnavailable},
+		{name: "json throttled", api: newContractAPI(newMockManager()).WithLimiter(exhausted), method: http.MethodPost, url: "/v1/transfers", body: transfersJSON, expectedStatus: http.StatusTooManyRequests},
+		{name: "unsupported media type", method: http.MethodPost, url: "/v1/transfers", contentType: "text/plain", body: "transfer", invalidRequest: true, expectedStatus: http.StatusUnsupportedMediaType},
+
+		{name: "pain.001", method: http.MethodPost, url: "/v1/transfers", contentType: MediaTypeXML, body: string(pain001), expectedStatus: http.StatusCreated},
+		{name: "pain.001 rejected", api: newContractAPI(newMockManager().WithError(core.ErrNotEnoughFunds)), method: http.MethodPost, url: "/v1/transfers", contentType: MediaTypeTextXML, body: string(pain001), expectedStatus: http.StatusUnprocessableEntity},
+		{name: "pain.001 held", api: newContractAPI(newMockManager().WithResult(heldResult)), method: http.MethodPost, url: "/v1/transfers", contentType: MediaTypeXML, body: string(pain001), expectedStatus: http.StatusAccepted},
+		{name: "pain.001 malformed", method: http.MethodPost, url: "/v1/transfers", contentType: MediaTypeXML, body: "<Document>", expectedStatus: http.StatusBadRequest},
+
+		{name: "csv", method: http.MethodPost, url: "/v1/transfers?organization_iban=FR10474608000002006107XXXXX&mode=best_effort", contentType: MediaTypeCSV, body: csvBody, expectedStatus: http.StatusOK},
+		{name: "csv invalid", method: http.MethodPost, url: "/v1/transfers?organization_iban=FR10474608000002006107XXXXX", contentType: MediaTypeCSV, body: csvBody + "Wile E Coyote,DE9935420810036209081725212,ZDRPLBQI,abc,EUR\n", expectedStatus: http.StatusBadRequest},
+
+		{name: "status report", api: newContractAPI(newMockManager()).WithReportManager(reports), method: http.MethodGet, url: "/v1/status-reports/1", expectedStatus: http.StatusOK},
+		{name: "status report not found", method: http.MethodGet, url: "/v1/status-reports/2", expectedStatus: http.StatusNotFound},
+		{name: "status report invalid id", method: http.MethodGet, url: "/v1/status-reports/abc", expectedStatus: http.StatusBadRequest},
+
+		{name: "statement", method: http.MethodGet, url: "/v1/accounts/FR10474608000002006107XXXXX/statements?from=2022-06-01&to=2022-06-02", expectedStatus: http.StatusOK},
+		{name: "statement unsupported type", method: http.MethodGet, url: "/v1/accounts/FR10474608000002006107XXXXX/statements?type=camt.054", expectedStatus: http.StatusBadRequest},
+		{name: "statement unknown account", api: newContractAPI(newMockManager()).WithStatementManager(newMockStatementManager().WithError(core.ErrAccountNotFound)), method: http.MethodGet, url: "/v1/accounts/FR10474608000002006107XXXXX/statements", expectedStatus: http.StatusNotFound},
+
+		{name: "export csv", method: http.MethodGet, url: "/v1/accounts/FR10474608000002006107XXXXX/transactions/export", expectedStatus: http.StatusOK},
+		{name: "export ndjson", method: http.MethodGet, url: "/v1/accounts/FR10474608000002006107XXXXX/transactions/export?format=ndjson&columns=id,amount", expectedStatus: http.StatusOK},
+		{name: "export unknown column", method: http.MethodGet, url: "/v1/accounts/FR10474608000002006107XXXXX/transactions/export?columns=balance", expectedStatus: http.StatusBadRequest},
+		{name: "export unknown account", api: newContractAPI(newMockManager()).WithHistoryManager(newMockHistoryManager().WithError(core.ErrAccountNotFound)), method: http.MethodGet, url: "/v1/accounts/FR10474608000002006107XXXXX/transactions/export", expectedStatus: http.StatusNotFound},
+
+		{name: "screening hits", api: newContractAPI(newMockManager()).WithScreeningManager(newMockScreeningManager(hits...)), method: http.MethodGet, url: "/v1/screening/hits", expectedStatus: http.StatusOK},
+		{name: "screening hits failure", api: newContractAPI(newMockManager()).WithScreeningManager(newMockScreeningManager().WithError(errors.New("db is down"))), method: http.MethodGet, url: "/v1/screening/hits?status=open", expectedStatus: http.StatusInternalServerError},
+		{name: "clear screening hit", api: newContractAPI(newMockManager()).WithScreeningManager(newMockScreeningManager(hits...)), method: http.MethodPost, url: "/v1/screening/hits/1/clear", expectedStatus: http.StatusOK},
+		{name: "clear unknown screening hit", method: http.MethodPost, url: "/v1/screening/hits/3/clear", expectedStatus: http.StatusNotFound},
+		{name: "clear screening hit invalid id", method: http.MethodPost, url: "/v1/screening/hits/abc/clear", expectedStatus: http.StatusBadRequest},
+		{name: "reload screening list", method: http.MethodPost, url: "/v1/screening/list/reload", expectedStatus: http.StatusOK},
+		{name: "reload screening list failure", api: newContractAPI(newMockManager()).WithScreeningManager(newMockScreeningManager().WithError(errors.New("list is missing"))), method: http.MethodPost, url: "/v1/screening/list/reload", expectedStatus: http.StatusInternalServerError},
+	}
+
+	covered := map[string]bool{}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := tc.api
+			if api == nil {
+				api = newContractAPI(newMockManager())
+			}
+			var route string
+			router := chi.NewRouter()
+			router.Use(func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next.ServeHTTP(w, r)
+					route = chi.RouteContext(r.Context()).RoutePattern()
+				})
+			})
+			api.Routes(router)
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(tc.method, "http://localhost"+tc.url, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				r.Header.Set(HeaderContentType, tc.contentType)
+			}
+			router.ServeHTTP(w, r)
+			response := w.Result()
+			require.Equal(t, tc.expectedStatus, response.StatusCode, w.Body.String())
+
+			operation := doc.operation(tc.method, route)
+			require.NotNil(t, operation, "%s %s is not documented", tc.method, route)
+			covered[tc.method+" "+route] = true
+
+			if tc.body != "" {
+				requestType := MediaTypeJSON
+				if tc.contentType != "" {
+					requestType, _, err = mime.ParseMediaType(tc.contentType)
+					require.NoError(t, err)
+				}
+				media := doc.child(operation, "requestBody", "content", requestType)
+				if !tc.invalidRequest {
+					require.NotNil(t, media, "request media type %s is not documented", requestType)
+					if requestType == MediaTypeJSON {
+						var value interface{}
+						require.NoError(t, json.Unmarshal([]byte(tc.body), &value))
+						assert.Empty(t, doc.validate(doc.child(media, "schema"), value, "request"))
+					}
+				}
+			}
+
+			responseSpec := doc.child(operation, "responses", strconv.Itoa(response.StatusCode))
+			require.NotNil(t, responseSpec, "status %d is not documented", response.StatusCode)
+			responseType, _, err := mime.ParseMediaType(response.Header.Get(HeaderContentType))
+			require.NoError(t, err)
+			media := doc.child(responseSpec, "content", responseType)
+			require.NotNil(t, media, "response media type %s is not documented", responseType)
+			if responseType == MediaTypeJSON || responseType == MediaTypeProblemJSON {
+				var value interface{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &value))
+				assert.Empty(t, doc.validate(doc.child(media, "schema"), value, "response"))
+			}
+		})
+	}
+
+	for _, operation := range doc.operations() {
+		assert.True(t, covered[operation], "%s is not covered by contract tests", operation)
+	}
+}
+
+// newContractAPI returns API with all optional endpoints enabled
+func newContractAPI(manager *mockManager) *qontoAPI {
+	return NewAPI(manager).
+		WithReportManager(newMockReportManager()).
+		WithStatementManager(newMockStatementManager()).
+		WithHistoryManager(newMockHistoryManager(core.Transaction{
+			ID:           1,
+			Amount:       core.Amount{Cents: 1450},
+			Currency:     core.CURRENCY_EURO,
+			CounterParty: core.Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+			CreatedAt:    time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC),
+		})).
+		WithScreeningManager(newMockScreeningManager())
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"github.com/go-chi/chi"
+)
+
+// Routes registers endpoints on the router, endpoints of optional managers are registered only if they are set.
+// Every route must be described in OpenAPI specification served at /openapi.json
+func (qapi *qontoAPI) Routes(router chi.Router) {
+	router.Get("/openapi.json", HandleOpenAPI)
+	router.Post("/v1/transfers", qapi.HandleTransfers)
+
+	if qapi.reports != nil {
+		router.Get("/v1/status-reports/{id}", qapi.HandleStatusReport)
+	}
+	if qapi.statements != nil {
+		router.Get("/v1/accounts/{iban}/statements", qapi.HandleStatement)
+	}
+	if qapi.history != nil {
+		router.Get("/v1/accounts/{iban}/transactions/export", qapi.HandleExportTransactions)
+	}
+	if qapi.screening != nil {
+		router.Get("/v1/screening/hits", qapi.HandleListScreeningHits)
+		router.Post("/v1/screening/hits/{id}/clear", qapi.HandleClearScreeningHit)
+		router.Post("/v1/screening/list/reload", qapi.HandleReloadScreeningList)
+	}
+}
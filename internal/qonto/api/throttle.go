@@ -0,0 +1,64 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
+)
+
+// HeaderRetryAfter tells throttled clients how many seconds to wait before retrying
+const HeaderRetryAfter = "Retry-After"
+
+// admitClient admits the request by client address if client limiter is set. It is called before the body
+// is decoded, so a client naming other accounts in its requests cannot make the server decode them without limit.
+// Throttled requests get 429 response and false is returned, otherwise release must be called when done
+func (qapi *qontoAPI) admitClient(w http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	return acquire(w, r, qapi.clientLimiter, clientAddress(r))
+}
+
+// throttle admits the request by every account it debits if limiter is set. Accounts are known only
+// once the body is decoded, so handlers call it right before processing and must call release when done.
+// Throttled requests get 429 response and false is returned
+func (qapi *qontoAPI) throttle(w http.ResponseWriter, r *http.Request, ibans ...string) (release func(), ok bool) {
+	keys := make([]string, 0, len(ibans))
+	for _, iban := range ibans {
+		keys = append(keys, throttleKey(r, iban))
+	}
+	return acquire(w, r, qapi.limiter, keys...)
+}
+
+// acquire admits the request by all keys of the limiter, nil limiter admits every request
+func acquire(w http.ResponseWriter, r *http.Request, limiter *ratelimit.Limiter, keys ...string) (release func(), ok bool) {
+	if limiter == nil {
+		return func() {}, true
+	}
+	release, err := limiter.AcquireAll(keys...)
+	if err != nil {
+		var throttled *ratelimit.ThrottledError
+		if errors.As(err, &throttled) {
+			w.Header().Set(HeaderRetryAfter, strconv.Itoa(int(math.Ceil(math.Max(throttled.RetryAfter.Seconds(), 1)))))
+		}
+		handleErrors(w, r, err)
+		return nil, false
+	}
+	return release, true
+}
+
+// throttleKey identifies the request by IBAN of the debited account, requests without it by client address.
+// Headers are not trusted, any client could name another organization in them
+func throttleKey(r *http.Request, iban string) string {
+	return ratelimit.AccountKey(iban, clientAddress(r))
+}
+
+// clientAddress returns host of the connection, forwarding headers are not trusted
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
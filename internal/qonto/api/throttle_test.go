@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleTransfersThrottled(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.Limits{Rate: 0.1, Burst: 1, MaxInFlight: 1})
+	qapi := NewAPI(newMockManager()).WithLimiter(limiter)
+
+	send := func(iban, organization string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := `{"organization_iban": "` + iban + `", "credit_transfers": []}`
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/v1/transfers", strings.NewReader(body))
+		r.Header.Set(HeaderOrganization, organization)
+		qapi.HandleTransfers(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusCreated, send("FR10474608000002006107XXXXX", "ACME Corp").Code)
+
+	w := send("FR10474608000002006107XXXXX", "ACME Corp")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get(HeaderRetryAfter))
+	response := errorResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, CodeRateLimited, response.Code)
+
+	// the header is not trusted and spacing or case of the IBAN do not make another account
+	assert.Equal(t, http.StatusTooManyRequests, send("FR10474608000002006107XXXXX", "Globex").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("fr10 4746 0800 0002 0061 07xx xxx", "ACME Corp").Code)
+
+	// in flight request is released once handled, so only rate limit applies to other accounts
+	assert.Equal(t, http.StatusCreated, send("DE9935420810036209081725212", "ACME Corp").Code)
+
+	// requests in flight are counted while handled
+	release, err := limiter.Acquire("EE383680981021245685")
+	require.NoError(t, err)
+	defer release()
+	w = send("EE383680981021245685", "Initech")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get(HeaderRetryAfter))
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, CodeTooManyRequestsInFlight, response.Code)
+}
+
+func TestHandleTransfersClientThrottled(t *testing.T) {
+	clientLimiter := ratelimit.NewLimiter(ratelimit.Limits{Rate: 0.1, Burst: 2})
+	qapi := NewAPI(newMockManager()).WithClientLimiter(clientLimiter)
+
+	send := func(remoteAddr, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/v1/transfers", strings.NewReader(body))
+		r.RemoteAddr = remoteAddr
+		qapi.HandleTransfers(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusCreated, send("192.0.2.1:1234", `{"organization_iban": "FR10474608000002006107XXXXX", "credit_transfers": []}`).Code)
+	// the client is admitted before the body is decoded, so malformed requests are counted too
+	require.Equal(t, http.StatusBadRequest, send("192.0.2.1:1234", `{"organization_iban":`).Code)
+
+	// naming another account does not admit the client again
+	w := send("192.0.2.1:4321", `{"organization_iban": "DE9935420810036209081725212", "credit_transfers": []}`)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get(HeaderRetryAfter))
+	response := errorResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, CodeRateLimited, response.Code)
+
+	assert.Equal(t, http.StatusCreated, send("198.51.100.1:1234", `{"organization_iban": "DE9935420810036209081725212", "credit_transfers": []}`).Code)
+}
+
+func TestHandleTransfersPain001Throttled(t *testing.T) {
+	sample, err := os.ReadFile("../iso20022/testdata/pain001.xml")
+	require.NoError(t, err)
+	// the second payment information block debits another account
+	message := string(sample)
+	last := strings.LastIndex(message, "FR10474608000002006107XXXXX")
+	message = message[:last] + "DE9935420810036209081725212" + message[last+len("FR10474608000002006107XXXXX"):]
+
+	limiter := ratelimit.NewLimiter(ratelimit.Limits{MaxInFlight: 1})
+	qapi := NewAPI(newMockManager()).WithLimiter(limiter)
+	send := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "http://localhost/v1/transfers", strings.NewReader(message))
+		r.Header.Set(HeaderContentType, MediaTypeXML)
+		qapi.HandleTransfers(w, r)
+		return w
+	}
+
+	release, err := limiter.Acquire("DE9935420810036209081725212")
+	require.NoError(t, err)
+	w := send()
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	response := errorResponse{}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, CodeTooManyRequestsInFlight, response.Code)
+
+	// the first debtor is not charged by the throttled message
+	first, err := limiter.Acquire("FR10474608000002006107XXXXX")
+	require.NoError(t, err)
+	first()
+
+	release()
+	assert.Equal(t, http.StatusCreated, send().Code)
+}
+
+func TestThrottleKey(t *testing.T) {
+	testCases := []struct {
+		name         string
+		iban         string
+		organization string
+		expected     string
+	}{
+		{name: "debited account", iban: "FR10474608000002006107XXXXX", expected: "FR10474608000002006107XXXXX"},
+		{name: "spaced lowercase account", iban: "fr10 4746 0800 0002 0061 07xx xxx", expected: "FR10474608000002006107XXXXX"},
+		{name: "header is ignored", iban: "FR10474608000002006107XXXXX", organization: "ACME Corp", expected: "FR10474608000002006107XXXXX"},
+		{name: "client address", organization: "ACME Corp", expected: "192.0.2.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://localhost/v1/transfers", nil)
+			if tc.organization != "" {
+				r.Header.Set(HeaderOrganization, tc.organization)
+			}
+			assert.Equal(t, tc.expected, throttleKey(r, tc.iban))
+		})
+	}
+}
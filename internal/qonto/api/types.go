@@ -1,7 +1,10 @@
 package api
 
 import (
+	"time"
+
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
 )
 
 type (
@@ -9,7 +12,14 @@ type (
 	}
 
 	qontoAPI struct {
-		manager core.TransferManager
+		manager    core.TransferManager
+		screening  core.ScreeningManager
+		reports    core.ReportManager
+		statements core.StatementManager
+		history    core.HistoryManager
+		limiter    *ratelimit.Limiter
+		// clientLimiter admits requests by client address before they are decoded
+		clientLimiter *ratelimit.Limiter
 	}
 
 	Transfer struct {
@@ -26,5 +36,60 @@ type (
 		OrganizationBIC  string     `json:"organization_bic,omitempty"`
 		OrganizationIBAN string     `json:"organization_iban,omitempty"`
 		CreditTransfers  []Transfer `json:"credit_transfers,omitempty"`
+		// AllowDuplicates forces execution of transfers detected as duplicates, such transactions are flagged
+		AllowDuplicates bool `json:"allow_duplicates,omitempty"`
+		// Mode is either "all_or_nothing" (default) or "best_effort"
+		Mode string `json:"mode,omitempty"`
+	}
+
+	// TransferResult is an outcome of a single transfer, Index refers to position in credit_transfers
+	TransferResult struct {
+		Index  int    `json:"index"`
+		Status string `json:"status"`
+		Code   string `json:"code,omitempty"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	// BulkResponse is returned for requests processed in best effort mode
+	BulkResponse struct {
+		Mode     string `json:"mode"`
+		Accepted int    `json:"accepted"`
+		Rejected int    `json:"rejected"`
+		// Held transfers are executed once screening hits of their counterparties are cleared
+		Held    int              `json:"held"`
+		Results []TransferResult `json:"results"`
+	}
+
+	// PaymentResult is an outcome of a single payment information block of pain.001 message
+	PaymentResult struct {
+		PmtInfId string `json:"pmt_inf_id"`
+		Status   string `json:"status"`
+		Code     string `json:"code,omitempty"`
+		Error    string `json:"error,omitempty"`
+	}
+
+	// Pain001Response is returned for processed pain.001 messages
+	Pain001Response struct {
+		MessageId string          `json:"message_id"`
+		Accepted  int             `json:"accepted"`
+		Rejected  int             `json:"rejected"`
+		Held      int             `json:"held"`
+		Payments  []PaymentResult `json:"payments"`
+		// StatusReportId refers to pain.002 report of the message, available at /v1/status-reports/{id}
+		StatusReportId int64 `json:"status_report_id,omitempty"`
+	}
+
+	ScreeningHit struct {
+		ID               int64      `json:"id"`
+		CounterpartyName string     `json:"counterparty_name"`
+		CounterpartyBIC  string     `json:"counterparty_bic"`
+		CounterpartyIBAN string     `json:"counterparty_iban"`
+		EntryID          string     `json:"entry_id"`
+		EntryName        string     `json:"entry_name"`
+		MatchedField     string     `json:"matched_field"`
+		Score            float64    `json:"score"`
+		Status           string     `json:"status"`
+		CreatedAt        time.Time  `json:"created_at"`
+		ClearedAt        *time.Time `json:"cleared_at,omitempty"`
 	}
 )
@@ -1,13 +1,87 @@
 package app
 
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// database drivers supported by the app
+const (
+	DB_DRIVER_MYSQL    = "mysql"
+	DB_DRIVER_POSTGRES = "postgres"
+)
+
 // Configuration holds application configuration
 type Configuration struct {
 	ListenAddress string
-	DB            struct {
+	// GRPCListenAddress is an address of gRPC server, it must differ from ListenAddress
+	GRPCListenAddress string
+	// ShutdownDelay is the time between failing readiness probe and stopping servers,
+	// so load balancers stop sending new requests
+	ShutdownDelay time.Duration
+	// TracingOutput is "stdout" or a path of the file spans are appended to, tracing is disabled if empty
+	TracingOutput string
+	// RulesFile is a path to risk rules configuration, no rules are evaluated if empty
+	RulesFile string
+	Screening struct {
+		// ListFile is a path to sanctions list, screening is disabled if empty
+		ListFile string
+		// Threshold is minimal similarity of names in [0, 1] range to report a hit
+		Threshold float64
+	}
+	Duplicates struct {
+		// Mode is one of "off", "flag", "reject"
+		Mode   string
+		Window time.Duration
+	}
+	// RateLimit limits bulk transfer requests per debited account, zero value of any limit disables it
+	RateLimit struct {
+		// Rate is the number of requests per second
+		Rate float64
+		// Burst is the number of requests admitted at once after a quiet period
+		Burst int
+		// MaxInFlight is the number of requests processed at the same time
+		MaxInFlight int
+		// Overrides are limits of particular accounts as "iban=rate:burst:max_in_flight,..."
+		Overrides string
+	}
+	// ClientRateLimit limits bulk transfer requests per client address before they are decoded,
+	// zero value of any limit disables it
+	ClientRateLimit struct {
+		Rate        float64
+		Burst       int
+		MaxInFlight int
+	}
+	// Dispatcher serializes transfer requests per debited account
+	Dispatcher struct {
+		// Workers is the number of requests of different accounts processed in parallel, zero disables the dispatcher
+		Workers int
+		// QueueSize is the number of requests the queue of every account holds before new ones are rejected, at least 1
+		QueueSize int
+		// IdleTimeout is how long the queue of an account lives without requests
+		IdleTimeout time.Duration
+	}
+	Log struct {
+		// Level is one of "debug", "info", "warn", "error"
+		Level string
+		// Format is one of "text", "json"
+		Format string
+	}
+	DB struct {
+		// Driver is one of "mysql", "postgres"
+		Driver   string
 		Address  string
 		User     string
 		Password string
 		Name     string
+		// Retry limits retries of deadlocked transactions
+		Retry struct {
+			// MaxAttempts is the number of times the transaction is run, 1 disables retries
+			MaxAttempts int
+			BaseDelay   time.Duration
+			MaxDelay    time.Duration
+		}
 	}
 }
 
@@ -20,10 +94,157 @@ func ConfigurationFromEnv(envGetter EnvGetterFunc) (*Configuration, error) {
 	}
 
 	config.ListenAddress = listenAddr
+	config.GRPCListenAddress = envGetter("QONTO_GRPC_LISTEN_ADDRESS")
+	if config.GRPCListenAddress == "" {
+		config.GRPCListenAddress = "127.0.0.1:9090"
+	}
+	config.ShutdownDelay = 5 * time.Second
+	if delay := envGetter("QONTO_SHUTDOWN_DELAY"); delay != "" {
+		value, err := time.ParseDuration(delay)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_SHUTDOWN_DELAY must be a non-negative duration, got %q", delay)
+		}
+		config.ShutdownDelay = value
+	}
+	config.TracingOutput = envGetter("QONTO_TRACING_OUTPUT")
+	config.RulesFile = envGetter("QONTO_RULES_FILE")
+	config.Screening.ListFile = envGetter("QONTO_SCREENING_LIST_FILE")
+	config.Screening.Threshold = 0.9
+	if threshold := envGetter("QONTO_SCREENING_THRESHOLD"); threshold != "" {
+		value, err := strconv.ParseFloat(threshold, 64)
+		if err != nil || value <= 0 || value > 1 {
+			return nil, fmt.Errorf("QONTO_SCREENING_THRESHOLD must be a number in (0, 1] range, got %q", threshold)
+		}
+		config.Screening.Threshold = value
+	}
+	config.Duplicates.Mode = envGetter("QONTO_DUPLICATES_MODE")
+	if config.Duplicates.Mode == "" {
+		// existing clients may send identical transfers on purpose, so they are only marked by default
+		config.Duplicates.Mode = "flag"
+	}
+	config.Duplicates.Window = 24 * time.Hour
+	if window := envGetter("QONTO_DUPLICATES_WINDOW"); window != "" {
+		value, err := time.ParseDuration(window)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("QONTO_DUPLICATES_WINDOW must be a positive duration, got %q", window)
+		}
+		config.Duplicates.Window = value
+	}
+
+	config.RateLimit.Rate = 10
+	if rate := envGetter("QONTO_RATE_LIMIT"); rate != "" {
+		value, err := strconv.ParseFloat(rate, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_RATE_LIMIT must be a non-negative number, got %q", rate)
+		}
+		config.RateLimit.Rate = value
+	}
+	config.RateLimit.Burst = 20
+	if burst := envGetter("QONTO_RATE_BURST"); burst != "" {
+		value, err := strconv.Atoi(burst)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_RATE_BURST must be a non-negative integer, got %q", burst)
+		}
+		config.RateLimit.Burst = value
+	}
+	config.RateLimit.MaxInFlight = 4
+	if maxInFlight := envGetter("QONTO_MAX_IN_FLIGHT"); maxInFlight != "" {
+		value, err := strconv.Atoi(maxInFlight)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_MAX_IN_FLIGHT must be a non-negative integer, got %q", maxInFlight)
+		}
+		config.RateLimit.MaxInFlight = value
+	}
+	config.RateLimit.Overrides = envGetter("QONTO_RATE_LIMIT_OVERRIDES")
+
+	config.ClientRateLimit.Rate = 20
+	if rate := envGetter("QONTO_CLIENT_RATE_LIMIT"); rate != "" {
+		value, err := strconv.ParseFloat(rate, 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_CLIENT_RATE_LIMIT must be a non-negative number, got %q", rate)
+		}
+		config.ClientRateLimit.Rate = value
+	}
+	config.ClientRateLimit.Burst = 40
+	if burst := envGetter("QONTO_CLIENT_RATE_BURST"); burst != "" {
+		value, err := strconv.Atoi(burst)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_CLIENT_RATE_BURST must be a non-negative integer, got %q", burst)
+		}
+		config.ClientRateLimit.Burst = value
+	}
+	config.ClientRateLimit.MaxInFlight = 8
+	if maxInFlight := envGetter("QONTO_CLIENT_MAX_IN_FLIGHT"); maxInFlight != "" {
+		value, err := strconv.Atoi(maxInFlight)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_CLIENT_MAX_IN_FLIGHT must be a non-negative integer, got %q", maxInFlight)
+		}
+		config.ClientRateLimit.MaxInFlight = value
+	}
+
+	config.Dispatcher.Workers = 16
+	if workers := envGetter("QONTO_DISPATCHER_WORKERS"); workers != "" {
+		value, err := strconv.Atoi(workers)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_DISPATCHER_WORKERS must be a non-negative integer, got %q", workers)
+		}
+		config.Dispatcher.Workers = value
+	}
+	config.Dispatcher.QueueSize = 32
+	if queueSize := envGetter("QONTO_DISPATCHER_QUEUE_SIZE"); queueSize != "" {
+		value, err := strconv.Atoi(queueSize)
+		if err != nil || value < 1 {
+			return nil, fmt.Errorf("QONTO_DISPATCHER_QUEUE_SIZE must be a positive integer, got %q", queueSize)
+		}
+		config.Dispatcher.QueueSize = value
+	}
+	config.Dispatcher.IdleTimeout = time.Minute
+	if idleTimeout := envGetter("QONTO_DISPATCHER_IDLE_TIMEOUT"); idleTimeout != "" {
+		value, err := time.ParseDuration(idleTimeout)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("QONTO_DISPATCHER_IDLE_TIMEOUT must be a positive duration, got %q", idleTimeout)
+		}
+		config.Dispatcher.IdleTimeout = value
+	}
+
+	config.Log.Level = envGetter("QONTO_LOG_LEVEL")
+	config.Log.Format = envGetter("QONTO_LOG_FORMAT")
+
+	config.DB.Driver = envGetter("QONTO_DB_DRIVER")
+	if config.DB.Driver == "" {
+		config.DB.Driver = DB_DRIVER_MYSQL
+	}
+	if config.DB.Driver != DB_DRIVER_MYSQL && config.DB.Driver != DB_DRIVER_POSTGRES {
+		return nil, fmt.Errorf("QONTO_DB_DRIVER must be one of %q, %q, got %q", DB_DRIVER_MYSQL, DB_DRIVER_POSTGRES, config.DB.Driver)
+	}
 	config.DB.Address = envGetter("QONTO_DB_ADDRESS")
 	config.DB.Name = envGetter("QONTO_DB_NAME")
 	config.DB.Password = envGetter("QONTO_DB_PASSWORD")
 	config.DB.User = envGetter("QONTO_DB_USER")
+	config.DB.Retry.MaxAttempts = 3
+	if maxAttempts := envGetter("QONTO_DB_RETRY_MAX_ATTEMPTS"); maxAttempts != "" {
+		value, err := strconv.Atoi(maxAttempts)
+		if err != nil || value < 1 {
+			return nil, fmt.Errorf("QONTO_DB_RETRY_MAX_ATTEMPTS must be a positive integer, got %q", maxAttempts)
+		}
+		config.DB.Retry.MaxAttempts = value
+	}
+	config.DB.Retry.BaseDelay = 10 * time.Millisecond
+	if delay := envGetter("QONTO_DB_RETRY_BASE_DELAY"); delay != "" {
+		value, err := time.ParseDuration(delay)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_DB_RETRY_BASE_DELAY must be a non-negative duration, got %q", delay)
+		}
+		config.DB.Retry.BaseDelay = value
+	}
+	config.DB.Retry.MaxDelay = 200 * time.Millisecond
+	if delay := envGetter("QONTO_DB_RETRY_MAX_DELAY"); delay != "" {
+		value, err := time.ParseDuration(delay)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("QONTO_DB_RETRY_MAX_DELAY must be a non-negative duration, got %q", delay)
+		}
+		config.DB.Retry.MaxDelay = value
+	}
 
 	return &config, nil
 }
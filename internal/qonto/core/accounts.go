@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// Account is a Qonto account with its current balance
+	Account struct {
+		Party    Party
+		Balance  Amount
+		Currency Currency
+	}
+
+	// AccountManager gives access to accounts
+	AccountManager interface {
+		// Account returns account identified by IBAN, ErrAccountNotFound if there is no such account
+		Account(ctx context.Context, iban string) (*Account, error)
+	}
+
+	qontoAccountManager struct {
+		storage storage.Storage
+	}
+)
+
+func NewQontoAccountManager(storage storage.Storage) *qontoAccountManager {
+	return &qontoAccountManager{
+		storage: storage,
+	}
+}
+
+// Account implements AccountManager interface
+func (am *qontoAccountManager) Account(ctx context.Context, iban string) (*Account, error) {
+	account, err := am.storage.FindAccountByIBAN(ctx, iban)
+	if err != nil {
+		return nil, fromStorage(err)
+	}
+
+	return &Account{
+		Party: Party{
+			Name: account.Name,
+			BIC:  account.BIC,
+			IBAN: account.IBAN,
+		},
+		Balance: Amount{Cents: account.BalanceCents},
+		// accounts are kept in euro only
+		Currency: CURRENCY_EURO,
+	}, nil
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -32,6 +33,16 @@ func ParseAmount(s string) (Amount, error) {
 	return Amount{Cents: int64(eurosInt)*100 + int64(centsInt)}, nil
 }
 
+// String formats amount with two decimals after period, the format is accepted by ParseAmount
+func (a Amount) String() string {
+	sign, cents := "", a.Cents
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
 func (a *Amount) MarshalJSON() ([]byte, error) {
 	euroes, cents := a.Cents/100, a.Cents%100
 	result := strconv.FormatInt(euroes, 10)
@@ -166,3 +166,27 @@ func TestAmountUnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestAmountString(t *testing.T) {
+	cases := []struct {
+		cents          int64
+		expectedResult string
+	}{
+		{cents: 0, expectedResult: "0.00"},
+		{cents: 5, expectedResult: "0.05"},
+		{cents: 1450, expectedResult: "14.50"},
+		{cents: 6123800, expectedResult: "61238.00"},
+		{cents: -150, expectedResult: "-1.50"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.expectedResult, func(t *testing.T) {
+			amount := Amount{Cents: tc.cents}
+			assert.Equal(t, tc.expectedResult, amount.String())
+			if tc.cents >= 0 {
+				parsed, err := ParseAmount(amount.String())
+				assert.NoError(t, err)
+				assert.Equal(t, amount, parsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// DuplicatesMode defines what happens to transfers already seen within the window
+	DuplicatesMode string
+
+	// DuplicatesPolicy configures detection of transfers repeated across requests
+	DuplicatesPolicy struct {
+		Mode   DuplicatesMode
+		Window time.Duration
+	}
+)
+
+const (
+	// DUPLICATES_OFF disables detection
+	DUPLICATES_OFF DuplicatesMode = "off"
+	// DUPLICATES_FLAG executes duplicates and marks them
+	DUPLICATES_FLAG DuplicatesMode = "flag"
+	// DUPLICATES_REJECT rejects the request unless duplicates are explicitly allowed in it
+	DUPLICATES_REJECT DuplicatesMode = "reject"
+)
+
+// ParseDuplicatesMode validates mode name
+func ParseDuplicatesMode(s string) (DuplicatesMode, error) {
+	switch mode := DuplicatesMode(s); mode {
+	case DUPLICATES_OFF, DUPLICATES_FLAG, DUPLICATES_REJECT:
+		return mode, nil
+	}
+
+	return "", fmt.Errorf("unknown duplicates mode %q", s)
+}
+
+// Fingerprint identifies the transfer by counterparty IBAN, amount and description
+func Fingerprint(transfer Transfer) string {
+	iban := strings.ToUpper(strings.Join(strings.Fields(transfer.CounterParty.IBAN), ""))
+	data := iban + "|" + strconv.FormatInt(transfer.Amount.Cents, 10) + "|" + strings.TrimSpace(transfer.Description)
+	sum := sha256.Sum256([]byte(data))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// paidFingerprints returns those of the fingerprints which the account has paid within the policy window.
+// Transactions executed by earlier chunks of the request are found too, as they are inserted by the same DB transaction.
+// Nothing is looked up if detection is disabled
+func paidFingerprints(ctx context.Context, s storage.Storage, policy DuplicatesPolicy, accountID int64, fingerprints []string, now time.Time) (map[string]bool, error) {
+	if !policy.enabled() {
+		return map[string]bool{}, nil
+	}
+
+	unique := make([]string, 0, len(fingerprints))
+	seen := make(map[string]bool, len(fingerprints))
+	for _, fp := range fingerprints {
+		if !seen[fp] {
+			seen[fp] = true
+			unique = append(unique, fp)
+		}
+	}
+
+	found, err := s.FindTransactionFingerprints(ctx, accountID, unique, now.Add(-policy.Window))
+	if err != nil {
+		return nil, err
+	}
+	paid := make(map[string]bool, len(found))
+	for _, fp := range found {
+		paid[fp] = true
+	}
+
+	return paid, nil
+}
+
+func (p DuplicatesPolicy) enabled() bool {
+	return p.Mode != "" && p.Mode != DUPLICATES_OFF
+}
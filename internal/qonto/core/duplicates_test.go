@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fingerprintsStub knows fingerprints of account transactions, other storage methods are not used
+type fingerprintsStub struct {
+	storage.Storage
+	known []string
+	since time.Time
+}
+
+func (fs *fingerprintsStub) FindTransactionFingerprints(ctx context.Context, accountID int64, fingerprints []string, since time.Time) ([]string, error) {
+	fs.since = since
+	found := []string{}
+	for _, fp := range fingerprints {
+		for _, known := range fs.known {
+			if fp == known {
+				found = append(found, fp)
+			}
+		}
+	}
+
+	return found, nil
+}
+
+func TestFingerprint(t *testing.T) {
+	base := Transfer{
+		Amount:       Amount{Cents: 1450},
+		Currency:     CURRENCY_EURO,
+		Description:  "Wonderland/4410",
+		CounterParty: Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+	}
+
+	cases := []struct {
+		name          string
+		transfer      Transfer
+		expectedEqual bool
+	}{
+		{
+			name: "counterparty name and BIC are ignored",
+			transfer: Transfer{
+				Amount:       Amount{Cents: 1450},
+				Description:  "Wonderland/4410",
+				CounterParty: Party{Name: "Bip-Bip", IBAN: "EE383680981021245685"},
+			},
+			expectedEqual: true,
+		},
+		{
+			name: "IBAN formatting is ignored",
+			transfer: Transfer{
+				Amount:       Amount{Cents: 1450},
+				Description:  " Wonderland/4410 ",
+				CounterParty: Party{IBAN: "ee38 3680 9810 2124 5685"},
+			},
+			expectedEqual: true,
+		},
+		{
+			name: "different amount",
+			transfer: Transfer{
+				Amount:       Amount{Cents: 1451},
+				Description:  "Wonderland/4410",
+				CounterParty: Party{IBAN: "EE383680981021245685"},
+			},
+			expectedEqual: false,
+		},
+		{
+			name: "different description",
+			transfer: Transfer{
+				Amount:       Amount{Cents: 1450},
+				Description:  "Wonderland/4411",
+				CounterParty: Party{IBAN: "EE383680981021245685"},
+			},
+			expectedEqual: false,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Len(t, Fingerprint(tc.transfer), 64)
+			assert.Equal(t, tc.expectedEqual, Fingerprint(base) == Fingerprint(tc.transfer))
+		})
+	}
+}
+
+func TestParseDuplicatesMode(t *testing.T) {
+	for _, mode := range []string{"off", "flag", "reject"} {
+		parsed, err := ParseDuplicatesMode(mode)
+		assert.NoError(t, err)
+		assert.Equal(t, DuplicatesMode(mode), parsed)
+	}
+
+	_, err := ParseDuplicatesMode("ignore")
+	assert.Error(t, err)
+}
+
+func TestPaidFingerprints(t *testing.T) {
+	now := time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC)
+	policy := DuplicatesPolicy{Mode: DUPLICATES_REJECT, Window: time.Hour}
+
+	cases := []struct {
+		name         string
+		policy       DuplicatesPolicy
+		known        []string
+		fingerprints []string
+		expectedPaid map[string]bool
+	}{
+		{
+			name:         "nothing paid",
+			policy:       policy,
+			fingerprints: []string{"a", "b"},
+			expectedPaid: map[string]bool{},
+		},
+		{
+			name:         "paid by account transaction",
+			policy:       policy,
+			known:        []string{"b"},
+			fingerprints: []string{"a", "b", "c"},
+			expectedPaid: map[string]bool{"b": true},
+		},
+		{
+			name:         "repeated fingerprints are looked up once",
+			policy:       policy,
+			known:        []string{"a"},
+			fingerprints: []string{"a", "b", "a"},
+			expectedPaid: map[string]bool{"a": true},
+		},
+		{
+			name:         "detection disabled",
+			policy:       DuplicatesPolicy{Mode: DUPLICATES_OFF, Window: time.Hour},
+			known:        []string{"a"},
+			fingerprints: []string{"a", "a"},
+			expectedPaid: map[string]bool{},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &fingerprintsStub{known: tc.known}
+			paid, err := paidFingerprints(context.Background(), stub, tc.policy, 1, tc.fingerprints, now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPaid, paid)
+			if tc.policy.Mode != DUPLICATES_OFF {
+				assert.Equal(t, now.Add(-time.Hour), stub.since)
+			}
+		})
+	}
+}
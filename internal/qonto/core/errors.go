@@ -1,5 +1,11 @@
 package core
 
+import (
+	"errors"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
 type Error string
 
 func (e Error) Error() string {
@@ -9,4 +15,52 @@ func (e Error) Error() string {
 const (
 	ErrNotEnoughFunds  = Error("not enough funds")
 	ErrInvalidCurrency = Error("provided currency is not valid")
+	ErrInvalidAmount   = Error("amount must be positive")
+
+	ErrSingleTransferLimitExceeded = Error("single transfer limit exceeded")
+	ErrDailyLimitExceeded          = Error("daily outgoing volume limit exceeded")
+	ErrMonthlyLimitExceeded        = Error("monthly outgoing volume limit exceeded")
+	ErrBatchSizeLimitExceeded      = Error("number of transfers per batch limit exceeded")
+
+	ErrTransferDenied            = Error("transfer denied by risk rules")
+	ErrNewBeneficiaryLargeAmount = Error("large amount to a new beneficiary")
+	ErrRoundAmountBurst          = Error("burst of round amount transfers")
+	ErrBlockedCountry            = Error("counterparty country is blocked")
+
+	ErrDuplicateTransfer = Error("duplicate of another transfer")
+
+	ErrScreeningHit         = Error("transfer is on hold: counterparty matches sanctions list")
+	ErrScreeningHitNotFound = Error("open screening hit not found")
+
+	ErrStatusReportNotFound = Error("status report not found")
+
+	ErrAccountNotFound = Error("account not found")
+	ErrInvalidPeriod   = Error("invalid period")
+
+	ErrDuplicateIBAN       = Error("account with the IBAN already exists")
+	ErrConstraintViolation = Error("request refers to missing or still referenced data")
+	ErrConflict            = Error("conflicting concurrent change, retry later")
 )
+
+// storageErrorMappings defines core errors of storage ones, first match wins
+var storageErrorMappings = []struct {
+	storage error
+	core    error
+}{
+	{storage.ErrAccountNotFound, ErrAccountNotFound},
+	{storage.ErrStatusReportNotFound, ErrStatusReportNotFound},
+	{storage.ErrScreeningHitNotFound, ErrScreeningHitNotFound},
+	{storage.ErrDuplicateIBAN, ErrDuplicateIBAN},
+	{storage.ErrConstraintViolation, ErrConstraintViolation},
+	{storage.ErrConflict, ErrConflict},
+}
+
+// fromStorage replaces known storage errors by core ones, so database details do not reach callers
+func fromStorage(err error) error {
+	for _, mapping := range storageErrorMappings {
+		if errors.Is(err, mapping.storage) {
+			return mapping.core
+		}
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromStorage(t *testing.T) {
+	errDBDown := errors.New("connection refused")
+
+	testCases := []struct {
+		err         error
+		expectedErr error
+	}{
+		{fmt.Errorf("%w: %v", storage.ErrAccountNotFound, sql.ErrNoRows), ErrAccountNotFound},
+		{storage.ErrStatusReportNotFound, ErrStatusReportNotFound},
+		{storage.ErrDuplicateIBAN, ErrDuplicateIBAN},
+		{storage.ErrConstraintViolation, ErrConstraintViolation},
+		{storage.ErrConflict, ErrConflict},
+		{ErrNotEnoughFunds, ErrNotEnoughFunds},
+		{errDBDown, errDBDown},
+		{nil, nil},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expectedErr, fromStorage(tc.err))
+	}
+}
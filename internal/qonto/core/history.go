@@ -0,0 +1,64 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// Transaction is a booked transfer of the account
+	Transaction struct {
+		ID               int64
+		Amount           Amount
+		Currency         Currency
+		Description      string
+		CounterParty     Party
+		CreatedAt        time.Time
+		FlaggedDuplicate bool
+	}
+
+	// HistoryManager gives access to transaction history of accounts
+	HistoryManager interface {
+		// EachTransaction calls f for every account transaction created within [from, to) period in creation order,
+		// zero time means no boundary. Transactions are not loaded in memory at once,
+		// so f may write them out as they come, error returned by f stops the iteration
+		EachTransaction(ctx context.Context, iban string, from, to time.Time, f func(Transaction) error) error
+	}
+
+	qontoHistoryManager struct {
+		storage storage.Storage
+	}
+)
+
+func NewQontoHistoryManager(storage storage.Storage) *qontoHistoryManager {
+	return &qontoHistoryManager{
+		storage: storage,
+	}
+}
+
+// EachTransaction implements HistoryManager interface
+func (hm *qontoHistoryManager) EachTransaction(ctx context.Context, iban string, from, to time.Time, f func(Transaction) error) error {
+	account, err := hm.storage.FindAccountByIBAN(ctx, iban)
+	if err != nil {
+		return fromStorage(err)
+	}
+
+	filter := storage.TransactionFilter{Since: from, Until: to}
+	return hm.storage.EachAccountTransaction(ctx, account.ID, filter, func(tx *storage.Transaction) error {
+		return f(Transaction{
+			ID:          tx.ID,
+			Amount:      Amount{Cents: tx.AmountCents},
+			Currency:    Currency(tx.AmountCurrency),
+			Description: tx.Description,
+			CounterParty: Party{
+				Name: tx.CounterpartyName,
+				BIC:  tx.CounterpartyBIC,
+				IBAN: tx.CounterpartyIBAN,
+			},
+			CreatedAt:        tx.CreatedAt,
+			FlaggedDuplicate: tx.FlaggedDuplicate,
+		})
+	})
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// volumeFunc returns outgoing volume of the account since the given time,
+	// only transfers to counterpartyIBAN are counted if it is not empty
+	volumeFunc func(ctx context.Context, counterpartyIBAN string, since time.Time) (int64, error)
+
+	// limitsChecker evaluates transfer limits of a single account
+	// and accumulates usage of transfers admitted so far
+	limitsChecker struct {
+		// limits are keyed by counterparty IBAN, empty key holds organization-wide limits
+		limits     map[string]storage.TransferLimit
+		usage      map[string]*limitUsage
+		volume     volumeFunc
+		dayStart   time.Time
+		monthStart time.Time
+	}
+
+	limitUsage struct {
+		daily     int64
+		monthly   int64
+		transfers int
+	}
+)
+
+func newLimitsChecker(limits []storage.TransferLimit, now time.Time, volume volumeFunc) *limitsChecker {
+	now = now.UTC()
+	lc := &limitsChecker{
+		limits:     make(map[string]storage.TransferLimit, len(limits)),
+		usage:      map[string]*limitUsage{},
+		volume:     volume,
+		dayStart:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		monthStart: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+	}
+	for _, limit := range limits {
+		lc.limits[limit.CounterpartyIBAN] = limit
+	}
+
+	return lc
+}
+
+// admit checks the transfer against organization-wide and counterparty limits.
+// Usage is accounted only if the transfer passes all of them
+func (lc *limitsChecker) admit(ctx context.Context, transfer Transfer) error {
+	keys := []string{""}
+	if transfer.CounterParty.IBAN != "" {
+		keys = append(keys, transfer.CounterParty.IBAN)
+	}
+
+	usages := make([]*limitUsage, 0, len(keys))
+	for _, key := range keys {
+		limit, ok := lc.limits[key]
+		if !ok {
+			continue
+		}
+		usage, err := lc.usageOf(ctx, limit)
+		if err != nil {
+			return err
+		}
+		if err := checkLimit(limit, usage, transfer.Amount.Cents); err != nil {
+			return fmt.Errorf("%w: %s", err, limitScope(limit))
+		}
+		usages = append(usages, usage)
+	}
+
+	for _, usage := range usages {
+		usage.daily += transfer.Amount.Cents
+		usage.monthly += transfer.Amount.Cents
+		usage.transfers++
+	}
+
+	return nil
+}
+
+func (lc *limitsChecker) usageOf(ctx context.Context, limit storage.TransferLimit) (*limitUsage, error) {
+	if usage, ok := lc.usage[limit.CounterpartyIBAN]; ok {
+		return usage, nil
+	}
+
+	usage := &limitUsage{}
+	var err error
+	if limit.MaxDailyCents > 0 {
+		if usage.daily, err = lc.volume(ctx, limit.CounterpartyIBAN, lc.dayStart); err != nil {
+			return nil, err
+		}
+	}
+	if limit.MaxMonthlyCents > 0 {
+		if usage.monthly, err = lc.volume(ctx, limit.CounterpartyIBAN, lc.monthStart); err != nil {
+			return nil, err
+		}
+	}
+	lc.usage[limit.CounterpartyIBAN] = usage
+
+	return usage, nil
+}
+
+func checkLimit(limit storage.TransferLimit, usage *limitUsage, amount int64) error {
+	switch {
+	case limit.MaxSingleTransferCents > 0 && amount > limit.MaxSingleTransferCents:
+		return ErrSingleTransferLimitExceeded
+	case limit.MaxTransfersPerBatch > 0 && usage.transfers+1 > limit.MaxTransfersPerBatch:
+		return ErrBatchSizeLimitExceeded
+	case limit.MaxDailyCents > 0 && usage.daily+amount > limit.MaxDailyCents:
+		return ErrDailyLimitExceeded
+	case limit.MaxMonthlyCents > 0 && usage.monthly+amount > limit.MaxMonthlyCents:
+		return ErrMonthlyLimitExceeded
+	}
+
+	return nil
+}
+
+func limitScope(limit storage.TransferLimit) string {
+	if limit.CounterpartyIBAN == "" {
+		return "organization"
+	}
+
+	return "counterparty " + limit.CounterpartyIBAN
+}
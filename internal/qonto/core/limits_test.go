@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitsCheckerAdmit(t *testing.T) {
+	now := time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC)
+	transferTo := func(iban string, cents int64) Transfer {
+		return Transfer{
+			Amount:       Amount{Cents: cents},
+			Currency:     CURRENCY_EURO,
+			CounterParty: Party{IBAN: iban},
+		}
+	}
+	// volumes already spent: 100 today and 500 this month for every scope
+	volume := func(ctx context.Context, counterpartyIBAN string, since time.Time) (int64, error) {
+		if since.Equal(time.Date(2022, 6, 15, 0, 0, 0, 0, time.UTC)) {
+			return 100, nil
+		}
+		if since.Equal(time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)) {
+			return 500, nil
+		}
+		return 0, errors.New("unexpected period start")
+	}
+
+	cases := []struct {
+		name          string
+		limits        []storage.TransferLimit
+		transfers     []Transfer
+		expectedError error
+	}{
+		{
+			name:      "no limits",
+			transfers: []Transfer{transferTo("iban1", 1000000), transferTo("iban2", 1000000)},
+		},
+		{
+			name:      "organization single transfer limit",
+			limits:    []storage.TransferLimit{{MaxSingleTransferCents: 1000}},
+			transfers: []Transfer{transferTo("iban1", 1000), transferTo("iban2", 1001)},
+
+			expectedError: ErrSingleTransferLimitExceeded,
+		},
+		{
+			name:      "counterparty single transfer limit does not affect other counterparties",
+			limits:    []storage.TransferLimit{{CounterpartyIBAN: "iban1", MaxSingleTransferCents: 1000}},
+			transfers: []Transfer{transferTo("iban1", 1000), transferTo("iban2", 5000)},
+		},
+		{
+			name:      "counterparty single transfer limit",
+			limits:    []storage.TransferLimit{{CounterpartyIBAN: "iban1", MaxSingleTransferCents: 1000}},
+			transfers: []Transfer{transferTo("iban2", 5000), transferTo("iban1", 1001)},
+
+			expectedError: ErrSingleTransferLimitExceeded,
+		},
+		{
+			name:      "organization transfers per batch limit",
+			limits:    []storage.TransferLimit{{MaxTransfersPerBatch: 2}},
+			transfers: []Transfer{transferTo("iban1", 1), transferTo("iban2", 1), transferTo("iban3", 1)},
+
+			expectedError: ErrBatchSizeLimitExceeded,
+		},
+		{
+			name:      "counterparty transfers per batch limit",
+			limits:    []storage.TransferLimit{{CounterpartyIBAN: "iban1", MaxTransfersPerBatch: 1}},
+			transfers: []Transfer{transferTo("iban1", 1), transferTo("iban2", 1), transferTo("iban1", 1)},
+
+			expectedError: ErrBatchSizeLimitExceeded,
+		},
+		{
+			name:      "daily limit reached exactly",
+			limits:    []storage.TransferLimit{{MaxDailyCents: 300}},
+			transfers: []Transfer{transferTo("iban1", 100), transferTo("iban2", 100)},
+		},
+		{
+			name:      "daily limit includes already spent volume",
+			limits:    []storage.TransferLimit{{MaxDailyCents: 300}},
+			transfers: []Transfer{transferTo("iban1", 100), transferTo("iban2", 101)},
+
+			expectedError: ErrDailyLimitExceeded,
+		},
+		{
+			name:      "monthly limit",
+			limits:    []storage.TransferLimit{{CounterpartyIBAN: "iban1", MaxMonthlyCents: 1000}},
+			transfers: []Transfer{transferTo("iban1", 400), transferTo("iban1", 101)},
+
+			expectedError: ErrMonthlyLimitExceeded,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			checker := newLimitsChecker(tc.limits, now, volume)
+			var err error
+			for _, transfer := range tc.transfers {
+				if err = checker.admit(context.Background(), transfer); err != nil {
+					break
+				}
+			}
+			if tc.expectedError == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestLimitsCheckerAdmitDoesNotAccountRejected(t *testing.T) {
+	limits := []storage.TransferLimit{
+		{MaxDailyCents: 1000},
+		{CounterpartyIBAN: "iban1", MaxSingleTransferCents: 500},
+	}
+	volume := func(ctx context.Context, counterpartyIBAN string, since time.Time) (int64, error) {
+		return 0, nil
+	}
+	checker := newLimitsChecker(limits, time.Now(), volume)
+
+	err := checker.admit(context.Background(), Transfer{Amount: Amount{Cents: 600}, CounterParty: Party{IBAN: "iban1"}})
+	assert.ErrorIs(t, err, ErrSingleTransferLimitExceeded)
+
+	// rejected transfer must not consume organization daily volume
+	err = checker.admit(context.Background(), Transfer{Amount: Amount{Cents: 1000}, CounterParty: Party{IBAN: "iban2"}})
+	assert.NoError(t, err)
+}
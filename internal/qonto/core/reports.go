@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// StatusReport is a payment status report generated for processed payment message,
+	// Content holds the report document as is
+	StatusReport struct {
+		ID                int64
+		MessageID         string
+		OriginalMessageID string
+		GroupStatus       string
+		Content           []byte
+		CreatedAt         time.Time
+	}
+
+	// ReportManager keeps generated status reports for later download
+	ReportManager interface {
+		// SaveStatusReport stores the report and sets its ID and creation time
+		SaveStatusReport(ctx context.Context, report *StatusReport) error
+		FindStatusReport(ctx context.Context, id int64) (StatusReport, error)
+	}
+
+	qontoReportManager struct {
+		storage storage.Storage
+	}
+)
+
+func NewQontoReportManager(storage storage.Storage) *qontoReportManager {
+	return &qontoReportManager{
+		storage: storage,
+	}
+}
+
+// SaveStatusReport implements ReportManager interface
+func (rm *qontoReportManager) SaveStatusReport(ctx context.Context, report *StatusReport) error {
+	createdAt := time.Now().UTC()
+	id, err := rm.storage.SavePaymentStatusReport(ctx, storage.PaymentStatusReport{
+		MessageID:         report.MessageID,
+		OriginalMessageID: report.OriginalMessageID,
+		GroupStatus:       report.GroupStatus,
+		Content:           report.Content,
+		CreatedAt:         createdAt,
+	})
+	if err != nil {
+		return fromStorage(err)
+	}
+	report.ID = id
+	report.CreatedAt = createdAt
+
+	return nil
+}
+
+// FindStatusReport implements ReportManager interface
+func (rm *qontoReportManager) FindStatusReport(ctx context.Context, id int64) (StatusReport, error) {
+	report, err := rm.storage.FindPaymentStatusReport(ctx, id)
+	if err != nil {
+		return StatusReport{}, fromStorage(err)
+	}
+
+	return StatusReport{
+		ID:                report.ID,
+		MessageID:         report.MessageID,
+		OriginalMessageID: report.OriginalMessageID,
+		GroupStatus:       report.GroupStatus,
+		Content:           report.Content,
+		CreatedAt:         report.CreatedAt,
+	}, nil
+}
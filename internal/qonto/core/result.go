@@ -0,0 +1,148 @@
+package core
+
+import (
+	"fmt"
+)
+
+type (
+	// Mode defines how the request is processed when some of its transfers cannot be executed
+	Mode string
+
+	// TransferStatus is an outcome of a single transfer of the request
+	TransferStatus string
+
+	// TransferResult is an outcome of the transfer, Err is the reason of rejected or held transfer
+	TransferResult struct {
+		Status TransferStatus
+		Err    error
+	}
+
+	// Result holds outcomes of all transfers of the request. Only outcomes of transfers which are not accepted
+	// are kept, so results of large requests take memory only if their transfers are rejected or held
+	Result struct {
+		size     int
+		outcomes map[int]TransferResult
+	}
+)
+
+const (
+	// MODE_ALL_OR_NOTHING rejects the whole request if any of transfers cannot be executed
+	MODE_ALL_OR_NOTHING Mode = "all_or_nothing"
+	// MODE_BEST_EFFORT executes valid transfers in order until funds run out
+	MODE_BEST_EFFORT Mode = "best_effort"
+
+	TRANSFER_ACCEPTED TransferStatus = "accepted"
+	TRANSFER_REJECTED TransferStatus = "rejected"
+	// TRANSFER_HELD is pending until screening hits of its counterparty are cleared, then it is executed
+	TRANSFER_HELD TransferStatus = "held"
+)
+
+// ParseMode validates mode name, empty name means default mode
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case "":
+		return MODE_ALL_OR_NOTHING, nil
+	case MODE_ALL_OR_NOTHING, MODE_BEST_EFFORT:
+		return mode, nil
+	}
+
+	return "", fmt.Errorf("unknown processing mode %q", s)
+}
+
+func newResult(size int) *Result {
+	return &Result{
+		size:     size,
+		outcomes: map[int]TransferResult{},
+	}
+}
+
+// ResultOf creates result of the given outcomes in order of transfers, e.g. returned by a mock manager
+func ResultOf(transfers ...TransferResult) *Result {
+	result := newResult(len(transfers))
+	for i, transfer := range transfers {
+		if transfer.Status != TRANSFER_ACCEPTED {
+			result.outcomes[i] = transfer
+		}
+	}
+
+	return result
+}
+
+// Len returns number of transfers of the request
+func (r *Result) Len() int {
+	return r.size
+}
+
+// Transfer returns outcome of the transfer
+func (r *Result) Transfer(i int) TransferResult {
+	if outcome, ok := r.outcomes[i]; ok {
+		return outcome
+	}
+
+	return TransferResult{Status: TRANSFER_ACCEPTED}
+}
+
+// Accepted returns number of accepted transfers
+func (r *Result) Accepted() int {
+	return r.size - len(r.outcomes)
+}
+
+// Held returns number of transfers on hold
+func (r *Result) Held() int {
+	held := 0
+	for _, t := range r.outcomes {
+		if t.Status == TRANSFER_HELD {
+			held++
+		}
+	}
+
+	return held
+}
+
+// Rejected reports whether the transfer is already rejected
+func (r *Result) Rejected(i int) bool {
+	return r.outcomes[i].Status == TRANSFER_REJECTED
+}
+
+// held reports whether the transfer is on hold
+func (r *Result) held(i int) bool {
+	return r.outcomes[i].Status == TRANSFER_HELD
+}
+
+// hold puts accepted transfer on hold, rejected transfer stays rejected
+func (r *Result) hold(i int, err error) {
+	if _, ok := r.outcomes[i]; ok {
+		return
+	}
+	r.outcomes[i] = TransferResult{
+		Status: TRANSFER_HELD,
+		Err:    err,
+	}
+}
+
+// accept releases held transfer, e.g. once screening hits of its counterparty are cleared
+func (r *Result) accept(i int) {
+	if r.held(i) {
+		delete(r.outcomes, i)
+	}
+}
+
+// reject marks the transfer as rejected, the first reason is kept. Held transfer is rejected too
+func (r *Result) reject(i int, err error) {
+	if r.Rejected(i) {
+		return
+	}
+	r.outcomes[i] = TransferResult{
+		Status: TRANSFER_REJECTED,
+		Err:    err,
+	}
+}
+
+func (r *Result) clone() *Result {
+	result := newResult(r.size)
+	for i, outcome := range r.outcomes {
+		result.outcomes[i] = outcome
+	}
+
+	return result
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultReject(t *testing.T) {
+	result := newResult(3)
+	assert.Equal(t, 3, result.Accepted())
+
+	result.reject(1, ErrInvalidCurrency)
+	result.reject(1, ErrNotEnoughFunds)
+	assert.Equal(t, 2, result.Accepted())
+	assert.True(t, result.Rejected(1))
+	assert.Equal(t, ErrInvalidCurrency, result.Transfer(1).Err, "first reason must be kept")
+
+	clone := result.clone()
+	clone.reject(0, ErrNotEnoughFunds)
+	assert.False(t, result.Rejected(0), "clone must not share transfers")
+}
+
+func TestResultHold(t *testing.T) {
+	result := newResult(3)
+	result.reject(0, ErrInvalidCurrency)
+	result.hold(0, ErrScreeningHit)
+	result.hold(1, ErrScreeningHit)
+	assert.True(t, result.Rejected(0), "rejected transfer must stay rejected")
+	assert.Equal(t, TRANSFER_HELD, result.Transfer(1).Status)
+	assert.Equal(t, 1, result.Held())
+	assert.Equal(t, 1, result.Accepted())
+
+	result.reject(1, ErrNotEnoughFunds)
+	assert.True(t, result.Rejected(1), "held transfer must be rejected")
+	assert.Equal(t, 0, result.Held())
+
+	result.hold(2, ErrScreeningHit)
+	result.accept(2)
+	assert.Equal(t, TransferResult{Status: TRANSFER_ACCEPTED}, result.Transfer(2), "released transfer must be accepted")
+}
+
+func TestResultOf(t *testing.T) {
+	result := ResultOf(
+		TransferResult{Status: TRANSFER_ACCEPTED},
+		TransferResult{Status: TRANSFER_REJECTED, Err: ErrNotEnoughFunds},
+		TransferResult{Status: TRANSFER_HELD, Err: ErrScreeningHit},
+	)
+	assert.Equal(t, 3, result.Len())
+	assert.Equal(t, 1, result.Accepted())
+	assert.Equal(t, 1, result.Held())
+	assert.True(t, result.Rejected(1))
+	assert.Equal(t, TransferResult{Status: TRANSFER_ACCEPTED}, result.Transfer(0))
+	assert.Equal(t, TransferResult{Status: TRANSFER_HELD, Err: ErrScreeningHit}, result.Transfer(2))
+}
+
+func TestParseMode(t *testing.T) {
+	cases := []struct {
+		input          string
+		expectedResult Mode
+		expectError    bool
+	}{
+		{input: "", expectedResult: MODE_ALL_OR_NOTHING},
+		{input: "all_or_nothing", expectedResult: MODE_ALL_OR_NOTHING},
+		{input: "best_effort", expectedResult: MODE_BEST_EFFORT},
+		{input: "some_effort", expectError: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := ParseMode(tc.input)
+			if (err == nil) != (tc.expectError == false) {
+				t.Errorf(`
+				expected error to be %v, got %v
+				`, tc.expectError, err)
+				return
+			}
+			assert.Equal(t, tc.expectedResult, result)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// Decision is an outcome of risk evaluation
+	Decision string
+
+	// TransactionCounter gives rules access to the payment history of the account
+	TransactionCounter interface {
+		CountAccountTransactions(ctx context.Context, accountID int64, filter storage.TransactionFilter) (int64, error)
+	}
+
+	// RuleInput holds everything a rule may need to evaluate the request
+	RuleInput struct {
+		History TransactionCounter
+		Account storage.Account
+		Request *Request
+		Now     time.Time
+	}
+
+	// Finding is a single observation of a rule.
+	// Transfer is an index of the transfer in the request or -1 if finding concerns the whole request
+	Finding struct {
+		Rule     string
+		Decision Decision
+		Reason   string
+		Transfer int
+		Err      error
+	}
+
+	// Assessment is an aggregated result of all rules
+	Assessment struct {
+		Decision Decision
+		Findings []Finding
+	}
+
+	// Rule is a single risk rule evaluated before funds move
+	Rule interface {
+		Name() string
+		Evaluate(ctx context.Context, input *RuleInput) ([]Finding, error)
+	}
+
+	// RuleEngine evaluates risk rules of the request before funds move
+	RuleEngine interface {
+		Evaluate(ctx context.Context, input *RuleInput) (*Assessment, error)
+	}
+
+	// DeniedError is returned when risk rules deny the request
+	DeniedError struct {
+		Findings []Finding
+	}
+
+	rulesEngine struct {
+		rules []Rule
+	}
+)
+
+const (
+	DECISION_ALLOW  Decision = "allow"
+	DECISION_REVIEW Decision = "review"
+	DECISION_DENY   Decision = "deny"
+)
+
+// severity orders decisions, the most severe decision of all findings wins
+func (d Decision) severity() int {
+	switch d {
+	case DECISION_DENY:
+		return 2
+	case DECISION_REVIEW:
+		return 1
+	}
+
+	return 0
+}
+
+// String formats finding as human-readable reason
+func (f Finding) String() string {
+	if f.Transfer < 0 {
+		return fmt.Sprintf("%s (%s): %s", f.Rule, f.Decision, f.Reason)
+	}
+
+	return fmt.Sprintf("%s (%s): transfer #%d: %s", f.Rule, f.Decision, f.Transfer+1, f.Reason)
+}
+
+// Reasons returns human-readable reasons of all findings
+func (a *Assessment) Reasons() []string {
+	reasons := make([]string, 0, len(a.Findings))
+	for _, f := range a.Findings {
+		reasons = append(reasons, f.String())
+	}
+
+	return reasons
+}
+
+func (e *DeniedError) Error() string {
+	reasons := []string{}
+	for _, f := range e.Findings {
+		if f.Decision == DECISION_DENY {
+			reasons = append(reasons, f.String())
+		}
+	}
+
+	return ErrTransferDenied.Error() + ": " + strings.Join(reasons, "; ")
+}
+
+// Is reports whether the request was denied by rule with target error
+func (e *DeniedError) Is(target error) bool {
+	if target == ErrTransferDenied {
+		return true
+	}
+	for _, f := range e.Findings {
+		if f.Decision == DECISION_DENY && f.Err == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewRuleEngine creates engine evaluating all provided rules
+func NewRuleEngine(rules ...Rule) *rulesEngine {
+	return &rulesEngine{
+		rules: rules,
+	}
+}
+
+// Evaluate implements RuleEngine interface
+func (re *rulesEngine) Evaluate(ctx context.Context, input *RuleInput) (*Assessment, error) {
+	assessment := &Assessment{
+		Decision: DECISION_ALLOW,
+		Findings: []Finding{},
+	}
+	for _, rule := range re.rules {
+		findings, err := rule.Evaluate(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", rule.Name(), err)
+		}
+		for _, f := range findings {
+			if f.Decision.severity() > assessment.Decision.severity() {
+				assessment.Decision = f.Decision
+			}
+		}
+		assessment.Findings = append(assessment.Findings, findings...)
+	}
+
+	return assessment, nil
+}
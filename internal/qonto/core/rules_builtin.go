@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// newBeneficiaryRule flags large transfers to counterparties the account has never paid before
+	newBeneficiaryRule struct {
+		decision  Decision
+		threshold Amount
+	}
+
+	// roundAmountBurstRule flags requests with too many large transfers of round amounts
+	roundAmountBurstRule struct {
+		decision  Decision
+		roundTo   Amount
+		minAmount Amount
+		maxCount  int
+	}
+
+	// blockedCountriesRule flags transfers to IBANs of blocked countries
+	blockedCountriesRule struct {
+		decision  Decision
+		countries map[string]bool
+	}
+)
+
+func (r *newBeneficiaryRule) Name() string {
+	return "new_beneficiary_large_amount"
+}
+
+func (r *newBeneficiaryRule) Evaluate(ctx context.Context, input *RuleInput) ([]Finding, error) {
+	findings := []Finding{}
+	err := input.Request.chunks(func(offset int, chunk []Transfer) error {
+		// history is looked up once per counterparty of the chunk, so the cache does not grow with the request
+		known := map[string]bool{}
+		for j, transfer := range chunk {
+			if transfer.Amount.Cents < r.threshold.Cents {
+				continue
+			}
+			iban := transfer.CounterParty.IBAN
+			if _, ok := known[iban]; !ok {
+				count, err := input.History.CountAccountTransactions(ctx, input.Account.ID, storage.TransactionFilter{CounterpartyIBAN: iban})
+				if err != nil {
+					return err
+				}
+				known[iban] = count > 0
+			}
+			if !known[iban] {
+				findings = append(findings, Finding{
+					Rule:     r.Name(),
+					Decision: r.decision,
+					Reason:   fmt.Sprintf("amount %s to new beneficiary %s reaches %s", formatAmount(transfer.Amount), iban, formatAmount(r.threshold)),
+					Transfer: offset + j,
+					Err:      ErrNewBeneficiaryLargeAmount,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+func (r *roundAmountBurstRule) Name() string {
+	return "round_amount_burst"
+}
+
+func (r *roundAmountBurstRule) Evaluate(ctx context.Context, input *RuleInput) ([]Finding, error) {
+	count := 0
+	err := input.Request.Each(func(i int, transfer Transfer) error {
+		if transfer.Amount.Cents >= r.minAmount.Cents && transfer.Amount.Cents%r.roundTo.Cents == 0 {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count <= r.maxCount {
+		return nil, nil
+	}
+
+	return []Finding{{
+		Rule:     r.Name(),
+		Decision: r.decision,
+		Reason:   fmt.Sprintf("%d transfers of round amounts, at most %d allowed", count, r.maxCount),
+		Transfer: -1,
+		Err:      ErrRoundAmountBurst,
+	}}, nil
+}
+
+func (r *blockedCountriesRule) Name() string {
+	return "blocked_country"
+}
+
+func (r *blockedCountriesRule) Evaluate(ctx context.Context, input *RuleInput) ([]Finding, error) {
+	findings := []Finding{}
+	err := input.Request.Each(func(i int, transfer Transfer) error {
+		country := ibanCountry(transfer.CounterParty.IBAN)
+		if r.countries[country] {
+			findings = append(findings, Finding{
+				Rule:     r.Name(),
+				Decision: r.decision,
+				Reason:   fmt.Sprintf("counterparty IBAN country %s is blocked", country),
+				Transfer: i,
+				Err:      ErrBlockedCountry,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+// ibanCountry returns ISO 3166 country code of the IBAN
+func ibanCountry(iban string) string {
+	iban = strings.TrimSpace(iban)
+	if len(iban) < 2 {
+		return ""
+	}
+
+	return strings.ToUpper(iban[:2])
+}
+
+func formatAmount(a Amount) string {
+	b, _ := a.MarshalJSON()
+	return string(b)
+}
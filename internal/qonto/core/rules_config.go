@@ -0,0 +1,97 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type (
+	// RulesConfig configures built-in risk rules, rules without configuration are disabled
+	RulesConfig struct {
+		NewBeneficiary   *NewBeneficiaryRuleConfig   `json:"new_beneficiary_large_amount,omitempty"`
+		RoundAmountBurst *RoundAmountBurstRuleConfig `json:"round_amount_burst,omitempty"`
+		BlockedCountries *BlockedCountriesRuleConfig `json:"blocked_country,omitempty"`
+	}
+
+	NewBeneficiaryRuleConfig struct {
+		Decision  Decision `json:"decision,omitempty"`
+		Threshold Amount   `json:"threshold"`
+	}
+
+	RoundAmountBurstRuleConfig struct {
+		Decision  Decision `json:"decision,omitempty"`
+		RoundTo   Amount   `json:"round_to"`
+		MinAmount Amount   `json:"min_amount"`
+		MaxCount  int      `json:"max_count"`
+	}
+
+	BlockedCountriesRuleConfig struct {
+		Decision  Decision `json:"decision,omitempty"`
+		Countries []string `json:"countries"`
+	}
+)
+
+// LoadRulesConfig reads rules configuration from JSON file
+func LoadRulesConfig(path string) (*RulesConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config := RulesConfig{}
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("cannot decode rules config %s: %w", path, err)
+	}
+
+	return &config, nil
+}
+
+// Rules builds configured rules
+func (rc *RulesConfig) Rules() ([]Rule, error) {
+	rules := []Rule{}
+	if c := rc.NewBeneficiary; c != nil {
+		decision, err := ruleDecision(c.Decision, DECISION_REVIEW)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &newBeneficiaryRule{decision: decision, threshold: c.Threshold})
+	}
+	if c := rc.RoundAmountBurst; c != nil {
+		decision, err := ruleDecision(c.Decision, DECISION_REVIEW)
+		if err != nil {
+			return nil, err
+		}
+		if c.RoundTo.Cents <= 0 {
+			return nil, fmt.Errorf("round_amount_burst: round_to must be positive")
+		}
+		rules = append(rules, &roundAmountBurstRule{decision: decision, roundTo: c.RoundTo, minAmount: c.MinAmount, maxCount: c.MaxCount})
+	}
+	if c := rc.BlockedCountries; c != nil {
+		decision, err := ruleDecision(c.Decision, DECISION_DENY)
+		if err != nil {
+			return nil, err
+		}
+		countries := make(map[string]bool, len(c.Countries))
+		for _, country := range c.Countries {
+			countries[strings.ToUpper(country)] = true
+		}
+		rules = append(rules, &blockedCountriesRule{decision: decision, countries: countries})
+	}
+	return rules, nil
+}
+
+func ruleDecision(d, defaultDecision Decision) (Decision, error) {
+	switch d {
+	case "":
+		return defaultDecision, nil
+	case DECISION_ALLOW, DECISION_REVIEW, DECISION_DENY:
+		return d, nil
+	}
+
+	return "", fmt.Errorf("unknown decision %q", d)
+}
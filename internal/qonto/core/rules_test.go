@@ -0,0 +1,217 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyStub counts transactions matching the filter in the list of known ones
+type historyStub []storage.Transaction
+
+func (hs historyStub) CountAccountTransactions(ctx context.Context, accountID int64, filter storage.TransactionFilter) (int64, error) {
+	var count int64
+	for _, tx := range hs {
+		if filter.CounterpartyIBAN != "" && filter.CounterpartyIBAN != tx.CounterpartyIBAN {
+			continue
+		}
+		if filter.AmountCents != 0 && filter.AmountCents != tx.AmountCents {
+			continue
+		}
+		if !filter.Since.IsZero() && tx.CreatedAt.Before(filter.Since) {
+			continue
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+func TestRulesEngineEvaluate(t *testing.T) {
+	now := time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC)
+	history := historyStub{
+		{CounterpartyIBAN: "FR001", AmountCents: 50000, CreatedAt: now.Add(-48 * time.Hour)},
+		{CounterpartyIBAN: "DE001", AmountCents: 1000, CreatedAt: now.Add(-5 * time.Minute)},
+	}
+	transferTo := func(iban string, cents int64) Transfer {
+		return Transfer{
+			Amount:       Amount{Cents: cents},
+			Currency:     CURRENCY_EURO,
+			CounterParty: Party{IBAN: iban},
+		}
+	}
+
+	cases := []struct {
+		name             string
+		rules            []Rule
+		transfers        []Transfer
+		expectedDecision Decision
+		expectedFindings []int
+		expectedError    error
+	}{
+		{
+			name:             "no rules",
+			transfers:        []Transfer{transferTo("KP001", 100000000)},
+			expectedDecision: DECISION_ALLOW,
+			expectedFindings: []int{},
+		},
+		{
+			name:             "large amount to known beneficiary",
+			rules:            []Rule{&newBeneficiaryRule{decision: DECISION_REVIEW, threshold: Amount{Cents: 10000}}},
+			transfers:        []Transfer{transferTo("FR001", 20000), transferTo("FR002", 9999)},
+			expectedDecision: DECISION_ALLOW,
+			expectedFindings: []int{},
+		},
+		{
+			name:             "large amount to new beneficiary",
+			rules:            []Rule{&newBeneficiaryRule{decision: DECISION_REVIEW, threshold: Amount{Cents: 10000}}},
+			transfers:        []Transfer{transferTo("FR001", 20000), transferTo("FR002", 10000)},
+			expectedDecision: DECISION_REVIEW,
+			expectedFindings: []int{1},
+		},
+		{
+			name:             "round amounts within allowed count",
+			rules:            []Rule{&roundAmountBurstRule{decision: DECISION_DENY, roundTo: Amount{Cents: 10000}, minAmount: Amount{Cents: 100000}, maxCount: 2}},
+			transfers:        []Transfer{transferTo("FR001", 100000), transferTo("FR002", 200000), transferTo("FR003", 10000), transferTo("FR003", 300001)},
+			expectedDecision: DECISION_ALLOW,
+			expectedFindings: []int{},
+		},
+		{
+			name:             "burst of round amounts",
+			rules:            []Rule{&roundAmountBurstRule{decision: DECISION_DENY, roundTo: Amount{Cents: 10000}, minAmount: Amount{Cents: 100000}, maxCount: 2}},
+			transfers:        []Transfer{transferTo("FR001", 100000), transferTo("FR002", 200000), transferTo("FR003", 300000)},
+			expectedDecision: DECISION_DENY,
+			expectedFindings: []int{-1},
+			expectedError:    ErrRoundAmountBurst,
+		},
+		{
+			name:             "blocked country",
+			rules:            []Rule{&blockedCountriesRule{decision: DECISION_DENY, countries: map[string]bool{"KP": true}}},
+			transfers:        []Transfer{transferTo("FR001", 100), transferTo("kp001", 100)},
+			expectedDecision: DECISION_DENY,
+			expectedFindings: []int{1},
+			expectedError:    ErrBlockedCountry,
+		},
+		{
+			name: "most severe decision wins",
+			rules: []Rule{
+				&blockedCountriesRule{decision: DECISION_DENY, countries: map[string]bool{"KP": true}},
+				&newBeneficiaryRule{decision: DECISION_REVIEW, threshold: Amount{Cents: 100}},
+			},
+			transfers:        []Transfer{transferTo("KP001", 100)},
+			expectedDecision: DECISION_DENY,
+			expectedFindings: []int{0, 0},
+			expectedError:    ErrBlockedCountry,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := &RuleInput{
+				History: history,
+				Account: storage.Account{ID: 1},
+				Request: &Request{CreditTransfers: TransferList(tc.transfers)},
+				Now:     now,
+			}
+			assessment, err := NewRuleEngine(tc.rules...).Evaluate(context.Background(), input)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDecision, assessment.Decision)
+
+			transfers := []int{}
+			for _, f := range assessment.Findings {
+				transfers = append(transfers, f.Transfer)
+			}
+			assert.Equal(t, tc.expectedFindings, transfers)
+
+			if tc.expectedError != nil {
+				err := &DeniedError{Findings: assessment.Findings}
+				assert.ErrorIs(t, err, ErrTransferDenied)
+				assert.ErrorIs(t, err, tc.expectedError)
+			}
+		})
+	}
+}
+
+func TestDeniedErrorIsOnlyDenyingRules(t *testing.T) {
+	err := &DeniedError{Findings: []Finding{
+		{Rule: "blocked_country", Decision: DECISION_DENY, Transfer: 0, Err: ErrBlockedCountry},
+		{Rule: "new_beneficiary_large_amount", Decision: DECISION_REVIEW, Transfer: 0, Err: ErrNewBeneficiaryLargeAmount},
+	}}
+
+	assert.True(t, errors.Is(err, ErrBlockedCountry))
+	assert.False(t, errors.Is(err, ErrNewBeneficiaryLargeAmount))
+	assert.NotContains(t, err.Error(), "new_beneficiary_large_amount")
+}
+
+func TestLoadRulesConfig(t *testing.T) {
+	cases := []struct {
+		name          string
+		content       string
+		expectedRules []string
+		expectError   bool
+	}{
+		{
+			name:          "empty config disables all rules",
+			content:       `{}`,
+			expectedRules: []string{},
+		},
+		{
+			name: "all rules",
+			content: `
+			{
+				"new_beneficiary_large_amount": {"threshold": "10000"},
+				"round_amount_burst": {"decision": "deny", "round_to": "100", "min_amount": "1000", "max_count": 3},
+				"blocked_country": {"countries": ["kp", "IR"]}
+			}
+			`,
+			expectedRules: []string{"new_beneficiary_large_amount", "round_amount_burst", "blocked_country"},
+		},
+		{
+			name:        "unknown decision",
+			content:     `{"blocked_country": {"decision": "maybe", "countries": ["KP"]}}`,
+			expectError: true,
+		},
+		{
+			name:        "unknown rule",
+			content:     `{"magic_rule": {}}`,
+			expectError: true,
+		},
+		{
+			name:        "removed duplicate_payment rule",
+			content:     `{"duplicate_payment": {"window": "10m"}}`,
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0600))
+
+			var rules []Rule
+			config, err := LoadRulesConfig(path)
+			if err == nil {
+				rules, err = config.Rules()
+			}
+			if (err == nil) != (tc.expectError == false) {
+				t.Errorf(`
+				expected error to be %v, got %v
+				`, tc.expectError, err)
+				return
+			}
+			if tc.expectError {
+				return
+			}
+			names := []string{}
+			for _, rule := range rules {
+				names = append(names, rule.Name())
+			}
+			assert.Equal(t, tc.expectedRules, names)
+		})
+	}
+}
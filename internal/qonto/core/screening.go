@@ -0,0 +1,273 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/screening"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// Screener matches parties against sanctions list
+	Screener interface {
+		Screen(name, iban, bic string) []screening.Match
+		// Reload loads the list again without interrupting screening
+		Reload() error
+	}
+
+	// ScreeningHit is a match of a counterparty against sanctions list,
+	// transfers to the counterparty are on hold until the hit is cleared
+	ScreeningHit struct {
+		ID           int64
+		AccountID    int64
+		CounterParty Party
+		EntryID      string
+		EntryName    string
+		MatchedField string
+		Score        float64
+		Status       string
+		CreatedAt    time.Time
+		ClearedAt    time.Time
+	}
+
+	// ScreeningManager manages screening hits and the list
+	ScreeningManager interface {
+		// ListHits returns hits with the given status, all hits if status is empty
+		ListHits(ctx context.Context, status string) ([]ScreeningHit, error)
+		// ClearHit confirms the hit is a false positive, so transfers to the counterparty are allowed.
+		// Transfers held by the hit are executed unless other hits of the counterparty are open
+		ClearHit(ctx context.Context, id int64) error
+		ReloadList() error
+	}
+
+	qontoScreeningManager struct {
+		storage  storage.Storage
+		screener Screener
+	}
+)
+
+func NewQontoScreeningManager(storage storage.Storage, screener Screener) *qontoScreeningManager {
+	return &qontoScreeningManager{
+		storage:  storage,
+		screener: screener,
+	}
+}
+
+// ListHits implements ScreeningManager interface
+func (sm *qontoScreeningManager) ListHits(ctx context.Context, status string) ([]ScreeningHit, error) {
+	hits, err := sm.storage.FindScreeningHits(ctx, storage.ScreeningHitFilter{Status: status})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]ScreeningHit, 0, len(hits))
+	for _, hit := range hits {
+		result = append(result, ScreeningHit{
+			ID:        hit.ID,
+			AccountID: hit.BankAccountID,
+			CounterParty: Party{
+				Name: hit.CounterpartyName,
+				BIC:  hit.CounterpartyBIC,
+				IBAN: hit.CounterpartyIBAN,
+			},
+			EntryID:      hit.EntryID,
+			EntryName:    hit.EntryName,
+			MatchedField: hit.MatchedField,
+			Score:        hit.Score,
+			Status:       hit.Status,
+			CreatedAt:    hit.CreatedAt,
+			ClearedAt:    hit.ClearedAt,
+		})
+	}
+
+	return result, nil
+}
+
+// ClearHit implements ScreeningManager interface, the hit is cleared and held transfers are released together
+func (sm *qontoScreeningManager) ClearHit(ctx context.Context, id int64) error {
+	now := time.Now().UTC()
+	err := sm.storage.WithTransactionStorage(ctx, func(ctx context.Context, txStorage storage.Storage) error {
+		cleared, err := txStorage.ClearScreeningHit(ctx, id, now)
+		if err != nil {
+			return err
+		}
+		if !cleared {
+			return ErrScreeningHitNotFound
+		}
+		hit, err := txStorage.FindScreeningHit(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		return releaseHeldTransfers(ctx, txStorage, hit.BankAccountID, hit.CounterpartyIBAN, now)
+	})
+
+	return fromStorage(err)
+}
+
+// ReloadList implements ScreeningManager interface
+func (sm *qontoScreeningManager) ReloadList() error {
+	return sm.screener.Reload()
+}
+
+// screenTransfers screens counterparties of all transfers and records new hits.
+// Indexes of transfers to hold are returned, transfer is not held if all its hits were cleared before
+func screenTransfers(ctx context.Context, s storage.Storage, screener Screener, account storage.Account, request *Request, now time.Time) ([]int, error) {
+	held := []int{}
+	err := request.Each(func(i int, transfer Transfer) error {
+		counterparty := transfer.CounterParty
+		matches := screener.Screen(counterparty.Name, counterparty.IBAN, counterparty.BIC)
+		if len(matches) == 0 {
+			return nil
+		}
+
+		hits, err := s.FindScreeningHits(ctx, storage.ScreeningHitFilter{BankAccountID: account.ID, CounterpartyIBAN: counterparty.IBAN})
+		if err != nil {
+			return err
+		}
+		matched := false
+		for _, match := range matches {
+			hit, found := findScreeningHit(hits, counterparty, match)
+			if found && hit.Status == storage.ScreeningHitCleared {
+				continue
+			}
+			matched = true
+			if found {
+				continue
+			}
+			_, err := s.SaveScreeningHit(ctx, storage.ScreeningHit{
+				BankAccountID:    account.ID,
+				CounterpartyName: counterparty.Name,
+				CounterpartyIBAN: counterparty.IBAN,
+				CounterpartyBIC:  counterparty.BIC,
+				EntryID:          match.Entry.ID,
+				EntryName:        match.Entry.Name,
+				MatchedField:     match.Field,
+				Score:            match.Score,
+				Status:           storage.ScreeningHitOpen,
+				CreatedAt:        now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if matched {
+			held = append(held, i)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return held, nil
+}
+
+// findScreeningHit looks up previously recorded hit of the same counterparty and list entry
+func findScreeningHit(hits []storage.ScreeningHit, counterparty Party, match screening.Match) (storage.ScreeningHit, bool) {
+	for _, hit := range hits {
+		if hit.CounterpartyName == counterparty.Name &&
+			hit.CounterpartyBIC == counterparty.BIC &&
+			hit.EntryID == match.Entry.ID &&
+			hit.EntryName == match.Entry.Name &&
+			hit.MatchedField == match.Field {
+			return hit, true
+		}
+	}
+
+	return storage.ScreeningHit{}, false
+}
+
+// hasOpenHit reports whether any hit of the counterparty recorded for the account is still open
+func hasOpenHit(ctx context.Context, s storage.Storage, accountID int64, counterparty Party) (bool, error) {
+	hits, err := s.FindScreeningHits(ctx, storage.ScreeningHitFilter{
+		BankAccountID:    accountID,
+		CounterpartyIBAN: counterparty.IBAN,
+		Status:           storage.ScreeningHitOpen,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(openHitsOf(hits, counterparty)) > 0, nil
+}
+
+// openHitsOf returns open hits of the counterparty, hits of other names or BICs with the same IBAN are skipped
+func openHitsOf(hits []storage.ScreeningHit, counterparty Party) []storage.ScreeningHit {
+	result := []storage.ScreeningHit{}
+	for _, hit := range hits {
+		if hit.Status == storage.ScreeningHitOpen && hit.CounterpartyName == counterparty.Name && hit.CounterpartyBIC == counterparty.BIC {
+			result = append(result, hit)
+		}
+	}
+
+	return result
+}
+
+// releaseHeldTransfers executes pending transfers of the account to the counterparty whose hits are all cleared.
+// The account is locked first, so transfers held by requests processed at the same time are not missed.
+// Transfers are released in order of arrival, ones exceeding limits or funds at the moment are rejected with the reason
+func releaseHeldTransfers(ctx context.Context, s storage.Storage, accountID int64, counterpartyIBAN string, now time.Time) error {
+	account, err := s.FindAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	pending, err := s.FindPendingHeldTransfers(ctx, accountID, counterpartyIBAN)
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+	hits, err := s.FindScreeningHits(ctx, storage.ScreeningHitFilter{
+		BankAccountID:    accountID,
+		CounterpartyIBAN: counterpartyIBAN,
+		Status:           storage.ScreeningHitOpen,
+	})
+	if err != nil {
+		return err
+	}
+	limits, err := s.FindTransferLimits(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	checker := newLimitsChecker(limits, now, func(ctx context.Context, counterpartyIBAN string, since time.Time) (int64, error) {
+		return s.SumAccountTransactions(ctx, accountID, storage.TransactionFilter{CounterpartyIBAN: counterpartyIBAN, Since: since})
+	})
+
+	balance := account.BalanceCents
+	transactions := []*storage.Transaction{}
+	for _, held := range pending {
+		transfer := Transfer{
+			Amount:      Amount{Cents: held.AmountCents},
+			Currency:    Currency(held.AmountCurrency),
+			Description: held.Description,
+			CounterParty: Party{
+				Name: held.CounterpartyName,
+				BIC:  held.CounterpartyBIC,
+				IBAN: held.CounterpartyIBAN,
+			},
+		}
+		if len(openHitsOf(hits, transfer.CounterParty)) > 0 {
+			continue
+		}
+
+		status, reason := storage.HeldTransferExecuted, ""
+		if err := checker.admit(ctx, transfer); err != nil {
+			// exceeded limit rejects the transfer, failed lookup of usage rolls back the release
+			var limitErr Error
+			if !errors.As(err, &limitErr) {
+				return err
+			}
+			status, reason = storage.HeldTransferRejected, err.Error()
+		} else if balance < transfer.Amount.Cents {
+			status, reason = storage.HeldTransferRejected, ErrNotEnoughFunds.Error()
+		} else {
+			balance -= transfer.Amount.Cents
+			transactions = append(transactions, newTransaction(accountID, transfer, held.Fingerprint, now))
+		}
+		if _, err := s.ReleaseHeldTransfer(ctx, held.ID, status, reason, now); err != nil {
+			return err
+		}
+	}
+
+	return executeTransactions(ctx, s, accountID, balance, transactions)
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// heldStub keeps held transfers and hits of a single account, other storage methods are not used
+type heldStub struct {
+	storage.Storage
+	account      storage.Account
+	hits         []storage.ScreeningHit
+	limits       []storage.TransferLimit
+	held         []*storage.HeldTransfer
+	transactions []*storage.Transaction
+}
+
+func (hs *heldStub) FindAccount(ctx context.Context, id int64) (storage.Account, error) {
+	return hs.account, nil
+}
+
+func (hs *heldStub) UpdateAccountBalance(ctx context.Context, id, balance int64) error {
+	hs.account.BalanceCents = balance
+	return nil
+}
+
+func (hs *heldStub) AppendAccountTransactions(ctx context.Context, transactions []*storage.Transaction) error {
+	hs.transactions = append(hs.transactions, transactions...)
+	return nil
+}
+
+func (hs *heldStub) SumAccountTransactions(ctx context.Context, accountID int64, filter storage.TransactionFilter) (int64, error) {
+	return 0, nil
+}
+
+func (hs *heldStub) FindTransferLimits(ctx context.Context, accountID int64) ([]storage.TransferLimit, error) {
+	return hs.limits, nil
+}
+
+func (hs *heldStub) FindScreeningHits(ctx context.Context, filter storage.ScreeningHitFilter) ([]storage.ScreeningHit, error) {
+	hits := []storage.ScreeningHit{}
+	for _, hit := range hs.hits {
+		if hit.CounterpartyIBAN == filter.CounterpartyIBAN && hit.Status == filter.Status {
+			hits = append(hits, hit)
+		}
+	}
+	return hits, nil
+}
+
+func (hs *heldStub) FindPendingHeldTransfers(ctx context.Context, accountID int64, counterpartyIBAN string) ([]*storage.HeldTransfer, error) {
+	pending := []*storage.HeldTransfer{}
+	for _, held := range hs.held {
+		if held.CounterpartyIBAN == counterpartyIBAN && held.Status == storage.HeldTransferPending {
+			pending = append(pending, held)
+		}
+	}
+	return pending, nil
+}
+
+func (hs *heldStub) ReleaseHeldTransfer(ctx context.Context, id int64, status, reason string, releasedAt time.Time) (bool, error) {
+	for _, held := range hs.held {
+		if held.ID == id && held.Status == storage.HeldTransferPending {
+			held.Status, held.Reason, held.ReleasedAt = status, reason, releasedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestReleaseHeldTransfers(t *testing.T) {
+	now := time.Date(2022, 6, 6, 12, 0, 0, 0, time.UTC)
+	heldTransfer := func(id, cents int64, name string) *storage.HeldTransfer {
+		return &storage.HeldTransfer{
+			ID:               id,
+			BankAccountID:    1,
+			CounterpartyName: name,
+			CounterpartyIBAN: "EE383680981021245685",
+			AmountCents:      cents,
+			AmountCurrency:   string(CURRENCY_EURO),
+			Fingerprint:      "fp",
+			Status:           storage.HeldTransferPending,
+		}
+	}
+	s := &heldStub{
+		account: storage.Account{ID: 1, BalanceCents: 1000},
+		// another name of the same IBAN is still under investigation
+		hits: []storage.ScreeningHit{
+			{ID: 2, BankAccountID: 1, CounterpartyName: "Bugs Bunny", CounterpartyIBAN: "EE383680981021245685", Status: storage.ScreeningHitOpen},
+		},
+		limits: []storage.TransferLimit{{BankAccountID: 1, MaxSingleTransferCents: 500}},
+		held: []*storage.HeldTransfer{
+			heldTransfer(1, 400, "Bip Bip"),
+			heldTransfer(2, 600, "Bip Bip"),
+			heldTransfer(3, 400, "Bugs Bunny"),
+			heldTransfer(4, 400, "Bip Bip"),
+			heldTransfer(5, 300, "Bip Bip"),
+		},
+	}
+
+	require.NoError(t, releaseHeldTransfers(context.Background(), s, 1, "EE383680981021245685", now))
+
+	statuses := []string{}
+	for _, held := range s.held {
+		statuses = append(statuses, held.Status)
+	}
+	assert.Equal(t, []string{
+		storage.HeldTransferExecuted,
+		storage.HeldTransferRejected,
+		storage.HeldTransferPending,
+		storage.HeldTransferExecuted,
+		storage.HeldTransferRejected,
+	}, statuses)
+	assert.Contains(t, s.held[1].Reason, ErrSingleTransferLimitExceeded.Error())
+	assert.Equal(t, ErrNotEnoughFunds.Error(), s.held[4].Reason)
+	assert.Equal(t, now, s.held[0].ReleasedAt)
+
+	assert.Equal(t, int64(200), s.account.BalanceCents)
+	require.Len(t, s.transactions, 2)
+	assert.Equal(t, "fp", s.transactions[0].Fingerprint)
+	assert.Equal(t, now, s.transactions[0].CreatedAt)
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// Statement lists account transactions booked within [From, To) period.
+	// Transactions are outgoing transfers, so every entry decreases the balance
+	Statement struct {
+		Account        Party
+		Currency       Currency
+		From           time.Time
+		To             time.Time
+		OpeningBalance Amount
+		ClosingBalance Amount
+		Entries        []StatementEntry
+	}
+
+	StatementEntry struct {
+		ID           int64
+		Amount       Amount
+		Currency     Currency
+		Description  string
+		CounterParty Party
+		BookedAt     time.Time
+	}
+
+	// StatementManager builds account statements
+	StatementManager interface {
+		Statement(ctx context.Context, iban string, from, to time.Time) (*Statement, error)
+	}
+
+	qontoStatementManager struct {
+		storage storage.Storage
+	}
+)
+
+func NewQontoStatementManager(storage storage.Storage) *qontoStatementManager {
+	return &qontoStatementManager{
+		storage: storage,
+	}
+}
+
+// Statement implements StatementManager interface.
+// Only current balance is stored, so balances of the period are calculated backwards from it
+func (sm *qontoStatementManager) Statement(ctx context.Context, iban string, from, to time.Time) (*Statement, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: %s is not before %s", ErrInvalidPeriod, from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	var statement *Statement
+	// the transaction gives consistent snapshot of balance and transactions
+	err := sm.storage.WithTransactionStorage(ctx, func(ctx context.Context, txStorage storage.Storage) error {
+		account, err := txStorage.FindAccountByIBAN(ctx, iban)
+		if err != nil {
+			return err
+		}
+
+		spentAfter, err := txStorage.SumAccountTransactions(ctx, account.ID, storage.TransactionFilter{Since: to})
+		if err != nil {
+			return err
+		}
+		transactions, err := txStorage.FilterAccountTransactions(ctx, account.ID, storage.TransactionFilter{Since: from, Until: to})
+		if err != nil {
+			return err
+		}
+
+		statement = &Statement{
+			Account: Party{
+				Name: account.Name,
+				BIC:  account.BIC,
+				IBAN: account.IBAN,
+			},
+			Currency:       CURRENCY_EURO,
+			From:           from,
+			To:             to,
+			ClosingBalance: Amount{Cents: account.BalanceCents + spentAfter},
+			Entries:        make([]StatementEntry, 0, len(transactions)),
+		}
+		spent := int64(0)
+		for _, tx := range transactions {
+			spent += tx.AmountCents
+			statement.Entries = append(statement.Entries, StatementEntry{
+				ID:          tx.ID,
+				Amount:      Amount{Cents: tx.AmountCents},
+				Currency:    Currency(tx.AmountCurrency),
+				Description: tx.Description,
+				CounterParty: Party{
+					Name: tx.CounterpartyName,
+					BIC:  tx.CounterpartyBIC,
+					IBAN: tx.CounterpartyIBAN,
+				},
+				BookedAt: tx.CreatedAt,
+			})
+		}
+		statement.OpeningBalance = Amount{Cents: statement.ClosingBalance.Cents + spent}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fromStorage(err)
+	}
+
+	return statement, nil
+}
+
+// ParsePeriodBoundary parses date or RFC 3339 time, dates are days in UTC.
+// End date of the period is included, so the next day is returned as exclusive boundary
+func ParsePeriodBoundary(s string, end bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q is neither date nor RFC 3339 time", ErrInvalidPeriod, s)
+	}
+
+	return t.UTC(), nil
+}
+
+// Daily splits the statement into statements of calendar days in the location of the period start
+func (s *Statement) Daily() []Statement {
+	result := []Statement{}
+	balance := s.OpeningBalance
+	entries := s.Entries
+	for from := s.From; from.Before(s.To); {
+		year, month, day := from.Date()
+		to := time.Date(year, month, day+1, 0, 0, 0, 0, from.Location())
+		if to.After(s.To) {
+			to = s.To
+		}
+
+		daily := Statement{
+			Account:        s.Account,
+			Currency:       s.Currency,
+			From:           from,
+			To:             to,
+			OpeningBalance: balance,
+			Entries:        []StatementEntry{},
+		}
+		for len(entries) > 0 && entries[0].BookedAt.Before(to) {
+			balance.Cents -= entries[0].Amount.Cents
+			daily.Entries = append(daily.Entries, entries[0])
+			entries = entries[1:]
+		}
+		daily.ClosingBalance = balance
+		result = append(result, daily)
+
+		from = to
+	}
+
+	return result
+}
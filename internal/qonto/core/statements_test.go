@@ -0,0 +1,47 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementDaily(t *testing.T) {
+	day := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(bookedAt time.Time, cents int64) StatementEntry {
+		return StatementEntry{Amount: Amount{Cents: cents}, Currency: CURRENCY_EURO, BookedAt: bookedAt}
+	}
+	statement := &Statement{
+		From:           day.Add(12 * time.Hour),
+		To:             day.Add(72 * time.Hour),
+		OpeningBalance: Amount{Cents: 10000},
+		ClosingBalance: Amount{Cents: 6500},
+		Entries: []StatementEntry{
+			entry(day.Add(13*time.Hour), 1000),
+			entry(day.Add(23*time.Hour+59*time.Minute), 500),
+			entry(day.Add(48*time.Hour), 2000),
+		},
+	}
+
+	daily := statement.Daily()
+	require.Len(t, daily, 3)
+
+	expected := []struct {
+		from, to         time.Time
+		opening, closing int64
+		entries          int
+	}{
+		{from: day.Add(12 * time.Hour), to: day.Add(24 * time.Hour), opening: 10000, closing: 8500, entries: 2},
+		{from: day.Add(24 * time.Hour), to: day.Add(48 * time.Hour), opening: 8500, closing: 8500, entries: 0},
+		{from: day.Add(48 * time.Hour), to: day.Add(72 * time.Hour), opening: 8500, closing: 6500, entries: 1},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.from, daily[i].From)
+		assert.Equal(t, e.to, daily[i].To)
+		assert.Equal(t, e.opening, daily[i].OpeningBalance.Cents)
+		assert.Equal(t, e.closing, daily[i].ClosingBalance.Cents)
+		assert.Len(t, daily[i].Entries, e.entries)
+	}
+}
@@ -10,7 +10,10 @@ import (
 
 type (
 	qontoTransferManager struct {
-		storage storage.Storage
+		storage    storage.Storage
+		rules      RuleEngine
+		screener   Screener
+		duplicates DuplicatesPolicy
 	}
 )
 
@@ -20,45 +23,288 @@ func NewQontoTransferManager(storage storage.Storage) *qontoTransferManager {
 	}
 }
 
-func (qm *qontoTransferManager) ProcessTransfers(ctx context.Context, request *Request) error {
-	transactions := make([]*storage.Transaction, 0, len(request.CreditTransfers))
+// WithRuleEngine sets risk rules engine evaluated on every request before funds move
+func (qm *qontoTransferManager) WithRuleEngine(engine RuleEngine) *qontoTransferManager {
+	qm.rules = engine
+	return qm
+}
+
+// WithScreener sets sanctions screener of transfer counterparties
+func (qm *qontoTransferManager) WithScreener(screener Screener) *qontoTransferManager {
+	qm.screener = screener
+	return qm
+}
+
+// WithDuplicatesPolicy sets detection of transfers repeated across requests
+func (qm *qontoTransferManager) WithDuplicatesPolicy(policy DuplicatesPolicy) *qontoTransferManager {
+	qm.duplicates = policy
+	return qm
+}
 
-	return qm.storage.WithTransactionStorage(ctx, func(ctx context.Context, txStorage storage.Storage) error {
-		var totalAmount int64
-		for _, ct := range request.CreditTransfers {
-			totalAmount += ct.Amount.Cents
+// ProcessTransfers implements TransferManager interface. Transfers are read and executed by chunks
+// in the same DB transaction, so requests of any size are processed in bounded memory
+func (qm *qontoTransferManager) ProcessTransfers(ctx context.Context, request *Request) (*Result, error) {
+	result := newResult(request.Len())
+	bestEffort := request.Mode == MODE_BEST_EFFORT
+
+	err := request.Each(func(i int, ct Transfer) error {
+		if err := validateTransfer(ct); err != nil {
+			if !bestEffort {
+				return fmt.Errorf("%w: transfer #%d", err, i+1)
+			}
+			result.reject(i, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := qm.precheck(ctx, request, result); err != nil {
+		return nil, fromStorage(err)
+	}
+
+	var txResult *Result
+	err = qm.storage.WithTransactionStorage(ctx, func(ctx context.Context, txStorage storage.Storage) error {
+		// the function may be called again, so outcomes of previous attempts must not leak
+		txResult = result.clone()
+		reject := func(i int, err error) error {
+			if !bestEffort {
+				return err
+			}
+			txResult.reject(i, err)
+			return nil
+		}
+
 		account, err := txStorage.FindAccountByIBAN(ctx, request.Party.IBAN)
 		if err != nil {
 			return err
 		}
+		now := time.Now().UTC()
 
-		if account.BalanceCents < totalAmount {
-			return ErrNotEnoughFunds
+		limits, err := txStorage.FindTransferLimits(ctx, account.ID)
+		if err != nil {
+			return err
 		}
+		checker := newLimitsChecker(limits, now, func(ctx context.Context, counterpartyIBAN string, since time.Time) (int64, error) {
+			return txStorage.SumAccountTransactions(ctx, account.ID, storage.TransactionFilter{CounterpartyIBAN: counterpartyIBAN, Since: since})
+		})
 
-		for _, tx := range request.CreditTransfers {
-			transactions = append(transactions,
-				&storage.Transaction{
-					CounterpartyName: tx.CounterParty.Name,
-					CounterpartyIBAN: tx.CounterParty.IBAN,
-					CounterpartyBIC:  tx.CounterParty.BIC,
-					AmountCents:      tx.Amount.Cents,
-					AmountCurrency:   string(CURRENCY_EURO),
-					BankAccountID:    account.ID,
-					Description:      fmt.Sprintf("[%s] Transfer to %s", time.Now().UTC().Format(time.RFC3339), tx.CounterParty.Name),
-				},
-			)
-		}
+		balance := account.BalanceCents
+		fundsExhausted := false
+		executed := 0
+		err = request.chunks(func(offset int, chunk []Transfer) error {
+			fingerprints := make([]string, 0, len(chunk))
+			for _, ct := range chunk {
+				fingerprints = append(fingerprints, Fingerprint(ct))
+			}
+			paid, err := paidFingerprints(ctx, txStorage, qm.duplicates, account.ID, fingerprints, now)
+			if err != nil {
+				return err
+			}
 
-		if err := txStorage.UpdateAccountBalance(ctx, account.ID, account.BalanceCents-totalAmount); err != nil {
+			transactions := make([]*storage.Transaction, 0, len(chunk))
+			held := []*storage.HeldTransfer{}
+			for j, tx := range chunk {
+				i := offset + j
+				if txResult.Rejected(i) {
+					continue
+				}
+				duplicate := paid[fingerprints[j]]
+				if duplicate && qm.duplicates.Mode == DUPLICATES_REJECT && !request.AllowDuplicates {
+					if !bestEffort {
+						return fmt.Errorf("%w within %v: transfer #%d", ErrDuplicateTransfer, qm.duplicates.Window, i+1)
+					}
+					txResult.reject(i, ErrDuplicateTransfer)
+					continue
+				}
+				if txResult.held(i) {
+					// the hit may be cleared since screening, the account lock orders this check after clearing
+					open, err := hasOpenHit(ctx, txStorage, account.ID, tx.CounterParty)
+					if err != nil {
+						return err
+					}
+					if open {
+						held = append(held, &storage.HeldTransfer{
+							BankAccountID:    account.ID,
+							CounterpartyName: tx.CounterParty.Name,
+							CounterpartyIBAN: tx.CounterParty.IBAN,
+							CounterpartyBIC:  tx.CounterParty.BIC,
+							AmountCents:      tx.Amount.Cents,
+							AmountCurrency:   string(tx.Currency),
+							Description:      tx.Description,
+							Fingerprint:      fingerprints[j],
+							Status:           storage.HeldTransferPending,
+							CreatedAt:        now,
+						})
+						continue
+					}
+					txResult.accept(i)
+				}
+				if err := checker.admit(ctx, tx); err != nil {
+					if err := reject(i, err); err != nil {
+						return err
+					}
+					continue
+				}
+				// transfers are executed in order, so once funds run out all following transfers are rejected
+				if fundsExhausted || balance < tx.Amount.Cents {
+					fundsExhausted = true
+					if err := reject(i, ErrNotEnoughFunds); err != nil {
+						return err
+					}
+					continue
+				}
+				balance -= tx.Amount.Cents
+
+				transaction := newTransaction(account.ID, tx, fingerprints[j], now)
+				transaction.FlaggedDuplicate = duplicate
+				transactions = append(transactions, transaction)
+				if qm.duplicates.enabled() {
+					// later transfers of the request repeating this one are duplicates too
+					paid[fingerprints[j]] = true
+				}
+			}
+
+			if len(held) > 0 {
+				if err := txStorage.AppendHeldTransfers(ctx, held); err != nil {
+					return err
+				}
+			}
+			if len(transactions) == 0 {
+				return nil
+			}
+			executed += len(transactions)
+			return txStorage.AppendAccountTransactions(ctx, transactions)
+		})
+		if err != nil || executed == 0 {
 			return err
 		}
 
-		if err := txStorage.AppendAccountTransactions(ctx, transactions); err != nil {
+		return txStorage.UpdateAccountBalance(ctx, account.ID, balance)
+	})
+	if err != nil {
+		return nil, fromStorage(err)
+	}
+
+	return txResult, nil
+}
+
+// newTransaction returns transaction executing the transfer at the given time
+func newTransaction(accountID int64, transfer Transfer, fingerprint string, now time.Time) *storage.Transaction {
+	return &storage.Transaction{
+		CounterpartyName: transfer.CounterParty.Name,
+		CounterpartyIBAN: transfer.CounterParty.IBAN,
+		CounterpartyBIC:  transfer.CounterParty.BIC,
+		AmountCents:      transfer.Amount.Cents,
+		AmountCurrency:   string(CURRENCY_EURO),
+		BankAccountID:    accountID,
+		Description:      fmt.Sprintf("[%s] Transfer to %s", now.Format(time.RFC3339), transfer.CounterParty.Name),
+		CreatedAt:        now,
+		Fingerprint:      fingerprint,
+	}
+}
+
+// executeTransactions debits the account by transactions and records them
+func executeTransactions(ctx context.Context, s storage.Storage, accountID, balance int64, transactions []*storage.Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	if err := s.UpdateAccountBalance(ctx, accountID, balance); err != nil {
+		return err
+	}
+
+	return s.AppendAccountTransactions(ctx, transactions)
+}
+
+// validateTransfer checks the transfer regardless of the API it came from,
+// zero or negative amounts would credit the debtor account instead of debiting it
+func validateTransfer(transfer Transfer) error {
+	if transfer.Currency != CURRENCY_EURO {
+		return ErrInvalidCurrency
+	}
+	if transfer.Amount.Cents <= 0 {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// precheck runs compliance checks which must be recorded even if request is rejected,
+// so they are done outside of the transaction moving funds
+func (qm *qontoTransferManager) precheck(ctx context.Context, request *Request, result *Result) error {
+	if qm.screener == nil && qm.rules == nil {
+		return nil
+	}
+
+	account, err := qm.storage.FindAccountByIBAN(ctx, request.Party.IBAN)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+
+	if qm.screener != nil {
+		held, err := screenTransfers(ctx, qm.storage, qm.screener, account, request, now)
+		if err != nil {
+			return err
+		}
+		for _, i := range held {
+			result.hold(i, ErrScreeningHit)
+		}
+	}
+	if qm.rules != nil {
+		if err := qm.assessRisk(ctx, account, request, result, now); err != nil {
 			return err
 		}
+	}
 
-		return nil
+	return nil
+}
+
+// assessRisk evaluates risk rules and persists the decision.
+// Denied request results in DeniedError, in best effort mode only findings
+// concerning the whole request deny it, otherwise concerned transfers are rejected
+func (qm *qontoTransferManager) assessRisk(ctx context.Context, account storage.Account, request *Request, result *Result, now time.Time) error {
+	assessment, err := qm.rules.Evaluate(ctx, &RuleInput{
+		History: qm.storage,
+		Account: account,
+		Request: request,
+		Now:     now,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = qm.storage.SaveRiskDecision(ctx, storage.RiskDecision{
+		BankAccountID: account.ID,
+		Decision:      string(assessment.Decision),
+		Reasons:       assessment.Reasons(),
+		CreatedAt:     now,
 	})
+	if err != nil {
+		return err
+	}
+
+	if assessment.Decision != DECISION_DENY {
+		return nil
+	}
+	if request.Mode != MODE_BEST_EFFORT {
+		return &DeniedError{Findings: assessment.Findings}
+	}
+
+	denied := map[int][]Finding{}
+	for _, f := range assessment.Findings {
+		if f.Decision != DECISION_DENY {
+			continue
+		}
+		if f.Transfer < 0 {
+			return &DeniedError{Findings: assessment.Findings}
+		}
+		denied[f.Transfer] = append(denied[f.Transfer], f)
+	}
+	for i, findings := range denied {
+		result.reject(i, &DeniedError{Findings: findings})
+	}
+
+	return nil
 }
@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTransfer(t *testing.T) {
+	cases := []struct {
+		name        string
+		transfer    Transfer
+		expectedErr error
+	}{
+		{name: "valid", transfer: Transfer{Amount: Amount{Cents: 1}, Currency: CURRENCY_EURO}},
+		{name: "other currency", transfer: Transfer{Amount: Amount{Cents: 1}, Currency: "USD"}, expectedErr: ErrInvalidCurrency},
+		{name: "zero amount", transfer: Transfer{Currency: CURRENCY_EURO}, expectedErr: ErrInvalidAmount},
+		{name: "negative amount", transfer: Transfer{Amount: Amount{Cents: -1450}, Currency: CURRENCY_EURO}, expectedErr: ErrInvalidAmount},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedErr, validateTransfer(tc.transfer))
+		})
+	}
+}
+
+// chunksStub keeps transactions of a single account in memory, other storage methods are not used
+type chunksStub struct {
+	storage.Storage
+	account      storage.Account
+	transactions []*storage.Transaction
+	// inserts holds number of transactions of every insert
+	inserts []int
+	updates int
+}
+
+func (cs *chunksStub) WithTransactionStorage(ctx context.Context, f func(context.Context, storage.Storage) error) error {
+	return f(ctx, cs)
+}
+
+func (cs *chunksStub) FindAccountByIBAN(ctx context.Context, iban string) (storage.Account, error) {
+	return cs.account, nil
+}
+
+func (cs *chunksStub) FindTransferLimits(ctx context.Context, accountID int64) ([]storage.TransferLimit, error) {
+	return nil, nil
+}
+
+func (cs *chunksStub) FindTransactionFingerprints(ctx context.Context, accountID int64, fingerprints []string, since time.Time) ([]string, error) {
+	found := []string{}
+	for _, fp := range fingerprints {
+		for _, tx := range cs.transactions {
+			if tx.Fingerprint == fp {
+				found = append(found, fp)
+				break
+			}
+		}
+	}
+	return found, nil
+}
+
+func (cs *chunksStub) AppendAccountTransactions(ctx context.Context, transactions []*storage.Transaction) error {
+	cs.inserts = append(cs.inserts, len(transactions))
+	cs.transactions = append(cs.transactions, transactions...)
+	return nil
+}
+
+func (cs *chunksStub) UpdateAccountBalance(ctx context.Context, id, balance int64) error {
+	cs.account.BalanceCents = balance
+	cs.updates++
+	return nil
+}
+
+func TestProcessTransfersByChunks(t *testing.T) {
+	// transfers of 1 cent, the last one repeats the first one
+	newRequest := func(mode Mode, count int) *Request {
+		transfers := make(TransferList, 0, count)
+		for i := 0; i < count-1; i++ {
+			transfers = append(transfers, Transfer{
+				Amount:       Amount{Cents: 1},
+				Currency:     CURRENCY_EURO,
+				Description:  fmt.Sprintf("payroll/%d", i),
+				CounterParty: Party{Name: "Bip Bip", IBAN: "EE383680981021245685"},
+			})
+		}
+		transfers = append(transfers, transfers[0])
+		return &Request{Party: Party{IBAN: "FR10474608000002006107XXXXX"}, CreditTransfers: transfers, Mode: mode}
+	}
+
+	cases := []struct {
+		name            string
+		request         *Request
+		balance         int64
+		duplicates      DuplicatesMode
+		expectedErr     error
+		expectedInserts []int
+		expectedBalance int64
+		check           func(t *testing.T, result *Result, stub *chunksStub)
+	}{
+		{
+			name:            "inserted by chunks",
+			request:         newRequest(MODE_ALL_OR_NOTHING, 2*CHUNK_SIZE+500),
+			balance:         1000000,
+			expectedInserts: []int{CHUNK_SIZE, CHUNK_SIZE, 500},
+			expectedBalance: 1000000 - 2*CHUNK_SIZE - 500,
+		},
+		{
+			name:            "repeat of transfer of earlier chunk is flagged",
+			request:         newRequest(MODE_ALL_OR_NOTHING, CHUNK_SIZE+1),
+			balance:         1000000,
+			duplicates:      DUPLICATES_FLAG,
+			expectedInserts: []int{CHUNK_SIZE, 1},
+			expectedBalance: 1000000 - CHUNK_SIZE - 1,
+			check: func(t *testing.T, result *Result, stub *chunksStub) {
+				assert.False(t, stub.transactions[0].FlaggedDuplicate)
+				assert.True(t, stub.transactions[CHUNK_SIZE].FlaggedDuplicate)
+			},
+		},
+		{
+			name:        "repeat of transfer of earlier chunk rejects the request",
+			request:     newRequest(MODE_ALL_OR_NOTHING, CHUNK_SIZE+1),
+			balance:     1000000,
+			duplicates:  DUPLICATES_REJECT,
+			expectedErr: ErrDuplicateTransfer,
+		},
+		{
+			name:            "funds run out in a later chunk",
+			request:         newRequest(MODE_BEST_EFFORT, 2*CHUNK_SIZE),
+			balance:         CHUNK_SIZE + 10,
+			duplicates:      DUPLICATES_REJECT,
+			expectedInserts: []int{CHUNK_SIZE, 10},
+			expectedBalance: 0,
+			check: func(t *testing.T, result *Result, stub *chunksStub) {
+				assert.Equal(t, CHUNK_SIZE+10, result.Accepted())
+				assert.Equal(t, TransferResult{Status: TRANSFER_REJECTED, Err: ErrNotEnoughFunds}, result.Transfer(CHUNK_SIZE+10))
+				assert.Equal(t, TransferResult{Status: TRANSFER_REJECTED, Err: ErrDuplicateTransfer}, result.Transfer(2*CHUNK_SIZE-1))
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := &chunksStub{account: storage.Account{ID: 1, BalanceCents: tc.balance}}
+			manager := NewQontoTransferManager(stub).WithDuplicatesPolicy(DuplicatesPolicy{Mode: tc.duplicates, Window: time.Hour})
+
+			result, err := manager.ProcessTransfers(context.Background(), tc.request)
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				assert.Contains(t, err.Error(), fmt.Sprintf("transfer #%d", tc.request.Len()))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.request.Len(), result.Len())
+			assert.Equal(t, tc.expectedInserts, stub.inserts)
+			assert.Equal(t, 1, stub.updates, "balance must be updated once")
+			assert.Equal(t, tc.expectedBalance, stub.account.BalanceCents)
+			if tc.check != nil {
+				tc.check(t, result, stub)
+			}
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package core
 
-import "context"
+import (
+	"context"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+)
 
 type (
 	TransferManager interface {
-		ProcessTransfers(ctx context.Context, request *Request) error
+		// ProcessTransfers executes transfers of the request according to its mode.
+		// Error is returned only if the request is rejected as a whole
+		ProcessTransfers(ctx context.Context, request *Request) (*Result, error)
 	}
 
 	Currency string
@@ -20,11 +26,29 @@ type (
 		Currency     Currency
 		Description  string
 		CounterParty Party
+		// Reference identifies the transfer for the initiating party, e.g. EndToEndId of pain.001,
+		// it is used in reports only
+		Reference string
+	}
+
+	// Transfers are credit transfers of a request in their order. They may be read any number of times,
+	// e.g. when a transaction is retried, and are read by chunks, so large requests are never held in memory as a whole
+	Transfers interface {
+		Len() int
+		// Chunks calls fn with consecutive chunks of at most size transfers, offset is the index
+		// of the first transfer of the chunk. The chunk must not be used after fn returns
+		Chunks(size int, fn func(offset int, chunk []Transfer) error) error
 	}
 
+	// TransferList is a request kept in memory
+	TransferList []Transfer
+
 	Request struct {
 		Party           Party
-		CreditTransfers []Transfer
+		CreditTransfers Transfers
+		// AllowDuplicates forces execution of transfers detected as duplicates
+		AllowDuplicates bool
+		Mode            Mode
 	}
 
 	Amount struct {
@@ -34,4 +58,70 @@ type (
 
 const (
 	CURRENCY_EURO Currency = "EUR"
+
+	// CHUNK_SIZE is the number of transfers read at once, only a chunk of a request is held in memory
+	CHUNK_SIZE = 1000
 )
+
+// Len implements Transfers interface
+func (l TransferList) Len() int {
+	return len(l)
+}
+
+// Chunks implements Transfers interface
+func (l TransferList) Chunks(size int, fn func(offset int, chunk []Transfer) error) error {
+	for offset := 0; offset < len(l); offset += size {
+		end := offset + size
+		if end > len(l) {
+			end = len(l)
+		}
+		if err := fn(offset, l[offset:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of credit transfers of the request
+func (r *Request) Len() int {
+	if r.CreditTransfers == nil {
+		return 0
+	}
+	return r.CreditTransfers.Len()
+}
+
+// Each calls fn with every credit transfer of the request in order, transfers are read by chunks
+func (r *Request) Each(fn func(i int, transfer Transfer) error) error {
+	return r.chunks(func(offset int, chunk []Transfer) error {
+		for j, transfer := range chunk {
+			if err := fn(offset+j, transfer); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// chunks calls fn with consecutive chunks of CHUNK_SIZE transfers of the request
+func (r *Request) chunks(fn func(offset int, chunk []Transfer) error) error {
+	if r.CreditTransfers == nil {
+		return nil
+	}
+	return r.CreditTransfers.Chunks(CHUNK_SIZE, fn)
+}
+
+// Redactor masks names and IBANs of the debtor and counterparties of the request in error messages.
+// Names of counterparties are gathered only once there is something to redact, so they are not kept
+// in memory while large requests are processed
+func (r *Request) Redactor() *qonto.Redactor {
+	return qonto.NewRedactorFunc(func() []string {
+		names := []string{r.Party.Name}
+		// names read before a failure are still masked
+		_ = r.Each(func(i int, transfer Transfer) error {
+			names = append(names, transfer.CounterParty.Name)
+			return nil
+		})
+		return names
+	})
+}
@@ -0,0 +1,265 @@
+package dispatch
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	ErrQueueFull = Error("too many requests of the account are queued")
+	ErrClosed    = Error("dispatcher is closed")
+)
+
+// DEFAULT_IDLE_TIMEOUT is how long the queue of an account lives without requests
+const DEFAULT_IDLE_TIMEOUT = time.Minute
+
+type (
+	// Stats describes queues of the dispatcher at the moment
+	Stats struct {
+		// Queued is the number of requests waiting in all queues
+		Queued int
+		// MaxQueued is the number of requests waiting in the longest queue
+		MaxQueued int
+		// InProgress is the number of requests being processed
+		InProgress int
+		// Accounts is the number of account queues alive
+		Accounts int
+	}
+
+	// Observer is notified about requests passing through the dispatcher
+	Observer interface {
+		// ObserveRejected is called when the request is rejected because its queue is full
+		ObserveRejected()
+		// ObserveWait is called when processing of the request starts
+		ObserveWait(wait time.Duration)
+	}
+
+	// Dispatcher processes requests of the same debited account one at a time in order of arrival.
+	// Every account gets its own queue and worker on its first request, both are dropped once the account
+	// is idle, so a slow or busy account never delays or fills the queue of another one.
+	// At most workers requests of different accounts are processed at the same time
+	Dispatcher struct {
+		next        core.TransferManager
+		queueSize   int
+		idleTimeout time.Duration
+		// slots bounds requests processed at the same time, so accounts cannot exhaust the database pool
+		slots    chan struct{}
+		observer Observer
+
+		// mu guards queues and closed, so requests are never sent to closed or evicted queue
+		mu         sync.Mutex
+		queues     map[string]*accountQueue
+		closed     bool
+		workers    sync.WaitGroup
+		inProgress int32
+	}
+
+	accountQueue struct {
+		jobs chan *job
+		// pending is the number of queued requests, including the one waiting for a processing slot
+		pending int32
+	}
+
+	job struct {
+		ctx      context.Context
+		request  *core.Request
+		queuedAt time.Time
+		done     chan outcome
+	}
+
+	outcome struct {
+		result *core.Result
+		err    error
+	}
+)
+
+// NewDispatcher creates dispatcher processing at most workers requests at the same time,
+// every account queues at most queueSize requests. Close must be called to stop workers
+func NewDispatcher(next core.TransferManager, workers, queueSize int) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	// unbuffered queue would reject every request which does not find its worker idle
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	return &Dispatcher{
+		next:        next,
+		queueSize:   queueSize,
+		idleTimeout: DEFAULT_IDLE_TIMEOUT,
+		slots:       make(chan struct{}, workers),
+		queues:      map[string]*accountQueue{},
+	}
+}
+
+// WithObserver sets observer of dispatched requests
+func (d *Dispatcher) WithObserver(observer Observer) *Dispatcher {
+	d.observer = observer
+	return d
+}
+
+// WithIdleTimeout sets how long the queue of an account lives without requests, DEFAULT_IDLE_TIMEOUT by default
+func (d *Dispatcher) WithIdleTimeout(timeout time.Duration) *Dispatcher {
+	d.idleTimeout = timeout
+	return d
+}
+
+// ProcessTransfers implements core.TransferManager interface, it waits until the request is processed
+// by the worker of its account. ErrQueueFull is returned at once if the queue of the account is full
+func (d *Dispatcher) ProcessTransfers(ctx context.Context, request *core.Request) (*core.Result, error) {
+	j := &job{
+		ctx:      ctx,
+		request:  request,
+		queuedAt: time.Now(),
+		// the worker never blocks on abandoned job
+		done: make(chan outcome, 1),
+	}
+	if err := d.enqueue(j); err != nil {
+		return nil, err
+	}
+
+	select {
+	case o := <-j.done:
+		return o.result, o.err
+	case <-ctx.Done():
+		// the worker skips the job if it has not started yet, otherwise cancelled context aborts the transaction
+		return nil, ctx.Err()
+	}
+}
+
+// enqueue sends the job to the queue of its account, starting the queue if the account has none
+func (d *Dispatcher) enqueue(j *job) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return ErrClosed
+	}
+
+	iban := j.request.Party.IBAN
+	queue, ok := d.queues[iban]
+	if !ok {
+		queue = &accountQueue{jobs: make(chan *job, d.queueSize)}
+		d.queues[iban] = queue
+		d.workers.Add(1)
+		go d.work(iban, queue)
+	}
+
+	// counted before sending, so the worker never takes the job before it is counted
+	atomic.AddInt32(&queue.pending, 1)
+	select {
+	case queue.jobs <- j:
+		return nil
+	default:
+		atomic.AddInt32(&queue.pending, -1)
+		if d.observer != nil {
+			d.observer.ObserveRejected()
+		}
+		return ErrQueueFull
+	}
+}
+
+// work processes requests of the account until its queue is closed or idle
+func (d *Dispatcher) work(iban string, queue *accountQueue) {
+	defer d.workers.Done()
+	idle := time.NewTimer(d.idleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case j, ok := <-queue.jobs:
+			if !ok {
+				return
+			}
+			if !idle.Stop() {
+				<-idle.C
+			}
+			d.process(queue, j)
+			idle.Reset(d.idleTimeout)
+		case <-idle.C:
+			if d.evict(iban, queue) {
+				return
+			}
+			idle.Reset(d.idleTimeout)
+		}
+	}
+}
+
+// process waits for a processing slot and processes the job of the queue unless it is cancelled
+func (d *Dispatcher) process(queue *accountQueue, j *job) {
+	acquired := false
+	if j.ctx.Err() == nil {
+		select {
+		case d.slots <- struct{}{}:
+			acquired = true
+		case <-j.ctx.Done():
+		}
+	}
+	atomic.AddInt32(&queue.pending, -1)
+	if !acquired {
+		j.done <- outcome{err: j.ctx.Err()}
+		return
+	}
+	defer func() { <-d.slots }()
+
+	if d.observer != nil {
+		d.observer.ObserveWait(time.Since(j.queuedAt))
+	}
+
+	atomic.AddInt32(&d.inProgress, 1)
+	result, err := d.next.ProcessTransfers(j.ctx, j.request)
+	atomic.AddInt32(&d.inProgress, -1)
+	j.done <- outcome{result: result, err: err}
+}
+
+// evict drops the queue of the account if nothing was sent to it, the next request of the account starts a new one
+func (d *Dispatcher) evict(iban string, queue *accountQueue) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// closed queue is drained by the worker
+	if d.closed || len(queue.jobs) > 0 {
+		return false
+	}
+	delete(d.queues, iban)
+	return true
+}
+
+// Stats returns current state of queues
+func (d *Dispatcher) Stats() Stats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := Stats{Accounts: len(d.queues)}
+	for _, queue := range d.queues {
+		queued := int(atomic.LoadInt32(&queue.pending))
+		stats.Queued += queued
+		if queued > stats.MaxQueued {
+			stats.MaxQueued = queued
+		}
+	}
+	stats.InProgress = int(atomic.LoadInt32(&d.inProgress))
+	return stats
+}
+
+// Close stops accepting requests and waits until queued ones are processed
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	for _, queue := range d.queues {
+		close(queue.jobs)
+	}
+	d.mu.Unlock()
+
+	d.workers.Wait()
+}
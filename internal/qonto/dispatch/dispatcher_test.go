@@ -0,0 +1,214 @@
+package dispatch
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func request(iban, description string) *core.Request {
+	return &core.Request{
+		Party: core.Party{IBAN: iban},
+		CreditTransfers: core.TransferList{
+			{Amount: core.Amount{Cents: 100}, Currency: "EUR", Description: description},
+		},
+	}
+}
+
+// waitQueued waits until the dispatcher queues n requests
+func waitQueued(t *testing.T, d *Dispatcher, n int) {
+	require.Eventually(t, func() bool { return d.Stats().Queued == n }, time.Second, time.Millisecond)
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("serializes requests of the same account in order", func(t *testing.T) {
+		started, release := make(chan string, 10), make(chan struct{})
+		manager := newMockManager().WithBlocking(started, release)
+		d := NewDispatcher(manager, 4, 10)
+		defer d.Close()
+
+		wg := sync.WaitGroup{}
+		submit := func(description string) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", description))
+				assert.NoError(t, err)
+			}()
+		}
+		submit("0")
+		<-started
+		for i := 1; i < 5; i++ {
+			submit(strconv.Itoa(i))
+			waitQueued(t, d, i)
+		}
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, []string{"0", "1", "2", "3", "4"}, manager.processed["FR10474608000002006107XXXXX"])
+		assert.Equal(t, 1, manager.maxActive["FR10474608000002006107XXXXX"])
+	})
+
+	t.Run("processes different accounts in parallel", func(t *testing.T) {
+		started, release := make(chan string, 10), make(chan struct{})
+		manager := newMockManager().WithBlocking(started, release)
+		d := NewDispatcher(manager, 4, 10)
+		defer d.Close()
+
+		wg := sync.WaitGroup{}
+		for _, iban := range []string{"FR10474608000002006107XXXXX", "DE89370400440532013000"} {
+			wg.Add(1)
+			go func(iban string) {
+				defer wg.Done()
+				_, err := d.ProcessTransfers(context.Background(), request(iban, iban))
+				assert.NoError(t, err)
+			}(iban)
+		}
+		for i := 0; i < 2; i++ {
+			select {
+			case <-started:
+			case <-time.After(time.Second):
+				t.Fatal("requests of different accounts are not processed in parallel")
+			}
+		}
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, 2, manager.maxTotal)
+	})
+
+	t.Run("rejects requests when queue is full", func(t *testing.T) {
+		started, release := make(chan string, 10), make(chan struct{})
+		observer := &mockObserver{}
+		d := NewDispatcher(newMockManager().WithBlocking(started, release), 1, 1).WithObserver(observer)
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "queued"))
+				assert.NoError(t, err)
+			}()
+			if i == 0 {
+				<-started
+			}
+		}
+		waitQueued(t, d, 1)
+
+		_, err := d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "rejected"))
+		assert.ErrorIs(t, err, ErrQueueFull)
+		assert.Equal(t, Stats{Queued: 1, MaxQueued: 1, InProgress: 1, Accounts: 1}, d.Stats())
+		assert.Equal(t, 1, observer.rejected)
+
+		close(release)
+		wg.Wait()
+		d.Close()
+		assert.Equal(t, 2, observer.waits)
+	})
+
+	t.Run("busy account does not block the others", func(t *testing.T) {
+		started, release := make(chan string, 10), make(chan struct{})
+		manager := newMockManager().WithBlocking(started, release)
+		d := NewDispatcher(manager, 2, 1)
+
+		go d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "first"))
+		<-started
+		go d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "queued"))
+		waitQueued(t, d, 1)
+		_, err := d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "rejected"))
+		assert.ErrorIs(t, err, ErrQueueFull)
+
+		errs := make(chan error)
+		go func() {
+			_, err := d.ProcessTransfers(context.Background(), request("DE89370400440532013000", "second"))
+			errs <- err
+		}()
+		select {
+		case iban := <-started:
+			assert.Equal(t, "DE89370400440532013000", iban)
+		case <-time.After(time.Second):
+			t.Fatal("request of another account waits for the busy one")
+		}
+
+		close(release)
+		assert.NoError(t, <-errs)
+		d.Close()
+		assert.Equal(t, []string{"first", "queued"}, manager.processed["FR10474608000002006107XXXXX"])
+		assert.Equal(t, []string{"second"}, manager.processed["DE89370400440532013000"])
+	})
+
+	t.Run("bounds requests processed at the same time", func(t *testing.T) {
+		started, release := make(chan string, 10), make(chan struct{})
+		manager := newMockManager().WithBlocking(started, release)
+		d := NewDispatcher(manager, 1, 1)
+
+		go d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "first"))
+		<-started
+
+		errs := make(chan error)
+		go func() {
+			_, err := d.ProcessTransfers(context.Background(), request("DE89370400440532013000", "second"))
+			errs <- err
+		}()
+		// the request waits for a processing slot in the queue of its account
+		waitQueued(t, d, 1)
+		assert.Equal(t, Stats{Queued: 1, MaxQueued: 1, InProgress: 1, Accounts: 2}, d.Stats())
+
+		close(release)
+		assert.NoError(t, <-errs)
+		d.Close()
+		assert.Equal(t, 1, manager.maxTotal)
+	})
+
+	t.Run("evicts idle accounts", func(t *testing.T) {
+		d := NewDispatcher(newMockManager(), 2, 1).WithIdleTimeout(10 * time.Millisecond)
+		defer d.Close()
+
+		_, err := d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "first"))
+		require.NoError(t, err)
+		require.Eventually(t, func() bool { return d.Stats().Accounts == 0 }, time.Second, time.Millisecond)
+
+		// the next request of the account starts a new queue
+		_, err = d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "second"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("skips cancelled requests", func(t *testing.T) {
+		started, release := make(chan string, 10), make(chan struct{})
+		manager := newMockManager().WithBlocking(started, release)
+		d := NewDispatcher(manager, 1, 10)
+
+		go d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "processed"))
+		<-started
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errs := make(chan error)
+		go func() {
+			_, err := d.ProcessTransfers(ctx, request("FR10474608000002006107XXXXX", "cancelled"))
+			errs <- err
+		}()
+		waitQueued(t, d, 1)
+		cancel()
+		assert.ErrorIs(t, <-errs, context.Canceled)
+
+		close(release)
+		d.Close()
+		assert.Equal(t, []string{"processed"}, manager.processed["FR10474608000002006107XXXXX"])
+	})
+
+	t.Run("closed dispatcher rejects requests", func(t *testing.T) {
+		d := NewDispatcher(newMockManager(), 2, 1)
+		d.Close()
+		d.Close()
+
+		_, err := d.ProcessTransfers(context.Background(), request("FR10474608000002006107XXXXX", "rejected"))
+		assert.ErrorIs(t, err, ErrClosed)
+	})
+}
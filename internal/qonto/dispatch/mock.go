@@ -0,0 +1,83 @@
+package dispatch
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+// mockManager records order and concurrency of processed requests per debited account
+type mockManager struct {
+	mu          sync.Mutex
+	active      map[string]int
+	maxActive   map[string]int
+	totalActive int
+	maxTotal    int
+	processed   map[string][]string
+	started     chan string
+	release     chan struct{}
+}
+
+func newMockManager() *mockManager {
+	return &mockManager{
+		active:    map[string]int{},
+		maxActive: map[string]int{},
+		processed: map[string][]string{},
+	}
+}
+
+// WithBlocking makes every request report its start to started and wait for release
+func (mm *mockManager) WithBlocking(started chan string, release chan struct{}) *mockManager {
+	mm.started = started
+	mm.release = release
+	return mm
+}
+
+func (mm *mockManager) ProcessTransfers(ctx context.Context, request *core.Request) (*core.Result, error) {
+	iban := request.Party.IBAN
+	mm.mu.Lock()
+	mm.active[iban]++
+	mm.totalActive++
+	if mm.active[iban] > mm.maxActive[iban] {
+		mm.maxActive[iban] = mm.active[iban]
+	}
+	if mm.totalActive > mm.maxTotal {
+		mm.maxTotal = mm.totalActive
+	}
+	mm.processed[iban] = append(mm.processed[iban], request.CreditTransfers.(core.TransferList)[0].Description)
+	mm.mu.Unlock()
+
+	if mm.started != nil {
+		mm.started <- iban
+	}
+	if mm.release != nil {
+		<-mm.release
+	}
+
+	mm.mu.Lock()
+	mm.active[iban]--
+	mm.totalActive--
+	mm.mu.Unlock()
+
+	return core.ResultOf(core.TransferResult{Status: core.TRANSFER_ACCEPTED}), nil
+}
+
+type mockObserver struct {
+	mu       sync.Mutex
+	rejected int
+	waits    int
+}
+
+func (mo *mockObserver) ObserveRejected() {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	mo.rejected++
+}
+
+func (mo *mockObserver) ObserveWait(wait time.Duration) {
+	mo.mu.Lock()
+	defer mo.mu.Unlock()
+	mo.waits++
+}
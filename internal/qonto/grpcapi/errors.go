@@ -0,0 +1,103 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/dispatch"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/grpcapi/qontov1"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorMappings defines gRPC status code of known errors, first match wins
+var errorMappings = []struct {
+	err  error
+	code codes.Code
+}{
+	{core.ErrAccountNotFound, codes.NotFound},
+	{core.ErrDuplicateTransfer, codes.AlreadyExists},
+	{core.ErrDuplicateIBAN, codes.AlreadyExists},
+	{core.ErrConstraintViolation, codes.FailedPrecondition},
+	{core.ErrConflict, codes.Aborted},
+	{core.ErrInvalidCurrency, codes.InvalidArgument},
+	{core.ErrInvalidAmount, codes.InvalidArgument},
+	{core.ErrInvalidPeriod, codes.InvalidArgument},
+	{ErrInvalidArgument, codes.InvalidArgument},
+	{core.ErrNotEnoughFunds, codes.FailedPrecondition},
+	{core.ErrSingleTransferLimitExceeded, codes.FailedPrecondition},
+	{core.ErrDailyLimitExceeded, codes.FailedPrecondition},
+	{core.ErrMonthlyLimitExceeded, codes.FailedPrecondition},
+	{core.ErrBatchSizeLimitExceeded, codes.FailedPrecondition},
+	{core.ErrNewBeneficiaryLargeAmount, codes.FailedPrecondition},
+	{core.ErrRoundAmountBurst, codes.FailedPrecondition},
+	{core.ErrBlockedCountry, codes.FailedPrecondition},
+	{core.ErrTransferDenied, codes.FailedPrecondition},
+	{core.ErrScreeningHit, codes.FailedPrecondition},
+	{ratelimit.ErrRateLimited, codes.ResourceExhausted},
+	{ratelimit.ErrConcurrencyLimited, codes.ResourceExhausted},
+	{dispatch.ErrQueueFull, codes.Unavailable},
+	{dispatch.ErrClosed, codes.Unavailable},
+	{context.Canceled, codes.Canceled},
+	{context.DeadlineExceeded, codes.DeadlineExceeded},
+}
+
+// transferErrorCodes defines codes of rejected transfers, first match wins:
+// findings of risk rules with own codes must be checked before the denial itself
+var transferErrorCodes = []struct {
+	err  error
+	code qontov1.TransferErrorCode
+}{
+	{core.ErrInvalidCurrency, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_INVALID_CURRENCY},
+	{core.ErrInvalidAmount, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_INVALID_AMOUNT},
+	{core.ErrNotEnoughFunds, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_NOT_ENOUGH_FUNDS},
+	{core.ErrSingleTransferLimitExceeded, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_SINGLE_TRANSFER_LIMIT_EXCEEDED},
+	{core.ErrDailyLimitExceeded, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_DAILY_LIMIT_EXCEEDED},
+	{core.ErrMonthlyLimitExceeded, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_MONTHLY_LIMIT_EXCEEDED},
+	{core.ErrBatchSizeLimitExceeded, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_BATCH_SIZE_LIMIT_EXCEEDED},
+	{core.ErrDuplicateTransfer, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_DUPLICATE_TRANSFER},
+	{core.ErrScreeningHit, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_SCREENING_HIT},
+	{core.ErrNewBeneficiaryLargeAmount, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_NEW_BENEFICIARY_LARGE_AMOUNT},
+	{core.ErrRoundAmountBurst, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_ROUND_AMOUNT_BURST},
+	{core.ErrBlockedCountry, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_BLOCKED_COUNTRY},
+	{core.ErrTransferDenied, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_TRANSFER_DENIED},
+}
+
+// transferErrorCode returns code of the rejected transfer, unknown errors are unspecified
+func transferErrorCode(err error) qontov1.TransferErrorCode {
+	for _, mapping := range transferErrorCodes {
+		if errors.Is(err, mapping.err) {
+			return mapping.code
+		}
+	}
+	return qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_UNSPECIFIED
+}
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	ErrInvalidArgument = Error("invalid argument")
+)
+
+// statusError converts error to gRPC status error, unknown errors are internal ones.
+// The message is redacted by the redactor of the context, so names of the request parties are hidden too
+func statusError(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	message := qonto.RedactorFromContext(ctx).Text(err.Error())
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return status.Error(mapping.code, message)
+		}
+	}
+
+	return status.Error(codes.Internal, message)
+}
@@ -0,0 +1,101 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+type mockManager struct {
+	err     error
+	result  *core.Result
+	request *core.Request
+}
+
+func newMockManager() *mockManager {
+	return &mockManager{}
+}
+
+func (mm *mockManager) ProcessTransfers(ctx context.Context, request *core.Request) (*core.Result, error) {
+	// transfers may be spooled and removed once the request is answered, so a copy read into memory is recorded
+	recorded := *request
+	transfers := core.TransferList{}
+	if err := request.Each(func(i int, transfer core.Transfer) error {
+		transfers = append(transfers, transfer)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	recorded.CreditTransfers = transfers
+	mm.request = &recorded
+	if mm.err != nil {
+		return nil, mm.err
+	}
+	if mm.result != nil {
+		return mm.result, nil
+	}
+
+	outcomes := make([]core.TransferResult, len(transfers))
+	for i := range outcomes {
+		outcomes[i].Status = core.TRANSFER_ACCEPTED
+	}
+	return core.ResultOf(outcomes...), nil
+}
+
+func (mm *mockManager) WithResult(result *core.Result) *mockManager {
+	mm.result = result
+	return mm
+}
+
+func (mm *mockManager) WithError(err error) *mockManager {
+	mm.err = err
+	return mm
+}
+
+type mockAccountManager struct {
+	accounts []core.Account
+}
+
+func newMockAccountManager(accounts ...core.Account) *mockAccountManager {
+	return &mockAccountManager{
+		accounts: accounts,
+	}
+}
+
+func (mam *mockAccountManager) Account(ctx context.Context, iban string) (*core.Account, error) {
+	for _, account := range mam.accounts {
+		if account.Party.IBAN == iban {
+			return &account, nil
+		}
+	}
+	return nil, core.ErrAccountNotFound
+}
+
+type mockHistoryManager struct {
+	transactions []core.Transaction
+	// err is returned after all transactions are passed
+	err      error
+	from, to time.Time
+}
+
+func newMockHistoryManager(transactions ...core.Transaction) *mockHistoryManager {
+	return &mockHistoryManager{
+		transactions: transactions,
+	}
+}
+
+func (mhm *mockHistoryManager) WithError(err error) *mockHistoryManager {
+	mhm.err = err
+	return mhm
+}
+
+func (mhm *mockHistoryManager) EachTransaction(ctx context.Context, iban string, from, to time.Time, f func(core.Transaction) error) error {
+	mhm.from, mhm.to = from, to
+	for _, tx := range mhm.transactions {
+		if err := f(tx); err != nil {
+			return err
+		}
+	}
+	return mhm.err
+}
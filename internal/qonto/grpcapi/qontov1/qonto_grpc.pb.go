@@ -0,0 +1,289 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.20.1
+// source: qonto/v1/qonto.proto
+
+package qontov1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// QontoServiceClient is the client API for QontoService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type QontoServiceClient interface {
+	// ProcessTransfers executes bulk transfers from the organization account.
+	// Rejected all_or_nothing requests and failures concerning the whole request are returned as errors
+	ProcessTransfers(ctx context.Context, in *ProcessTransfersRequest, opts ...grpc.CallOption) (*ProcessTransfersResponse, error)
+	// StreamTransfers executes bulk transfers sent by a stream of messages, so requests of any size fit
+	// the message size limit. Transfers are executed once the client closes the stream and results
+	// of the response list only transfers which are not accepted
+	StreamTransfers(ctx context.Context, opts ...grpc.CallOption) (QontoService_StreamTransfersClient, error)
+	// GetAccount returns account identified by IBAN with its current balance
+	GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*Account, error)
+	// ListTransactions streams account transactions in creation order
+	ListTransactions(ctx context.Context, in *ListTransactionsRequest, opts ...grpc.CallOption) (QontoService_ListTransactionsClient, error)
+}
+
+type qontoServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewQontoServiceClient(cc grpc.ClientConnInterface) QontoServiceClient {
+	return &qontoServiceClient{cc}
+}
+
+func (c *qontoServiceClient) ProcessTransfers(ctx context.Context, in *ProcessTransfersRequest, opts ...grpc.CallOption) (*ProcessTransfersResponse, error) {
+	out := new(ProcessTransfersResponse)
+	err := c.cc.Invoke(ctx, "/qonto.v1.QontoService/ProcessTransfers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qontoServiceClient) StreamTransfers(ctx context.Context, opts ...grpc.CallOption) (QontoService_StreamTransfersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &QontoService_ServiceDesc.Streams[0], "/qonto.v1.QontoService/StreamTransfers", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &qontoServiceStreamTransfersClient{stream}
+	return x, nil
+}
+
+type QontoService_StreamTransfersClient interface {
+	Send(*StreamTransfersRequest) error
+	CloseAndRecv() (*ProcessTransfersResponse, error)
+	grpc.ClientStream
+}
+
+type qontoServiceStreamTransfersClient struct {
+	grpc.ClientStream
+}
+
+func (x *qontoServiceStreamTransfersClient) Send(m *StreamTransfersRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *qontoServiceStreamTransfersClient) CloseAndRecv() (*ProcessTransfersResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ProcessTransfersResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *qontoServiceClient) GetAccount(ctx context.Context, in *GetAccountRequest, opts ...grpc.CallOption) (*Account, error) {
+	out := new(Account)
+	err := c.cc.Invoke(ctx, "/qonto.v1.QontoService/GetAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *qontoServiceClient) ListTransactions(ctx context.Context, in *ListTransactionsRequest, opts ...grpc.CallOption) (QontoService_ListTransactionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &QontoService_ServiceDesc.Streams[1], "/qonto.v1.QontoService/ListTransactions", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &qontoServiceListTransactionsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type QontoService_ListTransactionsClient interface {
+	Recv() (*Transaction, error)
+	grpc.ClientStream
+}
+
+type qontoServiceListTransactionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *qontoServiceListTransactionsClient) Recv() (*Transaction, error) {
+	m := new(Transaction)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// QontoServiceServer is the server API for QontoService service.
+// All implementations must embed UnimplementedQontoServiceServer
+// for forward compatibility
+type QontoServiceServer interface {
+	// ProcessTransfers executes bulk transfers from the organization account.
+	// Rejected all_or_nothing requests and failures concerning the whole request are returned as errors
+	ProcessTransfers(context.Context, *ProcessTransfersRequest) (*ProcessTransfersResponse, error)
+	// StreamTransfers executes bulk transfers sent by a stream of messages, so requests of any size fit
+	// the message size limit. Transfers are executed once the client closes the stream and results
+	// of the response list only transfers which are not accepted
+	StreamTransfers(QontoService_StreamTransfersServer) error
+	// GetAccount returns account identified by IBAN with its current balance
+	GetAccount(context.Context, *GetAccountRequest) (*Account, error)
+	// ListTransactions streams account transactions in creation order
+	ListTransactions(*ListTransactionsRequest, QontoService_ListTransactionsServer) error
+	mustEmbedUnimplementedQontoServiceServer()
+}
+
+// UnimplementedQontoServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedQontoServiceServer struct {
+}
+
+func (UnimplementedQontoServiceServer) ProcessTransfers(context.Context, *ProcessTransfersRequest) (*ProcessTransfersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProcessTransfers not implemented")
+}
+func (UnimplementedQontoServiceServer) StreamTransfers(QontoService_StreamTransfersServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTransfers not implemented")
+}
+func (UnimplementedQontoServiceServer) GetAccount(context.Context, *GetAccountRequest) (*Account, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAccount not implemented")
+}
+func (UnimplementedQontoServiceServer) ListTransactions(*ListTransactionsRequest, QontoService_ListTransactionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListTransactions not implemented")
+}
+func (UnimplementedQontoServiceServer) mustEmbedUnimplementedQontoServiceServer() {}
+
+// UnsafeQontoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to QontoServiceServer will
+// result in compilation errors.
+type UnsafeQontoServiceServer interface {
+	mustEmbedUnimplementedQontoServiceServer()
+}
+
+func RegisterQontoServiceServer(s grpc.ServiceRegistrar, srv QontoServiceServer) {
+	s.RegisterService(&QontoService_ServiceDesc, srv)
+}
+
+func _QontoService_ProcessTransfers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessTransfersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QontoServiceServer).ProcessTransfers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qonto.v1.QontoService/ProcessTransfers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QontoServiceServer).ProcessTransfers(ctx, req.(*ProcessTransfersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QontoService_StreamTransfers_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(QontoServiceServer).StreamTransfers(&qontoServiceStreamTransfersServer{stream})
+}
+
+type QontoService_StreamTransfersServer interface {
+	SendAndClose(*ProcessTransfersResponse) error
+	Recv() (*StreamTransfersRequest, error)
+	grpc.ServerStream
+}
+
+type qontoServiceStreamTransfersServer struct {
+	grpc.ServerStream
+}
+
+func (x *qontoServiceStreamTransfersServer) SendAndClose(m *ProcessTransfersResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *qontoServiceStreamTransfersServer) Recv() (*StreamTransfersRequest, error) {
+	m := new(StreamTransfersRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _QontoService_GetAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QontoServiceServer).GetAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/qonto.v1.QontoService/GetAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QontoServiceServer).GetAccount(ctx, req.(*GetAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _QontoService_ListTransactions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListTransactionsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(QontoServiceServer).ListTransactions(m, &qontoServiceListTransactionsServer{stream})
+}
+
+type QontoService_ListTransactionsServer interface {
+	Send(*Transaction) error
+	grpc.ServerStream
+}
+
+type qontoServiceListTransactionsServer struct {
+	grpc.ServerStream
+}
+
+func (x *qontoServiceListTransactionsServer) Send(m *Transaction) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// QontoService_ServiceDesc is the grpc.ServiceDesc for QontoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var QontoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "qonto.v1.QontoService",
+	HandlerType: (*QontoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ProcessTransfers",
+			Handler:    _QontoService_ProcessTransfers_Handler,
+		},
+		{
+			MethodName: "GetAccount",
+			Handler:    _QontoService_GetAccount_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamTransfers",
+			Handler:       _QontoService_StreamTransfers_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ListTransactions",
+			Handler:       _QontoService_ListTransactions_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "qonto/v1/qonto.proto",
+}
@@ -0,0 +1,347 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/grpcapi/qontov1"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/spool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type qontoServer struct {
+	qontov1.UnimplementedQontoServiceServer
+
+	manager  core.TransferManager
+	accounts core.AccountManager
+	history  core.HistoryManager
+	limiter  *ratelimit.Limiter
+	// clientLimiter admits calls by peer address before the debited account is looked at
+	clientLimiter *ratelimit.Limiter
+}
+
+func NewServer(transferManager core.TransferManager) *qontoServer {
+	return &qontoServer{
+		manager: transferManager,
+	}
+}
+
+// WithAccountManager enables account queries
+func (qs *qontoServer) WithAccountManager(accounts core.AccountManager) *qontoServer {
+	qs.accounts = accounts
+	return qs
+}
+
+// WithHistoryManager enables transaction history queries
+func (qs *qontoServer) WithHistoryManager(history core.HistoryManager) *qontoServer {
+	qs.history = history
+	return qs
+}
+
+// WithLimiter throttles transfer requests per debited account
+func (qs *qontoServer) WithLimiter(limiter *ratelimit.Limiter) *qontoServer {
+	qs.limiter = limiter
+	return qs
+}
+
+// WithClientLimiter throttles transfer requests per peer address
+func (qs *qontoServer) WithClientLimiter(limiter *ratelimit.Limiter) *qontoServer {
+	qs.clientLimiter = limiter
+	return qs
+}
+
+// Register registers the service on gRPC server
+func (qs *qontoServer) Register(server *grpc.Server) {
+	qontov1.RegisterQontoServiceServer(server, qs)
+}
+
+// ProcessTransfers implements qontov1.QontoServiceServer interface
+func (qs *qontoServer) ProcessTransfers(ctx context.Context, request *qontov1.ProcessTransfersRequest) (*qontov1.ProcessTransfersResponse, error) {
+	// gRPC decodes the message before the call, its size is bounded by the server receive limit
+	if qs.clientLimiter != nil {
+		release, err := qs.clientLimiter.Acquire(peerAddress(ctx))
+		if err != nil {
+			return nil, statusError(ctx, err)
+		}
+		defer release()
+	}
+	if qs.limiter != nil {
+		release, err := qs.limiter.Acquire(throttleKey(ctx, request.GetOrganization()))
+		if err != nil {
+			return nil, statusError(ctx, err)
+		}
+		defer release()
+	}
+
+	mode, err := requestMode(request.GetMode())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	organization := request.GetOrganization()
+	return qs.execute(ctx, &core.Request{
+		Party: core.Party{
+			Name: organization.GetName(),
+			BIC:  organization.GetBic(),
+			IBAN: organization.GetIban(),
+		},
+		CreditTransfers: protoTransfers(request.GetCreditTransfers()),
+		AllowDuplicates: request.GetAllowDuplicates(),
+		Mode:            mode,
+	}, true)
+}
+
+// StreamTransfers implements qontov1.QontoServiceServer interface.
+// Received transfers are spooled, so the request is never held in memory as a whole
+func (qs *qontoServer) StreamTransfers(stream qontov1.QontoService_StreamTransfersServer) error {
+	ctx := stream.Context()
+	if qs.clientLimiter != nil {
+		release, err := qs.clientLimiter.Acquire(peerAddress(ctx))
+		if err != nil {
+			return statusError(ctx, err)
+		}
+		defer release()
+	}
+
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return statusError(ctx, fmt.Errorf("%w: the stream is empty", ErrInvalidArgument))
+	}
+	if err != nil {
+		return err
+	}
+	if qs.limiter != nil {
+		release, err := qs.limiter.Acquire(throttleKey(ctx, first.GetOrganization()))
+		if err != nil {
+			return statusError(ctx, err)
+		}
+		defer release()
+	}
+	mode, err := requestMode(first.GetMode())
+	if err != nil {
+		return statusError(ctx, err)
+	}
+
+	transfers := spool.New("")
+	defer func() {
+		if err := transfers.Close(); err != nil {
+			qonto.LoggerFromContext(ctx).Error("error removing spooled transfers", "error", err)
+		}
+	}()
+	for message := first; ; {
+		for _, transfer := range message.GetCreditTransfers() {
+			if err := transfers.Append(coreTransfer(transfer)); err != nil {
+				return statusError(ctx, err)
+			}
+		}
+		if message, err = stream.Recv(); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	organization := first.GetOrganization()
+	response, err := qs.execute(ctx, &core.Request{
+		Party: core.Party{
+			Name: organization.GetName(),
+			BIC:  organization.GetBic(),
+			IBAN: organization.GetIban(),
+		},
+		CreditTransfers: transfers,
+		AllowDuplicates: first.GetAllowDuplicates(),
+		Mode:            mode,
+	}, false)
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(response)
+}
+
+// execute processes the request and reports outcomes of its transfers, accepted ones are listed
+// only if listAccepted is set, so responses of large requests stay small
+func (qs *qontoServer) execute(ctx context.Context, request *core.Request, listAccepted bool) (*qontov1.ProcessTransfersResponse, error) {
+	// neither spans nor errors of the request may repeat names of its parties
+	redactor := request.Redactor()
+	ctx = qonto.ContextWithRedactor(ctx, redactor)
+	result, err := qs.manager.ProcessTransfers(ctx, request)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	response := &qontov1.ProcessTransfersResponse{}
+	if listAccepted {
+		response.Results = make([]*qontov1.TransferResult, 0, result.Len())
+	}
+	if request.Mode == core.MODE_BEST_EFFORT {
+		response.Mode = qontov1.Mode_MODE_BEST_EFFORT
+	} else {
+		response.Mode = qontov1.Mode_MODE_ALL_OR_NOTHING
+	}
+	for i := 0; i < result.Len(); i++ {
+		transfer := result.Transfer(i)
+		item := &qontov1.TransferResult{
+			Index:  int32(i),
+			Status: qontov1.TransferStatus_TRANSFER_STATUS_ACCEPTED,
+		}
+		if transfer.Err != nil {
+			item.Error = redactor.Text(transfer.Err.Error())
+			item.Code = transferErrorCode(transfer.Err)
+		}
+		switch transfer.Status {
+		case core.TRANSFER_REJECTED:
+			item.Status = qontov1.TransferStatus_TRANSFER_STATUS_REJECTED
+			response.Rejected++
+		case core.TRANSFER_HELD:
+			item.Status = qontov1.TransferStatus_TRANSFER_STATUS_HELD
+			response.Held++
+		default:
+			response.Accepted++
+			if !listAccepted {
+				continue
+			}
+		}
+		response.Results = append(response.Results, item)
+	}
+
+	return response, nil
+}
+
+// requestMode converts mode of the request, unspecified one is all-or-nothing
+func requestMode(mode qontov1.Mode) (core.Mode, error) {
+	switch mode {
+	case qontov1.Mode_MODE_UNSPECIFIED, qontov1.Mode_MODE_ALL_OR_NOTHING:
+		return core.MODE_ALL_OR_NOTHING, nil
+	case qontov1.Mode_MODE_BEST_EFFORT:
+		return core.MODE_BEST_EFFORT, nil
+	}
+
+	return "", fmt.Errorf("%w: unknown mode %v", ErrInvalidArgument, mode)
+}
+
+// protoTransfers are transfers of a unary request, they are converted chunk by chunk
+type protoTransfers []*qontov1.Transfer
+
+// Len implements core.Transfers interface
+func (pt protoTransfers) Len() int {
+	return len(pt)
+}
+
+// Chunks implements core.Transfers interface
+func (pt protoTransfers) Chunks(size int, fn func(offset int, chunk []core.Transfer) error) error {
+	chunk := make([]core.Transfer, 0, size)
+	for offset := 0; offset < len(pt); offset += size {
+		end := offset + size
+		if end > len(pt) {
+			end = len(pt)
+		}
+		chunk = chunk[:0]
+		for _, transfer := range pt[offset:end] {
+			chunk = append(chunk, coreTransfer(transfer))
+		}
+		if err := fn(offset, chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func coreTransfer(transfer *qontov1.Transfer) core.Transfer {
+	return core.Transfer{
+		Amount:   core.Amount{Cents: transfer.GetAmountCents()},
+		Currency: core.Currency(transfer.GetCurrency()),
+		CounterParty: core.Party{
+			Name: transfer.GetCounterparty().GetName(),
+			BIC:  transfer.GetCounterparty().GetBic(),
+			IBAN: transfer.GetCounterparty().GetIban(),
+		},
+		Description: transfer.GetDescription(),
+	}
+}
+
+// GetAccount implements qontov1.QontoServiceServer interface
+func (qs *qontoServer) GetAccount(ctx context.Context, request *qontov1.GetAccountRequest) (*qontov1.Account, error) {
+	if qs.accounts == nil {
+		return qs.UnimplementedQontoServiceServer.GetAccount(ctx, request)
+	}
+	account, err := qs.accounts.Account(ctx, request.GetIban())
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+
+	return &qontov1.Account{
+		Party:        party(account.Party),
+		BalanceCents: account.Balance.Cents,
+		Currency:     string(account.Currency),
+	}, nil
+}
+
+// ListTransactions implements qontov1.QontoServiceServer interface
+func (qs *qontoServer) ListTransactions(request *qontov1.ListTransactionsRequest, stream qontov1.QontoService_ListTransactionsServer) error {
+	if qs.history == nil {
+		return qs.UnimplementedQontoServiceServer.ListTransactions(request, stream)
+	}
+	var from, to time.Time
+	if request.GetFrom() != nil {
+		from = request.GetFrom().AsTime()
+	}
+	if request.GetTo() != nil {
+		to = request.GetTo().AsTime()
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return statusError(stream.Context(), fmt.Errorf("%w: from must be before to", core.ErrInvalidPeriod))
+	}
+
+	err := qs.history.EachTransaction(stream.Context(), request.GetIban(), from, to, func(tx core.Transaction) error {
+		return stream.Send(&qontov1.Transaction{
+			Id:               tx.ID,
+			AmountCents:      tx.Amount.Cents,
+			Currency:         string(tx.Currency),
+			Description:      tx.Description,
+			Counterparty:     party(tx.CounterParty),
+			CreatedAt:        timestamppb.New(tx.CreatedAt),
+			FlaggedDuplicate: tx.FlaggedDuplicate,
+		})
+	})
+	if err != nil {
+		return statusError(stream.Context(), err)
+	}
+
+	return nil
+}
+
+func party(p core.Party) *qontov1.Party {
+	return &qontov1.Party{
+		Name: p.Name,
+		Bic:  p.BIC,
+		Iban: p.IBAN,
+	}
+}
+
+// throttleKey identifies the call by IBAN of the debited account, the same as HTTP API does,
+// calls without it by peer address. Metadata is not trusted, any client could name another organization in it
+func throttleKey(ctx context.Context, organization *qontov1.Party) string {
+	return ratelimit.AccountKey(organization.GetIban(), peerAddress(ctx))
+}
+
+// peerAddress returns host of the calling peer, empty if it is unknown
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	address := p.Addr.String()
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
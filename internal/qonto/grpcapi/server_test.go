@@ -0,0 +1,642 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/dispatch"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/grpcapi/qontov1"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// newTestClient serves the service over in-memory connection
+func newTestClient(t *testing.T, service *qontoServer) qontov1.QontoServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	service.Register(server)
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			t.Logf("server stopped: %v", err)
+		}
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return qontov1.NewQontoServiceClient(conn)
+}
+
+func TestProcessTransfers(t *testing.T) {
+	request := &qontov1.ProcessTransfersRequest{
+		Organization: &qontov1.Party{Name: "ACME Corp", Bic: "OIVUSCLQXXX", Iban: "FR10474608000002006107XXXXX"},
+		CreditTransfers: []*qontov1.Transfer{
+			{
+				AmountCents:  1450,
+				Currency:     "EUR",
+				Description:  "Wonderland/4410",
+				Counterparty: &qontov1.Party{Name: "Bip Bip", Bic: "CRLYFRPPTOU", Iban: "EE383680981021245685"},
+			},
+			{
+				AmountCents:  6123800,
+				Currency:     "EUR",
+				Counterparty: &qontov1.Party{Name: "Wile E Coyote", Bic: "ZDRPLBQI", Iban: "DE9935420810036209081725212"},
+			},
+		},
+	}
+	bestEffort := &qontov1.ProcessTransfersRequest{
+		Organization:    request.Organization,
+		CreditTransfers: request.CreditTransfers,
+		Mode:            qontov1.Mode_MODE_BEST_EFFORT,
+	}
+	invalidMode := &qontov1.ProcessTransfersRequest{
+		Organization: request.Organization,
+		Mode:         qontov1.Mode(7),
+	}
+
+	testCases := []struct {
+		name             string
+		manager          *mockManager
+		request          *qontov1.ProcessTransfersRequest
+		expectedCode     codes.Code
+		expectedResponse *qontov1.ProcessTransfersResponse
+	}{
+		{
+			name:         "all or nothing",
+			manager:      newMockManager(),
+			request:      request,
+			expectedCode: codes.OK,
+			expectedResponse: &qontov1.ProcessTransfersResponse{
+				Mode:     qontov1.Mode_MODE_ALL_OR_NOTHING,
+				Accepted: 2,
+				Results: []*qontov1.TransferResult{
+					{Index: 0, Status: qontov1.TransferStatus_TRANSFER_STATUS_ACCEPTED},
+					{Index: 1, Status: qontov1.TransferStatus_TRANSFER_STATUS_ACCEPTED},
+				},
+			},
+		},
+		{
+			name: "best effort",
+			manager: newMockManager().WithResult(core.ResultOf(
+				core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+				core.TransferResult{Status: core.TRANSFER_REJECTED, Err: core.ErrNotEnoughFunds},
+			)),
+			request:      bestEffort,
+			expectedCode: codes.OK,
+			expectedResponse: &qontov1.ProcessTransfersResponse{
+				Mode:     qontov1.Mode_MODE_BEST_EFFORT,
+				Accepted: 1,
+				Rejected: 1,
+				Results: []*qontov1.TransferResult{
+					{Index: 0, Status: qontov1.TransferStatus_TRANSFER_STATUS_ACCEPTED},
+					{
+						Index:  1,
+						Status: qontov1.TransferStatus_TRANSFER_STATUS_REJECTED,
+						Error:  core.ErrNotEnoughFunds.Error(),
+						Code:   qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_NOT_ENOUGH_FUNDS,
+					},
+				},
+			},
+		},
+		{
+			name: "held by screening hit",
+			manager: newMockManager().WithResult(core.ResultOf(
+				core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+				core.TransferResult{Status: core.TRANSFER_HELD, Err: core.ErrScreeningHit},
+			)),
+			request:      request,
+			expectedCode: codes.OK,
+			expectedResponse: &qontov1.ProcessTransfersResponse{
+				Mode:     qontov1.Mode_MODE_ALL_OR_NOTHING,
+				Accepted: 1,
+				Held:     1,
+				Results: []*qontov1.TransferResult{
+					{Index: 0, Status: qontov1.TransferStatus_TRANSFER_STATUS_ACCEPTED},
+					{
+						Index:  1,
+						Status: qontov1.TransferStatus_TRANSFER_STATUS_HELD,
+						Error:  core.ErrScreeningHit.Error(),
+						Code:   qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_SCREENING_HIT,
+					},
+				},
+			},
+		},
+		{
+			name:         "not enough funds",
+			manager:      newMockManager().WithError(core.ErrNotEnoughFunds),
+			request:      request,
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			name:         "denied by risk rules",
+			manager:      newMockManager().WithError(core.ErrBlockedCountry),
+			request:      request,
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			name:         "unknown account",
+			manager:      newMockManager().WithError(core.ErrAccountNotFound),
+			request:      request,
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "duplicate transfer",
+			manager:      newMockManager().WithError(core.ErrDuplicateTransfer),
+			request:      request,
+			expectedCode: codes.AlreadyExists,
+		},
+		{
+			name:         "invalid currency",
+			manager:      newMockManager().WithError(core.ErrInvalidCurrency),
+			request:      request,
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "invalid amount",
+			manager:      newMockManager().WithError(core.ErrInvalidAmount),
+			request:      request,
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "conflicting change",
+			manager:      newMockManager().WithError(core.ErrConflict),
+			request:      request,
+			expectedCode: codes.Aborted,
+		},
+		{
+			name:         "account queue full",
+			manager:      newMockManager().WithError(dispatch.ErrQueueFull),
+			request:      request,
+			expectedCode: codes.Unavailable,
+		},
+		{
+			name:         "invalid mode",
+			manager:      newMockManager(),
+			request:      invalidMode,
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "internal error",
+			manager:      newMockManager().WithError(errors.New("db is down")),
+			request:      request,
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, NewServer(tc.manager))
+			response, err := client.ProcessTransfers(context.Background(), tc.request)
+			require.Equal(t, tc.expectedCode, status.Code(err), "error: %v", err)
+			if tc.expectedResponse == nil {
+				return
+			}
+			assert.Equal(t, tc.expectedResponse.Mode, response.Mode)
+			assert.Equal(t, tc.expectedResponse.Accepted, response.Accepted)
+			assert.Equal(t, tc.expectedResponse.Rejected, response.Rejected)
+			require.Len(t, response.Results, len(tc.expectedResponse.Results))
+			for i, result := range tc.expectedResponse.Results {
+				assert.Equal(t, result.Index, response.Results[i].Index)
+				assert.Equal(t, result.Status, response.Results[i].Status)
+				assert.Equal(t, result.Error, response.Results[i].Error)
+			}
+		})
+	}
+}
+
+func TestProcessTransfersMapsRequest(t *testing.T) {
+	manager := newMockManager()
+	client := newTestClient(t, NewServer(manager))
+	_, err := client.ProcessTransfers(context.Background(), &qontov1.ProcessTransfersRequest{
+		Organization:    &qontov1.Party{Name: "ACME Corp", Bic: "OIVUSCLQXXX", Iban: "FR10474608000002006107XXXXX"},
+		AllowDuplicates: true,
+		Mode:            qontov1.Mode_MODE_BEST_EFFORT,
+		CreditTransfers: []*qontov1.Transfer{
+			{
+				AmountCents:  1450,
+				Currency:     "EUR",
+				Description:  "Wonderland/4410",
+				Counterparty: &qontov1.Party{Name: "Bip Bip", Bic: "CRLYFRPPTOU", Iban: "EE383680981021245685"},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, &core.Request{
+		Party: core.Party{Name: "ACME Corp", BIC: "OIVUSCLQXXX", IBAN: "FR10474608000002006107XXXXX"},
+		CreditTransfers: core.TransferList{
+			{
+				Amount:       core.Amount{Cents: 1450},
+				Currency:     core.CURRENCY_EURO,
+				Description:  "Wonderland/4410",
+				CounterParty: core.Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+			},
+		},
+		AllowDuplicates: true,
+		Mode:            core.MODE_BEST_EFFORT,
+	}, manager.request)
+}
+
+func TestProtoTransfersChunks(t *testing.T) {
+	transfers := make(protoTransfers, 2*core.CHUNK_SIZE+1)
+	for i := range transfers {
+		transfers[i] = &qontov1.Transfer{AmountCents: int64(i + 1), Currency: "EUR"}
+	}
+
+	offsets, sizes := []int{}, []int{}
+	err := transfers.Chunks(core.CHUNK_SIZE, func(offset int, chunk []core.Transfer) error {
+		offsets, sizes = append(offsets, offset), append(sizes, len(chunk))
+		for j, transfer := range chunk {
+			require.Equal(t, core.Amount{Cents: int64(offset + j + 1)}, transfer.Amount)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, core.CHUNK_SIZE, 2 * core.CHUNK_SIZE}, offsets)
+	assert.Equal(t, []int{core.CHUNK_SIZE, core.CHUNK_SIZE, 1}, sizes)
+}
+
+func TestStreamTransfers(t *testing.T) {
+	organization := &qontov1.Party{Name: "ACME Corp", Bic: "OIVUSCLQXXX", Iban: "FR10474608000002006107XXXXX"}
+	// batches of transfers numbered by their descriptions
+	batches := func(sizes ...int) [][]*qontov1.Transfer {
+		result := [][]*qontov1.Transfer{}
+		n := 0
+		for _, size := range sizes {
+			batch := []*qontov1.Transfer{}
+			for i := 0; i < size; i++ {
+				batch = append(batch, &qontov1.Transfer{
+					AmountCents:  1,
+					Currency:     "EUR",
+					Description:  fmt.Sprintf("payroll/%d", n),
+					Counterparty: &qontov1.Party{Name: "Bip Bip", Iban: "EE383680981021245685"},
+				})
+				n++
+			}
+			result = append(result, batch)
+		}
+		return result
+	}
+
+	happy := batches(700, 800, 1000)
+
+	testCases := []struct {
+		name             string
+		manager          *mockManager
+		messages         []*qontov1.StreamTransfersRequest
+		expectedCode     codes.Code
+		expectedResponse *qontov1.ProcessTransfersResponse
+		expectedCount    int
+	}{
+		{
+			name:    "happy",
+			manager: newMockManager(),
+			messages: []*qontov1.StreamTransfersRequest{
+				{Organization: organization, AllowDuplicates: true, CreditTransfers: happy[0]},
+				{CreditTransfers: happy[1]},
+				{},
+				{CreditTransfers: happy[2]},
+			},
+			expectedCode: codes.OK,
+			expectedResponse: &qontov1.ProcessTransfersResponse{
+				Mode:     qontov1.Mode_MODE_ALL_OR_NOTHING,
+				Accepted: 2500,
+			},
+			expectedCount: 2500,
+		},
+		{
+			name: "only transfers which are not accepted are listed",
+			manager: newMockManager().WithResult(core.ResultOf(
+				core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+				core.TransferResult{Status: core.TRANSFER_REJECTED, Err: core.ErrNotEnoughFunds},
+			)),
+			messages: []*qontov1.StreamTransfersRequest{
+				{Organization: organization, Mode: qontov1.Mode_MODE_BEST_EFFORT, CreditTransfers: batches(2)[0]},
+			},
+			expectedCode: codes.OK,
+			expectedResponse: &qontov1.ProcessTransfersResponse{
+				Mode:     qontov1.Mode_MODE_BEST_EFFORT,
+				Accepted: 1,
+				Rejected: 1,
+				Results: []*qontov1.TransferResult{
+					{Index: 1, Status: qontov1.TransferStatus_TRANSFER_STATUS_REJECTED, Error: "not enough funds"},
+				},
+			},
+			expectedCount: 2,
+		},
+		{
+			name:         "empty stream",
+			manager:      newMockManager(),
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:    "invalid mode",
+			manager: newMockManager(),
+			messages: []*qontov1.StreamTransfersRequest{
+				{Organization: organization, Mode: qontov1.Mode(7), CreditTransfers: batches(1)[0]},
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:    "rejected request",
+			manager: newMockManager().WithError(core.ErrNotEnoughFunds),
+			messages: []*qontov1.StreamTransfersRequest{
+				{Organization: organization, CreditTransfers: batches(1)[0]},
+			},
+			expectedCode:  codes.FailedPrecondition,
+			expectedCount: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, NewServer(tc.manager))
+			stream, err := client.StreamTransfers(context.Background())
+			require.NoError(t, err)
+			for _, message := range tc.messages {
+				require.NoError(t, stream.Send(message))
+			}
+			response, err := stream.CloseAndRecv()
+			require.Equal(t, tc.expectedCode, status.Code(err), "error: %v", err)
+
+			if tc.expectedCount > 0 {
+				require.NotNil(t, tc.manager.request)
+				assert.Equal(t, core.Party{Name: "ACME Corp", BIC: "OIVUSCLQXXX", IBAN: "FR10474608000002006107XXXXX"}, tc.manager.request.Party)
+				transfers := tc.manager.request.CreditTransfers.(core.TransferList)
+				require.Len(t, transfers, tc.expectedCount)
+				for i, transfer := range transfers {
+					require.Equal(t, fmt.Sprintf("payroll/%d", i), transfer.Description)
+				}
+			}
+			if tc.expectedResponse == nil {
+				return
+			}
+			assert.Equal(t, tc.expectedResponse.Mode, response.Mode)
+			assert.Equal(t, tc.expectedResponse.Accepted, response.Accepted)
+			assert.Equal(t, tc.expectedResponse.Rejected, response.Rejected)
+			require.Len(t, response.Results, len(tc.expectedResponse.Results))
+			for i, result := range tc.expectedResponse.Results {
+				assert.Equal(t, result.Index, response.Results[i].Index)
+				assert.Equal(t, result.Status, response.Results[i].Status)
+				assert.Equal(t, result.Error, response.Results[i].Error)
+			}
+		})
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	accounts := newMockAccountManager(core.Account{
+		Party:    core.Party{Name: "ACME Corp", BIC: "OIVUSCLQXXX", IBAN: "FR10474608000002006107XXXXX"},
+		Balance:  core.Amount{Cents: 1000000},
+		Currency: core.CURRENCY_EURO,
+	})
+	client := newTestClient(t, NewServer(newMockManager()).WithAccountManager(accounts))
+
+	account, err := client.GetAccount(context.Background(), &qontov1.GetAccountRequest{Iban: "FR10474608000002006107XXXXX"})
+	require.NoError(t, err)
+	assert.Equal(t, "ACME Corp", account.Party.Name)
+	assert.Equal(t, "OIVUSCLQXXX", account.Party.Bic)
+	assert.Equal(t, int64(1000000), account.BalanceCents)
+	assert.Equal(t, "EUR", account.Currency)
+
+	_, err = client.GetAccount(context.Background(), &qontov1.GetAccountRequest{Iban: "DE9935420810036209081725212"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	client = newTestClient(t, NewServer(newMockManager()))
+	_, err = client.GetAccount(context.Background(), &qontov1.GetAccountRequest{Iban: "FR10474608000002006107XXXXX"})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestListTransactions(t *testing.T) {
+	createdAt := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	transactions := []core.Transaction{
+		{
+			ID:           1,
+			Amount:       core.Amount{Cents: 1450},
+			Currency:     core.CURRENCY_EURO,
+			Description:  "Wonderland/4410",
+			CounterParty: core.Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+			CreatedAt:    createdAt,
+		},
+		{
+			ID:               2,
+			Amount:           core.Amount{Cents: 6123800},
+			Currency:         core.CURRENCY_EURO,
+			CounterParty:     core.Party{Name: "Wile E Coyote", BIC: "ZDRPLBQI", IBAN: "DE9935420810036209081725212"},
+			CreatedAt:        createdAt.Add(time.Hour),
+			FlaggedDuplicate: true,
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		history      *mockHistoryManager
+		request      *qontov1.ListTransactionsRequest
+		expectedIDs  []int64
+		expectedCode codes.Code
+		expectedFrom time.Time
+		expectedTo   time.Time
+	}{
+		{
+			name:         "all transactions",
+			history:      newMockHistoryManager(transactions...),
+			request:      &qontov1.ListTransactionsRequest{Iban: "FR10474608000002006107XXXXX"},
+			expectedIDs:  []int64{1, 2},
+			expectedCode: codes.OK,
+		},
+		{
+			name:    "period",
+			history: newMockHistoryManager(transactions...),
+			request: &qontov1.ListTransactionsRequest{
+				Iban: "FR10474608000002006107XXXXX",
+				From: timestamppb.New(createdAt),
+				To:   timestamppb.New(createdAt.Add(24 * time.Hour)),
+			},
+			expectedIDs:  []int64{1, 2},
+			expectedCode: codes.OK,
+			expectedFrom: createdAt,
+			expectedTo:   createdAt.Add(24 * time.Hour),
+		},
+		{
+			name:    "invalid period",
+			history: newMockHistoryManager(transactions...),
+			request: &qontov1.ListTransactionsRequest{
+				Iban: "FR10474608000002006107XXXXX",
+				From: timestamppb.New(createdAt),
+				To:   timestamppb.New(createdAt),
+			},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "unknown account",
+			history:      newMockHistoryManager().WithError(core.ErrAccountNotFound),
+			request:      &qontov1.ListTransactionsRequest{Iban: "FR10474608000002006107XXXXX"},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "failure after first transactions",
+			history:      newMockHistoryManager(transactions...).WithError(errors.New("connection lost")),
+			request:      &qontov1.ListTransactionsRequest{Iban: "FR10474608000002006107XXXXX"},
+			expectedIDs:  []int64{1, 2},
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, NewServer(newMockManager()).WithHistoryManager(tc.history))
+			stream, err := client.ListTransactions(context.Background(), tc.request)
+			require.NoError(t, err)
+
+			ids := []int64{}
+			received := []*qontov1.Transaction{}
+			for {
+				tx, err := stream.Recv()
+				if err == io.EOF {
+					err = nil
+				}
+				if err != nil || tx == nil {
+					require.Equal(t, tc.expectedCode, status.Code(err), "error: %v", err)
+					break
+				}
+				ids = append(ids, tx.Id)
+				received = append(received, tx)
+			}
+
+			if tc.expectedIDs == nil {
+				tc.expectedIDs = []int64{}
+			}
+			assert.Equal(t, tc.expectedIDs, ids)
+			assert.Equal(t, tc.expectedFrom, tc.history.from)
+			assert.Equal(t, tc.expectedTo, tc.history.to)
+			if len(received) == 2 {
+				assert.Equal(t, int64(6123800), received[1].AmountCents)
+				assert.Equal(t, "Wile E Coyote", received[1].Counterparty.Name)
+				assert.True(t, received[1].CreatedAt.AsTime().Equal(createdAt.Add(time.Hour)))
+				assert.True(t, received[1].FlaggedDuplicate)
+			}
+		})
+	}
+}
+
+func TestStatusErrorRedacts(t *testing.T) {
+	ctx := qonto.ContextWithRedactor(context.Background(), qonto.NewRedactor("ACME Corp", "Wile E Coyote"))
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode codes.Code
+	}{
+		{"known error", fmt.Errorf("%w: account FR10474608000002006107XXXXX of ACME Corp", core.ErrAccountNotFound), codes.NotFound},
+		{"internal error", errors.New("deadlock while paying Wile E Coyote EE383680981021245685"), codes.Internal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := status.Convert(statusError(ctx, tc.err))
+			assert.Equal(t, tc.expectedCode, st.Code())
+			for _, value := range []string{"FR10474608000002006107XXXXX", "EE383680981021245685", "ACME Corp", "Wile E Coyote"} {
+				assert.NotContains(t, st.Message(), value)
+			}
+			assert.Contains(t, st.Message(), "****")
+			assert.Contains(t, st.Message(), "sha256:")
+		})
+	}
+}
+
+func TestTransferErrorCode(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		expectedCode qontov1.TransferErrorCode
+	}{
+		{"wrapped error", fmt.Errorf("%w: transfer #2", core.ErrInvalidAmount), qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_INVALID_AMOUNT},
+		{"risk rule with own code", &core.DeniedError{Findings: []core.Finding{
+			{Rule: "blocked_country", Decision: core.DECISION_DENY, Err: core.ErrBlockedCountry},
+		}}, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_BLOCKED_COUNTRY},
+		{"custom risk rule", &core.DeniedError{Findings: []core.Finding{
+			{Rule: "custom", Decision: core.DECISION_DENY},
+		}}, qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_TRANSFER_DENIED},
+		{"unknown error", errors.New("connection refused"), qontov1.TransferErrorCode_TRANSFER_ERROR_CODE_UNSPECIFIED},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedCode, transferErrorCode(tc.err))
+		})
+	}
+}
+
+func TestProcessTransfersThrottled(t *testing.T) {
+	limiter := ratelimit.NewLimiter(ratelimit.Limits{Rate: 0.1, Burst: 1})
+	client := newTestClient(t, NewServer(newMockManager()).WithLimiter(limiter))
+	request := &qontov1.ProcessTransfersRequest{
+		Organization: &qontov1.Party{Name: "ACME Corp", Iban: "FR10474608000002006107XXXXX"},
+	}
+
+	_, err := client.ProcessTransfers(context.Background(), request)
+	require.NoError(t, err)
+	_, err = client.ProcessTransfers(context.Background(), request)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// metadata is not trusted and spacing or case of the IBAN do not make another account
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-qonto-organization", "Globex")
+	_, err = client.ProcessTransfers(ctx, request)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = client.ProcessTransfers(context.Background(), &qontov1.ProcessTransfersRequest{
+		Organization: &qontov1.Party{Iban: "fr10 4746 0800 0002 0061 07xx xxx"},
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	_, err = client.ProcessTransfers(context.Background(), &qontov1.ProcessTransfersRequest{
+		Organization: &qontov1.Party{Iban: "DE9935420810036209081725212"},
+	})
+	require.NoError(t, err)
+}
+
+func TestProcessTransfersClientThrottled(t *testing.T) {
+	clientLimiter := ratelimit.NewLimiter(ratelimit.Limits{Rate: 0.1, Burst: 1})
+	client := newTestClient(t, NewServer(newMockManager()).WithClientLimiter(clientLimiter))
+
+	_, err := client.ProcessTransfers(context.Background(), &qontov1.ProcessTransfersRequest{
+		Organization: &qontov1.Party{Iban: "FR10474608000002006107XXXXX"},
+	})
+	require.NoError(t, err)
+	// naming another account does not admit the peer again
+	_, err = client.ProcessTransfers(context.Background(), &qontov1.ProcessTransfersRequest{
+		Organization: &qontov1.Party{Iban: "DE9935420810036209081725212"},
+	})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestThrottleKey(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 50051}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-qonto-organization", "ACME Corp"))
+
+	assert.Equal(t, "FR10474608000002006107XXXXX", throttleKey(ctx, &qontov1.Party{Name: "ACME Corp", Iban: "FR10474608000002006107XXXXX"}))
+	assert.Equal(t, "192.0.2.1", throttleKey(ctx, nil))
+	assert.Equal(t, "192.0.2.1", peerAddress(ctx))
+	assert.Equal(t, "", peerAddress(context.Background()))
+}
@@ -0,0 +1,40 @@
+package health
+
+import (
+	"context"
+	"fmt"
+)
+
+type (
+	// Pinger is a dependency reachable over the network
+	Pinger interface {
+		Ping(ctx context.Context) error
+	}
+
+	// MigrationVersioner reports applied migrations
+	MigrationVersioner interface {
+		MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
+	}
+)
+
+// PingCheck fails if the dependency cannot be reached
+func PingCheck(pinger Pinger) Check {
+	return pinger.Ping
+}
+
+// MigrationsCheck fails unless the latest migration is applied successfully
+func MigrationsCheck(versioner MigrationVersioner, latest uint) Check {
+	return func(ctx context.Context) error {
+		version, dirty, err := versioner.MigrationVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d failed", version)
+		}
+		if version < latest {
+			return fmt.Errorf("migration %d is applied, expected %d", version, latest)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+)
+
+type (
+	// Check reports why a dependency of the app is not usable
+	Check func(ctx context.Context) error
+
+	// Probe tracks app lifecycle and serves liveness, readiness and startup probes
+	Probe struct {
+		started      int32
+		shuttingDown int32
+		timeout      time.Duration
+
+		mu     sync.Mutex
+		checks []namedCheck
+	}
+
+	namedCheck struct {
+		name  string
+		check Check
+	}
+
+	// Response is returned by all probes, Checks holds "ok" or "failed" of every readiness check.
+	// Reasons of failures are logged only, so probes do not disclose internals of dependencies
+	Response struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks,omitempty"`
+	}
+)
+
+// statuses of probe responses
+const (
+	STATUS_OK            = "ok"
+	STATUS_STARTING      = "starting"
+	STATUS_SHUTTING_DOWN = "shutting_down"
+	STATUS_UNAVAILABLE   = "unavailable"
+	STATUS_FAILED        = "failed"
+)
+
+// NewProbe creates probe of not yet started app, every readiness check is limited by timeout
+func NewProbe(timeout time.Duration) *Probe {
+	return &Probe{
+		timeout: timeout,
+	}
+}
+
+// WithCheck adds readiness check
+func (p *Probe) WithCheck(name string, check Check) *Probe {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.checks = append(p.checks, namedCheck{name: name, check: check})
+	return p
+}
+
+// SetStarted marks the app as started, so startup probe succeeds and readiness checks are run
+func (p *Probe) SetStarted() {
+	atomic.StoreInt32(&p.started, 1)
+}
+
+// SetShuttingDown fails readiness probe, so load balancers stop sending new requests
+func (p *Probe) SetShuttingDown() {
+	atomic.StoreInt32(&p.shuttingDown, 1)
+}
+
+func (p *Probe) isStarted() bool {
+	return atomic.LoadInt32(&p.started) == 1
+}
+
+func (p *Probe) isShuttingDown() bool {
+	return atomic.LoadInt32(&p.shuttingDown) == 1
+}
+
+// HandleLive reports that the process is up and serves requests
+func (p *Probe) HandleLive(w http.ResponseWriter, r *http.Request) {
+	respond(w, r, http.StatusOK, Response{Status: STATUS_OK})
+}
+
+// HandleStartup succeeds once the app is started
+func (p *Probe) HandleStartup(w http.ResponseWriter, r *http.Request) {
+	if !p.isStarted() {
+		respond(w, r, http.StatusServiceUnavailable, Response{Status: STATUS_STARTING})
+		return
+	}
+	respond(w, r, http.StatusOK, Response{Status: STATUS_OK})
+}
+
+// HandleReady succeeds if the app is started, is not shutting down and all checks pass
+func (p *Probe) HandleReady(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case p.isShuttingDown():
+		respond(w, r, http.StatusServiceUnavailable, Response{Status: STATUS_SHUTTING_DOWN})
+		return
+	case !p.isStarted():
+		respond(w, r, http.StatusServiceUnavailable, Response{Status: STATUS_STARTING})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), p.timeout)
+	defer cancel()
+
+	p.mu.Lock()
+	checks := p.checks
+	p.mu.Unlock()
+
+	response := Response{Status: STATUS_OK, Checks: make(map[string]string, len(checks))}
+	status := http.StatusOK
+	for _, c := range checks {
+		if err := c.check(ctx); err != nil {
+			qonto.LoggerFromContext(r.Context()).Warn("readiness check failed", "check", c.name, "error", err)
+			response.Checks[c.name] = STATUS_FAILED
+			response.Status = STATUS_UNAVAILABLE
+			status = http.StatusServiceUnavailable
+			continue
+		}
+		response.Checks[c.name] = STATUS_OK
+	}
+	respond(w, r, status, response)
+}
+
+func respond(w http.ResponseWriter, r *http.Request, status int, response Response) {
+	w.Header().Set("Content-Type", "application/json")
+	// probes must never be cached by proxies
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		qonto.LoggerFromContext(r.Context()).Error("error writing response", "error", err)
+	}
+}
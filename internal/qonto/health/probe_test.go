@@ -0,0 +1,201 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockStorage struct {
+	pingErr error
+	version uint
+	dirty   bool
+	err     error
+}
+
+func (ms *mockStorage) Ping(ctx context.Context) error {
+	return ms.pingErr
+}
+
+func (ms *mockStorage) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	return ms.version, ms.dirty, ms.err
+}
+
+func TestProbe(t *testing.T) {
+	testCases := []struct {
+		name            string
+		storage         *mockStorage
+		started         bool
+		shuttingDown    bool
+		handler         func(*Probe) http.HandlerFunc
+		expectedStatus  int
+		expectedPayload Response
+		// expectedLogs are reasons of failed checks, they are logged instead of being responded
+		expectedLogs []string
+	}{
+		{
+			name:            "live before start",
+			storage:         &mockStorage{pingErr: errors.New("connection refused")},
+			handler:         func(p *Probe) http.HandlerFunc { return p.HandleLive },
+			expectedStatus:  http.StatusOK,
+			expectedPayload: Response{Status: STATUS_OK},
+		},
+		{
+			name:            "startup before start",
+			storage:         &mockStorage{version: 5},
+			handler:         func(p *Probe) http.HandlerFunc { return p.HandleStartup },
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedPayload: Response{Status: STATUS_STARTING},
+		},
+		{
+			name:            "startup",
+			storage:         &mockStorage{version: 5},
+			started:         true,
+			handler:         func(p *Probe) http.HandlerFunc { return p.HandleStartup },
+			expectedStatus:  http.StatusOK,
+			expectedPayload: Response{Status: STATUS_OK},
+		},
+		{
+			name:            "ready before start",
+			storage:         &mockStorage{version: 5},
+			handler:         func(p *Probe) http.HandlerFunc { return p.HandleReady },
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedPayload: Response{Status: STATUS_STARTING},
+		},
+		{
+			name:           "ready",
+			storage:        &mockStorage{version: 5},
+			started:        true,
+			handler:        func(p *Probe) http.HandlerFunc { return p.HandleReady },
+			expectedStatus: http.StatusOK,
+			expectedPayload: Response{
+				Status: STATUS_OK,
+				Checks: map[string]string{"database": STATUS_OK, "migrations": STATUS_OK},
+			},
+		},
+		{
+			name:           "database is down",
+			storage:        &mockStorage{pingErr: errors.New("connection refused"), err: errors.New("connection refused")},
+			started:        true,
+			handler:        func(p *Probe) http.HandlerFunc { return p.HandleReady },
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedPayload: Response{
+				Status: STATUS_UNAVAILABLE,
+				Checks: map[string]string{"database": STATUS_FAILED, "migrations": STATUS_FAILED},
+			},
+			expectedLogs: []string{"connection refused"},
+		},
+		{
+			name:           "migrations are behind",
+			storage:        &mockStorage{version: 4},
+			started:        true,
+			handler:        func(p *Probe) http.HandlerFunc { return p.HandleReady },
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedPayload: Response{
+				Status: STATUS_UNAVAILABLE,
+				Checks: map[string]string{"database": STATUS_OK, "migrations": STATUS_FAILED},
+			},
+			expectedLogs: []string{"migration 4 is applied, expected 5"},
+		},
+		{
+			name:           "migration failed",
+			storage:        &mockStorage{version: 5, dirty: true},
+			started:        true,
+			handler:        func(p *Probe) http.HandlerFunc { return p.HandleReady },
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedPayload: Response{
+				Status: STATUS_UNAVAILABLE,
+				Checks: map[string]string{"database": STATUS_OK, "migrations": STATUS_FAILED},
+			},
+			expectedLogs: []string{"migration 5 failed"},
+		},
+		{
+			name:           "no migrations",
+			storage:        &mockStorage{err: storage.ErrNoMigrations},
+			started:        true,
+			handler:        func(p *Probe) http.HandlerFunc { return p.HandleReady },
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedPayload: Response{
+				Status: STATUS_UNAVAILABLE,
+				Checks: map[string]string{"database": STATUS_OK, "migrations": STATUS_FAILED},
+			},
+			expectedLogs: []string{"no migrations applied"},
+		},
+		{
+			name:            "shutting down",
+			storage:         &mockStorage{version: 5},
+			started:         true,
+			shuttingDown:    true,
+			handler:         func(p *Probe) http.HandlerFunc { return p.HandleReady },
+			expectedStatus:  http.StatusServiceUnavailable,
+			expectedPayload: Response{Status: STATUS_SHUTTING_DOWN},
+		},
+		{
+			name:            "live while shutting down",
+			storage:         &mockStorage{version: 5},
+			started:         true,
+			shuttingDown:    true,
+			handler:         func(p *Probe) http.HandlerFunc { return p.HandleLive },
+			expectedStatus:  http.StatusOK,
+			expectedPayload: Response{Status: STATUS_OK},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			probe := NewProbe(time.Second).
+				WithCheck("database", PingCheck(tc.storage)).
+				WithCheck("migrations", MigrationsCheck(tc.storage, 5))
+			if tc.started {
+				probe.SetStarted()
+			}
+			if tc.shuttingDown {
+				probe.SetShuttingDown()
+			}
+
+			logs := &bytes.Buffer{}
+			r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			r = r.WithContext(qonto.ContextWithLogger(r.Context(), qonto.NewInstanceLogger(logs, "Qonto")))
+			w := httptest.NewRecorder()
+			tc.handler(probe)(w, r)
+
+			require.Equal(t, tc.expectedStatus, w.Result().StatusCode)
+			assert.Equal(t, "application/json", w.Result().Header.Get("Content-Type"))
+			var payload Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &payload))
+			assert.Equal(t, tc.expectedPayload, payload)
+			for _, expected := range tc.expectedLogs {
+				assert.Contains(t, logs.String(), expected)
+				assert.NotContains(t, w.Body.String(), expected)
+			}
+		})
+	}
+}
+
+func TestProbeCheckTimeout(t *testing.T) {
+	probe := NewProbe(10*time.Millisecond).WithCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	probe.SetStarted()
+
+	logs := &bytes.Buffer{}
+	r := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+	r = r.WithContext(qonto.ContextWithLogger(r.Context(), qonto.NewInstanceLogger(logs, "Qonto")))
+	w := httptest.NewRecorder()
+	probe.HandleReady(w, r)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Result().StatusCode)
+	assert.Contains(t, w.Body.String(), `"slow":"failed"`)
+	assert.Contains(t, logs.String(), context.DeadlineExceeded.Error())
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/mysql"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/postgres"
+)
+
+type (
+	// testStorage is implemented by storages of all backends
+	testStorage interface {
+		storage.Storage
+		storage.Health
+		Close() error
+	}
+
+	// backend is a database the integration tests are run against
+	backend struct {
+		name string
+		// migrationsDir is relative to the project root
+		migrationsDir string
+		// newTestDatabase creates new migrated database, its storage retries transactions with the policy
+		newTestDatabase func(ctx context.Context, t *testing.T, policy storage.RetryPolicy, observer storage.RetryObserver) (testStorage, string)
+		// deadlock is an error the backend retries as a deadlock
+		deadlock error
+	}
+)
+
+var backends = []backend{
+	{
+		name:          "mysql",
+		migrationsDir: "/migrations/",
+		newTestDatabase: func(ctx context.Context, t *testing.T, policy storage.RetryPolicy, observer storage.RetryObserver) (testStorage, string) {
+			mysqlStorage, dbName := mysql.NewTestDatabase(ctx, t)
+			return mysqlStorage.WithRetryPolicy(policy).WithRetryObserver(observer), dbName
+		},
+		deadlock: &driver.MySQLError{Number: mysql.ER_LOCK_DEADLOCK, Message: "injected deadlock"},
+	},
+	{
+		name:          "postgres",
+		migrationsDir: "/migrations/postgres/",
+		newTestDatabase: func(ctx context.Context, t *testing.T, policy storage.RetryPolicy, observer storage.RetryObserver) (testStorage, string) {
+			postgresStorage, dbName := postgres.NewTestDatabase(ctx, t)
+			return postgresStorage.WithRetryPolicy(policy).WithRetryObserver(observer), dbName
+		},
+		deadlock: &pq.Error{Code: postgres.DEADLOCK_DETECTED, Message: "injected deadlock"},
+	},
+}
+
+// testDatabase creates new migrated database with default retries of transactions
+func (b backend) testDatabase(ctx context.Context, t *testing.T) (testStorage, string) {
+	return b.newTestDatabase(ctx, t, storage.DefaultRetryPolicy, nil)
+}
+
+// forEachBackend runs f as a subtest for every backend,
+// backends which are not configured in the environment are skipped
+func forEachBackend(t *testing.T, f func(t *testing.T, b backend)) {
+	for _, b := range backends {
+		b := b
+		t.Run(b.name, func(t *testing.T) {
+			f(t, b)
+		})
+	}
+}
@@ -2,11 +2,17 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/health"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/screening"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -15,238 +21,1022 @@ func TestProcessTransfers_happy(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer cancel()
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
 
-	mysqlStorage, dbName := storage.NewTestDatabase(ctx, t)
-	defer mysqlStorage.Close()
-	t.Logf("test db name: %s", dbName)
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
 
-	qontoAccount := core.Party{
-		Name: "Qonto customer corp",
-		BIC:  "ARWKDJFU",
-		IBAN: "UA9935420810036209081725212",
-	}
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
 
-	var accountBalance int64 = 20000
-	qontoAccountID, err := mysqlStorage.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
-	require.NoError(t, err)
+		var accountBalance int64 = 20000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
+		require.NoError(t, err)
 
-	transferManager := core.NewQontoTransferManager(mysqlStorage)
-	// total amount should be less than accountBalance
-	request := core.Request{
-		Party: qontoAccount,
-		CreditTransfers: []core.Transfer{
-			{
-				Amount:      core.Amount{Cents: 8000},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 1",
-					BIC:  "bic1",
-					IBAN: "iban1",
+		transferManager := core.NewQontoTransferManager(db)
+		// total amount should be less than accountBalance
+		request := core.Request{
+			Party: qontoAccount,
+			CreditTransfers: core.TransferList{
+				{
+					Amount:      core.Amount{Cents: 8000},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 1",
+						BIC:  "bic1",
+						IBAN: "iban1",
+					},
 				},
-			},
-			{
-				Amount:      core.Amount{Cents: 5000},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 2",
-					BIC:  "bic2",
-					IBAN: "iban2",
+				{
+					Amount:      core.Amount{Cents: 5000},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 2",
+						BIC:  "bic2",
+						IBAN: "iban2",
+					},
 				},
-			},
-			{
-				Amount:      core.Amount{Cents: 3000},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 3",
-					BIC:  "bic3",
-					IBAN: "iban3",
+				{
+					Amount:      core.Amount{Cents: 3000},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 3",
+						BIC:  "bic3",
+						IBAN: "iban3",
+					},
 				},
-			},
-			{
-				Amount:      core.Amount{Cents: 1000},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 4",
-					BIC:  "bic4",
-					IBAN: "iban4",
+				{
+					Amount:      core.Amount{Cents: 1000},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 4",
+						BIC:  "bic4",
+						IBAN: "iban4",
+					},
 				},
 			},
-		},
+		}
+
+		_, err = transferManager.ProcessTransfers(ctx, &request)
+		require.NoError(t, err)
+		qontoAccountAfterProcessing, err := db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		var expectedBalance int64 = 3000
+		assert.Equal(t, expectedBalance, qontoAccountAfterProcessing.BalanceCents)
+
+		transactions, err := db.FindAccountTransactions(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Equal(t, request.Len(), len(transactions))
+		var expectedTransactionHistoryAmount int64 = 17000
+		var actualTransactionsAmount int64
+		for _, tx := range transactions {
+			actualTransactionsAmount += tx.AmountCents
+		}
+
+		assert.Equal(t, expectedTransactionHistoryAmount, actualTransactionsAmount)
+	})
+}
+
+func TestProcessTransfers_largeBatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
 	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
 
-	err = transferManager.ProcessTransfers(ctx, &request)
-	require.NoError(t, err)
-	qontoAccountAfterProcessing, err := mysqlStorage.FindAccount(ctx, qontoAccountID)
-	require.NoError(t, err)
-	var expectedBalance int64 = 3000
-	assert.Equal(t, expectedBalance, qontoAccountAfterProcessing.BalanceCents)
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
 
-	transactions, err := mysqlStorage.FindAccountTransactions(ctx, qontoAccountID)
-	require.NoError(t, err)
-	assert.Equal(t, len(request.CreditTransfers), len(transactions))
-	var expectedTransactionHistoryAmount int64 = 17000
-	var actualTransactionsAmount int64
-	for _, tx := range transactions {
-		actualTransactionsAmount += tx.AmountCents
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
+		// more rows than a single insert can take placeholders for
+		transfersCount := 10000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, int64(transfersCount)*100)
+		require.NoError(t, err)
+
+		transfers := make(core.TransferList, 0, transfersCount)
+		for i := 0; i < transfersCount; i++ {
+			transfers = append(transfers, core.Transfer{
+				Amount:   core.Amount{Cents: 100},
+				Currency: core.CURRENCY_EURO,
+				CounterParty: core.Party{
+					Name: fmt.Sprintf("counterparty %d", i),
+					BIC:  "bic",
+					IBAN: fmt.Sprintf("iban%d", i),
+				},
+			})
+		}
+		request := core.Request{Party: qontoAccount, CreditTransfers: transfers}
+
+		_, err = core.NewQontoTransferManager(db).ProcessTransfers(ctx, &request)
+		require.NoError(t, err)
+
+		account, err := db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), account.BalanceCents)
+		count, err := db.CountAccountTransactions(ctx, qontoAccountID, storage.TransactionFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(transfersCount), count)
+	})
+}
+
+func TestProcessTransfers_concurrentDebits(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
 	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
+		// the balance covers only some of the requests, the others must be declined instead of overdrawing it
+		var accountBalance int64 = 20000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
+		require.NoError(t, err)
+
+		transferManager := core.NewQontoTransferManager(db)
+		requestsCount := 20
+		var amountCents int64 = 1500
+		errs := make(chan error, requestsCount)
+		start := make(chan struct{})
+		for i := 0; i < requestsCount; i++ {
+			request := core.Request{
+				Party: qontoAccount,
+				CreditTransfers: core.TransferList{
+					{
+						Amount:   core.Amount{Cents: amountCents},
+						Currency: core.CURRENCY_EURO,
+						CounterParty: core.Party{
+							Name: fmt.Sprintf("counterparty %d", i),
+							BIC:  "bic",
+							IBAN: fmt.Sprintf("iban%d", i),
+						},
+					},
+				},
+			}
+			go func() {
+				<-start
+				_, err := transferManager.ProcessTransfers(ctx, &request)
+				errs <- err
+			}()
+		}
+		close(start)
+
+		processed := 0
+		for i := 0; i < requestsCount; i++ {
+			err := <-errs
+			if err == nil {
+				processed++
+				continue
+			}
+			assert.ErrorIs(t, err, core.ErrNotEnoughFunds)
+		}
 
-	assert.Equal(t, expectedTransactionHistoryAmount, actualTransactionsAmount)
+		assert.Equal(t, int(accountBalance/amountCents), processed)
+		account, err := db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Equal(t, accountBalance-int64(processed)*amountCents, account.BalanceCents)
+		count, err := db.CountAccountTransactions(ctx, qontoAccountID, storage.TransactionFilter{})
+		require.NoError(t, err)
+		assert.Equal(t, int64(processed), count)
+	})
 }
 
 func TestProcessTransfers_happyAllMoney(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer cancel()
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
 
-	mysqlStorage, dbName := storage.NewTestDatabase(ctx, t)
-	defer mysqlStorage.Close()
-	t.Logf("test db name: %s", dbName)
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
 
-	qontoAccount := core.Party{
-		Name: "Qonto customer corp",
-		BIC:  "ARWKDJFU",
-		IBAN: "UA9935420810036209081725212",
-	}
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
 
-	var accountBalance int64 = 10000
-	qontoAccountID, err := mysqlStorage.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
-	require.NoError(t, err)
+		var accountBalance int64 = 10000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
+		require.NoError(t, err)
 
-	transferManager := core.NewQontoTransferManager(mysqlStorage)
-	// total amount should be less than accountBalance
-	request := core.Request{
-		Party: qontoAccount,
-		CreditTransfers: []core.Transfer{
-			{
-				Amount:      core.Amount{Cents: 2000},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 1",
-					BIC:  "bic1",
-					IBAN: "iban1",
+		transferManager := core.NewQontoTransferManager(db)
+		// total amount should be less than accountBalance
+		request := core.Request{
+			Party: qontoAccount,
+			CreditTransfers: core.TransferList{
+				{
+					Amount:      core.Amount{Cents: 2000},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 1",
+						BIC:  "bic1",
+						IBAN: "iban1",
+					},
 				},
-			},
-			{
-				Amount:      core.Amount{Cents: 5000},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 2",
-					BIC:  "bic2",
-					IBAN: "iban2",
+				{
+					Amount:      core.Amount{Cents: 5000},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 2",
+						BIC:  "bic2",
+						IBAN: "iban2",
+					},
 				},
-			},
-			{
-				Amount:      core.Amount{Cents: 3000},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 3",
-					BIC:  "bic3",
-					IBAN: "iban3",
+				{
+					Amount:      core.Amount{Cents: 3000},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 3",
+						BIC:  "bic3",
+						IBAN: "iban3",
+					},
 				},
 			},
-		},
-	}
+		}
 
-	err = transferManager.ProcessTransfers(ctx, &request)
-	require.NoError(t, err)
-	qontoAccountAfterProcessing, err := mysqlStorage.FindAccount(ctx, qontoAccountID)
-	require.NoError(t, err)
-	var expectedBalance int64 = 0
-	assert.Equal(t, expectedBalance, qontoAccountAfterProcessing.BalanceCents)
+		_, err = transferManager.ProcessTransfers(ctx, &request)
+		require.NoError(t, err)
+		qontoAccountAfterProcessing, err := db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		var expectedBalance int64 = 0
+		assert.Equal(t, expectedBalance, qontoAccountAfterProcessing.BalanceCents)
 
-	transactions, err := mysqlStorage.FindAccountTransactions(ctx, qontoAccountID)
-	require.NoError(t, err)
-	assert.Equal(t, len(request.CreditTransfers), len(transactions))
-	var expectedTransactionHistoryAmount int64 = accountBalance
-	var actualTransactionsAmount int64
-	for _, tx := range transactions {
-		actualTransactionsAmount += tx.AmountCents
-	}
+		transactions, err := db.FindAccountTransactions(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Equal(t, request.Len(), len(transactions))
+		var expectedTransactionHistoryAmount int64 = accountBalance
+		var actualTransactionsAmount int64
+		for _, tx := range transactions {
+			actualTransactionsAmount += tx.AmountCents
+		}
 
-	assert.Equal(t, expectedTransactionHistoryAmount, actualTransactionsAmount)
+		assert.Equal(t, expectedTransactionHistoryAmount, actualTransactionsAmount)
+	})
 }
 
 func TestProcessTransfers_decline(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer cancel()
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
+
+		var accountBalance int64 = 20000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
+		require.NoError(t, err)
+
+		transferManager := core.NewQontoTransferManager(db)
+		// total amount should be less than accountBalance
+		request := core.Request{
+			Party: qontoAccount,
+			CreditTransfers: core.TransferList{
+				{
+					Amount:      core.Amount{Cents: 9000},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 1",
+						BIC:  "bic1",
+						IBAN: "iban1",
+					},
+				},
+				{
+					Amount:      core.Amount{Cents: 8000},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 2",
+						BIC:  "bic2",
+						IBAN: "iban2",
+					},
+				},
+				{
+					Amount:      core.Amount{Cents: 3001},
+					Currency:    core.CURRENCY_EURO,
+					Description: "",
+					CounterParty: core.Party{
+						Name: "counterparty 3",
+						BIC:  "bic3",
+						IBAN: "iban3",
+					},
+				},
+			},
+		}
+
+		_, err = transferManager.ProcessTransfers(ctx, &request)
+		assert.Error(t, err)
 
-	mysqlStorage, dbName := storage.NewTestDatabase(ctx, t)
-	defer mysqlStorage.Close()
-	t.Logf("test db name: %s", dbName)
+		qontoAccountAfterProcessing, err := db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Equal(t, accountBalance, qontoAccountAfterProcessing.BalanceCents)
 
-	qontoAccount := core.Party{
-		Name: "Qonto customer corp",
-		BIC:  "ARWKDJFU",
-		IBAN: "UA9935420810036209081725212",
+		var expectedBalance int64 = accountBalance
+		assert.Equal(t, expectedBalance, qontoAccountAfterProcessing.BalanceCents)
+
+		transactions, err := db.FindAccountTransactions(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Len(t, transactions, 0)
+	})
+}
+
+func TestProcessTransfers_limits(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
 	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
 
-	var accountBalance int64 = 20000
-	qontoAccountID, err := mysqlStorage.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
-	require.NoError(t, err)
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
 
-	transferManager := core.NewQontoTransferManager(mysqlStorage)
-	// total amount should be less than accountBalance
-	request := core.Request{
-		Party: qontoAccount,
-		CreditTransfers: []core.Transfer{
-			{
-				Amount:      core.Amount{Cents: 9000},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 1",
-					BIC:  "bic1",
-					IBAN: "iban1",
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
+
+		var accountBalance int64 = 20000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
+		require.NoError(t, err)
+		require.NoError(t, db.SaveTransferLimit(ctx, storage.TransferLimit{
+			BankAccountID: qontoAccountID,
+			MaxDailyCents: 10000,
+		}))
+		require.NoError(t, db.SaveTransferLimit(ctx, storage.TransferLimit{
+			BankAccountID:          qontoAccountID,
+			CounterpartyIBAN:       "iban2",
+			MaxSingleTransferCents: 1000,
+		}))
+
+		transferManager := core.NewQontoTransferManager(db)
+		transferTo := func(iban string, cents int64) core.Transfer {
+			return core.Transfer{
+				Amount:       core.Amount{Cents: cents},
+				Currency:     core.CURRENCY_EURO,
+				CounterParty: core.Party{Name: iban, BIC: "bic", IBAN: iban},
+			}
+		}
+
+		_, err = transferManager.ProcessTransfers(ctx, &core.Request{
+			Party:           qontoAccount,
+			CreditTransfers: core.TransferList{transferTo("iban1", 5000), transferTo("iban2", 1001)},
+		})
+		assert.ErrorIs(t, err, core.ErrSingleTransferLimitExceeded)
+
+		_, err = transferManager.ProcessTransfers(ctx, &core.Request{
+			Party:           qontoAccount,
+			CreditTransfers: core.TransferList{transferTo("iban1", 8000), transferTo("iban2", 1000)},
+		})
+		require.NoError(t, err)
+
+		// 9000 spent today, daily limit is 10000
+		_, err = transferManager.ProcessTransfers(ctx, &core.Request{
+			Party:           qontoAccount,
+			CreditTransfers: core.TransferList{transferTo("iban1", 1001)},
+		})
+		assert.ErrorIs(t, err, core.ErrDailyLimitExceeded)
+
+		qontoAccountAfterProcessing, err := db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		var expectedBalance int64 = 11000
+		assert.Equal(t, expectedBalance, qontoAccountAfterProcessing.BalanceCents)
+	})
+}
+
+func TestProcessTransfers_riskRules(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
+
+		var accountBalance int64 = 20000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
+		require.NoError(t, err)
+
+		rulesConfig := core.RulesConfig{
+			BlockedCountries: &core.BlockedCountriesRuleConfig{Countries: []string{"KP"}},
+		}
+		rules, err := rulesConfig.Rules()
+		require.NoError(t, err)
+		transferManager := core.NewQontoTransferManager(db).WithRuleEngine(core.NewRuleEngine(rules...))
+		request := core.Request{
+			Party: qontoAccount,
+			CreditTransfers: core.TransferList{
+				{
+					Amount:       core.Amount{Cents: 1000},
+					Currency:     core.CURRENCY_EURO,
+					CounterParty: core.Party{Name: "counterparty 1", BIC: "bic1", IBAN: "iban1"},
 				},
 			},
-			{
-				Amount:      core.Amount{Cents: 8000},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 2",
-					BIC:  "bic2",
-					IBAN: "iban2",
+		}
+
+		_, err = transferManager.ProcessTransfers(ctx, &request)
+		require.NoError(t, err)
+		request.CreditTransfers.(core.TransferList)[0].CounterParty.IBAN = "KP9935420810036209081725212"
+		_, err = transferManager.ProcessTransfers(ctx, &request)
+		assert.ErrorIs(t, err, core.ErrBlockedCountry)
+
+		qontoAccountAfterProcessing, err := db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		var expectedBalance int64 = 19000
+		assert.Equal(t, expectedBalance, qontoAccountAfterProcessing.BalanceCents)
+
+		decisions, err := db.FindRiskDecisions(ctx, qontoAccountID)
+		require.NoError(t, err)
+		require.Len(t, decisions, 2)
+		assert.Equal(t, string(core.DECISION_ALLOW), decisions[0].Decision)
+		assert.Equal(t, string(core.DECISION_DENY), decisions[1].Decision)
+		assert.Len(t, decisions[1].Reasons, 1)
+	})
+}
+
+// listScreener screens parties against in-memory list
+type listScreener struct {
+	list *screening.List
+}
+
+func (ls *listScreener) Screen(name, iban, bic string) []screening.Match {
+	return ls.list.Match(name, iban, bic, 0.9)
+}
+
+func (ls *listScreener) Reload() error {
+	return nil
+}
+
+func TestProcessTransfers_screening(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
+
+		var accountBalance int64 = 15000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
+		require.NoError(t, err)
+
+		screener := &listScreener{list: screening.NewList([]screening.Entry{{ID: "EU.1", Name: "Bugs Bunny"}})}
+		transferManager := core.NewQontoTransferManager(db).WithScreener(screener)
+		screeningManager := core.NewQontoScreeningManager(db, screener)
+		request := core.Request{
+			Party: qontoAccount,
+			CreditTransfers: core.TransferList{
+				{
+					Amount:       core.Amount{Cents: 1000},
+					Currency:     core.CURRENCY_EURO,
+					CounterParty: core.Party{Name: "Wile E Coyote", BIC: "bic1", IBAN: "iban1"},
+				},
+				{
+					Amount:       core.Amount{Cents: 9000},
+					Currency:     core.CURRENCY_EURO,
+					CounterParty: core.Party{Name: "Bunny, Bugs", BIC: "bic2", IBAN: "iban2"},
 				},
 			},
-			{
-				Amount:      core.Amount{Cents: 3001},
-				Currency:    core.CURRENCY_EURO,
-				Description: "",
-				CounterParty: core.Party{
-					Name: "counterparty 3",
-					BIC:  "bic3",
-					IBAN: "iban3",
+		}
+
+		// the hit holds its transfer only, repeated request does not create new hit
+		for i := 0; i < 2; i++ {
+			result, err := transferManager.ProcessTransfers(ctx, &request)
+			require.NoError(t, err)
+			assert.Equal(t, core.TRANSFER_ACCEPTED, result.Transfer(0).Status)
+			assert.Equal(t, core.TRANSFER_HELD, result.Transfer(1).Status)
+			assert.ErrorIs(t, result.Transfer(1).Err, core.ErrScreeningHit)
+		}
+		hits, err := screeningManager.ListHits(ctx, storage.ScreeningHitOpen)
+		require.NoError(t, err)
+		require.Len(t, hits, 1)
+		assert.Equal(t, "iban2", hits[0].CounterParty.IBAN)
+		pending, err := db.FindPendingHeldTransfers(ctx, qontoAccountID, "iban2")
+		require.NoError(t, err)
+		require.Len(t, pending, 2)
+		qontoAccountAfterProcessing, err := db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(13000), qontoAccountAfterProcessing.BalanceCents)
+
+		// held transfers are released in order of arrival, the second one finds no funds
+		require.NoError(t, screeningManager.ClearHit(ctx, hits[0].ID))
+		assert.ErrorIs(t, screeningManager.ClearHit(ctx, hits[0].ID), core.ErrScreeningHitNotFound)
+		pending, err = db.FindPendingHeldTransfers(ctx, qontoAccountID, "iban2")
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+		qontoAccountAfterProcessing, err = db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(4000), qontoAccountAfterProcessing.BalanceCents)
+		transactions, err := db.FindAccountTransactions(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Len(t, transactions, 3)
+
+		// cleared counterparty is not held anymore
+		transfers := request.CreditTransfers.(core.TransferList)[1:]
+		transfers[0].Amount = core.Amount{Cents: 4000}
+		request.CreditTransfers = transfers
+		result, err := transferManager.ProcessTransfers(ctx, &request)
+		require.NoError(t, err)
+		assert.Equal(t, core.TRANSFER_ACCEPTED, result.Transfer(0).Status)
+		qontoAccountAfterProcessing, err = db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), qontoAccountAfterProcessing.BalanceCents)
+	})
+}
+
+func TestProcessTransfers_duplicates(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
+
+		var accountBalance int64 = 20000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
+		require.NoError(t, err)
+
+		transferManager := core.NewQontoTransferManager(db).
+			WithDuplicatesPolicy(core.DuplicatesPolicy{Mode: core.DUPLICATES_REJECT, Window: time.Hour})
+		request := core.Request{
+			Party: qontoAccount,
+			CreditTransfers: core.TransferList{
+				{
+					Amount:       core.Amount{Cents: 1000},
+					Currency:     core.CURRENCY_EURO,
+					Description:  "payroll/2022-06",
+					CounterParty: core.Party{Name: "counterparty 1", BIC: "bic1", IBAN: "iban1"},
 				},
+				{
+					Amount:       core.Amount{Cents: 2000},
+					Currency:     core.CURRENCY_EURO,
+					Description:  "payroll/2022-06",
+					CounterParty: core.Party{Name: "counterparty 2", BIC: "bic2", IBAN: "iban2"},
+				},
+			},
+		}
+
+		_, err = transferManager.ProcessTransfers(ctx, &request)
+		require.NoError(t, err)
+		_, err = transferManager.ProcessTransfers(ctx, &request)
+		assert.ErrorIs(t, err, core.ErrDuplicateTransfer)
+
+		// the request flag forces execution, the transactions are still flagged
+		request.AllowDuplicates = true
+		_, err = transferManager.ProcessTransfers(ctx, &request)
+		require.NoError(t, err)
+
+		transactions, err := db.FindAccountTransactions(ctx, qontoAccountID)
+		require.NoError(t, err)
+		require.Len(t, transactions, 4)
+		flagged := 0
+		for _, tx := range transactions {
+			assert.NotEmpty(t, tx.Fingerprint)
+			if tx.FlaggedDuplicate {
+				flagged++
+			}
+		}
+		assert.Equal(t, 2, flagged)
+	})
+}
+
+func TestProcessTransfers_bestEffort(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
+
+		var accountBalance int64 = 10000
+		qontoAccountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, accountBalance)
+		require.NoError(t, err)
+		require.NoError(t, db.SaveTransferLimit(ctx, storage.TransferLimit{
+			BankAccountID:          qontoAccountID,
+			MaxSingleTransferCents: 5000,
+		}))
+
+		transferManager := core.NewQontoTransferManager(db)
+		transferTo := func(iban string, cents int64, currency core.Currency) core.Transfer {
+			return core.Transfer{
+				Amount:       core.Amount{Cents: cents},
+				Currency:     currency,
+				CounterParty: core.Party{Name: iban, BIC: "bic", IBAN: iban},
+			}
+		}
+		request := core.Request{
+			Party: qontoAccount,
+			Mode:  core.MODE_BEST_EFFORT,
+			CreditTransfers: core.TransferList{
+				transferTo("iban1", 4000, core.CURRENCY_EURO),
+				transferTo("iban2", 1000, "USD"),
+				transferTo("iban3", 6000, core.CURRENCY_EURO),
+				transferTo("iban4", 5000, core.CURRENCY_EURO),
+				transferTo("iban5", 2000, core.CURRENCY_EURO),
+				transferTo("iban6", 500, core.CURRENCY_EURO),
+				transferTo("iban7", -500, core.CURRENCY_EURO),
 			},
-		},
+		}
+
+		result, err := transferManager.ProcessTransfers(ctx, &request)
+		require.NoError(t, err)
+		require.Equal(t, request.Len(), result.Len())
+		expectedErrors := []error{nil, core.ErrInvalidCurrency, core.ErrSingleTransferLimitExceeded, nil, core.ErrNotEnoughFunds, core.ErrNotEnoughFunds, core.ErrInvalidAmount}
+		for i, expectedErr := range expectedErrors {
+			if expectedErr == nil {
+				assert.Equal(t, core.TRANSFER_ACCEPTED, result.Transfer(i).Status, "transfer #%d", i+1)
+				continue
+			}
+			assert.Equal(t, core.TRANSFER_REJECTED, result.Transfer(i).Status, "transfer #%d", i+1)
+			assert.ErrorIs(t, result.Transfer(i).Err, expectedErr, "transfer #%d", i+1)
+		}
+
+		qontoAccountAfterProcessing, err := db.FindAccount(ctx, qontoAccountID)
+		require.NoError(t, err)
+		var expectedBalance int64 = 1000
+		assert.Equal(t, expectedBalance, qontoAccountAfterProcessing.BalanceCents)
+
+		transactions, err := db.FindAccountTransactions(ctx, qontoAccountID)
+		require.NoError(t, err)
+		assert.Len(t, transactions, 2)
+	})
+}
+
+func TestStatusReports(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
 	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		reportManager := core.NewQontoReportManager(db)
+		report := &core.StatusReport{
+			MessageID:         "STS-1",
+			OriginalMessageID: "MSG-1",
+			GroupStatus:       "RJCT",
+			Content:           []byte("<Document/>"),
+		}
+		require.NoError(t, reportManager.SaveStatusReport(ctx, report))
+		require.NotZero(t, report.ID)
 
-	err = transferManager.ProcessTransfers(ctx, &request)
-	assert.Error(t, err)
+		found, err := reportManager.FindStatusReport(ctx, report.ID)
+		require.NoError(t, err)
+		assert.Equal(t, report.MessageID, found.MessageID)
+		assert.Equal(t, report.OriginalMessageID, found.OriginalMessageID)
+		assert.Equal(t, report.GroupStatus, found.GroupStatus)
+		assert.Equal(t, report.Content, found.Content)
 
-	qontoAccountAfterProcessing, err := mysqlStorage.FindAccount(ctx, qontoAccountID)
-	require.NoError(t, err)
-	assert.Equal(t, accountBalance, qontoAccountAfterProcessing.BalanceCents)
+		_, err = reportManager.FindStatusReport(ctx, report.ID+1)
+		assert.ErrorIs(t, err, core.ErrStatusReportNotFound)
+	})
+}
 
-	var expectedBalance int64 = accountBalance
-	assert.Equal(t, expectedBalance, qontoAccountAfterProcessing.BalanceCents)
+func TestStatements(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		qontoAccount := core.Party{
+			Name: "Qonto customer corp",
+			BIC:  "ARWKDJFU",
+			IBAN: "UA9935420810036209081725212",
+		}
+		accountID, err := db.CreateAccount(ctx, qontoAccount.Name, qontoAccount.IBAN, qontoAccount.BIC, 100000)
+		require.NoError(t, err)
+
+		day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -2)
+		transactions := []*storage.Transaction{
+			{CounterpartyName: "counterparty 1", CounterpartyIBAN: "iban1", AmountCents: 1000, AmountCurrency: "EUR", BankAccountID: accountID, CreatedAt: day.Add(-time.Hour)},
+			{CounterpartyName: "counterparty 2", CounterpartyIBAN: "iban2", AmountCents: 2000, AmountCurrency: "EUR", BankAccountID: accountID, CreatedAt: day.Add(time.Hour)},
+			{CounterpartyName: "counterparty 3", CounterpartyIBAN: "iban3", AmountCents: 3000, AmountCurrency: "EUR", BankAccountID: accountID, CreatedAt: day.Add(25 * time.Hour)},
+			{CounterpartyName: "counterparty 4", CounterpartyIBAN: "iban4", AmountCents: 4000, AmountCurrency: "EUR", BankAccountID: accountID, CreatedAt: day.Add(49 * time.Hour)},
+		}
+		require.NoError(t, db.AppendAccountTransactions(ctx, transactions))
+		require.NoError(t, db.UpdateAccountBalance(ctx, accountID, 90000))
+
+		statementManager := core.NewQontoStatementManager(db)
+		statement, err := statementManager.Statement(ctx, qontoAccount.IBAN, day, day.AddDate(0, 0, 2))
+		require.NoError(t, err)
+		assert.Equal(t, int64(99000), statement.OpeningBalance.Cents)
+		assert.Equal(t, int64(94000), statement.ClosingBalance.Cents)
+		require.Len(t, statement.Entries, 2)
+		assert.Equal(t, "iban2", statement.Entries[0].CounterParty.IBAN)
+		assert.Equal(t, "iban3", statement.Entries[1].CounterParty.IBAN)
+
+		_, err = statementManager.Statement(ctx, "FR0010009380540930414023042", day, day.AddDate(0, 0, 1))
+		assert.ErrorIs(t, err, core.ErrAccountNotFound)
+	})
+}
+
+func TestTransactionHistory(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
 
-	transactions, err := mysqlStorage.FindAccountTransactions(ctx, qontoAccountID)
-	require.NoError(t, err)
-	assert.Len(t, transactions, 0)
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		iban := "UA9935420810036209081725212"
+		accountID, err := db.CreateAccount(ctx, "Qonto customer corp", iban, "ARWKDJFU", 100000)
+		require.NoError(t, err)
+
+		since := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+		transactions := []*storage.Transaction{}
+		for i := 0; i < 5; i++ {
+			transactions = append(transactions, &storage.Transaction{
+				CounterpartyName: "counterparty",
+				CounterpartyIBAN: "iban",
+				AmountCents:      int64(100 * (i + 1)),
+				AmountCurrency:   "EUR",
+				BankAccountID:    accountID,
+				CreatedAt:        since.Add(time.Duration(i) * time.Hour),
+			})
+		}
+		require.NoError(t, db.AppendAccountTransactions(ctx, transactions))
+
+		historyManager := core.NewQontoHistoryManager(db)
+		amounts := []int64{}
+		err = historyManager.EachTransaction(ctx, iban, since.Add(time.Hour), since.Add(4*time.Hour), func(tx core.Transaction) error {
+			amounts = append(amounts, tx.Amount.Cents)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []int64{200, 300, 400}, amounts)
+
+		// error of the callback stops reading
+		stop := errors.New("stop")
+		count := 0
+		err = historyManager.EachTransaction(ctx, iban, time.Time{}, time.Time{}, func(tx core.Transaction) error {
+			count++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, count)
+
+		err = historyManager.EachTransaction(ctx, "FR0010009380540930414023042", time.Time{}, time.Time{}, func(tx core.Transaction) error {
+			return nil
+		})
+		assert.ErrorIs(t, err, core.ErrAccountNotFound)
+	})
+}
+
+func TestAccounts(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		iban := "UA9935420810036209081725212"
+		_, err := db.CreateAccount(ctx, "Qonto customer corp", iban, "ARWKDJFU", 100000)
+		require.NoError(t, err)
+
+		accountManager := core.NewQontoAccountManager(db)
+		account, err := accountManager.Account(ctx, iban)
+		require.NoError(t, err)
+		assert.Equal(t, core.Party{Name: "Qonto customer corp", BIC: "ARWKDJFU", IBAN: iban}, account.Party)
+		assert.Equal(t, core.Amount{Cents: 100000}, account.Balance)
+		assert.Equal(t, core.CURRENCY_EURO, account.Currency)
+
+		_, err = accountManager.Account(ctx, "DE9935420810036209081725212")
+		assert.True(t, errors.Is(err, core.ErrAccountNotFound))
+
+		_, err = db.CreateAccount(ctx, "Another customer corp", iban, "ARWKDJFU", 0)
+		assert.ErrorIs(t, err, storage.ErrDuplicateIBAN)
+
+		_, err = core.NewQontoTransferManager(db).ProcessTransfers(ctx, &core.Request{
+			Party: core.Party{Name: "Unknown corp", IBAN: "DE9935420810036209081725212"},
+			CreditTransfers: core.TransferList{
+				{Amount: core.Amount{Cents: 100}, Currency: core.CURRENCY_EURO, CounterParty: core.Party{Name: "counterparty", IBAN: iban}},
+			},
+		})
+		assert.Equal(t, core.ErrAccountNotFound, err)
+
+		err = db.SaveTransferLimit(ctx, storage.TransferLimit{BankAccountID: -1, MaxSingleTransferCents: 100})
+		assert.ErrorIs(t, err, storage.ErrConstraintViolation)
+	})
+}
+
+func TestHealthChecks(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		db, dbName := b.testDatabase(ctx, t)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		latest, err := storage.LatestMigration("file://" + utils.ProjectRootDir() + b.migrationsDir)
+		require.NoError(t, err)
+		assert.NoError(t, health.PingCheck(db)(ctx))
+		assert.NoError(t, health.MigrationsCheck(db, latest)(ctx))
+		assert.Error(t, health.MigrationsCheck(db, latest+1)(ctx))
+	})
+}
+
+type retryCounter struct {
+	mu      sync.Mutex
+	retries map[string]int
+}
+
+func (rc *retryCounter) ObserveRetry(reason string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.retries[reason]++
+}
+
+func (rc *retryCounter) ObserveRetriesExhausted(reason string) {}
+
+func TestTransactionRetry(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	forEachBackend(t, func(t *testing.T, b backend) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		defer cancel()
+
+		counter := &retryCounter{retries: map[string]int{}}
+		db, dbName := b.newTestDatabase(ctx, t,
+			storage.RetryPolicy{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}, counter)
+		defer db.Close()
+		t.Logf("test db name: %s", dbName)
+
+		firstID, err := db.CreateAccount(ctx, "Qonto customer corp", "UA9935420810036209081725212", "ARWKDJFU", 10000)
+		require.NoError(t, err)
+		secondID, err := db.CreateAccount(ctx, "Another customer corp", "DE9935420810036209081725212", "ARWKDJFU", 10000)
+		require.NoError(t, err)
+
+		t.Run("injected deadlock rolls back the attempt", func(t *testing.T) {
+			attempts := 0
+			err := db.WithTransactionStorage(ctx, func(ctx context.Context, txStorage storage.Storage) error {
+				attempts++
+				account, err := txStorage.FindAccount(ctx, firstID)
+				if err != nil {
+					return err
+				}
+				if err := txStorage.UpdateAccountBalance(ctx, firstID, account.BalanceCents-1000); err != nil {
+					return err
+				}
+				if attempts == 1 {
+					return b.deadlock
+				}
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 2, attempts)
+
+			account, err := db.FindAccount(ctx, firstID)
+			require.NoError(t, err)
+			assert.Equal(t, int64(9000), account.BalanceCents)
+		})
+
+		t.Run("concurrent transactions locking accounts in opposite order", func(t *testing.T) {
+			counter.retries = map[string]int{}
+			// both transactions lock their first account before any of them locks the second one
+			locked := sync.WaitGroup{}
+			locked.Add(2)
+			transfer := func(from, to int64) error {
+				attempts := 0
+				return db.WithTransactionStorage(ctx, func(ctx context.Context, txStorage storage.Storage) error {
+					attempts++
+					if err := addBalance(ctx, txStorage, from, -100); err != nil {
+						return err
+					}
+					if attempts == 1 {
+						locked.Done()
+						locked.Wait()
+					}
+					return addBalance(ctx, txStorage, to, 100)
+				})
+			}
+
+			errs := make(chan error, 2)
+			go func() { errs <- transfer(firstID, secondID) }()
+			go func() { errs <- transfer(secondID, firstID) }()
+			require.NoError(t, <-errs)
+			require.NoError(t, <-errs)
+
+			assert.Equal(t, 1, counter.retries[storage.RETRY_REASON_DEADLOCK])
+			first, err := db.FindAccount(ctx, firstID)
+			require.NoError(t, err)
+			second, err := db.FindAccount(ctx, secondID)
+			require.NoError(t, err)
+			assert.Equal(t, int64(9000), first.BalanceCents)
+			assert.Equal(t, int64(10000), second.BalanceCents)
+		})
+	})
+}
+
+// addBalance changes balance of the account, the row is locked when read and stays locked until the transaction ends
+func addBalance(ctx context.Context, s storage.Storage, accountID, deltaCents int64) error {
+	account, err := s.FindAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+	return s.UpdateAccountBalance(ctx, accountID, account.BalanceCents+deltaCents)
 }
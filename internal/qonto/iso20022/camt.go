@@ -0,0 +1,287 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+// supported account statement messages
+const (
+	MESSAGE_CAMT052 = "camt.052"
+	MESSAGE_CAMT053 = "camt.053"
+
+	NamespaceCamt052 = "urn:iso:std:iso:20022:tech:xsd:camt.052.001.02"
+	NamespaceCamt053 = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+)
+
+// balance types and entry codes used in statements
+const (
+	BALANCE_OPENING_BOOKED = "OPBD"
+	BALANCE_CLOSING_BOOKED = "CLBD"
+	BALANCE_INTERIM_BOOKED = "ITBD"
+
+	CREDIT = "CRDT"
+	DEBIT  = "DBIT"
+
+	ENTRY_BOOKED = "BOOK"
+)
+
+type (
+	// Document is a generated message
+	Document interface {
+		Marshal() ([]byte, error)
+	}
+
+	// Camt053 is a bank to customer statement message (camt.053.001.02) with a statement per calendar day
+	Camt053 struct {
+		XMLName xml.Name           `xml:"Document"`
+		Xmlns   string             `xml:"xmlns,attr"`
+		GrpHdr  MessageHeader      `xml:"BkToCstmrStmt>GrpHdr"`
+		Stmt    []AccountStatement `xml:"BkToCstmrStmt>Stmt"`
+	}
+
+	// Camt052 is a bank to customer intraday account report message (camt.052.001.02)
+	Camt052 struct {
+		XMLName xml.Name           `xml:"Document"`
+		Xmlns   string             `xml:"xmlns,attr"`
+		GrpHdr  MessageHeader      `xml:"BkToCstmrAcctRpt>GrpHdr"`
+		Rpt     []AccountStatement `xml:"BkToCstmrAcctRpt>Rpt"`
+	}
+
+	// AccountStatement is the content of both statement and report
+	AccountStatement struct {
+		Id      string `xml:"Id"`
+		CreDtTm string `xml:"CreDtTm"`
+		FrToDt  struct {
+			FrDtTm string `xml:"FrDtTm"`
+			ToDtTm string `xml:"ToDtTm"`
+		} `xml:"FrToDt"`
+		Acct      StatementAccount `xml:"Acct"`
+		Bal       []Balance        `xml:"Bal"`
+		TxsSummry struct {
+			TtlNtries struct {
+				NbOfNtries    int    `xml:"NbOfNtries"`
+				Sum           string `xml:"Sum"`
+				TtlNetNtryAmt string `xml:"TtlNetNtryAmt"`
+				CdtDbtInd     string `xml:"CdtDbtInd"`
+			} `xml:"TtlNtries"`
+		} `xml:"TxsSummry"`
+		Ntry []Entry `xml:"Ntry"`
+	}
+
+	StatementAccount struct {
+		IBAN string `xml:"Id>IBAN"`
+		Ccy  string `xml:"Ccy"`
+		Ownr struct {
+			Nm string `xml:"Nm"`
+		} `xml:"Ownr"`
+		Svcr *Agent `xml:"Svcr,omitempty"`
+	}
+
+	Balance struct {
+		Tp        string          `xml:"Tp>CdOrPrtry>Cd"`
+		Amt       CurrencyAmount  `xml:"Amt"`
+		CdtDbtInd string          `xml:"CdtDbtInd"`
+		Dt        DateAndDateTime `xml:"Dt"`
+	}
+
+	CurrencyAmount struct {
+		Ccy   string `xml:"Ccy,attr"`
+		Value string `xml:",chardata"`
+	}
+
+	DateAndDateTime struct {
+		Dt   string `xml:"Dt,omitempty"`
+		DtTm string `xml:"DtTm,omitempty"`
+	}
+
+	Entry struct {
+		NtryRef     string          `xml:"NtryRef"`
+		Amt         CurrencyAmount  `xml:"Amt"`
+		CdtDbtInd   string          `xml:"CdtDbtInd"`
+		Sts         string          `xml:"Sts"`
+		BookgDt     DateAndDateTime `xml:"BookgDt"`
+		ValDt       DateAndDateTime `xml:"ValDt"`
+		AcctSvcrRef string          `xml:"AcctSvcrRef"`
+		BkTxCd      struct {
+			Cd        string `xml:"Domn>Cd"`
+			FmlyCd    string `xml:"Domn>Fmly>Cd"`
+			SubFmlyCd string `xml:"Domn>Fmly>SubFmlyCd"`
+		} `xml:"BkTxCd"`
+		TxDtls EntryTransaction `xml:"NtryDtls>TxDtls"`
+	}
+
+	EntryTransaction struct {
+		AcctSvcrRef string `xml:"Refs>AcctSvcrRef"`
+		RltdPties   struct {
+			Cdtr struct {
+				Nm string `xml:"Nm"`
+			} `xml:"Cdtr"`
+			CdtrAcct Account `xml:"CdtrAcct"`
+		} `xml:"RltdPties"`
+		RltdAgts *struct {
+			CdtrAgt Agent `xml:"CdtrAgt"`
+		} `xml:"RltdAgts,omitempty"`
+		RmtInf *struct {
+			Ustrd string `xml:"Ustrd"`
+		} `xml:"RmtInf,omitempty"`
+	}
+)
+
+// NewStatementMessage builds camt.053 or camt.052 message of the statement
+func NewStatementMessage(messageType string, statement *core.Statement, msgID string, now time.Time) (Document, error) {
+	header := MessageHeader{
+		MsgId:   msgID,
+		CreDtTm: isoDateTime(now),
+	}
+	switch messageType {
+	case MESSAGE_CAMT053:
+		message := &Camt053{Xmlns: NamespaceCamt053, GrpHdr: header}
+		for i, daily := range statement.Daily() {
+			stmt := newAccountStatement(&daily, fmt.Sprintf("%s/%d", msgID, i+1), now)
+			stmt.Bal = []Balance{
+				newBalance(BALANCE_OPENING_BOOKED, daily.Currency, daily.OpeningBalance, DateAndDateTime{Dt: isoDate(daily.From)}),
+				newBalance(BALANCE_CLOSING_BOOKED, daily.Currency, daily.ClosingBalance, DateAndDateTime{Dt: isoDate(daily.From)}),
+			}
+			message.Stmt = append(message.Stmt, stmt)
+		}
+		return message, nil
+	case MESSAGE_CAMT052:
+		message := &Camt052{Xmlns: NamespaceCamt052, GrpHdr: header}
+		rpt := newAccountStatement(statement, msgID+"/1", now)
+		rpt.Bal = []Balance{
+			newBalance(BALANCE_OPENING_BOOKED, statement.Currency, statement.OpeningBalance, DateAndDateTime{DtTm: isoDateTime(statement.From)}),
+			newBalance(BALANCE_INTERIM_BOOKED, statement.Currency, statement.ClosingBalance, DateAndDateTime{DtTm: isoDateTime(statement.To)}),
+		}
+		message.Rpt = []AccountStatement{rpt}
+		return message, nil
+	}
+
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedMessage, messageType)
+}
+
+// Marshal encodes the statement into XML document
+func (c *Camt053) Marshal() ([]byte, error) {
+	return marshalDocument(c)
+}
+
+// Marshal encodes the report into XML document
+func (c *Camt052) Marshal() ([]byte, error) {
+	return marshalDocument(c)
+}
+
+// StatementPeriod parses statement period boundaries given as dates or RFC 3339 times.
+// Dates are days in UTC and both are included into the period.
+// By default camt.053 covers the previous day and camt.052 covers the current day up to now
+func StatementPeriod(messageType, from, to string, now time.Time) (time.Time, time.Time, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var periodFrom, periodTo time.Time
+	switch messageType {
+	case MESSAGE_CAMT053:
+		periodFrom, periodTo = today.AddDate(0, 0, -1), today
+	case MESSAGE_CAMT052:
+		periodFrom, periodTo = today, now
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %q", ErrUnsupportedMessage, messageType)
+	}
+
+	var err error
+	if from != "" {
+		if periodFrom, err = core.ParsePeriodBoundary(from, false); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if to != "" {
+		if periodTo, err = core.ParsePeriodBoundary(to, true); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if !periodFrom.Before(periodTo) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: %s is not before %s", core.ErrInvalidPeriod, from, to)
+	}
+
+	return periodFrom, periodTo, nil
+}
+
+func newAccountStatement(statement *core.Statement, id string, now time.Time) AccountStatement {
+	stmt := AccountStatement{
+		Id:      id,
+		CreDtTm: isoDateTime(now),
+		Ntry:    make([]Entry, 0, len(statement.Entries)),
+	}
+	stmt.FrToDt.FrDtTm = isoDateTime(statement.From)
+	// the period end is exclusive, while ToDtTm is the last second of the period
+	stmt.FrToDt.ToDtTm = isoDateTime(statement.To.Add(-time.Second))
+	stmt.Acct.IBAN = statement.Account.IBAN
+	stmt.Acct.Ccy = string(statement.Currency)
+	stmt.Acct.Ownr.Nm = statement.Account.Name
+	if statement.Account.BIC != "" {
+		stmt.Acct.Svcr = &Agent{BIC: statement.Account.BIC}
+	}
+
+	var sum int64
+	for _, e := range statement.Entries {
+		sum += e.Amount.Cents
+		stmt.Ntry = append(stmt.Ntry, newEntry(e))
+	}
+	stmt.TxsSummry.TtlNtries.NbOfNtries = len(statement.Entries)
+	stmt.TxsSummry.TtlNtries.Sum = formatAmount(sum)
+	stmt.TxsSummry.TtlNtries.TtlNetNtryAmt = formatAmount(sum)
+	stmt.TxsSummry.TtlNtries.CdtDbtInd = DEBIT
+
+	return stmt
+}
+
+// newEntry maps transaction into booked entry, transactions are outgoing credit transfers
+func newEntry(e core.StatementEntry) Entry {
+	ref := fmt.Sprint(e.ID)
+	entry := Entry{
+		NtryRef:     ref,
+		Amt:         CurrencyAmount{Ccy: string(e.Currency), Value: formatAmount(e.Amount.Cents)},
+		CdtDbtInd:   DEBIT,
+		Sts:         ENTRY_BOOKED,
+		BookgDt:     DateAndDateTime{DtTm: isoDateTime(e.BookedAt)},
+		ValDt:       DateAndDateTime{DtTm: isoDateTime(e.BookedAt)},
+		AcctSvcrRef: ref,
+	}
+	entry.BkTxCd.Cd = "PMNT"
+	entry.BkTxCd.FmlyCd = "ICDT"
+	entry.BkTxCd.SubFmlyCd = "ESCT"
+
+	entry.TxDtls.AcctSvcrRef = ref
+	entry.TxDtls.RltdPties.Cdtr.Nm = e.CounterParty.Name
+	entry.TxDtls.RltdPties.CdtrAcct.IBAN = e.CounterParty.IBAN
+	if e.CounterParty.BIC != "" {
+		entry.TxDtls.RltdAgts = &struct {
+			CdtrAgt Agent `xml:"CdtrAgt"`
+		}{CdtrAgt: Agent{BIC: e.CounterParty.BIC}}
+	}
+	if e.Description != "" {
+		entry.TxDtls.RmtInf = &struct {
+			Ustrd string `xml:"Ustrd"`
+		}{Ustrd: truncate(e.Description, maxUnstructuredLength)}
+	}
+
+	return entry
+}
+
+func newBalance(balanceType string, currency core.Currency, amount core.Amount, date DateAndDateTime) Balance {
+	indicator := CREDIT
+	cents := amount.Cents
+	if cents < 0 {
+		indicator = DEBIT
+		cents = -cents
+	}
+
+	return Balance{
+		Tp:        balanceType,
+		Amt:       CurrencyAmount{Ccy: string(currency), Value: formatAmount(cents)},
+		CdtDbtInd: indicator,
+		Dt:        date,
+	}
+}
@@ -0,0 +1,194 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStatement() *core.Statement {
+	day := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+	return &core.Statement{
+		Account:        core.Party{Name: "ACME Corp", BIC: "OIVUSCLQXXX", IBAN: "FR10474608000002006107XXXXX"},
+		Currency:       core.CURRENCY_EURO,
+		From:           day,
+		To:             day.AddDate(0, 0, 2),
+		OpeningBalance: core.Amount{Cents: 100000},
+		ClosingBalance: core.Amount{Cents: 37312},
+		Entries: []core.StatementEntry{
+			{
+				ID:           1,
+				Amount:       core.Amount{Cents: 1450},
+				Currency:     core.CURRENCY_EURO,
+				Description:  "Wonderland/4410 " + strings.Repeat("x", 200),
+				CounterParty: core.Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+				BookedAt:     day.Add(10 * time.Hour),
+			},
+			{
+				ID:           2,
+				Amount:       core.Amount{Cents: 61238},
+				Currency:     core.CURRENCY_EURO,
+				CounterParty: core.Party{Name: "Wile E Coyote", IBAN: "DE9935420810036209081725212"},
+				BookedAt:     day.Add(30 * time.Hour),
+			},
+		},
+	}
+}
+
+func TestCamt053(t *testing.T) {
+	now := time.Date(2022, 6, 3, 1, 0, 0, 0, time.UTC)
+	message, err := NewStatementMessage(MESSAGE_CAMT053, testStatement(), "STM-1", now)
+	require.NoError(t, err)
+	content, err := message.Marshal()
+	require.NoError(t, err)
+
+	assert.Empty(t, validateCamt(content, MESSAGE_CAMT053, NamespaceCamt053))
+
+	decoded := Camt053{}
+	require.NoError(t, xml.Unmarshal(content, &decoded))
+	require.Len(t, decoded.Stmt, 2, "statement per day expected")
+
+	expected := []struct {
+		id               string
+		from, to         string
+		opening, closing string
+		entries          []string
+		sum              string
+	}{
+		{id: "STM-1/1", from: "2022-06-01T00:00:00", to: "2022-06-01T23:59:59", opening: "1000.00", closing: "985.50", entries: []string{"14.50"}, sum: "14.50"},
+		{id: "STM-1/2", from: "2022-06-02T00:00:00", to: "2022-06-02T23:59:59", opening: "985.50", closing: "373.12", entries: []string{"612.38"}, sum: "612.38"},
+	}
+	for i, e := range expected {
+		stmt := decoded.Stmt[i]
+		assert.Equal(t, e.id, stmt.Id)
+		assert.Equal(t, e.from, stmt.FrToDt.FrDtTm)
+		assert.Equal(t, e.to, stmt.FrToDt.ToDtTm)
+		require.Len(t, stmt.Bal, 2)
+		assert.Equal(t, BALANCE_OPENING_BOOKED, stmt.Bal[0].Tp)
+		assert.Equal(t, e.opening, stmt.Bal[0].Amt.Value)
+		assert.Equal(t, BALANCE_CLOSING_BOOKED, stmt.Bal[1].Tp)
+		assert.Equal(t, e.closing, stmt.Bal[1].Amt.Value)
+		assert.Equal(t, e.sum, stmt.TxsSummry.TtlNtries.Sum)
+		amounts := []string{}
+		for _, entry := range stmt.Ntry {
+			assert.Equal(t, DEBIT, entry.CdtDbtInd)
+			amounts = append(amounts, entry.Amt.Value)
+		}
+		assert.Equal(t, e.entries, amounts)
+	}
+	assert.Equal(t, "CRLYFRPPTOU", decoded.Stmt[0].Ntry[0].TxDtls.RltdAgts.CdtrAgt.BIC)
+	assert.Nil(t, decoded.Stmt[1].Ntry[0].TxDtls.RltdAgts)
+}
+
+func TestCamt052(t *testing.T) {
+	statement := testStatement()
+	statement.To = statement.From.Add(12 * time.Hour)
+	statement.ClosingBalance = core.Amount{Cents: 98550}
+	statement.Entries = statement.Entries[:1]
+
+	message, err := NewStatementMessage(MESSAGE_CAMT052, statement, "RPT-1", statement.To)
+	require.NoError(t, err)
+	content, err := message.Marshal()
+	require.NoError(t, err)
+
+	assert.Empty(t, validateCamt(content, MESSAGE_CAMT052, NamespaceCamt052))
+
+	decoded := Camt052{}
+	require.NoError(t, xml.Unmarshal(content, &decoded))
+	require.Len(t, decoded.Rpt, 1)
+	require.Len(t, decoded.Rpt[0].Bal, 2)
+	assert.Equal(t, BALANCE_INTERIM_BOOKED, decoded.Rpt[0].Bal[1].Tp)
+	assert.Equal(t, "985.50", decoded.Rpt[0].Bal[1].Amt.Value)
+	assert.Equal(t, "2022-06-01T12:00:00", decoded.Rpt[0].Bal[1].Dt.DtTm)
+	assert.Len(t, decoded.Rpt[0].Ntry, 1)
+}
+
+func TestCamtSchemaValidation(t *testing.T) {
+	message, err := NewStatementMessage(MESSAGE_CAMT053, testStatement(), "STM-1", time.Now())
+	require.NoError(t, err)
+	content, err := message.Marshal()
+	require.NoError(t, err)
+
+	// make sure the validator does catch broken documents
+	broken := strings.Replace(string(content), "<Cd>OPBD</Cd>", "<Cd>OPEN</Cd>", 1)
+	assert.NotEmpty(t, validateCamt([]byte(broken), MESSAGE_CAMT053, NamespaceCamt053))
+	broken = strings.Replace(string(content), "<MsgId>STM-1</MsgId>", "", 1)
+	assert.NotEmpty(t, validateCamt([]byte(broken), MESSAGE_CAMT053, NamespaceCamt053))
+	assert.NotEmpty(t, validateCamt(content, MESSAGE_CAMT052, NamespaceCamt052))
+}
+
+func TestStatementPeriod(t *testing.T) {
+	now := time.Date(2022, 6, 15, 13, 30, 0, 0, time.UTC)
+	cases := []struct {
+		name         string
+		messageType  string
+		from, to     string
+		expectedFrom time.Time
+		expectedTo   time.Time
+		expectError  bool
+	}{
+		{
+			name:         "statement of previous day by default",
+			messageType:  MESSAGE_CAMT053,
+			expectedFrom: time.Date(2022, 6, 14, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2022, 6, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "intraday report of current day by default",
+			messageType:  MESSAGE_CAMT052,
+			expectedFrom: time.Date(2022, 6, 15, 0, 0, 0, 0, time.UTC),
+			expectedTo:   now,
+		},
+		{
+			name:         "dates include end day",
+			messageType:  MESSAGE_CAMT053,
+			from:         "2022-06-01",
+			to:           "2022-06-10",
+			expectedFrom: time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2022, 6, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "times",
+			messageType:  MESSAGE_CAMT052,
+			from:         "2022-06-15T10:00:00+02:00",
+			to:           "2022-06-15T12:00:00Z",
+			expectedFrom: time.Date(2022, 6, 15, 8, 0, 0, 0, time.UTC),
+			expectedTo:   time.Date(2022, 6, 15, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:        "reversed period",
+			messageType: MESSAGE_CAMT053,
+			from:        "2022-06-10",
+			to:          "2022-06-01",
+			expectError: true,
+		},
+		{
+			name:        "invalid date",
+			messageType: MESSAGE_CAMT053,
+			from:        "01/06/2022",
+			expectError: true,
+		},
+		{
+			name:        "unknown message",
+			messageType: "camt.054",
+			expectError: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			from, to, err := StatementPeriod(tc.messageType, tc.from, tc.to, now)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedFrom, from)
+			assert.Equal(t, tc.expectedTo, to)
+		})
+	}
+}
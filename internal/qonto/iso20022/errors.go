@@ -0,0 +1,61 @@
+package iso20022
+
+import "strings"
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	ErrInvalidMessage     = Error("invalid ISO 20022 message")
+	ErrUnsupportedMessage = Error("unsupported ISO 20022 message type")
+)
+
+type (
+	// ValidationError references the place of the message the error was found in,
+	// empty references mean the error concerns upper level of the message
+	ValidationError struct {
+		PmtInfId   string
+		EndToEndId string
+		Message    string
+	}
+
+	// ValidationErrors holds all errors found in the message
+	ValidationErrors []ValidationError
+)
+
+func (ve ValidationError) with(message string) ValidationError {
+	ve.Message = message
+	return ve
+}
+
+func (ve ValidationError) Error() string {
+	parts := []string{}
+	if ve.PmtInfId != "" {
+		parts = append(parts, "PmtInfId "+ve.PmtInfId)
+	}
+	if ve.EndToEndId != "" {
+		parts = append(parts, "EndToEndId "+ve.EndToEndId)
+	}
+	if len(parts) == 0 {
+		return ve.Message
+	}
+
+	return strings.Join(parts, ", ") + ": " + ve.Message
+}
+
+func (ve ValidationErrors) Error() string {
+	messages := make([]string, 0, len(ve))
+	for _, err := range ve {
+		messages = append(messages, err.Error())
+	}
+
+	return ErrInvalidMessage.Error() + ": " + strings.Join(messages, "; ")
+}
+
+// Is makes validation errors match ErrInvalidMessage
+func (ve ValidationErrors) Is(target error) bool {
+	return target == ErrInvalidMessage
+}
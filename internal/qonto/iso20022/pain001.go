@@ -0,0 +1,362 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/spool"
+)
+
+// NamespacePain001 is XML namespace of supported customer credit transfer initiation version
+const NamespacePain001 = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03"
+
+type (
+	// Pain001 is a customer credit transfer initiation message (pain.001.001.03).
+	// Only elements used by the service are mapped. The message is parsed element by element,
+	// so transactions are not kept in the message, but checked and spooled as they are read.
+	// It must be closed to remove the spooled transactions
+	Pain001 struct {
+		XMLName xml.Name
+		GrpHdr  GroupHeader
+		PmtInf  []PaymentInformation
+	}
+
+	GroupHeader struct {
+		MsgId    string `xml:"MsgId"`
+		CreDtTm  string `xml:"CreDtTm"`
+		NbOfTxs  string `xml:"NbOfTxs"`
+		CtrlSum  string `xml:"CtrlSum"`
+		InitgPty struct {
+			Nm string `xml:"Nm"`
+		} `xml:"InitgPty"`
+	}
+
+	PaymentInformation struct {
+		PmtInfId string
+		PmtMtd   string
+		NbOfTxs  string
+		CtrlSum  string
+		Dbtr     struct {
+			Nm string `xml:"Nm"`
+		}
+		DbtrAcct Account
+		DbtrAgt  Agent
+
+		// transfers are converted CdtTrfTxInf elements, count and sum are their actual totals
+		// and errs are errors found in them
+		transfers *spool.Transfers
+		count     int
+		sum       int64
+		errs      []ValidationError
+		redact    *qonto.Redactor
+	}
+
+	CreditTransferTransaction struct {
+		PmtId struct {
+			EndToEndId string `xml:"EndToEndId"`
+		} `xml:"PmtId"`
+		Amt struct {
+			InstdAmt struct {
+				Ccy   string `xml:"Ccy,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"InstdAmt"`
+		} `xml:"Amt"`
+		CdtrAgt Agent `xml:"CdtrAgt"`
+		Cdtr    struct {
+			Nm string `xml:"Nm"`
+		} `xml:"Cdtr"`
+		CdtrAcct Account `xml:"CdtrAcct"`
+		RmtInf   struct {
+			Ustrd []string `xml:"Ustrd"`
+		} `xml:"RmtInf"`
+	}
+
+	Account struct {
+		IBAN string `xml:"Id>IBAN"`
+	}
+
+	Agent struct {
+		BIC string `xml:"FinInstnId>BIC"`
+	}
+)
+
+// ParsePain001 decodes pain.001 message, the message must be validated before use.
+// Transactions beyond the first chunk of every payment information block are spooled to disk
+func ParsePain001(r io.Reader) (*Pain001, error) {
+	message := &Pain001{}
+	if err := message.decode(xml.NewDecoder(r)); err != nil {
+		_ = message.Close()
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	return message, nil
+}
+
+// decode reads the document element by element, unknown elements are skipped
+func (p *Pain001) decode(decoder *xml.Decoder) error {
+	root, err := nextElement(decoder)
+	if err != nil {
+		return err
+	}
+	if root.Name.Local != "Document" {
+		return fmt.Errorf("expected element type <Document> but have <%s>", root.Name.Local)
+	}
+	p.XMLName = root.Name
+
+	return eachChild(decoder, func(start xml.StartElement) error {
+		if start.Name.Local != "CstmrCdtTrfInitn" {
+			return decoder.Skip()
+		}
+		return eachChild(decoder, func(start xml.StartElement) error {
+			switch start.Name.Local {
+			case "GrpHdr":
+				return decoder.DecodeElement(&p.GrpHdr, &start)
+			case "PmtInf":
+				p.PmtInf = append(p.PmtInf, PaymentInformation{transfers: spool.New("")})
+				return p.PmtInf[len(p.PmtInf)-1].decode(decoder)
+			default:
+				return decoder.Skip()
+			}
+		})
+	})
+}
+
+// decode reads the block element by element, every transaction is checked and spooled once it is read
+func (p *PaymentInformation) decode(decoder *xml.Decoder) error {
+	return eachChild(decoder, func(start xml.StartElement) error {
+		switch start.Name.Local {
+		case "PmtInfId":
+			return decoder.DecodeElement(&p.PmtInfId, &start)
+		case "PmtMtd":
+			return decoder.DecodeElement(&p.PmtMtd, &start)
+		case "NbOfTxs":
+			return decoder.DecodeElement(&p.NbOfTxs, &start)
+		case "CtrlSum":
+			return decoder.DecodeElement(&p.CtrlSum, &start)
+		case "Dbtr":
+			return decoder.DecodeElement(&p.Dbtr, &start)
+		case "DbtrAcct":
+			return decoder.DecodeElement(&p.DbtrAcct, &start)
+		case "DbtrAgt":
+			return decoder.DecodeElement(&p.DbtrAgt, &start)
+		case "CdtTrfTxInf":
+			tx := CreditTransferTransaction{}
+			if err := decoder.DecodeElement(&tx, &start); err != nil {
+				return err
+			}
+			return p.add(tx)
+		default:
+			return decoder.Skip()
+		}
+	})
+}
+
+// add checks the transaction and appends it to transfers of the block
+func (p *PaymentInformation) add(tx CreditTransferTransaction) error {
+	txRef := ValidationError{PmtInfId: p.PmtInfId, EndToEndId: tx.PmtId.EndToEndId}
+	if strings.TrimSpace(tx.PmtId.EndToEndId) == "" {
+		p.errs = append(p.errs, txRef.with("PmtId/EndToEndId is required"))
+	}
+	amount, err := parseAmount(tx.Amt.InstdAmt.Value)
+	if err != nil {
+		p.errs = append(p.errs, txRef.with("Amt/InstdAmt: "+err.Error()))
+	}
+	if tx.Amt.InstdAmt.Ccy != string(core.CURRENCY_EURO) {
+		p.errs = append(p.errs, txRef.with(fmt.Sprintf("Amt/InstdAmt currency must be %s, got %q", core.CURRENCY_EURO, tx.Amt.InstdAmt.Ccy)))
+	}
+	if strings.TrimSpace(tx.Cdtr.Nm) == "" {
+		p.errs = append(p.errs, txRef.with("Cdtr/Nm is required"))
+	}
+	if strings.TrimSpace(tx.CdtrAcct.IBAN) == "" {
+		p.errs = append(p.errs, txRef.with("CdtrAcct/Id/IBAN is required"))
+	}
+	p.count++
+	p.sum += amount.Cents
+
+	return p.transfers.Append(core.Transfer{
+		Amount:      amount,
+		Currency:    core.Currency(tx.Amt.InstdAmt.Ccy),
+		Description: strings.Join(tx.RmtInf.Ustrd, " "),
+		CounterParty: core.Party{
+			Name: tx.Cdtr.Nm,
+			BIC:  tx.CdtrAgt.BIC,
+			IBAN: tx.CdtrAcct.IBAN,
+		},
+		Reference: tx.PmtId.EndToEndId,
+	})
+}
+
+// nextElement skips the prolog up to the first element
+func nextElement(decoder *xml.Decoder) (xml.StartElement, error) {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start, nil
+		}
+	}
+}
+
+// eachChild calls fn for every child element of the element being read up to its end,
+// fn must read the child up to its end too
+func eachChild(decoder *xml.Decoder, fn func(start xml.StartElement) error) error {
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if err := fn(t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// Close removes spooled transactions of the message
+func (p *Pain001) Close() error {
+	var err error
+	for i := range p.PmtInf {
+		if p.PmtInf[i].transfers == nil {
+			continue
+		}
+		if closeErr := p.PmtInf[i].transfers.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+
+	return err
+}
+
+// Validate checks structure and consistency of the message:
+// required elements, number of transactions and control sums on group and payment levels
+func (p *Pain001) Validate() error {
+	errs := ValidationErrors{}
+	if p.XMLName.Space != NamespacePain001 {
+		errs = append(errs, ValidationError{Message: fmt.Sprintf("unsupported namespace %q, %q expected", p.XMLName.Space, NamespacePain001)})
+	}
+	if strings.TrimSpace(p.GrpHdr.MsgId) == "" {
+		errs = append(errs, ValidationError{Message: "GrpHdr/MsgId is required"})
+	}
+	if len(p.PmtInf) == 0 {
+		errs = append(errs, ValidationError{Message: "at least one PmtInf is required"})
+	}
+
+	var total int
+	var totalSum int64
+	for _, pmtInf := range p.PmtInf {
+		ref := ValidationError{PmtInfId: pmtInf.PmtInfId}
+		if strings.TrimSpace(pmtInf.PmtInfId) == "" {
+			errs = append(errs, ref.with("PmtInfId is required"))
+		}
+		if pmtInf.PmtMtd != "TRF" {
+			errs = append(errs, ref.with(fmt.Sprintf("PmtMtd must be TRF, got %q", pmtInf.PmtMtd)))
+		}
+		if strings.TrimSpace(pmtInf.DbtrAcct.IBAN) == "" {
+			errs = append(errs, ref.with("DbtrAcct/Id/IBAN is required"))
+		}
+		if pmtInf.count == 0 {
+			errs = append(errs, ref.with("at least one CdtTrfTxInf is required"))
+		}
+		errs = append(errs, pmtInf.errs...)
+
+		errs = append(errs, checkTotals(ref, pmtInf.NbOfTxs, pmtInf.CtrlSum, pmtInf.count, pmtInf.sum)...)
+		total += pmtInf.count
+		totalSum += pmtInf.sum
+	}
+
+	if strings.TrimSpace(p.GrpHdr.NbOfTxs) == "" {
+		errs = append(errs, ValidationError{Message: "GrpHdr/NbOfTxs is required"})
+	}
+	for _, err := range checkTotals(ValidationError{}, p.GrpHdr.NbOfTxs, p.GrpHdr.CtrlSum, total, totalSum) {
+		err.Message = "GrpHdr/" + err.Message
+		errs = append(errs, err)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// checkTotals compares declared number of transactions and control sum with actual ones, empty declarations are skipped
+func checkTotals(ref ValidationError, nbOfTxs, ctrlSum string, count int, sum int64) []ValidationError {
+	errs := []ValidationError{}
+	if nbOfTxs = strings.TrimSpace(nbOfTxs); nbOfTxs != "" && nbOfTxs != fmt.Sprint(count) {
+		errs = append(errs, ref.with(fmt.Sprintf("NbOfTxs is %s, but message contains %d transactions", nbOfTxs, count)))
+	}
+	if ctrlSum = strings.TrimSpace(ctrlSum); ctrlSum != "" {
+		declared, err := core.ParseAmount(ctrlSum)
+		if err != nil {
+			errs = append(errs, ref.with("CtrlSum: "+err.Error()))
+		} else if declared.Cents != sum {
+			errs = append(errs, ref.with(fmt.Sprintf("CtrlSum is %s, but sum of transactions is %s", ctrlSum, formatAmount(sum))))
+		}
+	}
+
+	return errs
+}
+
+// Requests maps every payment information block of validated message into a separate request,
+// transfers of the requests are read from the spool of the message
+func (p *Pain001) Requests() []*core.Request {
+	requests := make([]*core.Request, 0, len(p.PmtInf))
+	for i := range p.PmtInf {
+		pmtInf := &p.PmtInf[i]
+		requests = append(requests, &core.Request{
+			Party: core.Party{
+				Name: pmtInf.Dbtr.Nm,
+				BIC:  pmtInf.DbtrAgt.BIC,
+				IBAN: pmtInf.DbtrAcct.IBAN,
+			},
+			CreditTransfers: pmtInf.Transfers(),
+			Mode:            core.MODE_ALL_OR_NOTHING,
+		})
+	}
+
+	return requests
+}
+
+// Transfers returns transactions of the block converted into transfers
+func (p *PaymentInformation) Transfers() core.Transfers {
+	if p.transfers == nil {
+		return core.TransferList(nil)
+	}
+	return p.transfers
+}
+
+// redactor masks names and IBANs of the block parties, so the report does not repeat them in free text.
+// Names are loaded once the first text is redacted
+func (p *PaymentInformation) redactor() *qonto.Redactor {
+	if p.redact == nil {
+		p.redact = (&core.Request{Party: core.Party{Name: p.Dbtr.Nm}, CreditTransfers: p.Transfers()}).Redactor()
+	}
+	return p.redact
+}
+
+// parseAmount parses positive ISO 20022 decimal amount
+func parseAmount(s string) (core.Amount, error) {
+	amount, err := core.ParseAmount(strings.TrimSpace(s))
+	if err != nil {
+		return core.Amount{}, fmt.Errorf("invalid amount %q: %v", s, err)
+	}
+	if amount.Cents <= 0 {
+		return core.Amount{}, fmt.Errorf("amount must be positive, got %q", s)
+	}
+
+	return amount, nil
+}
+
+func formatAmount(cents int64) string {
+	return core.Amount{Cents: cents}.String()
+}
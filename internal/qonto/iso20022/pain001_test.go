@@ -0,0 +1,183 @@
+package iso20022
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/spool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPain001Requests(t *testing.T) {
+	f, err := os.Open("testdata/pain001.xml")
+	require.NoError(t, err)
+	defer f.Close()
+
+	message, err := ParsePain001(f)
+	require.NoError(t, err)
+	defer message.Close()
+	require.NoError(t, message.Validate())
+
+	requests := message.Requests()
+	require.Len(t, requests, 2)
+
+	assert.Equal(t, core.Party{Name: "ACME Corp", BIC: "OIVUSCLQXXX", IBAN: "FR10474608000002006107XXXXX"}, requests[0].Party)
+	assert.Equal(t, []core.Transfer{
+		{
+			Amount:       core.Amount{Cents: 1450},
+			Currency:     core.CURRENCY_EURO,
+			Description:  "Wonderland/4410",
+			CounterParty: core.Party{Name: "Bip Bip", BIC: "CRLYFRPPTOU", IBAN: "EE383680981021245685"},
+			Reference:    "E2E-1",
+		},
+		{
+			Amount:       core.Amount{Cents: 6123800},
+			Currency:     core.CURRENCY_EURO,
+			Description:  "//TeslaMotors/Invoice/12",
+			CounterParty: core.Party{Name: "Wile E Coyote", BIC: "ZDRPLBQI", IBAN: "DE9935420810036209081725212"},
+			Reference:    "E2E-2",
+		},
+	}, readTransfers(t, requests[0].CreditTransfers))
+	assert.Equal(t, 1, requests[1].Len())
+}
+
+func TestParsePain001SpoolsLargeBlocks(t *testing.T) {
+	const count = 2*core.CHUNK_SIZE + 10
+	message, err := ParsePain001(strings.NewReader(largePain001(count)))
+	require.NoError(t, err)
+	defer message.Close()
+	require.NoError(t, message.Validate())
+
+	requests := message.Requests()
+	require.Len(t, requests, 1)
+	transfers := readTransfers(t, requests[0].CreditTransfers)
+	require.Len(t, transfers, count)
+	for i, transfer := range transfers {
+		assert.Equal(t, fmt.Sprintf("E2E-%d", i), transfer.Reference)
+	}
+
+	require.NoError(t, message.Close())
+	assert.ErrorIs(t, requests[0].CreditTransfers.Chunks(core.CHUNK_SIZE, func(int, []core.Transfer) error { return nil }), spool.ErrClosed)
+}
+
+// largePain001 generates a message of one block with count transactions of 1.00 each
+func largePain001(count int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<Document xmlns="%s"><CstmrCdtTrfInitn>`, NamespacePain001)
+	fmt.Fprintf(&b, `<GrpHdr><MsgId>MSG-1</MsgId><NbOfTxs>%d</NbOfTxs><CtrlSum>%d</CtrlSum></GrpHdr>`, count, count)
+	b.WriteString(`<PmtInf><PmtInfId>PMT-1</PmtInfId><PmtMtd>TRF</PmtMtd><Dbtr><Nm>ACME Corp</Nm></Dbtr>`)
+	b.WriteString(`<DbtrAcct><Id><IBAN>FR10474608000002006107XXXXX</IBAN></Id></DbtrAcct>`)
+	for i := 0; i < count; i++ {
+		fmt.Fprintf(&b, `<CdtTrfTxInf><PmtId><EndToEndId>E2E-%d</EndToEndId></PmtId>`, i)
+		b.WriteString(`<Amt><InstdAmt Ccy="EUR">1.00</InstdAmt></Amt><Cdtr><Nm>Bip Bip</Nm></Cdtr>`)
+		b.WriteString(`<CdtrAcct><Id><IBAN>EE383680981021245685</IBAN></Id></CdtrAcct></CdtTrfTxInf>`)
+	}
+	b.WriteString(`</PmtInf></CstmrCdtTrfInitn></Document>`)
+
+	return b.String()
+}
+
+// readTransfers reads all transfers into memory
+func readTransfers(t *testing.T, transfers core.Transfers) []core.Transfer {
+	list := []core.Transfer{}
+	require.NoError(t, transfers.Chunks(core.CHUNK_SIZE, func(offset int, chunk []core.Transfer) error {
+		list = append(list, chunk...)
+		return nil
+	}))
+	return list
+}
+
+func TestPain001Validate(t *testing.T) {
+	sample, err := os.ReadFile("testdata/pain001.xml")
+	require.NoError(t, err)
+
+	cases := []struct {
+		name           string
+		old, new       string
+		expectedErrors []ValidationError
+	}{
+		{
+			name:           "group control sum mismatch",
+			old:            "<CtrlSum>62251.5</CtrlSum>",
+			new:            "<CtrlSum>62251.49</CtrlSum>",
+			expectedErrors: []ValidationError{{Message: "GrpHdr/CtrlSum is 62251.49, but sum of transactions is 62251.50"}},
+		},
+		{
+			name:           "group number of transactions mismatch",
+			old:            "<NbOfTxs>3</NbOfTxs>",
+			new:            "<NbOfTxs>4</NbOfTxs>",
+			expectedErrors: []ValidationError{{Message: "GrpHdr/NbOfTxs is 4, but message contains 3 transactions"}},
+		},
+		{
+			name:           "payment number of transactions mismatch",
+			old:            "<NbOfTxs>2</NbOfTxs>",
+			new:            "<NbOfTxs>1</NbOfTxs>",
+			expectedErrors: []ValidationError{{PmtInfId: "PMT-1", Message: "NbOfTxs is 1, but message contains 2 transactions"}},
+		},
+		{
+			name: "transaction currency",
+			old:  `<InstdAmt Ccy="EUR">999</InstdAmt>`,
+			new:  `<InstdAmt Ccy="USD">999</InstdAmt>`,
+			expectedErrors: []ValidationError{
+				{PmtInfId: "PMT-2", EndToEndId: "E2E-3", Message: `Amt/InstdAmt currency must be EUR, got "USD"`},
+			},
+		},
+		{
+			name: "missing creditor account",
+			old:  "<IBAN>EE383680981021245685</IBAN>",
+			new:  "<IBAN></IBAN>",
+			expectedErrors: []ValidationError{
+				{PmtInfId: "PMT-1", EndToEndId: "E2E-1", Message: "CdtrAcct/Id/IBAN is required"},
+			},
+		},
+		{
+			name: "negative amount",
+			old:  `<InstdAmt Ccy="EUR">14.50</InstdAmt>`,
+			new:  `<InstdAmt Ccy="EUR">-14.50</InstdAmt>`,
+			expectedErrors: []ValidationError{
+				{PmtInfId: "PMT-1", EndToEndId: "E2E-1", Message: `Amt/InstdAmt: amount must be positive, got "-14.50"`},
+				{PmtInfId: "PMT-1", Message: "CtrlSum is 61252.50, but sum of transactions is 61238.00"},
+				{Message: "GrpHdr/CtrlSum is 62251.5, but sum of transactions is 62237.00"},
+			},
+		},
+		{
+			name: "other message version",
+			old:  "pain.001.001.03",
+			new:  "pain.001.001.09",
+			expectedErrors: []ValidationError{
+				{Message: `unsupported namespace "urn:iso:std:iso:20022:tech:xsd:pain.001.001.09", "urn:iso:std:iso:20022:tech:xsd:pain.001.001.03" expected`},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			content := strings.Replace(string(sample), tc.old, tc.new, 1)
+			message, err := ParsePain001(strings.NewReader(content))
+			require.NoError(t, err)
+			defer message.Close()
+
+			err = message.Validate()
+			assert.True(t, errors.Is(err, ErrInvalidMessage))
+			var validationErrs ValidationErrors
+			require.True(t, errors.As(err, &validationErrs))
+			assert.Equal(t, tc.expectedErrors, []ValidationError(validationErrs))
+		})
+	}
+}
+
+func TestParsePain001Malformed(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"<Document><CstmrCdtTrfInitn>",
+		"<Report></Report>",
+		"<Document><CstmrCdtTrfInitn><PmtInf><CdtTrfTxInf><Amt></CdtTrfTxInf></PmtInf></CstmrCdtTrfInitn></Document>",
+	} {
+		_, err := ParsePain001(strings.NewReader(content))
+		assert.ErrorIs(t, err, ErrInvalidMessage, content)
+	}
+}
@@ -0,0 +1,221 @@
+package iso20022
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+// NamespacePain002 is XML namespace of generated customer payment status report version
+const NamespacePain002 = "urn:iso:std:iso:20022:tech:xsd:pain.002.001.03"
+
+// Status codes used in the report, transfers are executed immediately,
+// so accepted ones are reported as settled. Transfers held by screening hits are pending
+const (
+	STATUS_ACCEPTED           = "ACSC"
+	STATUS_PARTIALLY_ACCEPTED = "PART"
+	STATUS_PENDING            = "PDNG"
+	STATUS_REJECTED           = "RJCT"
+)
+
+// lengths of free text elements are limited by the schema
+const (
+	maxAdditionalInfoLength = 105
+	maxUnstructuredLength   = 140
+)
+
+type (
+	// PaymentOutcome is an outcome of a payment information block. Err is set if the block is rejected
+	// as a whole, otherwise Result holds outcomes of its transactions in the same order as in the block
+	PaymentOutcome struct {
+		Err    error
+		Result *core.Result
+	}
+
+	// Pain002 is a customer payment status report message (pain.002.001.03)
+	Pain002 struct {
+		XMLName           xml.Name                `xml:"Document"`
+		Xmlns             string                  `xml:"xmlns,attr"`
+		GrpHdr            MessageHeader           `xml:"CstmrPmtStsRpt>GrpHdr"`
+		OrgnlGrpInfAndSts OriginalGroupStatus     `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts"`
+		OrgnlPmtInfAndSts []OriginalPaymentStatus `xml:"CstmrPmtStsRpt>OrgnlPmtInfAndSts"`
+	}
+
+	// MessageHeader is a group header of generated messages
+	MessageHeader struct {
+		MsgId   string `xml:"MsgId"`
+		CreDtTm string `xml:"CreDtTm"`
+	}
+
+	OriginalGroupStatus struct {
+		OrgnlMsgId   string `xml:"OrgnlMsgId"`
+		OrgnlMsgNmId string `xml:"OrgnlMsgNmId"`
+		OrgnlNbOfTxs string `xml:"OrgnlNbOfTxs,omitempty"`
+		OrgnlCtrlSum string `xml:"OrgnlCtrlSum,omitempty"`
+		GrpSts       string `xml:"GrpSts"`
+	}
+
+	OriginalPaymentStatus struct {
+		OrgnlPmtInfId string              `xml:"OrgnlPmtInfId"`
+		OrgnlNbOfTxs  string              `xml:"OrgnlNbOfTxs,omitempty"`
+		OrgnlCtrlSum  string              `xml:"OrgnlCtrlSum,omitempty"`
+		PmtInfSts     string              `xml:"PmtInfSts"`
+		StsRsnInf     *StatusReason       `xml:"StsRsnInf,omitempty"`
+		TxInfAndSts   []TransactionStatus `xml:"TxInfAndSts"`
+	}
+
+	TransactionStatus struct {
+		OrgnlEndToEndId string        `xml:"OrgnlEndToEndId"`
+		TxSts           string        `xml:"TxSts"`
+		StsRsnInf       *StatusReason `xml:"StsRsnInf,omitempty"`
+	}
+
+	StatusReason struct {
+		Cd       string `xml:"Rsn>Cd"`
+		AddtlInf string `xml:"AddtlInf,omitempty"`
+	}
+)
+
+// NewPain002 builds status report of the processed message.
+// Outcomes hold outcomes of payment information blocks in the same order as in the message.
+// Blocks are processed as a whole, so transactions have status of their block and only held ones
+// are listed with their own status, reports of blocks of any size stay small
+func NewPain002(original *Pain001, outcomes []PaymentOutcome, msgID string, now time.Time) (*Pain002, error) {
+	if len(outcomes) != len(original.PmtInf) {
+		return nil, fmt.Errorf("got %d outcomes for %d payment information blocks", len(outcomes), len(original.PmtInf))
+	}
+
+	report := &Pain002{
+		Xmlns: NamespacePain002,
+		GrpHdr: MessageHeader{
+			MsgId:   msgID,
+			CreDtTm: isoDateTime(now),
+		},
+		OrgnlGrpInfAndSts: OriginalGroupStatus{
+			OrgnlMsgId:   original.GrpHdr.MsgId,
+			OrgnlMsgNmId: "pain.001.001.03",
+			OrgnlNbOfTxs: original.GrpHdr.NbOfTxs,
+			OrgnlCtrlSum: original.GrpHdr.CtrlSum,
+		},
+		OrgnlPmtInfAndSts: make([]OriginalPaymentStatus, 0, len(original.PmtInf)),
+	}
+
+	statuses := map[string]bool{}
+	for i := range original.PmtInf {
+		pmtInf := &original.PmtInf[i]
+		payment := OriginalPaymentStatus{
+			OrgnlPmtInfId: pmtInf.PmtInfId,
+			OrgnlNbOfTxs:  pmtInf.NbOfTxs,
+			OrgnlCtrlSum:  pmtInf.CtrlSum,
+			PmtInfSts:     STATUS_ACCEPTED,
+		}
+		result := outcomes[i].Result
+		switch {
+		case outcomes[i].Err != nil:
+			payment.PmtInfSts = STATUS_REJECTED
+			payment.StsRsnInf = pmtInf.statusReason(outcomes[i].Err)
+		case result != nil && result.Held() > 0:
+			held, err := pmtInf.heldStatuses(result)
+			if err != nil {
+				return nil, err
+			}
+			payment.TxInfAndSts = held
+			payment.PmtInfSts = STATUS_PENDING
+			if result.Held() < result.Len() {
+				payment.PmtInfSts = STATUS_PARTIALLY_ACCEPTED
+			}
+		}
+		statuses[payment.PmtInfSts] = true
+		report.OrgnlPmtInfAndSts = append(report.OrgnlPmtInfAndSts, payment)
+	}
+
+	// the group has status of its blocks if they all share it
+	report.OrgnlGrpInfAndSts.GrpSts = STATUS_PARTIALLY_ACCEPTED
+	if len(statuses) == 1 {
+		for status := range statuses {
+			report.OrgnlGrpInfAndSts.GrpSts = status
+		}
+	}
+
+	return report, nil
+}
+
+// heldStatuses lists held transactions of the block, they are found by reading spooled transactions
+func (p *PaymentInformation) heldStatuses(result *core.Result) ([]TransactionStatus, error) {
+	statuses := make([]TransactionStatus, 0, result.Held())
+	err := p.Transfers().Chunks(core.CHUNK_SIZE, func(offset int, chunk []core.Transfer) error {
+		for j, transfer := range chunk {
+			if outcome := result.Transfer(offset + j); outcome.Status == core.TRANSFER_HELD {
+				statuses = append(statuses, TransactionStatus{
+					OrgnlEndToEndId: transfer.Reference,
+					TxSts:           STATUS_PENDING,
+					StsRsnInf:       p.statusReason(outcome.Err),
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading transactions of %s: %w", p.PmtInfId, err)
+	}
+
+	return statuses, nil
+}
+
+// statusReason describes the error without names of the block parties
+func (p *PaymentInformation) statusReason(err error) *StatusReason {
+	return &StatusReason{
+		Cd:       ReasonCode(err),
+		AddtlInf: truncate(p.redactor().Text(err.Error()), maxAdditionalInfoLength),
+	}
+}
+
+// Marshal encodes the report into XML document
+func (p *Pain002) Marshal() ([]byte, error) {
+	return marshalDocument(p)
+}
+
+// GroupStatus returns status of the original message as a whole
+func (p *Pain002) GroupStatus() string {
+	return p.OrgnlGrpInfAndSts.GrpSts
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+
+	return string(runes[:length])
+}
+
+func marshalDocument(document interface{}) ([]byte, error) {
+	content, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), content...), nil
+}
+
+func isoDateTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05")
+}
+
+func isoDate(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+// NewMessageID generates unique message identification, it is at most 30 characters long for prefixes up to 6 characters
+func NewMessageID(prefix string, now time.Time) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s-%s-%s", prefix, now.UTC().Format("20060102150405"), hex.EncodeToString(suffix)), nil
+}
@@ -0,0 +1,217 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPain002(t *testing.T) {
+	f, err := os.Open("testdata/pain001.xml")
+	require.NoError(t, err)
+	defer f.Close()
+	original, err := ParsePain001(f)
+	require.NoError(t, err)
+	defer original.Close()
+
+	now := time.Date(2022, 6, 1, 10, 0, 5, 0, time.UTC)
+	cases := []struct {
+		name                string
+		outcomes            []PaymentOutcome
+		expectedGroupStatus string
+		expectedStatuses    []string
+		expectedReasons     []string
+	}{
+		{
+			name:                "all accepted",
+			outcomes:            []PaymentOutcome{{}, {}},
+			expectedGroupStatus: STATUS_ACCEPTED,
+			expectedStatuses:    []string{STATUS_ACCEPTED, STATUS_ACCEPTED},
+			expectedReasons:     []string{"", ""},
+		},
+		{
+			name:                "partially accepted",
+			outcomes:            []PaymentOutcome{{Err: fmt.Errorf("%w: transfer #2", core.ErrNotEnoughFunds)}, {}},
+			expectedGroupStatus: STATUS_PARTIALLY_ACCEPTED,
+			expectedStatuses:    []string{STATUS_REJECTED, STATUS_ACCEPTED},
+			expectedReasons:     []string{REASON_INSUFFICIENT_FUNDS, ""},
+		},
+		{
+			name:                "all rejected",
+			outcomes:            []PaymentOutcome{{Err: core.ErrDailyLimitExceeded}, {Err: core.ErrTransferDenied}},
+			expectedGroupStatus: STATUS_REJECTED,
+			expectedStatuses:    []string{STATUS_REJECTED, STATUS_REJECTED},
+			expectedReasons:     []string{REASON_AMOUNT_EXCEEDS_LIMIT, REASON_TRANSACTION_FORBIDDEN},
+		},
+		{
+			name: "all held",
+			outcomes: []PaymentOutcome{
+				{Result: core.ResultOf(
+					core.TransferResult{Status: core.TRANSFER_HELD, Err: core.ErrScreeningHit},
+					core.TransferResult{Status: core.TRANSFER_HELD, Err: core.ErrScreeningHit},
+				)},
+				{Result: core.ResultOf(core.TransferResult{Status: core.TRANSFER_HELD, Err: core.ErrScreeningHit})},
+			},
+			expectedGroupStatus: STATUS_PENDING,
+			expectedStatuses:    []string{STATUS_PENDING, STATUS_PENDING},
+			expectedReasons:     []string{REASON_REGULATORY_REASON, REASON_REGULATORY_REASON},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := NewPain002(original, tc.outcomes, "STS-1", now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedGroupStatus, report.GroupStatus())
+			assert.Equal(t, "MSG-2022-06-001", report.OrgnlGrpInfAndSts.OrgnlMsgId)
+			require.Len(t, report.OrgnlPmtInfAndSts, 2)
+
+			for i, payment := range report.OrgnlPmtInfAndSts {
+				assert.Equal(t, original.PmtInf[i].PmtInfId, payment.OrgnlPmtInfId)
+				assert.Equal(t, tc.expectedStatuses[i], payment.PmtInfSts)
+				if payment.PmtInfSts != STATUS_PENDING {
+					// transactions have status of their block
+					assert.Empty(t, payment.TxInfAndSts)
+					assert.Equal(t, tc.expectedReasons[i], reasonCode(payment.StsRsnInf))
+					continue
+				}
+
+				assert.Nil(t, payment.StsRsnInf)
+				transfers := readTransfers(t, original.PmtInf[i].Transfers())
+				require.Len(t, payment.TxInfAndSts, len(transfers))
+				for j, tx := range payment.TxInfAndSts {
+					assert.Equal(t, transfers[j].Reference, tx.OrgnlEndToEndId)
+					assert.Equal(t, STATUS_PENDING, tx.TxSts)
+					assert.Equal(t, tc.expectedReasons[i], reasonCode(tx.StsRsnInf))
+				}
+			}
+		})
+	}
+}
+
+func reasonCode(reason *StatusReason) string {
+	if reason == nil {
+		return ""
+	}
+	return reason.Cd
+}
+
+func TestNewPain002PartiallyHeld(t *testing.T) {
+	f, err := os.Open("testdata/pain001.xml")
+	require.NoError(t, err)
+	defer f.Close()
+	original, err := ParsePain001(f)
+	require.NoError(t, err)
+	defer original.Close()
+
+	outcomes := []PaymentOutcome{
+		{Result: core.ResultOf(
+			core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+			core.TransferResult{Status: core.TRANSFER_HELD, Err: core.ErrScreeningHit},
+		)},
+		{Result: core.ResultOf(core.TransferResult{Status: core.TRANSFER_ACCEPTED})},
+	}
+	report, err := NewPain002(original, outcomes, "STS-1", time.Now())
+	require.NoError(t, err)
+
+	assert.Equal(t, STATUS_PARTIALLY_ACCEPTED, report.GroupStatus())
+	payment := report.OrgnlPmtInfAndSts[0]
+	assert.Equal(t, STATUS_PARTIALLY_ACCEPTED, payment.PmtInfSts)
+	assert.Nil(t, payment.StsRsnInf)
+	// only the held transaction is listed, the accepted one has status of the block
+	require.Len(t, payment.TxInfAndSts, 1)
+	assert.Equal(t, "E2E-2", payment.TxInfAndSts[0].OrgnlEndToEndId)
+	assert.Equal(t, STATUS_PENDING, payment.TxInfAndSts[0].TxSts)
+	assert.Equal(t, REASON_REGULATORY_REASON, reasonCode(payment.TxInfAndSts[0].StsRsnInf))
+	assert.Equal(t, STATUS_ACCEPTED, report.OrgnlPmtInfAndSts[1].PmtInfSts)
+	assert.Empty(t, report.OrgnlPmtInfAndSts[1].TxInfAndSts)
+}
+
+func TestNewPain002RedactsAdditionalInfo(t *testing.T) {
+	f, err := os.Open("testdata/pain001.xml")
+	require.NoError(t, err)
+	defer f.Close()
+	original, err := ParsePain001(f)
+	require.NoError(t, err)
+	defer original.Close()
+
+	outcomes := []PaymentOutcome{
+		{Err: fmt.Errorf("%w: transfer to Wile E Coyote de99 3542 0810 0362 0908 1725 212", core.ErrNotEnoughFunds)},
+		{Err: fmt.Errorf("account of ACME Corp FR10474608000002006107XXXXX is locked")},
+	}
+	report, err := NewPain002(original, outcomes, "STS-1", time.Now())
+	require.NoError(t, err)
+
+	reasons := []string{
+		"not enough funds: transfer to " + qonto.RedactName("Wile E Coyote") + " DE****5212",
+		"account of " + qonto.RedactName("ACME Corp") + " FR****XXXX is locked",
+	}
+	for i, payment := range report.OrgnlPmtInfAndSts {
+		require.NotNil(t, payment.StsRsnInf)
+		assert.Equal(t, reasons[i], payment.StsRsnInf.AddtlInf)
+	}
+}
+
+func TestNewPain002OutcomesMismatch(t *testing.T) {
+	_, err := NewPain002(&Pain001{PmtInf: make([]PaymentInformation, 2)}, []PaymentOutcome{{}}, "STS-1", time.Now())
+	assert.Error(t, err)
+}
+
+func TestPain002Marshal(t *testing.T) {
+	original := &Pain001{
+		GrpHdr: GroupHeader{MsgId: "MSG-1", NbOfTxs: "1"},
+		PmtInf: []PaymentInformation{{PmtInfId: "PMT-1"}},
+	}
+	report, err := NewPain002(original, []PaymentOutcome{{Err: fmt.Errorf("%w within 1h0m0s: transfers #1", core.ErrDuplicateTransfer)}}, "STS-1", time.Date(2022, 6, 1, 10, 0, 5, 0, time.UTC))
+	require.NoError(t, err)
+
+	content, err := report.Marshal()
+	require.NoError(t, err)
+
+	decoded := struct {
+		XMLName xml.Name `xml:"Document"`
+		MsgId   string   `xml:"CstmrPmtStsRpt>GrpHdr>MsgId"`
+		CreDtTm string   `xml:"CstmrPmtStsRpt>GrpHdr>CreDtTm"`
+		GrpSts  string   `xml:"CstmrPmtStsRpt>OrgnlGrpInfAndSts>GrpSts"`
+		Status  string   `xml:"CstmrPmtStsRpt>OrgnlPmtInfAndSts>PmtInfSts"`
+		Reason  string   `xml:"CstmrPmtStsRpt>OrgnlPmtInfAndSts>StsRsnInf>Rsn>Cd"`
+	}{}
+	require.NoError(t, xml.Unmarshal(content, &decoded))
+	assert.Equal(t, NamespacePain002, decoded.XMLName.Space)
+	assert.Equal(t, "STS-1", decoded.MsgId)
+	assert.Equal(t, "2022-06-01T10:00:05", decoded.CreDtTm)
+	assert.Equal(t, STATUS_REJECTED, decoded.GrpSts)
+	assert.Equal(t, STATUS_REJECTED, decoded.Status)
+	assert.Equal(t, REASON_DUPLICATION, decoded.Reason)
+}
+
+func TestReasonCode(t *testing.T) {
+	cases := []struct {
+		err          error
+		expectedCode string
+	}{
+		{err: fmt.Errorf("%w: transfer #1", core.ErrInvalidCurrency), expectedCode: REASON_INVALID_CURRENCY},
+		{err: fmt.Errorf("%w: transfer #2", core.ErrInvalidAmount), expectedCode: REASON_INVALID_AMOUNT},
+		{err: core.ErrSingleTransferLimitExceeded, expectedCode: REASON_AMOUNT_EXCEEDS_LIMIT},
+		{err: core.ErrBatchSizeLimitExceeded, expectedCode: REASON_INVALID_NUMBER_OF_TXS},
+		{err: core.ErrDuplicateTransfer, expectedCode: REASON_DUPLICATION},
+		{
+			err: &core.DeniedError{Findings: []core.Finding{
+				{Rule: "blocked_country", Decision: core.DECISION_DENY, Err: core.ErrBlockedCountry},
+			}},
+			expectedCode: REASON_TRANSACTION_FORBIDDEN,
+		},
+		{err: fmt.Errorf("connection refused"), expectedCode: REASON_NARRATIVE},
+	}
+	for _, tc := range cases {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			assert.Equal(t, tc.expectedCode, ReasonCode(tc.err))
+		})
+	}
+}
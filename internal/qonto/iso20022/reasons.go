@@ -0,0 +1,49 @@
+package iso20022
+
+import (
+	"errors"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+// ISO 20022 external status reason codes reported for rejected transfers
+const (
+	REASON_INSUFFICIENT_FUNDS    = "AM04"
+	REASON_DUPLICATION           = "AM05"
+	REASON_INVALID_CURRENCY      = "AM11"
+	REASON_INVALID_AMOUNT        = "AM12"
+	REASON_AMOUNT_EXCEEDS_LIMIT  = "AM14"
+	REASON_INVALID_NUMBER_OF_TXS = "AM18"
+	REASON_TRANSACTION_FORBIDDEN = "AG01"
+	REASON_REGULATORY_REASON     = "RR04"
+	REASON_NARRATIVE             = "NARR"
+)
+
+// reasonCodes is ordered, the first matching error wins:
+// duplicate payments are denied by risk rules, so they must be checked before denial
+var reasonCodes = []struct {
+	err  error
+	code string
+}{
+	{err: core.ErrNotEnoughFunds, code: REASON_INSUFFICIENT_FUNDS},
+	{err: core.ErrDuplicateTransfer, code: REASON_DUPLICATION},
+	{err: core.ErrInvalidCurrency, code: REASON_INVALID_CURRENCY},
+	{err: core.ErrInvalidAmount, code: REASON_INVALID_AMOUNT},
+	{err: core.ErrSingleTransferLimitExceeded, code: REASON_AMOUNT_EXCEEDS_LIMIT},
+	{err: core.ErrDailyLimitExceeded, code: REASON_AMOUNT_EXCEEDS_LIMIT},
+	{err: core.ErrMonthlyLimitExceeded, code: REASON_AMOUNT_EXCEEDS_LIMIT},
+	{err: core.ErrBatchSizeLimitExceeded, code: REASON_INVALID_NUMBER_OF_TXS},
+	{err: core.ErrScreeningHit, code: REASON_REGULATORY_REASON},
+	{err: core.ErrTransferDenied, code: REASON_TRANSACTION_FORBIDDEN},
+}
+
+// ReasonCode maps core error to status reason code, unknown errors are reported as narrative
+func ReasonCode(err error) string {
+	for _, rc := range reasonCodes {
+		if errors.Is(err, rc.err) {
+			return rc.code
+		}
+	}
+
+	return REASON_NARRATIVE
+}
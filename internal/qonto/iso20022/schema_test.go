@@ -0,0 +1,423 @@
+package iso20022
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// The standard library has no XSD validation, so generated documents are checked against
+// the structure of camt.053.001.02 and camt.052.001.02 schemas transcribed below:
+// order of elements, their cardinality and simple types.
+// Complex types the service never emits are left without definition and rejected if met.
+
+type (
+	schemaElement struct {
+		name     string
+		typeName string
+		min, max int
+	}
+
+	schemaType struct {
+		// choice requires exactly one of elements, otherwise elements are a sequence
+		choice   bool
+		elements []schemaElement
+	}
+
+	xmlNode struct {
+		name     string
+		attrs    map[string]string
+		children []*xmlNode
+		text     string
+	}
+)
+
+const unbounded = -1
+
+func seq(elements ...schemaElement) schemaType {
+	return schemaType{elements: elements}
+}
+
+func choice(elements ...schemaElement) schemaType {
+	return schemaType{choice: true, elements: elements}
+}
+
+func el(name, typeName string, min, max int) schemaElement {
+	return schemaElement{name: name, typeName: typeName, min: min, max: max}
+}
+
+var camtSchema = map[string]schemaType{
+	"Document.camt.053": seq(el("BkToCstmrStmt", "BankToCustomerStatementV02", 1, 1)),
+	"Document.camt.052": seq(el("BkToCstmrAcctRpt", "BankToCustomerAccountReportV02", 1, 1)),
+	"BankToCustomerStatementV02": seq(
+		el("GrpHdr", "GroupHeader42", 1, 1),
+		el("Stmt", "AccountStatement2", 1, unbounded),
+	),
+	"BankToCustomerAccountReportV02": seq(
+		el("GrpHdr", "GroupHeader42", 1, 1),
+		el("Rpt", "AccountReport11", 1, unbounded),
+	),
+	"GroupHeader42": seq(
+		el("MsgId", "Max35Text", 1, 1),
+		el("CreDtTm", "ISODateTime", 1, 1),
+		el("MsgRcpt", "", 0, 1),
+		el("MsgPgntn", "", 0, 1),
+		el("AddtlInf", "Max500Text", 0, 1),
+	),
+	"AccountStatement2": seq(accountStatementElements("AddtlStmtInf", 1)...),
+	"AccountReport11":   seq(accountStatementElements("AddtlRptInf", 0)...),
+	"DateTimePeriodDetails": seq(
+		el("FrDtTm", "ISODateTime", 1, 1),
+		el("ToDtTm", "ISODateTime", 1, 1),
+	),
+	"CashAccount20": seq(
+		el("Id", "AccountIdentification4Choice", 1, 1),
+		el("Tp", "", 0, 1),
+		el("Ccy", "ActiveOrHistoricCurrencyCode", 0, 1),
+		el("Nm", "Max70Text", 0, 1),
+		el("Ownr", "PartyIdentification32", 0, 1),
+		el("Svcr", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+	),
+	"CashAccount16": seq(
+		el("Id", "AccountIdentification4Choice", 1, 1),
+		el("Tp", "", 0, 1),
+		el("Ccy", "ActiveOrHistoricCurrencyCode", 0, 1),
+		el("Nm", "Max70Text", 0, 1),
+	),
+	"AccountIdentification4Choice": choice(
+		el("IBAN", "IBAN2007Identifier", 1, 1),
+		el("Othr", "", 1, 1),
+	),
+	"PartyIdentification32": seq(
+		el("Nm", "Max140Text", 0, 1),
+		el("PstlAdr", "", 0, 1),
+		el("Id", "", 0, 1),
+		el("CtryOfRes", "", 0, 1),
+		el("CtctDtls", "", 0, 1),
+	),
+	"BranchAndFinancialInstitutionIdentification4": seq(
+		el("FinInstnId", "FinancialInstitutionIdentification7", 1, 1),
+		el("BrnchId", "", 0, 1),
+	),
+	"FinancialInstitutionIdentification7": seq(
+		el("BIC", "BICIdentifier", 0, 1),
+		el("ClrSysMmbId", "", 0, 1),
+		el("Nm", "Max140Text", 0, 1),
+		el("PstlAdr", "", 0, 1),
+		el("Othr", "", 0, 1),
+	),
+	"CashBalance3": seq(
+		el("Tp", "BalanceType12", 1, 1),
+		el("CdtLine", "", 0, 1),
+		el("Amt", "ActiveOrHistoricCurrencyAndAmount", 1, 1),
+		el("CdtDbtInd", "CreditDebitCode", 1, 1),
+		el("Dt", "DateAndDateTimeChoice", 1, 1),
+		el("Avlbty", "", 0, unbounded),
+	),
+	"BalanceType12": seq(
+		el("CdOrPrtry", "BalanceType12Choice", 1, 1),
+		el("SubTp", "", 0, 1),
+	),
+	"BalanceType12Choice": choice(
+		el("Cd", "BalanceType12Code", 1, 1),
+		el("Prtry", "Max35Text", 1, 1),
+	),
+	"DateAndDateTimeChoice": choice(
+		el("Dt", "ISODate", 1, 1),
+		el("DtTm", "ISODateTime", 1, 1),
+	),
+	"TotalTransactions2": seq(
+		el("TtlNtries", "NumberAndSumOfTransactions2", 0, 1),
+		el("TtlCdtNtries", "", 0, 1),
+		el("TtlDbtNtries", "", 0, 1),
+		el("TtlNtriesPerBkTxCd", "", 0, unbounded),
+	),
+	"NumberAndSumOfTransactions2": seq(
+		el("NbOfNtries", "Max15NumericText", 0, 1),
+		el("Sum", "DecimalNumber", 0, 1),
+		el("TtlNetNtryAmt", "DecimalNumber", 0, 1),
+		el("CdtDbtInd", "CreditDebitCode", 0, 1),
+	),
+	"ReportEntry2": seq(
+		el("NtryRef", "Max35Text", 0, 1),
+		el("Amt", "ActiveOrHistoricCurrencyAndAmount", 1, 1),
+		el("CdtDbtInd", "CreditDebitCode", 1, 1),
+		el("RvslInd", "", 0, 1),
+		el("Sts", "EntryStatus2Code", 1, 1),
+		el("BookgDt", "DateAndDateTimeChoice", 0, 1),
+		el("ValDt", "DateAndDateTimeChoice", 0, 1),
+		el("AcctSvcrRef", "Max35Text", 0, 1),
+		el("Avlbty", "", 0, unbounded),
+		el("BkTxCd", "BankTransactionCodeStructure4", 1, 1),
+		el("ComssnWvrInd", "", 0, 1),
+		el("AddtlInfInd", "", 0, 1),
+		el("AmtDtls", "", 0, 1),
+		el("Chrgs", "", 0, unbounded),
+		el("TechInptChanl", "", 0, 1),
+		el("Intrst", "", 0, unbounded),
+		el("NtryDtls", "EntryDetails1", 0, unbounded),
+		el("AddtlNtryInf", "Max500Text", 0, 1),
+	),
+	"BankTransactionCodeStructure4": seq(
+		el("Domn", "BankTransactionCodeStructure5", 0, 1),
+		el("Prtry", "", 0, 1),
+	),
+	"BankTransactionCodeStructure5": seq(
+		el("Cd", "ExternalCode", 1, 1),
+		el("Fmly", "BankTransactionCodeStructure6", 1, 1),
+	),
+	"BankTransactionCodeStructure6": seq(
+		el("Cd", "ExternalCode", 1, 1),
+		el("SubFmlyCd", "ExternalCode", 1, 1),
+	),
+	"EntryDetails1": seq(
+		el("Btch", "", 0, 1),
+		el("TxDtls", "EntryTransaction2", 0, unbounded),
+	),
+	"EntryTransaction2": seq(
+		el("Refs", "TransactionReferences2", 0, 1),
+		el("AmtDtls", "", 0, 1),
+		el("Avlbty", "", 0, unbounded),
+		el("BkTxCd", "BankTransactionCodeStructure4", 0, 1),
+		el("Chrgs", "", 0, unbounded),
+		el("Intrst", "", 0, unbounded),
+		el("RltdPties", "TransactionParty2", 0, 1),
+		el("RltdAgts", "TransactionAgents2", 0, 1),
+		el("Purp", "", 0, 1),
+		el("RltdRmtInf", "", 0, 10),
+		el("RmtInf", "RemittanceInformation5", 0, 1),
+		el("RltdDts", "", 0, 1),
+		el("RltdPric", "", 0, 1),
+		el("RltdQties", "", 0, unbounded),
+		el("FinInstrmId", "", 0, 1),
+		el("Tax", "", 0, 1),
+		el("RtrInf", "", 0, 1),
+		el("CorpActn", "", 0, 1),
+		el("SfkpgAcct", "", 0, 1),
+		el("AddtlTxInf", "Max500Text", 0, 1),
+	),
+	"TransactionReferences2": seq(
+		el("MsgId", "Max35Text", 0, 1),
+		el("AcctSvcrRef", "Max35Text", 0, 1),
+		el("PmtInfId", "Max35Text", 0, 1),
+		el("InstrId", "Max35Text", 0, 1),
+		el("EndToEndId", "Max35Text", 0, 1),
+		el("TxId", "Max35Text", 0, 1),
+		el("MndtId", "Max35Text", 0, 1),
+		el("ChqNb", "Max35Text", 0, 1),
+		el("ClrSysRef", "Max35Text", 0, 1),
+		el("Prtry", "", 0, 1),
+	),
+	"TransactionParty2": seq(
+		el("InitgPty", "PartyIdentification32", 0, 1),
+		el("Dbtr", "PartyIdentification32", 0, 1),
+		el("DbtrAcct", "CashAccount16", 0, 1),
+		el("UltmtDbtr", "PartyIdentification32", 0, 1),
+		el("Cdtr", "PartyIdentification32", 0, 1),
+		el("CdtrAcct", "CashAccount16", 0, 1),
+		el("UltmtCdtr", "PartyIdentification32", 0, 1),
+		el("TradgPty", "PartyIdentification32", 0, 1),
+		el("Prtry", "", 0, unbounded),
+	),
+	"TransactionAgents2": seq(
+		el("DbtrAgt", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+		el("CdtrAgt", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+		el("IntrmyAgt1", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+		el("IntrmyAgt2", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+		el("IntrmyAgt3", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+		el("RcvgAgt", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+		el("DlvrgAgt", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+		el("IssgAgt", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+		el("SttlmPlc", "BranchAndFinancialInstitutionIdentification4", 0, 1),
+		el("Prtry", "", 0, unbounded),
+	),
+	"RemittanceInformation5": seq(
+		el("Ustrd", "Max140Text", 0, unbounded),
+		el("Strd", "", 0, unbounded),
+	),
+}
+
+// accountStatementElements are shared by statement and report, they differ in minimal number of balances
+func accountStatementElements(additionalInfo string, minBalances int) []schemaElement {
+	return []schemaElement{
+		el("Id", "Max35Text", 1, 1),
+		el("ElctrncSeqNb", "DecimalNumber", 0, 1),
+		el("LglSeqNb", "DecimalNumber", 0, 1),
+		el("CreDtTm", "ISODateTime", 1, 1),
+		el("FrToDt", "DateTimePeriodDetails", 0, 1),
+		el("CpyDplctInd", "", 0, 1),
+		el("RptgSrc", "", 0, 1),
+		el("Acct", "CashAccount20", 1, 1),
+		el("RltdAcct", "", 0, 1),
+		el("Intrst", "", 0, unbounded),
+		el("Bal", "CashBalance3", minBalances, unbounded),
+		el("TxsSummry", "TotalTransactions2", 0, 1),
+		el("Ntry", "ReportEntry2", 0, unbounded),
+		el(additionalInfo, "Max500Text", 0, 1),
+	}
+}
+
+var simpleTypes = map[string]func(string) bool{
+	"Max35Text":                    maxText(35),
+	"Max70Text":                    maxText(70),
+	"Max140Text":                   maxText(140),
+	"Max500Text":                   maxText(500),
+	"ISODateTime":                  pattern(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`),
+	"ISODate":                      pattern(`^\d{4}-\d{2}-\d{2}$`),
+	"IBAN2007Identifier":           pattern(`^[A-Z]{2}[0-9]{2}[a-zA-Z0-9]{1,30}$`),
+	"BICIdentifier":                pattern(`^[A-Z]{6}[A-Z2-9][A-NP-Z0-9]([A-Z0-9]{3})?$`),
+	"ActiveOrHistoricCurrencyCode": pattern(`^[A-Z]{3}$`),
+	"ActiveOrHistoricCurrencyAndAmount": func(s string) bool {
+		return regexp.MustCompile(`^\d{1,13}(\.\d{1,5})?$`).MatchString(s)
+	},
+	"DecimalNumber":     pattern(`^\d{1,13}(\.\d{1,17})?$`),
+	"Max15NumericText":  pattern(`^[0-9]{1,15}$`),
+	"CreditDebitCode":   enumeration("CRDT", "DBIT"),
+	"EntryStatus2Code":  enumeration("BOOK", "PDNG", "INFO"),
+	"BalanceType12Code": enumeration("XPCD", "OPAV", "ITAV", "CLAV", "FWAV", "CLBD", "ITBD", "OPBD", "PRCD", "INFO"),
+	"ExternalCode":      maxText(4),
+}
+
+func maxText(length int) func(string) bool {
+	return func(s string) bool {
+		n := utf8.RuneCountInString(s)
+		return n >= 1 && n <= length
+	}
+}
+
+func pattern(expr string) func(string) bool {
+	re := regexp.MustCompile(expr)
+	return re.MatchString
+}
+
+func enumeration(values ...string) func(string) bool {
+	return func(s string) bool {
+		for _, v := range values {
+			if s == v {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// validateCamt checks the document against the schema of the message type
+func validateCamt(content []byte, messageType, namespace string) []string {
+	root, err := parseXMLTree(content)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	errs := []string{}
+	if root.name != "Document" {
+		errs = append(errs, fmt.Sprintf("root element is %s, Document expected", root.name))
+	}
+	if root.attrs["xmlns"] != namespace {
+		errs = append(errs, fmt.Sprintf("namespace is %q, %q expected", root.attrs["xmlns"], namespace))
+	}
+
+	return append(errs, validateNode(root, "Document."+messageType, "/Document")...)
+}
+
+func validateNode(node *xmlNode, typeName, path string) []string {
+	if check, ok := simpleTypes[typeName]; ok {
+		if len(node.children) > 0 {
+			return []string{fmt.Sprintf("%s: simple type %s must not have children", path, typeName)}
+		}
+		errs := []string{}
+		if !check(node.text) {
+			errs = append(errs, fmt.Sprintf("%s: %q is not valid %s", path, node.text, typeName))
+		}
+		if typeName == "ActiveOrHistoricCurrencyAndAmount" && !simpleTypes["ActiveOrHistoricCurrencyCode"](node.attrs["Ccy"]) {
+			errs = append(errs, fmt.Sprintf("%s: Ccy attribute %q is not valid", path, node.attrs["Ccy"]))
+		}
+		return errs
+	}
+	complexType, ok := camtSchema[typeName]
+	if !ok {
+		return []string{fmt.Sprintf("%s: type %q is not supported by the test schema", path, typeName)}
+	}
+	if strings.TrimSpace(node.text) != "" {
+		return []string{fmt.Sprintf("%s: complex type %s must not have text", path, typeName)}
+	}
+
+	errs := []string{}
+	if complexType.choice {
+		if len(node.children) != 1 {
+			return []string{fmt.Sprintf("%s: exactly one element of choice %s expected, got %d", path, typeName, len(node.children))}
+		}
+		for _, e := range complexType.elements {
+			if e.name == node.children[0].name {
+				return validateNode(node.children[0], e.typeName, path+"/"+e.name)
+			}
+		}
+		return []string{fmt.Sprintf("%s: unexpected element %s", path, node.children[0].name)}
+	}
+
+	i := 0
+	for _, e := range complexType.elements {
+		count := 0
+		for i < len(node.children) && node.children[i].name == e.name {
+			errs = append(errs, validateNode(node.children[i], e.typeName, path+"/"+e.name)...)
+			count++
+			i++
+		}
+		if count < e.min {
+			errs = append(errs, fmt.Sprintf("%s: element %s is required", path, e.name))
+		}
+		if e.max != unbounded && count > e.max {
+			errs = append(errs, fmt.Sprintf("%s: element %s occurs %d times, at most %d allowed", path, e.name, count, e.max))
+		}
+	}
+	if i < len(node.children) {
+		errs = append(errs, fmt.Sprintf("%s: unexpected element %s", path, node.children[i].name))
+	}
+
+	return errs
+}
+
+func parseXMLTree(content []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(strings.NewReader(string(content)))
+	stack := []*xmlNode{}
+	var root *xmlNode
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local, attrs: map[string]string{}}
+			for _, attr := range t.Attr {
+				name := attr.Name.Local
+				if attr.Name.Space != "" {
+					name = attr.Name.Space + ":" + name
+				}
+				node.attrs[name] = attr.Value
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("empty document")
+	}
+
+	return root, nil
+}
@@ -1,37 +1,219 @@
 package qonto
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
-	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
+var levelNames = map[Level]string{
+	LEVEL_DEBUG: "debug",
+	LEVEL_INFO:  "info",
+	LEVEL_WARN:  "warn",
+	LEVEL_ERROR: "error",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return strconv.Itoa(int(l))
+}
+
+// ParseLevel parses level name, empty name means info
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return LEVEL_INFO, nil
+	}
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, must be one of debug, info, warn, error", name)
+}
+
+// ParseFormat parses format name, empty name means text
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case "", FORMAT_TEXT:
+		return FORMAT_TEXT, nil
+	case FORMAT_JSON:
+		return FORMAT_JSON, nil
+	}
+	return "", fmt.Errorf("unknown log format %q, must be one of text, json", name)
+}
+
 type instanceLogger struct {
-	stdLogger *log.Logger
-	instance  string
-	out       io.Writer
+	// mu is shared by the logger and its descendants, so entries written to the same output do not interleave
+	mu       *sync.Mutex
+	out      io.Writer
+	instance string
+	level    Level
+	format   Format
+	fields   []interface{}
+	now      func() time.Time
+}
+
+// NewInstanceLogger create new logger with a given instance name,
+// it writes entries of info level and above as text
+func NewInstanceLogger(out io.Writer, instance string) *instanceLogger {
+	return &instanceLogger{
+		mu:       &sync.Mutex{},
+		out:      out,
+		instance: instance,
+		level:    LEVEL_INFO,
+		format:   FORMAT_TEXT,
+		now:      time.Now,
+	}
+}
+
+// WithLevel sets minimal level of written entries
+func (il *instanceLogger) WithLevel(level Level) *instanceLogger {
+	il.level = level
+	return il
+}
+
+// WithFormat sets encoding of entries
+func (il *instanceLogger) WithFormat(format Format) *instanceLogger {
+	il.format = format
+	return il
+}
+
+// Debug implements Debug method of Logger interface
+func (il *instanceLogger) Debug(msg string, keyvals ...interface{}) {
+	il.log(LEVEL_DEBUG, msg, keyvals)
 }
 
 // Info implements Info method of Logger interface
-func (il *instanceLogger) Info(msg string, args ...interface{}) {
-	il.stdLogger.Printf(msg, args...)
+func (il *instanceLogger) Info(msg string, keyvals ...interface{}) {
+	il.log(LEVEL_INFO, msg, keyvals)
+}
+
+// Warn implements Warn method of Logger interface
+func (il *instanceLogger) Warn(msg string, keyvals ...interface{}) {
+	il.log(LEVEL_WARN, msg, keyvals)
 }
 
-// Error implements Info method of Logger interface
-func (il *instanceLogger) Error(msg string, args ...interface{}) {
-	il.stdLogger.Printf("ERROR: "+msg, args...)
+// Error implements Error method of Logger interface
+func (il *instanceLogger) Error(msg string, keyvals ...interface{}) {
+	il.log(LEVEL_ERROR, msg, keyvals)
 }
 
-// SubLogger implements interface
+// With implements With method of Logger interface
+func (il *instanceLogger) With(keyvals ...interface{}) Logger {
+	child := *il
+	child.fields = append(append(make([]interface{}, 0, len(il.fields)+len(keyvals)), il.fields...), keyvals...)
+	return &child
+}
+
+// SubLogger implements Logger interface
 func (il *instanceLogger) SubLogger(name string) Logger {
-	return NewInstanceLogger(il.out, il.instance+":"+name)
+	child := *il
+	child.instance = il.instance + ":" + name
+	return &child
 }
 
-// NewInstanceLogger create new logger with a given instance name
-func NewInstanceLogger(out io.Writer, instance string) *instanceLogger {
-	return &instanceLogger{
-		instance:  instance,
-		out:       out,
-		stdLogger: log.New(out, "["+instance+"] ", log.Flags()|log.Lmsgprefix),
+func (il *instanceLogger) log(level Level, msg string, keyvals []interface{}) {
+	if level < il.level {
+		return
+	}
+
+	keyvals = append(append([]interface{}{
+		"time", il.now().UTC().Format(time.RFC3339Nano),
+		"level", level.String(),
+		"logger", il.instance,
+		"msg", RedactText(msg),
+	}, il.fields...), keyvals...)
+	// a value without key is still written
+	if len(keyvals)%2 != 0 {
+		keyvals = append(keyvals[:len(keyvals)-1], "EXTRA", keyvals[len(keyvals)-1])
+	}
+	// sensitive data never reaches the output
+	for i := 8; i < len(keyvals); i += 2 {
+		keyvals[i+1] = redactField(fmt.Sprint(keyvals[i]), keyvals[i+1])
 	}
 
+	buf := &bytes.Buffer{}
+	if il.format == FORMAT_JSON {
+		encodeJSON(buf, keyvals)
+	} else {
+		encodeText(buf, keyvals)
+	}
+	buf.WriteByte('\n')
+
+	il.mu.Lock()
+	defer il.mu.Unlock()
+	il.out.Write(buf.Bytes())
+}
+
+// fieldValue converts errors and stringers to strings, so they are readable in both formats
+func fieldValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return value
+}
+
+func encodeJSON(buf *bytes.Buffer, keyvals []interface{}) {
+	buf.WriteByte('{')
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(keyvals[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(keyvals[i+1])
+		if err != nil {
+			value, _ = json.Marshal(fmt.Sprint(keyvals[i+1]))
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+}
+
+func encodeText(buf *bytes.Buffer, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(fmt.Sprint(keyvals[i]))
+		buf.WriteByte('=')
+		value := fmt.Sprint(keyvals[i+1])
+		if value == "" || strings.ContainsAny(value, " =\"\n\t") {
+			value = strconv.Quote(value)
+		}
+		buf.WriteString(value)
+	}
+}
+
+type loggerKey struct{}
+
+// defaultLogger is used when there is no logger in the context
+var defaultLogger Logger = NewInstanceLogger(os.Stderr, "Qonto")
+
+// ContextWithLogger returns a copy of the context carrying the logger
+func ContextWithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns logger bound to the context, e.g. with fields of the request being handled
+func LoggerFromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(Logger); ok {
+		return logger
+	}
+	return defaultLogger
 }
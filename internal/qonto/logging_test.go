@@ -0,0 +1,120 @@
+package qonto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLogger(buf *bytes.Buffer) *instanceLogger {
+	logger := NewInstanceLogger(buf, "Qonto")
+	logger.now = func() time.Time { return time.Date(2022, 6, 6, 10, 30, 0, 0, time.UTC) }
+	return logger
+}
+
+func TestInstanceLogger(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   Format
+		level    Level
+		log      func(Logger)
+		expected string
+	}{
+		{
+			name:   "text",
+			format: FORMAT_TEXT,
+			level:  LEVEL_INFO,
+			log: func(l Logger) {
+				l.Info("loaded sanctions list", "entries", 42, "file", "/etc/qonto/sanctions list.csv")
+			},
+			expected: `time=2022-06-06T10:30:00Z level=info logger=Qonto msg="loaded sanctions list" entries=42 file="/etc/qonto/sanctions list.csv"` + "\n",
+		},
+		{
+			name:   "json",
+			format: FORMAT_JSON,
+			level:  LEVEL_INFO,
+			log: func(l Logger) {
+				l.Error("request failed", "error", errors.New(`"quoted" failure`), "timeout", 10*time.Second, "rows", 3)
+			},
+			expected: `{"time":"2022-06-06T10:30:00Z","level":"error","logger":"Qonto","msg":"request failed","error":"\"quoted\" failure","timeout":"10s","rows":3}` + "\n",
+		},
+		{
+			name:   "levels below the logger level are dropped",
+			format: FORMAT_TEXT,
+			level:  LEVEL_WARN,
+			log: func(l Logger) {
+				l.Debug("debug")
+				l.Info("info")
+				l.Warn("warn")
+				l.Error("error")
+			},
+			expected: "time=2022-06-06T10:30:00Z level=warn logger=Qonto msg=warn\n" +
+				"time=2022-06-06T10:30:00Z level=error logger=Qonto msg=error\n",
+		},
+		{
+			name:   "sublogger and fields",
+			format: FORMAT_JSON,
+			level:  LEVEL_DEBUG,
+			log: func(l Logger) {
+				sub := l.SubLogger("http").With("request_id", "abc")
+				sub.With("route", "/v1/transfers").Debug("handled")
+				sub.Info("finished", "status", 201)
+				l.Info("untouched")
+			},
+			expected: `{"time":"2022-06-06T10:30:00Z","level":"debug","logger":"Qonto:http","msg":"handled","request_id":"abc","route":"/v1/transfers"}` + "\n" +
+				`{"time":"2022-06-06T10:30:00Z","level":"info","logger":"Qonto:http","msg":"finished","request_id":"abc","status":201}` + "\n" +
+				`{"time":"2022-06-06T10:30:00Z","level":"info","logger":"Qonto","msg":"untouched"}` + "\n",
+		},
+		{
+			name:   "value without key",
+			format: FORMAT_TEXT,
+			level:  LEVEL_INFO,
+			log: func(l Logger) {
+				l.Info("odd", "rows", 1, "dangling")
+			},
+			expected: "time=2022-06-06T10:30:00Z level=info logger=Qonto msg=odd rows=1 EXTRA=dangling\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			tc.log(newTestLogger(buf).WithFormat(tc.format).WithLevel(tc.level))
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	level, err := ParseLevel("")
+	require.NoError(t, err)
+	assert.Equal(t, LEVEL_INFO, level)
+	level, err = ParseLevel("WARN")
+	require.NoError(t, err)
+	assert.Equal(t, LEVEL_WARN, level)
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+
+	format, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FORMAT_TEXT, format)
+	format, err = ParseFormat("json")
+	require.NoError(t, err)
+	assert.Equal(t, FORMAT_JSON, format)
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+func TestLoggerFromContext(t *testing.T) {
+	assert.Equal(t, defaultLogger, LoggerFromContext(context.Background()))
+
+	buf := &bytes.Buffer{}
+	logger := newTestLogger(buf).With("request_id", "abc")
+	LoggerFromContext(ContextWithLogger(context.Background(), logger)).Info("handled")
+	assert.Equal(t, "time=2022-06-06T10:30:00Z level=info logger=Qonto msg=handled request_id=abc\n", buf.String())
+}
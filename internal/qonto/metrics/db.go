@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"database/sql"
+	"time"
+)
+
+// DBMetrics observes database transactions, their retries and connection pool
+type DBMetrics struct {
+	duration  *HistogramVec
+	rollbacks *CounterVec
+	retries   *CounterVec
+	exhausted *CounterVec
+}
+
+// NewDBMetrics registers metrics of the pool, stats is called on every scrape, e.g. (*sql.DB).Stats
+func NewDBMetrics(registry *Registry, stats func() sql.DBStats) *DBMetrics {
+	registry.NewGaugeFunc("qonto_db_max_open_connections", "Maximum number of open connections to the database.",
+		func() float64 { return float64(stats().MaxOpenConnections) })
+	registry.NewGaugeFunc("qonto_db_open_connections", "Number of established connections, both in use and idle.",
+		func() float64 { return float64(stats().OpenConnections) })
+	registry.NewGaugeFunc("qonto_db_in_use_connections", "Number of connections currently in use.",
+		func() float64 { return float64(stats().InUse) })
+	registry.NewGaugeFunc("qonto_db_idle_connections", "Number of idle connections.",
+		func() float64 { return float64(stats().Idle) })
+	registry.NewCounterFunc("qonto_db_wait_count_total", "Number of connections waited for.",
+		func() float64 { return float64(stats().WaitCount) })
+	registry.NewCounterFunc("qonto_db_wait_duration_seconds_total", "Time blocked waiting for a new connection.",
+		func() float64 { return stats().WaitDuration.Seconds() })
+	registry.NewCounterFunc("qonto_db_max_idle_closed_total", "Number of connections closed due to SetMaxIdleConns.",
+		func() float64 { return float64(stats().MaxIdleClosed) })
+	registry.NewCounterFunc("qonto_db_max_idle_time_closed_total", "Number of connections closed due to SetConnMaxIdleTime.",
+		func() float64 { return float64(stats().MaxIdleTimeClosed) })
+	registry.NewCounterFunc("qonto_db_max_lifetime_closed_total", "Number of connections closed due to SetConnMaxLifetime.",
+		func() float64 { return float64(stats().MaxLifetimeClosed) })
+
+	return &DBMetrics{
+		duration: registry.NewHistogramVec("qonto_db_transaction_duration_seconds",
+			"Duration of database transactions from begin to commit or rollback by outcome.", DurationBuckets, "outcome"),
+		rollbacks: registry.NewCounterVec("qonto_db_transaction_rollbacks_total",
+			"Number of rolled back database transactions."),
+		retries: registry.NewCounterVec("qonto_db_transaction_retries_total",
+			"Number of database transactions run again by reason.", "reason"),
+		exhausted: registry.NewCounterVec("qonto_db_transaction_retries_exhausted_total",
+			"Number of database transactions failed with retriable error after the last attempt by reason.", "reason"),
+	}
+}
+
+// ObserveTransaction records finished transaction, err is the reason of rollback or failed commit
+func (m *DBMetrics) ObserveTransaction(duration time.Duration, committed bool, err error) {
+	outcome := "commit"
+	switch {
+	case !committed:
+		outcome = "rollback"
+		m.rollbacks.Inc()
+	case err != nil:
+		outcome = "commit_error"
+	}
+	m.duration.Observe(duration.Seconds(), outcome)
+}
+
+// ObserveRetry implements storage.RetryObserver interface
+func (m *DBMetrics) ObserveRetry(reason string) {
+	m.retries.Inc(reason)
+}
+
+// ObserveRetriesExhausted implements storage.RetryObserver interface
+func (m *DBMetrics) ObserveRetriesExhausted(reason string) {
+	m.exhausted.Inc(reason)
+}
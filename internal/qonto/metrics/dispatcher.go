@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/dispatch"
+)
+
+// DispatcherMetrics observes queues of per-account dispatcher
+type DispatcherMetrics struct {
+	rejected *CounterVec
+	wait     *HistogramVec
+}
+
+// NewDispatcherMetrics registers metrics of the queues, stats is called on every scrape, e.g. (*dispatch.Dispatcher).Stats
+func NewDispatcherMetrics(registry *Registry, stats func() dispatch.Stats) *DispatcherMetrics {
+	registry.NewGaugeFunc("qonto_dispatcher_queued_requests", "Number of requests waiting in all account queues.",
+		func() float64 { return float64(stats().Queued) })
+	registry.NewGaugeFunc("qonto_dispatcher_max_queue_depth", "Number of requests waiting in the longest account queue.",
+		func() float64 { return float64(stats().MaxQueued) })
+	registry.NewGaugeFunc("qonto_dispatcher_in_progress_requests", "Number of requests being processed by workers.",
+		func() float64 { return float64(stats().InProgress) })
+	registry.NewGaugeFunc("qonto_dispatcher_accounts", "Number of account queues alive.",
+		func() float64 { return float64(stats().Accounts) })
+
+	return &DispatcherMetrics{
+		rejected: registry.NewCounterVec("qonto_dispatcher_rejected_requests_total",
+			"Number of requests rejected because their account queue is full."),
+		wait: registry.NewHistogramVec("qonto_dispatcher_wait_duration_seconds",
+			"Time requests spend in account queue before processing.", DurationBuckets),
+	}
+}
+
+// ObserveRejected implements dispatch.Observer interface
+func (m *DispatcherMetrics) ObserveRejected() {
+	m.rejected.Inc()
+}
+
+// ObserveWait implements dispatch.Observer interface
+func (m *DispatcherMetrics) ObserveWait(wait time.Duration) {
+	m.wait.Observe(wait.Seconds())
+}
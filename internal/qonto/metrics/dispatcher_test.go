@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/dispatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDispatcherMetrics(t *testing.T) {
+	registry := NewRegistry()
+	dispatcher := dispatch.NewDispatcher(&mockManager{result: &core.Result{}}, 2, 3)
+	dispatcherMetrics := NewDispatcherMetrics(registry, dispatcher.Stats)
+	dispatcher.WithObserver(dispatcherMetrics)
+
+	_, err := dispatcher.ProcessTransfers(context.Background(), &core.Request{
+		Party:           core.Party{IBAN: "FR10474608000002006107XXXXX"},
+		CreditTransfers: core.TransferList{{Amount: core.Amount{Cents: 100}, Currency: "EUR"}},
+	})
+	require.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	require.NoError(t, registry.Write(out))
+	assert.Contains(t, out.String(), "qonto_dispatcher_queued_requests 0\n")
+	assert.Contains(t, out.String(), "qonto_dispatcher_accounts 1\n")
+	assert.Contains(t, out.String(), "qonto_dispatcher_wait_duration_seconds_count 1\n")
+	assert.Equal(t, float64(0), dispatcherMetrics.rejected.Value())
+	dispatcher.Close()
+}
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+)
+
+// DurationBuckets are upper bounds in seconds of request and database transaction durations
+var DurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// HTTPMetrics counts requests and their durations per route
+type HTTPMetrics struct {
+	requests *CounterVec
+	duration *HistogramVec
+}
+
+func NewHTTPMetrics(registry *Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: registry.NewCounterVec("qonto_http_requests_total",
+			"Number of HTTP requests by method, route and status.", "method", "route", "status"),
+		duration: registry.NewHistogramVec("qonto_http_request_duration_seconds",
+			"Duration of HTTP requests by method and route.", DurationBuckets, "method", "route"),
+	}
+}
+
+// Middleware observes requests handled by chi router, route pattern is used as a label
+// so that path parameters do not make series explode
+func (m *HTTPMetrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			// nothing is written, so net/http responds with 200
+			status = http.StatusOK
+		}
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		m.requests.Inc(r.Method, route, strconv.Itoa(status))
+		m.duration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
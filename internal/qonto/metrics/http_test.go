@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPMetricsMiddleware(t *testing.T) {
+	registry := NewRegistry()
+	httpMetrics := NewHTTPMetrics(registry)
+	router := chi.NewRouter()
+	router.Use(httpMetrics.Middleware)
+	router.Post("/v1/transfers", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+	router.Get("/v1/accounts/{iban}/transactions/export", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id\n"))
+		_, flushable := w.(http.Flusher)
+		assert.True(t, flushable, "streaming handlers must be able to flush")
+	})
+	router.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {})
+
+	requests := []struct {
+		method, url string
+	}{
+		{http.MethodPost, "/v1/transfers"},
+		{http.MethodPost, "/v1/transfers"},
+		{http.MethodGet, "/v1/accounts/FR10474608000002006107XXXXX/transactions/export"},
+		{http.MethodGet, "/v1/accounts/DE9935420810036209081725212/transactions/export"},
+		{http.MethodGet, "/unknown"},
+		{http.MethodGet, "/healthz"},
+	}
+	for _, request := range requests {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(request.method, "http://localhost"+request.url, nil))
+	}
+
+	assert.Equal(t, float64(2), httpMetrics.requests.Value(http.MethodPost, "/v1/transfers", "201"))
+	assert.Equal(t, float64(2), httpMetrics.requests.Value(http.MethodGet, "/v1/accounts/{iban}/transactions/export", "200"))
+	assert.Equal(t, float64(1), httpMetrics.requests.Value(http.MethodGet, "unmatched", "404"))
+	assert.Equal(t, float64(1), httpMetrics.requests.Value(http.MethodGet, "/healthz", "200"), "nothing written is 200")
+	assert.Equal(t, uint64(2), httpMetrics.duration.Count(http.MethodPost, "/v1/transfers"))
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import "github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
+
+type (
+	// ThrottleMetrics counts requests rejected by rate limiters
+	ThrottleMetrics struct {
+		throttled *CounterVec
+	}
+
+	// scopedThrottleMetrics counts requests rejected by the limiter of the scope
+	scopedThrottleMetrics struct {
+		metrics *ThrottleMetrics
+		scope   string
+	}
+)
+
+func NewThrottleMetrics(registry *Registry) *ThrottleMetrics {
+	return &ThrottleMetrics{
+		throttled: registry.NewCounterVec("qonto_throttled_requests_total",
+			"Number of requests rejected by rate limiter by limiter scope and reason.", "scope", "reason"),
+	}
+}
+
+// Scope returns observer of the limiter of the scope, e.g. ratelimit.SCOPE_CLIENT
+func (tm *ThrottleMetrics) Scope(scope string) ratelimit.Observer {
+	return &scopedThrottleMetrics{metrics: tm, scope: scope}
+}
+
+// ObserveThrottled implements ratelimit.Observer interface
+func (stm *scopedThrottleMetrics) ObserveThrottled(reason string) {
+	stm.metrics.throttled.Inc(stm.scope, reason)
+}
@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottleMetrics(t *testing.T) {
+	throttleMetrics := NewThrottleMetrics(NewRegistry())
+	limiter := ratelimit.NewLimiter(ratelimit.Limits{Rate: 0.1, Burst: 1, MaxInFlight: 1}).WithObserver(throttleMetrics.Scope(ratelimit.SCOPE_ACCOUNT))
+
+	release, err := limiter.Acquire("ACME Corp")
+	require.NoError(t, err)
+	_, err = limiter.Acquire("ACME Corp")
+	require.Error(t, err)
+	release()
+	_, err = limiter.Acquire("ACME Corp")
+	require.Error(t, err)
+
+	assert.Equal(t, float64(1), throttleMetrics.throttled.Value(ratelimit.SCOPE_ACCOUNT, ratelimit.REASON_CONCURRENCY))
+	assert.Equal(t, float64(1), throttleMetrics.throttled.Value(ratelimit.SCOPE_ACCOUNT, ratelimit.REASON_RATE))
+	assert.Equal(t, float64(0), throttleMetrics.throttled.Value(ratelimit.SCOPE_CLIENT, ratelimit.REASON_RATE))
+}
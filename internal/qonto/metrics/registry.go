@@ -0,0 +1,297 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+)
+
+// ContentType is the media type of Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type (
+	// Registry holds metrics and writes them in Prometheus text exposition format
+	Registry struct {
+		mu         sync.Mutex
+		collectors []collector
+	}
+
+	collector interface {
+		write(w *bufio.Writer)
+	}
+
+	// CounterVec is a counter partitioned by labels
+	CounterVec struct {
+		desc
+		mu     sync.Mutex
+		series map[string]*counterSeries
+	}
+
+	counterSeries struct {
+		labelValues []string
+		value       float64
+	}
+
+	// HistogramVec is a histogram partitioned by labels
+	HistogramVec struct {
+		desc
+		buckets []float64
+		mu      sync.Mutex
+		series  map[string]*histogramSeries
+	}
+
+	histogramSeries struct {
+		labelValues []string
+		// counts are per bucket, they are accumulated on write
+		counts []uint64
+		count  uint64
+		sum    float64
+	}
+
+	// funcMetric reads its value on every scrape
+	funcMetric struct {
+		desc
+		kind string
+		f    func() float64
+	}
+
+	desc struct {
+		name   string
+		help   string
+		labels []string
+	}
+)
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// NewCounterVec registers counter with the label names
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		desc:   desc{name: name, help: help, labels: labels},
+		series: map[string]*counterSeries{},
+	}
+	r.register(c)
+	return c
+}
+
+// NewHistogramVec registers histogram with the upper bounds of buckets in increasing order and the label names
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		series:  map[string]*histogramSeries{},
+	}
+	r.register(h)
+	return h
+}
+
+// NewGaugeFunc registers gauge which value is read by f on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, f func() float64) {
+	r.register(&funcMetric{desc: desc{name: name, help: help}, kind: "gauge", f: f})
+}
+
+// NewCounterFunc registers counter which value is read by f on every scrape, f must never decrease
+func (r *Registry) NewCounterFunc(name, help string, f func() float64) {
+	r.register(&funcMetric{desc: desc{name: name, help: help}, kind: "counter", f: f})
+}
+
+// Write writes all metrics in registration order
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := r.collectors
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves metrics to Prometheus scraper
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	if err := r.Write(w); err != nil {
+		qonto.LoggerFromContext(req.Context()).Error("error writing metrics", "error", err)
+	}
+}
+
+// Inc increments counter of the label values given in the order of label names
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds non-negative value to counter of the label values
+func (c *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &counterSeries{labelValues: labelValues}
+		c.series[key] = s
+	}
+	s.value += value
+}
+
+// Value returns current value of counter of the label values
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.series[c.key(labelValues)]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.writeHeader(w, "counter")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		c.writeSample(w, c.name, s.labelValues, "", "", s.value)
+	}
+}
+
+// Observe adds value to histogram of the label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labelValues: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+// Count returns number of observations of the label values
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.series[h.key(labelValues)]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.writeHeader(w, "histogram")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			h.writeSample(w, h.name+"_bucket", s.labelValues, "le", formatFloat(bound), float64(cumulative))
+		}
+		h.writeSample(w, h.name+"_bucket", s.labelValues, "le", "+Inf", float64(s.count))
+		h.writeSample(w, h.name+"_sum", s.labelValues, "", "", s.sum)
+		h.writeSample(w, h.name+"_count", s.labelValues, "", "", float64(s.count))
+	}
+}
+
+func (m *funcMetric) write(w *bufio.Writer) {
+	m.writeHeader(w, m.kind)
+	m.writeSample(w, m.name, nil, "", "", m.f())
+}
+
+// key identifies series by label values, missing values are empty and extra ones are ignored
+func (d *desc) key(labelValues []string) string {
+	if len(labelValues) != len(d.labels) {
+		values := make([]string, len(d.labels))
+		copy(values, labelValues)
+		labelValues = values
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func (d *desc) writeHeader(w *bufio.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, kind)
+}
+
+// writeSample writes a line of the sample, extra label is appended to labels of the metric if set
+func (d *desc) writeSample(w *bufio.Writer, name string, labelValues []string, extraLabel, extraValue string, value float64) {
+	w.WriteString(name)
+	pairs := make([]string, 0, len(d.labels)+1)
+	for i, label := range d.labels {
+		labelValue := ""
+		if i < len(labelValues) {
+			labelValue = labelValues[i]
+		}
+		pairs = append(pairs, label+`="`+escapeLabelValue(labelValue)+`"`)
+	}
+	if extraLabel != "" {
+		pairs = append(pairs, extraLabel+`="`+extraValue+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch series := m.(type) {
+	case map[string]*counterSeries:
+		for key := range series {
+			keys = append(keys, key)
+		}
+	case map[string]*histogramSeries:
+		for key := range series {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("requests_total", "Number of requests.", "method", "path")
+	histogram := registry.NewHistogramVec("duration_seconds", "Duration\nof requests.", []float64{0.1, 1}, "method")
+	registry.NewGaugeFunc("connections", "Open connections.", func() float64 { return 3 })
+	registry.NewCounterFunc("waits_total", "Waits.", func() float64 { return 0.5 })
+	unlabeled := registry.NewCounterVec("rollbacks_total", "Rollbacks.")
+
+	counter.Inc("POST", "/v1/transfers")
+	counter.Add(2, "POST", "/v1/transfers")
+	counter.Inc("GET", `/v1/"quoted"\path`)
+	counter.Add(-1, "GET", "/ignored")
+	histogram.Observe(0.05, "POST")
+	histogram.Observe(0.5, "POST")
+	histogram.Observe(2, "POST")
+	unlabeled.Inc()
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, registry.Write(buf))
+	assert.Equal(t, `# HELP requests_total Number of requests.
+# TYPE requests_total counter
+requests_total{method="GET",path="/v1/\"quoted\"\\path"} 1
+requests_total{method="POST",path="/v1/transfers"} 3
+# HELP duration_seconds Duration\nof requests.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{method="POST",le="0.1"} 1
+duration_seconds_bucket{method="POST",le="1"} 2
+duration_seconds_bucket{method="POST",le="+Inf"} 3
+duration_seconds_sum{method="POST"} 2.55
+duration_seconds_count{method="POST"} 3
+# HELP connections Open connections.
+# TYPE connections gauge
+connections 3
+# HELP waits_total Waits.
+# TYPE waits_total counter
+waits_total 0.5
+# HELP rollbacks_total Rollbacks.
+# TYPE rollbacks_total counter
+rollbacks_total 1
+`, buf.String())
+
+	assert.Equal(t, float64(3), counter.Value("POST", "/v1/transfers"))
+	assert.Equal(t, uint64(3), histogram.Count("POST"))
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGaugeFunc("infinite", "Infinite gauge.", func() float64 { return math.Inf(1) })
+
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.Equal(t, ContentType, w.Result().Header.Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "infinite +Inf\n")
+}
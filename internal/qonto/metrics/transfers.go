@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+var (
+	// BatchSizeBuckets are upper bounds of number of transfers per request
+	BatchSizeBuckets = []float64{1, 5, 10, 50, 100, 500, 1000, 5000, 10000, 50000}
+	// AmountBuckets are upper bounds of transfer amounts in currency units
+	AmountBuckets = []float64{10, 100, 1000, 10000, 100000, 1000000}
+)
+
+// transferManager observes outcomes of transfers processed by wrapped manager
+type transferManager struct {
+	next core.TransferManager
+	// reason names rejection cause, it must return a small set of values
+	reason func(error) string
+
+	transfers *CounterVec
+	requests  *CounterVec
+	batchSize *HistogramVec
+	amounts   *HistogramVec
+}
+
+// NewTransferManager wraps the manager, reason converts rejection errors to label values,
+// e.g. API error codes
+func NewTransferManager(next core.TransferManager, registry *Registry, reason func(error) string) *transferManager {
+	return &transferManager{
+		next:   next,
+		reason: reason,
+		transfers: registry.NewCounterVec("qonto_transfers_total",
+			"Number of processed transfers by status and rejection reason.", "status", "reason"),
+		requests: registry.NewCounterVec("qonto_transfer_requests_total",
+			"Number of processed bulk transfer requests by mode and outcome.", "mode", "outcome"),
+		batchSize: registry.NewHistogramVec("qonto_transfer_batch_size",
+			"Number of transfers per bulk request.", BatchSizeBuckets),
+		amounts: registry.NewHistogramVec("qonto_transfer_amount",
+			"Amounts of requested transfers by currency.", AmountBuckets, "currency"),
+	}
+}
+
+// ProcessTransfers implements core.TransferManager interface
+func (tm *transferManager) ProcessTransfers(ctx context.Context, request *core.Request) (*core.Result, error) {
+	tm.batchSize.Observe(float64(request.Len()))
+	// amounts are observed even if transfers cannot be read, processing reports the failure
+	_ = request.Each(func(i int, transfer core.Transfer) error {
+		tm.amounts.Observe(float64(transfer.Amount.Cents)/100, currencyLabel(transfer.Currency))
+		return nil
+	})
+
+	mode := string(request.Mode)
+	if mode == "" {
+		mode = string(core.MODE_ALL_OR_NOTHING)
+	}
+
+	result, err := tm.next.ProcessTransfers(ctx, request)
+	if err != nil {
+		// the whole request is rejected
+		tm.requests.Inc(mode, "rejected")
+		tm.transfers.Add(float64(request.Len()), string(core.TRANSFER_REJECTED), tm.reason(err))
+		return result, err
+	}
+
+	outcome := "accepted"
+	for i := 0; i < result.Len(); i++ {
+		transfer := result.Transfer(i)
+		if transfer.Status == core.TRANSFER_REJECTED {
+			outcome = "partially_accepted"
+			tm.transfers.Inc(string(core.TRANSFER_REJECTED), tm.reason(transfer.Err))
+			continue
+		}
+		tm.transfers.Inc(string(transfer.Status), "")
+	}
+	tm.requests.Inc(mode, outcome)
+
+	return result, nil
+}
+
+// currencyLabel collapses currencies which are not supported to "other", requests are observed before validation,
+// so clients must not be able to create new series by sending arbitrary currencies
+func currencyLabel(currency core.Currency) string {
+	if currency == core.CURRENCY_EURO {
+		return string(currency)
+	}
+	return "other"
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockManager struct {
+	result *core.Result
+	err    error
+}
+
+func (mm *mockManager) ProcessTransfers(ctx context.Context, request *core.Request) (*core.Result, error) {
+	return mm.result, mm.err
+}
+
+func reason(err error) string {
+	if errors.Is(err, core.ErrNotEnoughFunds) {
+		return "not_enough_funds"
+	}
+	return "internal_error"
+}
+
+func TestTransferManager(t *testing.T) {
+	request := &core.Request{
+		CreditTransfers: core.TransferList{
+			{Amount: core.Amount{Cents: 1450}, Currency: core.CURRENCY_EURO},
+			{Amount: core.Amount{Cents: 6123800}, Currency: core.CURRENCY_EURO},
+		},
+	}
+	bestEffort := &core.Request{CreditTransfers: request.CreditTransfers, Mode: core.MODE_BEST_EFFORT}
+
+	registry := NewRegistry()
+	manager := &mockManager{result: core.ResultOf(
+		core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+		core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+	)}
+	tm := NewTransferManager(manager, registry, reason)
+
+	_, err := tm.ProcessTransfers(context.Background(), request)
+	require.NoError(t, err)
+
+	manager.err = core.ErrNotEnoughFunds
+	_, err = tm.ProcessTransfers(context.Background(), request)
+	assert.Equal(t, core.ErrNotEnoughFunds, err)
+
+	manager.err = nil
+	manager.result = core.ResultOf(
+		core.TransferResult{Status: core.TRANSFER_ACCEPTED},
+		core.TransferResult{Status: core.TRANSFER_REJECTED, Err: core.ErrNotEnoughFunds},
+	)
+	_, err = tm.ProcessTransfers(context.Background(), bestEffort)
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(3), tm.transfers.Value("accepted", ""))
+	assert.Equal(t, float64(3), tm.transfers.Value("rejected", "not_enough_funds"))
+	assert.Equal(t, float64(1), tm.requests.Value("all_or_nothing", "accepted"))
+	assert.Equal(t, float64(1), tm.requests.Value("all_or_nothing", "rejected"))
+	assert.Equal(t, float64(1), tm.requests.Value("best_effort", "partially_accepted"))
+	assert.Equal(t, uint64(3), tm.batchSize.Count())
+	assert.Equal(t, uint64(6), tm.amounts.Count("EUR"))
+
+	manager.result = core.ResultOf(
+		core.TransferResult{Status: core.TRANSFER_REJECTED, Err: core.ErrInvalidCurrency},
+		core.TransferResult{Status: core.TRANSFER_REJECTED, Err: core.ErrInvalidCurrency},
+	)
+	_, err = tm.ProcessTransfers(context.Background(), &core.Request{
+		CreditTransfers: core.TransferList{
+			{Amount: core.Amount{Cents: 1450}, Currency: "USD"},
+			{Amount: core.Amount{Cents: 1450}, Currency: "\"}; DROP TABLE"},
+		},
+		Mode: core.MODE_BEST_EFFORT,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), tm.amounts.Count("other"))
+	assert.Zero(t, tm.amounts.Count("USD"))
+}
+
+func TestDBMetrics(t *testing.T) {
+	registry := NewRegistry()
+	dbMetrics := NewDBMetrics(registry, func() sql.DBStats {
+		return sql.DBStats{MaxOpenConnections: 30, OpenConnections: 4, InUse: 1, Idle: 3, WaitDuration: 1500 * time.Millisecond}
+	})
+
+	dbMetrics.ObserveTransaction(10*time.Millisecond, true, nil)
+	dbMetrics.ObserveTransaction(20*time.Millisecond, false, core.ErrNotEnoughFunds)
+	dbMetrics.ObserveTransaction(30*time.Millisecond, true, errors.New("connection lost"))
+
+	assert.Equal(t, float64(1), dbMetrics.rollbacks.Value())
+	assert.Equal(t, uint64(1), dbMetrics.duration.Count("commit"))
+	assert.Equal(t, uint64(1), dbMetrics.duration.Count("rollback"))
+	assert.Equal(t, uint64(1), dbMetrics.duration.Count("commit_error"))
+
+	dbMetrics.ObserveRetry(storage.RETRY_REASON_DEADLOCK)
+	dbMetrics.ObserveRetry(storage.RETRY_REASON_DEADLOCK)
+	dbMetrics.ObserveRetriesExhausted(storage.RETRY_REASON_LOCK_WAIT_TIMEOUT)
+	assert.Equal(t, float64(2), dbMetrics.retries.Value(storage.RETRY_REASON_DEADLOCK))
+	assert.Equal(t, float64(1), dbMetrics.exhausted.Value(storage.RETRY_REASON_LOCK_WAIT_TIMEOUT))
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, registry.Write(buf))
+	assert.Contains(t, buf.String(), "qonto_db_max_open_connections 30\n")
+	assert.Contains(t, buf.String(), "qonto_db_idle_connections 3\n")
+	assert.Contains(t, buf.String(), "qonto_db_wait_duration_seconds_total 1.5\n")
+}
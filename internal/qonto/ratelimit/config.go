@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseOverrides parses limits of keys given as comma separated "key=rate:burst:max_in_flight" list,
+// e.g. "ACME Corp=50:100:10,Globex=1:2:1". Empty string means no overrides
+func ParseOverrides(value string) (map[string]Limits, error) {
+	overrides := map[string]Limits{}
+	if strings.TrimSpace(value) == "" {
+		return overrides, nil
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		key, spec, ok := cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid limits override %q, must be key=rate:burst:max_in_flight", item)
+		}
+		parts := strings.Split(strings.TrimSpace(spec), ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid limits of %q: %q, must be rate:burst:max_in_flight", key, spec)
+		}
+		rate, err := strconv.ParseFloat(parts[0], 64)
+		if err != nil || rate < 0 {
+			return nil, fmt.Errorf("invalid rate of %q: %q", key, parts[0])
+		}
+		burst, err := strconv.Atoi(parts[1])
+		if err != nil || burst < 0 {
+			return nil, fmt.Errorf("invalid burst of %q: %q", key, parts[1])
+		}
+		maxInFlight, err := strconv.Atoi(parts[2])
+		if err != nil || maxInFlight < 0 {
+			return nil, fmt.Errorf("invalid max in flight of %q: %q", key, parts[2])
+		}
+		overrides[key] = Limits{Rate: rate, Burst: burst, MaxInFlight: maxInFlight}
+	}
+
+	return overrides, nil
+}
+
+// cut is strings.Cut which is not available in Go 1.17
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOverrides(t *testing.T) {
+	testCases := []struct {
+		name          string
+		value         string
+		expected      map[string]Limits
+		expectedError bool
+	}{
+		{name: "empty", value: "", expected: map[string]Limits{}},
+		{
+			name:  "several organizations",
+			value: "ACME Corp=50:100:10, Globex=0.5:1:0",
+			expected: map[string]Limits{
+				"ACME Corp": {Rate: 50, Burst: 100, MaxInFlight: 10},
+				"Globex":    {Rate: 0.5, Burst: 1},
+			},
+		},
+		{name: "missing limits", value: "ACME Corp", expectedError: true},
+		{name: "missing key", value: "=1:1:1", expectedError: true},
+		{name: "not enough limits", value: "ACME Corp=1:1", expectedError: true},
+		{name: "negative rate", value: "ACME Corp=-1:1:1", expectedError: true},
+		{name: "fractional burst", value: "ACME Corp=1:1.5:1", expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			overrides, err := ParseOverrides(tc.value)
+			if tc.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, overrides)
+		})
+	}
+}
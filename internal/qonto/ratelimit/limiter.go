@@ -0,0 +1,233 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	ErrRateLimited        = Error("rate limit exceeded")
+	ErrConcurrencyLimited = Error("too many requests in flight")
+)
+
+// reasons of throttling reported to observer
+const (
+	REASON_RATE        = "rate"
+	REASON_CONCURRENCY = "concurrency"
+)
+
+// scopes of limiters, requests are admitted by client address before decoding and by debited accounts after it
+const (
+	SCOPE_CLIENT  = "client"
+	SCOPE_ACCOUNT = "account"
+)
+
+// maxTrackedKeys is the number of keys after which idle ones are forgotten
+const maxTrackedKeys = 10000
+
+type (
+	// Limits of requests of a single key, zero value of any limit means the limit is not set
+	Limits struct {
+		// Rate is the number of requests per second the bucket is refilled with
+		Rate float64
+		// Burst is the bucket size, it is at least 1 if Rate is set
+		Burst int
+		// MaxInFlight is the number of requests processed at the same time
+		MaxInFlight int
+	}
+
+	// ThrottledError is returned when request is not admitted, it matches ErrRateLimited or ErrConcurrencyLimited
+	ThrottledError struct {
+		Err error
+		// RetryAfter is the time after which the request is likely to be admitted
+		RetryAfter time.Duration
+	}
+
+	// Observer is notified about throttled requests
+	Observer interface {
+		ObserveThrottled(reason string)
+	}
+
+	// Limiter admits requests by token bucket and caps requests in flight per key, e.g. per debited account
+	Limiter struct {
+		mu        sync.Mutex
+		defaults  Limits
+		overrides map[string]Limits
+		keys      map[string]*keyState
+		observer  Observer
+		now       func() time.Time
+	}
+
+	keyState struct {
+		tokens   float64
+		updated  time.Time
+		inFlight int
+	}
+)
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", e.Err, e.RetryAfter)
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return e.Err
+}
+
+// AccountKey identifies requests by IBAN of the debited account ignoring its spaces and case,
+// so the same account cannot get separate limits. Requests without the account are identified by client address
+func AccountKey(iban, clientAddress string) string {
+	if iban = strings.ToUpper(strings.Join(strings.Fields(iban), "")); iban != "" {
+		return iban
+	}
+	return clientAddress
+}
+
+// NewLimiter creates limiter applying default limits to every key without override
+func NewLimiter(defaults Limits) *Limiter {
+	return &Limiter{
+		defaults:  defaults,
+		overrides: map[string]Limits{},
+		keys:      map[string]*keyState{},
+		now:       time.Now,
+	}
+}
+
+// WithOverride sets limits of the key instead of defaults
+func (l *Limiter) WithOverride(key string, limits Limits) *Limiter {
+	l.overrides[key] = limits
+	return l
+}
+
+// WithObserver sets observer of throttled requests
+func (l *Limiter) WithObserver(observer Observer) *Limiter {
+	l.observer = observer
+	return l
+}
+
+// Limits returns limits applied to the key
+func (l *Limiter) Limits(key string) Limits {
+	if limits, ok := l.overrides[key]; ok {
+		return limits
+	}
+	return l.defaults
+}
+
+// Acquire admits request of the key, release must be called once the request is processed.
+// ThrottledError is returned if the request is not admitted
+func (l *Limiter) Acquire(key string) (release func(), err error) {
+	return l.AcquireAll(key)
+}
+
+// AcquireAll admits request of several keys, e.g. a message debiting several accounts. The request is charged
+// to all keys or, if any of them throttles it, to none. Repeated keys are charged once,
+// release must be called once the request is processed
+func (l *Limiter) AcquireAll(keys ...string) (release func(), err error) {
+	unique := make([]string, 0, len(keys))
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, key)
+		}
+	}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range unique {
+		if _, ok := l.keys[key]; !ok {
+			// idle keys are dropped before states of the request are taken, so none of them is dropped
+			l.forgetIdle(now)
+			break
+		}
+	}
+	states := make([]*keyState, 0, len(unique))
+	for _, key := range unique {
+		limits := l.Limits(key)
+		state, ok := l.keys[key]
+		if !ok {
+			state = &keyState{tokens: float64(burst(limits)), updated: now}
+			l.keys[key] = state
+		}
+
+		if limits.MaxInFlight > 0 && state.inFlight >= limits.MaxInFlight {
+			return nil, l.throttle(REASON_CONCURRENCY, &ThrottledError{Err: ErrConcurrencyLimited, RetryAfter: time.Second})
+		}
+		if limits.Rate > 0 {
+			state.refill(limits, now)
+			if state.tokens < 1 {
+				wait := time.Duration((1 - state.tokens) / limits.Rate * float64(time.Second))
+				return nil, l.throttle(REASON_RATE, &ThrottledError{Err: ErrRateLimited, RetryAfter: wait})
+			}
+		}
+		states = append(states, state)
+	}
+
+	for i, state := range states {
+		if l.Limits(unique[i]).Rate > 0 {
+			state.tokens--
+		}
+		state.inFlight++
+	}
+	released := false
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if !released {
+			released = true
+			for _, state := range states {
+				state.inFlight--
+			}
+		}
+	}, nil
+}
+
+func (l *Limiter) throttle(reason string, err *ThrottledError) error {
+	if l.observer != nil {
+		l.observer.ObserveThrottled(reason)
+	}
+	return err
+}
+
+// forgetIdle drops states of keys without requests in flight and with full bucket,
+// so the number of tracked keys does not grow infinitely. It must be called with the lock held
+func (l *Limiter) forgetIdle(now time.Time) {
+	if len(l.keys) < maxTrackedKeys {
+		return
+	}
+	for key, state := range l.keys {
+		limits := l.Limits(key)
+		state.refill(limits, now)
+		if state.inFlight == 0 && state.tokens >= float64(burst(limits)) {
+			delete(l.keys, key)
+		}
+	}
+}
+
+func (s *keyState) refill(limits Limits, now time.Time) {
+	if limits.Rate <= 0 {
+		return
+	}
+	elapsed := now.Sub(s.updated).Seconds()
+	if elapsed > 0 {
+		s.tokens = math.Min(float64(burst(limits)), s.tokens+elapsed*limits.Rate)
+		s.updated = now
+	}
+}
+
+func burst(limits Limits) int {
+	if limits.Burst < 1 {
+		return 1
+	}
+	return limits.Burst
+}
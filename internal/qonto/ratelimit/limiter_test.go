@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockObserver struct {
+	throttled map[string]int
+}
+
+func (mo *mockObserver) ObserveThrottled(reason string) {
+	mo.throttled[reason]++
+}
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newTestLimiter(defaults Limits) (*Limiter, *clock, *mockObserver) {
+	c := &clock{now: time.Date(2022, 6, 6, 10, 0, 0, 0, time.UTC)}
+	observer := &mockObserver{throttled: map[string]int{}}
+	limiter := NewLimiter(defaults).WithObserver(observer)
+	limiter.now = c.Now
+	return limiter, c, observer
+}
+
+func TestLimiterRate(t *testing.T) {
+	limiter, c, observer := newTestLimiter(Limits{Rate: 2, Burst: 3})
+
+	// burst is admitted at once
+	for i := 0; i < 3; i++ {
+		release, err := limiter.Acquire("ACME Corp")
+		require.NoError(t, err, "request #%d", i+1)
+		release()
+	}
+	_, err := limiter.Acquire("ACME Corp")
+	require.True(t, errors.Is(err, ErrRateLimited))
+	var throttled *ThrottledError
+	require.True(t, errors.As(err, &throttled))
+	assert.Equal(t, 500*time.Millisecond, throttled.RetryAfter)
+
+	// other organizations have their own bucket
+	_, err = limiter.Acquire("Globex")
+	assert.NoError(t, err)
+
+	// bucket is refilled with the rate
+	c.now = c.now.Add(250 * time.Millisecond)
+	_, err = limiter.Acquire("ACME Corp")
+	require.True(t, errors.As(err, &throttled))
+	assert.Equal(t, 250*time.Millisecond, throttled.RetryAfter)
+	c.now = c.now.Add(250 * time.Millisecond)
+	_, err = limiter.Acquire("ACME Corp")
+	assert.NoError(t, err)
+
+	// bucket never exceeds burst
+	c.now = c.now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		_, err := limiter.Acquire("ACME Corp")
+		require.NoError(t, err)
+	}
+	_, err = limiter.Acquire("ACME Corp")
+	assert.True(t, errors.Is(err, ErrRateLimited))
+
+	assert.Equal(t, map[string]int{REASON_RATE: 3}, observer.throttled)
+}
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	limiter, _, observer := newTestLimiter(Limits{MaxInFlight: 2})
+
+	first, err := limiter.Acquire("ACME Corp")
+	require.NoError(t, err)
+	_, err = limiter.Acquire("ACME Corp")
+	require.NoError(t, err)
+
+	_, err = limiter.Acquire("ACME Corp")
+	require.True(t, errors.Is(err, ErrConcurrencyLimited))
+	var throttled *ThrottledError
+	require.True(t, errors.As(err, &throttled))
+	assert.Equal(t, time.Second, throttled.RetryAfter)
+
+	first()
+	// repeated release does not admit more requests
+	first()
+	_, err = limiter.Acquire("ACME Corp")
+	require.NoError(t, err)
+	_, err = limiter.Acquire("ACME Corp")
+	assert.True(t, errors.Is(err, ErrConcurrencyLimited))
+
+	assert.Equal(t, map[string]int{REASON_CONCURRENCY: 2}, observer.throttled)
+}
+
+func TestLimiterAcquireAll(t *testing.T) {
+	limiter, _, observer := newTestLimiter(Limits{Rate: 1, Burst: 2, MaxInFlight: 1})
+
+	// repeated key is charged once
+	release, err := limiter.AcquireAll("ACME Corp", "Globex", "ACME Corp")
+	require.NoError(t, err)
+	assert.Equal(t, 1, limiter.keys["ACME Corp"].inFlight)
+	assert.Equal(t, float64(1), limiter.keys["ACME Corp"].tokens)
+
+	// throttled by one key, the other one is not charged
+	_, err = limiter.AcquireAll("Initech", "Globex")
+	assert.True(t, errors.Is(err, ErrConcurrencyLimited))
+	assert.Equal(t, 0, limiter.keys["Initech"].inFlight)
+	assert.Equal(t, float64(2), limiter.keys["Initech"].tokens)
+
+	release()
+	release()
+	assert.Equal(t, 0, limiter.keys["ACME Corp"].inFlight)
+	assert.Equal(t, 0, limiter.keys["Globex"].inFlight)
+
+	release, err = limiter.AcquireAll("Initech", "Globex")
+	require.NoError(t, err)
+	release()
+	_, err = limiter.AcquireAll("Initech", "Globex")
+	assert.True(t, errors.Is(err, ErrRateLimited), "Globex bucket is empty")
+	assert.Equal(t, float64(1), limiter.keys["Initech"].tokens)
+
+	assert.Equal(t, map[string]int{REASON_CONCURRENCY: 1, REASON_RATE: 1}, observer.throttled)
+}
+
+func TestLimiterOverrides(t *testing.T) {
+	limiter, _, _ := newTestLimiter(Limits{Rate: 1, Burst: 1})
+	limiter.WithOverride("Globex", Limits{})
+
+	assert.Equal(t, Limits{Rate: 1, Burst: 1}, limiter.Limits("ACME Corp"))
+	assert.Equal(t, Limits{}, limiter.Limits("Globex"))
+	for i := 0; i < 100; i++ {
+		_, err := limiter.Acquire("Globex")
+		require.NoError(t, err, "unlimited organization is never throttled")
+	}
+}
+
+func TestLimiterForgetsIdleKeys(t *testing.T) {
+	limiter, c, _ := newTestLimiter(Limits{Rate: 1, Burst: 1})
+	busy, err := limiter.Acquire("busy")
+	require.NoError(t, err)
+	defer busy()
+	for i := 0; i < maxTrackedKeys; i++ {
+		release, err := limiter.Acquire(fmt.Sprintf("org-%d", i))
+		require.NoError(t, err)
+		release()
+	}
+
+	c.now = c.now.Add(time.Second)
+	_, err = limiter.Acquire("newcomer")
+	require.NoError(t, err)
+	assert.Len(t, limiter.keys, 2)
+	assert.Contains(t, limiter.keys, "busy")
+}
+
+func TestAccountKey(t *testing.T) {
+	assert.Equal(t, "FR10474608000002006107XXXXX", AccountKey("FR10474608000002006107XXXXX", "192.0.2.1"))
+	assert.Equal(t, "FR10474608000002006107XXXXX", AccountKey(" fr10 4746 0800 0002 0061 07xx xxx", "192.0.2.1"))
+	assert.Equal(t, "192.0.2.1", AccountKey(" ", "192.0.2.1"))
+}
@@ -0,0 +1,178 @@
+package qonto
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ibanPattern matches IBAN-like tokens of any case: country code, check digits and 11 to 30 alphanumerics of BBAN,
+// either compact or printed in groups of 4 separated by spaces
+var ibanPattern = regexp.MustCompile(`(?i)\b[A-Z]{2}[0-9]{2}(?:[A-Z0-9]{11,30}|(?: [A-Z0-9]{4}){2,7}(?: [A-Z0-9]{1,4})?)\b`)
+
+// minRedactedNameLength skips names so short that masking them would garble unrelated words
+const minRedactedNameLength = 3
+
+// ibanFields and nameFields are log fields redacted regardless of their content,
+// opaqueFields are hex identifiers generated by the app, some of them look like lowercase IBANs
+var (
+	opaqueFields = map[string]bool{
+		"trace_id": true,
+	}
+	ibanFields = map[string]bool{
+		"iban":              true,
+		"organization_iban": true,
+		"counterparty_iban": true,
+	}
+	nameFields = map[string]bool{
+		"name":              true,
+		"organization":      true,
+		"organization_name": true,
+		"counterparty_name": true,
+	}
+)
+
+// RedactIBAN keeps country code and last 4 characters of the IBAN, so it is still recognizable by its owner
+func RedactIBAN(iban string) string {
+	iban = strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+	if len(iban) <= 6 {
+		return strings.Repeat("*", len(iban))
+	}
+	return iban[:2] + "****" + iban[len(iban)-4:]
+}
+
+// RedactName replaces the name by a short hash, entries of the same name can still be correlated
+func RedactName(name string) string {
+	if name == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(name))
+	return "sha256:" + hex.EncodeToString(sum[:6])
+}
+
+// RedactText masks all IBANs found in free text, e.g. error messages.
+// Names cannot be recognized in free text, use Redactor if names of the parties are known
+func RedactText(text string) string {
+	return ibanPattern.ReplaceAllStringFunc(text, RedactIBAN)
+}
+
+// Redactor masks IBANs and names of known parties in free text, nil redactor masks IBANs only
+type Redactor struct {
+	once sync.Once
+	load func() []string
+	// names are indexed by their first bytes, so the text is scanned once whatever the number of names
+	names map[string][]string
+}
+
+// NewRedactor creates redactor of the given names, e.g. debtor and counterparties of the request
+func NewRedactor(names ...string) *Redactor {
+	redactor := &Redactor{}
+	redactor.index(names)
+	return redactor
+}
+
+// NewRedactorFunc creates redactor of names returned by load, it is called when the redactor is used
+// for the first time, so names of large requests are gathered only if there is something to redact
+func NewRedactorFunc(load func() []string) *Redactor {
+	return &Redactor{load: load}
+}
+
+func (r *Redactor) index(names []string) {
+	seen := make(map[string]bool, len(names))
+	r.names = map[string][]string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if utf8.RuneCountInString(name) < minRedactedNameLength || seen[name] {
+			continue
+		}
+		seen[name] = true
+		key := name[:minRedactedNameLength]
+		r.names[key] = append(r.names[key], name)
+	}
+	// longer names go first, so a name containing another one is masked as a whole
+	for _, names := range r.names {
+		sort.Slice(names, func(i, j int) bool {
+			return len(names[i]) > len(names[j])
+		})
+	}
+}
+
+// Text masks IBANs and known names found in the text
+func (r *Redactor) Text(text string) string {
+	text = RedactText(text)
+	if r == nil {
+		return text
+	}
+	r.once.Do(func() {
+		if r.load != nil {
+			r.index(r.load())
+			r.load = nil
+		}
+	})
+
+	var redacted strings.Builder
+	last := 0
+	for i := 0; i+minRedactedNameLength <= len(text); {
+		name := r.nameAt(text[i:])
+		if name == "" {
+			i++
+			continue
+		}
+		redacted.WriteString(text[last:i])
+		redacted.WriteString(RedactName(name))
+		i += len(name)
+		last = i
+	}
+	if last == 0 {
+		return text
+	}
+	redacted.WriteString(text[last:])
+	return redacted.String()
+}
+
+// nameAt returns the longest known name the text starts with
+func (r *Redactor) nameAt(text string) string {
+	for _, name := range r.names[text[:minRedactedNameLength]] {
+		if strings.HasPrefix(text, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+type redactorKey struct{}
+
+// ContextWithRedactor returns a copy of the context carrying the redactor
+func ContextWithRedactor(ctx context.Context, redactor *Redactor) context.Context {
+	return context.WithValue(ctx, redactorKey{}, redactor)
+}
+
+// RedactorFromContext returns redactor bound to the context, e.g. with names of the request being processed,
+// nil redactor is returned if there is none
+func RedactorFromContext(ctx context.Context) *Redactor {
+	redactor, _ := ctx.Value(redactorKey{}).(*Redactor)
+	return redactor
+}
+
+// redactField redacts value of the known sensitive field, other strings and errors are scanned for IBANs
+func redactField(key string, value interface{}) interface{} {
+	value = fieldValue(value)
+	text, ok := value.(string)
+	if !ok {
+		return value
+	}
+	switch {
+	case opaqueFields[key]:
+		return text
+	case ibanFields[key]:
+		return RedactIBAN(text)
+	case nameFields[key]:
+		return RedactName(text)
+	}
+	return RedactText(text)
+}
@@ -0,0 +1,134 @@
+package qonto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ibans used by tests, none of them may be found in the output
+var testIBANs = []string{"FR10474608000002006107XXXXX", "EE383680981021245685", "DE89370400440532013000"}
+
+func assertNoIBAN(t *testing.T, output string) {
+	t.Helper()
+	for _, iban := range testIBANs {
+		assert.NotContains(t, output, iban)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	testCases := []struct {
+		name     string
+		redact   func(string) string
+		input    string
+		expected string
+	}{
+		{"iban", RedactIBAN, "FR10474608000002006107XXXXX", "FR****XXXX"},
+		{"iban with spaces", RedactIBAN, "DE89 3704 0044 0532 0130 00", "DE****3000"},
+		{"short iban", RedactIBAN, "FR10", "****"},
+		{"name", RedactName, "Bip Bip", "sha256:49d5f87e904c"},
+		{"empty name", RedactName, "", ""},
+		{"text", RedactText, "transfer to EE383680981021245685 failed: account FR10474608000002006107XXXXX not found", "transfer to EE****5685 failed: account FR****XXXX not found"},
+		{"text without iban", RedactText, "amount 14.50 EUR to FR is blocked", "amount 14.50 EUR to FR is blocked"},
+		{"text with lowercase iban", RedactText, "transfer to ee383680981021245685 failed", "transfer to EE****5685 failed"},
+		{"text with spaced iban", RedactText, "transfer to DE89 3704 0044 0532 0130 00 failed", "transfer to DE****3000 failed"},
+		{"text with spaced lowercase iban", RedactText, "account fr10 4746 0800 0002 0061 07xx xxx: not found", "account FR****XXXX: not found"},
+		{"text with spaced iban at the end", RedactText, "transfer to FR76 3000 6000 0112 3456 7890 189", "transfer to FR****0189"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.redact(tc.input))
+		})
+	}
+
+	assert.Equal(t, RedactName("Bip Bip"), RedactName("Bip Bip"), "hash is stable so entries can be correlated")
+}
+
+func TestRedactor(t *testing.T) {
+	redactor := NewRedactor("ACME Corp", "Bip Bip", "Bip Bip Inc", "", "Al", "Bip Bip")
+
+	testCases := []struct {
+		name     string
+		redactor *Redactor
+		input    string
+		expected string
+	}{
+		{
+			name:     "names and ibans",
+			redactor: redactor,
+			input:    "transfer of ACME Corp to Bip Bip (ee38 3680 9810 2124 5685) failed",
+			expected: "transfer of " + RedactName("ACME Corp") + " to " + RedactName("Bip Bip") + " (EE****5685) failed",
+		},
+		{
+			name:     "name containing another one is masked as a whole",
+			redactor: redactor,
+			input:    "Duplicate entry 'Bip Bip Inc' for key 'counterparty_name'",
+			expected: "Duplicate entry '" + RedactName("Bip Bip Inc") + "' for key 'counterparty_name'",
+		},
+		{
+			name:     "short names are kept",
+			redactor: redactor,
+			input:    "Also transfer to Al",
+			expected: "Also transfer to Al",
+		},
+		{
+			name:     "nil redactor masks ibans only",
+			input:    "transfer to Bip Bip EE383680981021245685 failed",
+			expected: "transfer to Bip Bip EE****5685 failed",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.redactor.Text(tc.input))
+		})
+	}
+
+	assert.Equal(t, redactor, RedactorFromContext(ContextWithRedactor(context.Background(), redactor)))
+	assert.Nil(t, RedactorFromContext(context.Background()))
+}
+
+func TestRedactorFunc(t *testing.T) {
+	loads := 0
+	redactor := NewRedactorFunc(func() []string {
+		loads++
+		return []string{"ACME Corp", "Bip Bip"}
+	})
+	assert.Zero(t, loads, "names must not be loaded before the first text")
+
+	assert.Equal(t, "transfer to "+RedactName("Bip Bip")+" failed", redactor.Text("transfer to Bip Bip failed"))
+	assert.Equal(t, RedactName("ACME Corp")+" is locked", redactor.Text("ACME Corp is locked"))
+	assert.Equal(t, 1, loads)
+}
+
+func TestLoggerRedaction(t *testing.T) {
+	wrapped := fmt.Errorf("could not process request of %s: %w", testIBANs[0], errors.New("transfer to "+testIBANs[1]+" failed"))
+
+	for _, format := range []Format{FORMAT_TEXT, FORMAT_JSON} {
+		t.Run(string(format), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := newTestLogger(buf).WithFormat(format).With("organization_iban", testIBANs[0], "organization_name", "ACME Corp")
+			logger.Error("request to "+testIBANs[2]+" failed",
+				"error", wrapped,
+				"counterparty_iban", testIBANs[1],
+				"counterparty_name", "Bip Bip",
+				"description", "refund of "+testIBANs[2],
+				"trace_id", "ab12c3f577b34da6a3ce929d0e0e4736",
+			)
+
+			output := buf.String()
+			assertNoIBAN(t, output)
+			assert.NotContains(t, output, "ACME Corp")
+			assert.NotContains(t, output, "Bip Bip")
+			assert.Contains(t, output, "FR****XXXX")
+			assert.Contains(t, output, "EE****5685")
+			assert.Contains(t, output, RedactName("Bip Bip"))
+			assert.Contains(t, output, "ab12c3f577b34da6a3ce929d0e0e4736", "trace ID looking like IBAN is kept")
+		})
+	}
+}
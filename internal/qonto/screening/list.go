@@ -0,0 +1,245 @@
+package screening
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+type (
+	// Entry is a single record of sanctions list, aliases of the same entity share ID
+	Entry struct {
+		ID   string
+		Name string
+		IBAN string
+		BIC  string
+	}
+
+	// Match describes an entry matched by screened party.
+	// Field is one of "name", "iban", "bic"; Score is 1 for exact matches
+	Match struct {
+		Entry Entry
+		Field string
+		Score float64
+	}
+
+	// List is an immutable, indexed sanctions list
+	List struct {
+		entries []Entry
+		names   []string // normalized names, same order as entries
+		ibans   map[string][]int
+		bics    map[string][]int
+	}
+
+	// Screener screens parties against sanctions list loaded from file,
+	// the list can be reloaded at any time without interrupting screening
+	Screener struct {
+		path      string
+		threshold float64
+
+		mu   sync.RWMutex
+		list *List
+	}
+)
+
+// header columns of the list file, matched case-insensitive
+const (
+	columnID   = "entity_id"
+	columnName = "name"
+	columnIBAN = "iban"
+	columnBIC  = "bic"
+)
+
+// LoadCSV reads list in CSV format with header row.
+// Comma and semicolon delimiters are supported, the one found in header wins
+func LoadCSV(r io.Reader) (*List, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(strings.NewReader(string(content)))
+	headerLine := string(content)
+	if i := strings.IndexByte(headerLine, '\n'); i >= 0 {
+		headerLine = headerLine[:i]
+	}
+	if strings.Count(headerLine, ";") > strings.Count(headerLine, ",") {
+		reader.Comma = ';'
+	}
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read list header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns[columnName]; !ok {
+		return nil, errors.New("list header must contain " + columnName + " column")
+	}
+
+	entries := []Entry{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		entry := Entry{
+			ID:   field(columnID),
+			Name: field(columnName),
+			IBAN: field(columnIBAN),
+			BIC:  field(columnBIC),
+		}
+		if entry.Name == "" && entry.IBAN == "" && entry.BIC == "" {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return NewList(entries), nil
+}
+
+// NewList indexes provided entries
+func NewList(entries []Entry) *List {
+	list := &List{
+		entries: entries,
+		names:   make([]string, len(entries)),
+		ibans:   map[string][]int{},
+		bics:    map[string][]int{},
+	}
+	for i, entry := range entries {
+		list.names[i] = NormalizeName(entry.Name)
+		if iban := NormalizeAccount(entry.IBAN); iban != "" {
+			list.ibans[iban] = append(list.ibans[iban], i)
+		}
+		if bic := bicKey(entry.BIC); bic != "" {
+			list.bics[bic] = append(list.bics[bic], i)
+		}
+	}
+
+	return list
+}
+
+// Len returns number of entries in the list
+func (l *List) Len() int {
+	return len(l.entries)
+}
+
+// Match finds entries matching the party by IBAN, BIC or name similar at least by threshold
+func (l *List) Match(name, iban, bic string, threshold float64) []Match {
+	matches := []Match{}
+	for _, i := range l.ibans[NormalizeAccount(iban)] {
+		matches = append(matches, Match{Entry: l.entries[i], Field: columnIBAN, Score: 1})
+	}
+	for _, i := range l.bics[bicKey(bic)] {
+		matches = append(matches, Match{Entry: l.entries[i], Field: columnBIC, Score: 1})
+	}
+	// 8 character BIC identifies the institution, so it matches all its branches too
+	if key := bicKey(bic); len(key) == 11 {
+		for _, i := range l.bics[key[:8]] {
+			matches = append(matches, Match{Entry: l.entries[i], Field: columnBIC, Score: 1})
+		}
+	}
+
+	normalized := NormalizeName(name)
+	if normalized == "" {
+		return matches
+	}
+	length := len([]rune(normalized))
+	for i, listName := range l.names {
+		if listName == "" {
+			continue
+		}
+		// skip names which cannot reach the threshold because of length difference alone
+		listLength := len([]rune(listName))
+		longest, diff := length, length-listLength
+		if listLength > longest {
+			longest = listLength
+		}
+		if diff < 0 {
+			diff = -diff
+		}
+		if 1-float64(diff)/float64(longest) < threshold {
+			continue
+		}
+		if score := similarity(normalized, listName); score >= threshold {
+			matches = append(matches, Match{Entry: l.entries[i], Field: columnName, Score: score})
+		}
+	}
+
+	return matches
+}
+
+func bicKey(bic string) string {
+	bic = NormalizeAccount(bic)
+	// "XXX" branch code means primary office
+	if len(bic) == 11 && strings.HasSuffix(bic, "XXX") {
+		return bic[:8]
+	}
+
+	return bic
+}
+
+// NewScreener creates screener and loads the list from the file at path
+func NewScreener(path string, threshold float64) (*Screener, error) {
+	s := &Screener{
+		path:      path,
+		threshold: threshold,
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Reload reads the list file again, current list stays in use if reading fails
+func (s *Screener) Reload() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	list, err := LoadCSV(f)
+	if err != nil {
+		return fmt.Errorf("cannot load sanctions list %s: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	s.list = list
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Len returns number of entries in currently loaded list
+func (s *Screener) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.list.Len()
+}
+
+// Screen matches the party against currently loaded list
+func (s *Screener) Screen(name, iban, bic string) []Match {
+	s.mu.RLock()
+	list := s.list
+	s.mu.RUnlock()
+
+	return list.Match(name, iban, bic, s.threshold)
+}
@@ -0,0 +1,133 @@
+package screening
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeName(t *testing.T) {
+	cases := []struct {
+		name           string
+		input          string
+		expectedResult string
+	}{
+		{
+			name:           "case and punctuation",
+			input:          "Wile E. Coyote",
+			expectedResult: "coyote e wile",
+		},
+		{
+			name:           "word order",
+			input:          "Coyote, Wile E",
+			expectedResult: "coyote e wile",
+		},
+		{
+			name:           "accents",
+			input:          "Société Générale",
+			expectedResult: "generale societe",
+		},
+		{
+			name:           "legal form",
+			input:          "ACME Corp. Ltd",
+			expectedResult: "acme",
+		},
+		{
+			name:           "empty",
+			input:          " - ",
+			expectedResult: "",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedResult, NormalizeName(tc.input))
+		})
+	}
+}
+
+func TestListMatch(t *testing.T) {
+	list, err := LoadCSV(strings.NewReader(`entity_id;name;iban;bic
+EU.1;Bugs Bunny;;
+EU.1;Rabbit Bugs;;
+EU.2;Carrot Holdings Ltd;DE99 3542 0810 0362 0908 1725 212;
+EU.3;;;ZDRPLBQI
+`))
+	require.NoError(t, err)
+	require.Equal(t, 4, list.Len())
+
+	cases := []struct {
+		name            string
+		party           [3]string
+		expectedMatches []string
+	}{
+		{
+			name:            "no match",
+			party:           [3]string{"Wile E Coyote", "FR0010009380540930414023042", "CRLYFRPPTOU"},
+			expectedMatches: []string{},
+		},
+		{
+			name:            "exact alias",
+			party:           [3]string{"bugs rabbit", "", ""},
+			expectedMatches: []string{"EU.1/name"},
+		},
+		{
+			name:            "fuzzy name",
+			party:           [3]string{"Bugz Bunny", "", ""},
+			expectedMatches: []string{"EU.1/name"},
+		},
+		{
+			name:            "iban without spaces",
+			party:           [3]string{"Someone", "de9935420810036209081725212", ""},
+			expectedMatches: []string{"EU.2/iban"},
+		},
+		{
+			name:            "branch of listed bic",
+			party:           [3]string{"Someone", "", "ZDRPLBQI123"},
+			expectedMatches: []string{"EU.3/bic"},
+		},
+		{
+			name:            "primary office bic",
+			party:           [3]string{"Someone", "", "ZDRPLBQIXXX"},
+			expectedMatches: []string{"EU.3/bic"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			matches := list.Match(tc.party[0], tc.party[1], tc.party[2], 0.85)
+			result := []string{}
+			for _, m := range matches {
+				result = append(result, m.Entry.ID+"/"+m.Field)
+			}
+			assert.Equal(t, tc.expectedMatches, result)
+		})
+	}
+}
+
+func TestLoadCSVRequiresName(t *testing.T) {
+	_, err := LoadCSV(strings.NewReader("id,iban\n1,DE00\n"))
+	assert.Error(t, err)
+}
+
+func TestScreenerReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "list.csv")
+	require.NoError(t, os.WriteFile(path, []byte("entity_id,name\n1,Bugs Bunny\n"), 0600))
+
+	screener, err := NewScreener(path, 0.9)
+	require.NoError(t, err)
+	assert.Len(t, screener.Screen("Bugs Bunny", "", ""), 1)
+	assert.Len(t, screener.Screen("Wile E Coyote", "", ""), 0)
+
+	require.NoError(t, os.WriteFile(path, []byte("entity_id,name\n2,Wile E Coyote\n"), 0600))
+	require.NoError(t, screener.Reload())
+	assert.Len(t, screener.Screen("Bugs Bunny", "", ""), 0)
+	assert.Len(t, screener.Screen("Wile E Coyote", "", ""), 1)
+
+	// broken file keeps previous list
+	require.NoError(t, os.WriteFile(path, []byte("entity_id,iban\n3,DE00\n"), 0600))
+	assert.Error(t, screener.Reload())
+	assert.Equal(t, 1, screener.Len())
+}
@@ -0,0 +1,97 @@
+package screening
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// accents maps common accented latin letters to their ASCII base
+var accents = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "č", "c", "ć", "c",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ě", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i",
+	"ñ", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ř", "r", "š", "s", "ś", "s", "ß", "ss", "ť", "t",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ů", "u",
+	"ý", "y", "ÿ", "y", "ž", "z", "ź", "z", "ż", "z", "ł", "l",
+)
+
+// legalForms are dropped from names, so "ACME Ltd" matches "ACME"
+var legalForms = map[string]bool{
+	"ltd": true, "llc": true, "inc": true, "corp": true, "co": true, "plc": true,
+	"sa": true, "sas": true, "sarl": true, "gmbh": true, "ag": true, "bv": true, "nv": true,
+	"ooo": true, "oao": true, "zao": true, "pjsc": true, "jsc": true,
+}
+
+// NormalizeName brings name to a canonical form: lower case ASCII letters and digits,
+// legal forms removed and words sorted, so word order and punctuation do not matter
+func NormalizeName(name string) string {
+	name = accents.Replace(strings.ToLower(name))
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	result := make([]string, 0, len(words))
+	for _, w := range words {
+		if !legalForms[w] {
+			result = append(result, w)
+		}
+	}
+	sort.Strings(result)
+
+	return strings.Join(result, " ")
+}
+
+// NormalizeAccount brings IBAN or BIC to upper case without spaces
+func NormalizeAccount(s string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(s), ""))
+}
+
+// similarity returns value in [0, 1] range based on Levenshtein distance, 1 means equal strings
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+
+	return result
+}
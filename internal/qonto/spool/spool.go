@@ -0,0 +1,131 @@
+package spool
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+)
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// ErrClosed is returned when spilled transfers are accessed after the list is closed
+const ErrClosed = Error("spool is closed")
+
+// Transfers is a list of transfers of a request being decoded. The first chunk is kept in memory,
+// further transfers are written to a temporary file, so requests of any size take bounded memory.
+// It implements core.Transfers and must be closed to remove the file
+type Transfers struct {
+	dir    string
+	memory core.TransferList
+	count  int
+	closed bool
+
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *gob.Encoder
+}
+
+// New creates an empty list which spills transfers to a file in dir, the default directory
+// for temporary files is used if dir is empty
+func New(dir string) *Transfers {
+	return &Transfers{
+		dir: dir,
+	}
+}
+
+// Append adds the transfer to the end of the list
+func (t *Transfers) Append(transfer core.Transfer) error {
+	if t.closed {
+		return ErrClosed
+	}
+	t.count++
+	if len(t.memory) < core.CHUNK_SIZE {
+		t.memory = append(t.memory, transfer)
+		return nil
+	}
+
+	if t.file == nil {
+		file, err := os.CreateTemp(t.dir, "qonto-transfers-*")
+		if err != nil {
+			return fmt.Errorf("error creating spool file: %w", err)
+		}
+		t.file = file
+		t.writer = bufio.NewWriter(file)
+		t.encoder = gob.NewEncoder(t.writer)
+	}
+	if err := t.encoder.Encode(&transfer); err != nil {
+		return fmt.Errorf("error writing spool file: %w", err)
+	}
+
+	return nil
+}
+
+// Len implements core.Transfers interface
+func (t *Transfers) Len() int {
+	return t.count
+}
+
+// Chunks implements core.Transfers interface, transfers spilled to the file are read back by chunks
+func (t *Transfers) Chunks(size int, fn func(offset int, chunk []core.Transfer) error) error {
+	if t.closed && t.count > len(t.memory) {
+		return ErrClosed
+	}
+	if err := t.memory.Chunks(size, fn); err != nil || t.file == nil {
+		return err
+	}
+
+	if err := t.writer.Flush(); err != nil {
+		return fmt.Errorf("error writing spool file: %w", err)
+	}
+	if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error reading spool file: %w", err)
+	}
+	// transfers appended after reading must go to the end of the file
+	defer t.file.Seek(0, io.SeekEnd)
+
+	decoder := gob.NewDecoder(bufio.NewReader(t.file))
+	chunk := make([]core.Transfer, size)
+	for offset := len(t.memory); offset < t.count; offset += size {
+		n := size
+		if remaining := t.count - offset; remaining < n {
+			n = remaining
+		}
+		for i := 0; i < n; i++ {
+			// zero fields are not encoded, so the previous chunk must not leak into the next one
+			chunk[i] = core.Transfer{}
+			if err := decoder.Decode(&chunk[i]); err != nil {
+				return fmt.Errorf("error reading spool file: %w", err)
+			}
+		}
+		if err := fn(offset, chunk[:n]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close removes the file, the list can still be read if none of its transfers were spilled to it
+func (t *Transfers) Close() error {
+	t.closed = true
+	if t.file == nil {
+		return nil
+	}
+
+	file := t.file
+	t.file, t.writer, t.encoder = nil, nil, nil
+	closeErr := file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		return err
+	}
+
+	return closeErr
+}
@@ -0,0 +1,99 @@
+package spool
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransfer(i int) core.Transfer {
+	transfer := core.Transfer{
+		Amount:       core.Amount{Cents: int64(i + 1)},
+		Currency:     core.CURRENCY_EURO,
+		Description:  fmt.Sprintf("payroll/%d", i),
+		CounterParty: core.Party{Name: "Bip Bip", IBAN: "EE383680981021245685"},
+	}
+	// zero fields must not be taken from the previous transfer
+	if i%2 == 1 {
+		transfer.Description = ""
+		transfer.CounterParty.BIC = "CRLYFRPPTOU"
+	}
+	return transfer
+}
+
+func TestTransfers(t *testing.T) {
+	cases := []struct {
+		name          string
+		count         int
+		size          int
+		expectedFile  bool
+		expectedSizes []int
+	}{
+		{name: "empty", count: 0, size: 10, expectedSizes: []int{}},
+		{name: "kept in memory", count: core.CHUNK_SIZE, size: 400, expectedSizes: []int{400, 400, 200}},
+		{name: "spilled to file", count: 2*core.CHUNK_SIZE + 10, size: core.CHUNK_SIZE, expectedFile: true, expectedSizes: []int{core.CHUNK_SIZE, core.CHUNK_SIZE, 10}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			transfers := New(dir)
+			for i := 0; i < tc.count; i++ {
+				require.NoError(t, transfers.Append(newTransfer(i)))
+			}
+			assert.Equal(t, tc.count, transfers.Len())
+
+			// transfers can be read more than once, e.g. when a transaction is retried
+			for attempt := 0; attempt < 2; attempt++ {
+				sizes := []int{}
+				next := 0
+				err := transfers.Chunks(tc.size, func(offset int, chunk []core.Transfer) error {
+					assert.Equal(t, next, offset)
+					for j, transfer := range chunk {
+						assert.Equal(t, newTransfer(offset+j), transfer)
+					}
+					next += len(chunk)
+					sizes = append(sizes, len(chunk))
+					return nil
+				})
+				require.NoError(t, err)
+				assert.Equal(t, tc.expectedSizes, sizes)
+			}
+
+			files, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedFile, len(files) == 1)
+
+			require.NoError(t, transfers.Close())
+			files, err = os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, files, "file must be removed")
+			if tc.expectedFile {
+				assert.ErrorIs(t, transfers.Chunks(tc.size, func(int, []core.Transfer) error { return nil }), ErrClosed)
+			}
+			assert.ErrorIs(t, transfers.Append(newTransfer(0)), ErrClosed)
+		})
+	}
+}
+
+func TestTransfersStopsOnError(t *testing.T) {
+	transfers := New(t.TempDir())
+	defer transfers.Close()
+	for i := 0; i < 3*core.CHUNK_SIZE; i++ {
+		require.NoError(t, transfers.Append(newTransfer(i)))
+	}
+
+	calls := 0
+	err := transfers.Chunks(core.CHUNK_SIZE, func(offset int, chunk []core.Transfer) error {
+		calls++
+		if offset == core.CHUNK_SIZE {
+			return assert.AnError
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 2, calls)
+}
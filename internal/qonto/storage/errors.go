@@ -0,0 +1,50 @@
+package storage
+
+import "errors"
+
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	ErrAccountNotFound      = Error("account not found")
+	ErrStatusReportNotFound = Error("status report not found")
+	ErrScreeningHitNotFound = Error("screening hit not found")
+	ErrDuplicateIBAN        = Error("account with the IBAN already exists")
+	// ErrConstraintViolation is returned when referenced record does not exist or referencing one prevents deletion
+	ErrConstraintViolation = Error("constraint violation")
+	// ErrConflict is returned when concurrent transaction prevents the change, even after retries
+	ErrConflict     = Error("conflicting concurrent change")
+	ErrNoMigrations = Error("no migrations applied")
+)
+
+// wrappedError matches its storage error while the backend error stays available for errors.As
+type wrappedError struct {
+	kind Error
+	err  error
+}
+
+// Wrap returns error matching kind, so backends report their errors as storage ones without losing details
+func Wrap(kind Error, err error) error {
+	return &wrappedError{kind: kind, err: err}
+}
+
+// IsWrapped reports whether err is already wrapped by Wrap
+func IsWrapped(err error) bool {
+	var wrapped *wrappedError
+	return errors.As(err, &wrapped)
+}
+
+func (e *wrappedError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *wrappedError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *wrappedError) Unwrap() error {
+	return e.err
+}
@@ -1,27 +1,33 @@
 package storage
 
 import (
-	"database/sql"
+	"errors"
+	"os"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
-func Migrate(source string, db *sql.DB) error {
-
-	driver, err := mysql.WithInstance(db, &mysql.Config{})
+// LatestMigration returns version of the last migration in the source
+func LatestMigration(sourceURL string) (uint, error) {
+	driver, err := source.Open(sourceURL)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	m, err := migrate.NewWithDatabaseInstance(source, "mysql", driver)
+	defer driver.Close()
+
+	version, err := driver.First()
 	if err != nil {
-		return err
+		return 0, err
 	}
-
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return err
+	for {
+		next, err := driver.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
 	}
-
-	return nil
 }
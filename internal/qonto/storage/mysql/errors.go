@@ -0,0 +1,34 @@
+package mysql
+
+import (
+	"errors"
+
+	driver "github.com/go-sql-driver/mysql"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+// MySQL errors translated to storage errors
+const (
+	ER_LOCK_WAIT_TIMEOUT   = 1205
+	ER_LOCK_DEADLOCK       = 1213
+	ER_DUP_ENTRY           = 1062
+	ER_ROW_IS_REFERENCED_2 = 1451
+	ER_NO_REFERENCED_ROW_2 = 1452
+)
+
+// RetryReason implements storage.RetryReasonFunc, the whole transaction can be run again
+// after deadlock or lock wait timeout
+func RetryReason(err error) string {
+	var mysqlErr *driver.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return ""
+	}
+	switch mysqlErr.Number {
+	case ER_LOCK_DEADLOCK:
+		return storage.RETRY_REASON_DEADLOCK
+	case ER_LOCK_WAIT_TIMEOUT:
+		return storage.RETRY_REASON_LOCK_WAIT_TIMEOUT
+	}
+	return ""
+}
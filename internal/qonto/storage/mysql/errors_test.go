@@ -0,0 +1,75 @@
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/sqlstorage"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errDeadlock        = &driver.MySQLError{Number: ER_LOCK_DEADLOCK, Message: "Deadlock found when trying to get lock"}
+	errLockWaitTimeout = &driver.MySQLError{Number: ER_LOCK_WAIT_TIMEOUT, Message: "Lock wait timeout exceeded"}
+)
+
+func TestRetryReason(t *testing.T) {
+	assert.Equal(t, storage.RETRY_REASON_DEADLOCK, RetryReason(errDeadlock))
+	assert.Equal(t, storage.RETRY_REASON_LOCK_WAIT_TIMEOUT, RetryReason(fmt.Errorf("update balance: %w", errLockWaitTimeout)))
+	assert.Equal(t, "", RetryReason(&driver.MySQLError{Number: ER_DUP_ENTRY, Message: "Duplicate entry"}))
+	assert.Equal(t, "", RetryReason(errors.New("deadlock")))
+	assert.Equal(t, "", RetryReason(nil))
+}
+
+func TestTranslateError(t *testing.T) {
+	errDBDown := errors.New("connection refused")
+
+	testCases := []struct {
+		name        string
+		err         error
+		notFound    storage.Error
+		expectedErr error
+	}{
+		{"no error", nil, storage.ErrAccountNotFound, nil},
+		{"no rows", sql.ErrNoRows, storage.ErrAccountNotFound, storage.ErrAccountNotFound},
+		{"no rows without not found error", sql.ErrNoRows, "", sql.ErrNoRows},
+		{"duplicate key", &driver.MySQLError{Number: ER_DUP_ENTRY, Message: "Duplicate entry"}, "", storage.ErrConflict},
+		{"missing referenced row", &driver.MySQLError{Number: ER_NO_REFERENCED_ROW_2, Message: "foreign key constraint fails"}, "", storage.ErrConstraintViolation},
+		{"referenced row", &driver.MySQLError{Number: ER_ROW_IS_REFERENCED_2, Message: "foreign key constraint fails"}, "", storage.ErrConstraintViolation},
+		{"wrapped deadlock", fmt.Errorf("append transactions: %w", errDeadlock), "", storage.ErrConflict},
+		{"lock wait timeout", errLockWaitTimeout, "", storage.ErrConflict},
+		{"unknown MySQL error", &driver.MySQLError{Number: 1146, Message: "Table doesn't exist"}, "", nil},
+		{"other error", errDBDown, storage.ErrAccountNotFound, errDBDown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := sqlstorage.TranslateError(dialect{}, tc.err, tc.notFound)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			if tc.expectedErr == nil {
+				assert.Equal(t, tc.err, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedErr)
+			// the original error stays available
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	t.Run("translated error is kept", func(t *testing.T) {
+		err := sqlstorage.TranslateError(dialect{}, storage.Wrap(storage.ErrDuplicateIBAN, &driver.MySQLError{Number: ER_DUP_ENTRY}), "")
+		assert.ErrorIs(t, err, storage.ErrDuplicateIBAN)
+		assert.NotErrorIs(t, err, storage.ErrConflict)
+	})
+
+	t.Run("translated deadlock is retried", func(t *testing.T) {
+		assert.Equal(t, storage.RETRY_REASON_DEADLOCK, RetryReason(sqlstorage.TranslateError(dialect{}, errDeadlock, "")))
+	})
+}
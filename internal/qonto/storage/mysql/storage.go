@@ -0,0 +1,84 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/sqlstorage"
+)
+
+// dialect adapts shared SQL storage to MySQL
+type dialect struct{}
+
+// NewStorage creates and initializes new MySQL storage instance
+func NewStorage(config *driver.Config) (*sqlstorage.Storage, error) {
+	db, err := sql.Open("mysql", config.FormatDSN())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(30)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	return sqlstorage.NewStorage(db, dialect{}), nil
+}
+
+// NewConfig initializes new MySQL connection configuration with sane defaults
+func NewConfig() *driver.Config {
+	mysqlConfig := driver.NewConfig()
+	mysqlConfig.AllowNativePasswords = true
+	mysqlConfig.MultiStatements = true // if false, SQL with >1 statement (e.g. create table in migrations) will fail
+	mysqlConfig.ParseTime = true
+
+	return mysqlConfig
+}
+
+// Rebind keeps the query as is, MySQL uses "?" placeholders
+func (dialect) Rebind(query string) string {
+	return query
+}
+
+func (dialect) InsertID(ctx context.Context, q sqlstorage.Querier, stmt string, args ...interface{}) (int64, error) {
+	result, err := q.ExecContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (dialect) Upsert(key, columns []string) string {
+	updates := make([]string, 0, len(columns))
+	for _, column := range columns {
+		updates = append(updates, column+" = VALUES("+column+")")
+	}
+	return "ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+}
+
+func (dialect) RetryReason(err error) string {
+	return RetryReason(err)
+}
+
+func (dialect) ErrorKind(err error) storage.Error {
+	var mysqlErr *driver.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return ""
+	}
+	switch mysqlErr.Number {
+	case ER_DUP_ENTRY, ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT:
+		return storage.ErrConflict
+	case ER_ROW_IS_REFERENCED_2, ER_NO_REFERENCED_ROW_2:
+		return storage.ErrConstraintViolation
+	}
+	return ""
+}
+
+func (dialect) IsUniqueViolation(err error) bool {
+	var mysqlErr *driver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == ER_DUP_ENTRY
+}
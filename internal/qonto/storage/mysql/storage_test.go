@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/sqlstorage"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	// fakeConnector opens connections which only begin and end transactions
+	fakeConnector struct {
+		rollbackErr error
+		rollbacks   int
+	}
+
+	fakeConn struct {
+		connector *fakeConnector
+	}
+
+	fakeTx struct {
+		connector *fakeConnector
+	}
+)
+
+func (c *fakeConnector) Connect(context.Context) (sqldriver.Conn, error) {
+	return &fakeConn{connector: c}, nil
+}
+
+func (c *fakeConnector) Driver() sqldriver.Driver {
+	return nil
+}
+
+func (c *fakeConn) Prepare(query string) (sqldriver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (sqldriver.Tx, error) {
+	return &fakeTx{connector: c.connector}, nil
+}
+
+func (tx *fakeTx) Commit() error {
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.connector.rollbacks++
+	return tx.connector.rollbackErr
+}
+
+func TestWithTransactionStorage(t *testing.T) {
+	errRollback := errors.New("connection lost")
+	errNotRetried := errors.New("insufficient funds")
+
+	testCases := []struct {
+		name          string
+		rollbackErr   error
+		errs          []error
+		wantAttempts  int
+		wantRollbacks int
+		wantErr       error
+	}{
+		{
+			name:          "wrapped deadlock is retried",
+			errs:          []error{fmt.Errorf("update balance: %w", errDeadlock), nil},
+			wantAttempts:  2,
+			wantRollbacks: 1,
+		},
+		{
+			name:          "deadlock is retried when rollback fails",
+			rollbackErr:   errRollback,
+			errs:          []error{fmt.Errorf("update balance: %w", errDeadlock), nil},
+			wantAttempts:  2,
+			wantRollbacks: 1,
+		},
+		{
+			name:          "other errors are returned unchanged",
+			errs:          []error{errNotRetried},
+			wantAttempts:  1,
+			wantRollbacks: 1,
+			wantErr:       errNotRetried,
+		},
+		{
+			name:          "failed rollback keeps the original error",
+			rollbackErr:   errRollback,
+			errs:          []error{errNotRetried},
+			wantAttempts:  1,
+			wantRollbacks: 1,
+			wantErr:       errNotRetried,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			connector := &fakeConnector{rollbackErr: tc.rollbackErr}
+			db := sql.OpenDB(connector)
+			defer db.Close()
+			s := sqlstorage.NewStorage(db, dialect{}).
+				WithRetryPolicy(storage.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})
+
+			attempts := 0
+			err := s.WithTransactionStorage(context.Background(), func(ctx context.Context, tx storage.Storage) error {
+				attempts++
+				return tc.errs[attempts-1]
+			})
+
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.wantErr)
+			}
+			if tc.rollbackErr == nil && tc.wantErr != nil {
+				assert.Equal(t, tc.wantErr, err)
+			}
+			assert.Equal(t, tc.wantAttempts, attempts)
+			assert.Equal(t, tc.wantRollbacks, connector.rollbacks)
+		})
+	}
+}
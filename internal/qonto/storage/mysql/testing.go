@@ -1,4 +1,4 @@
-package storage
+package mysql
 
 import (
 	"context"
@@ -9,25 +9,36 @@ import (
 	"testing"
 	"time"
 
-	"github.com/go-sql-driver/mysql"
+	driver "github.com/go-sql-driver/mysql"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/app"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/sqlstorage"
 	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/utils"
 )
 
 var (
 	once        sync.Once
-	mysqlConfig *mysql.Config
+	mysqlConfig *driver.Config
 	appConfig   *app.Configuration
 )
 
-func NewTestDatabase(ctx context.Context, t *testing.T) (*mysqlStorage, string) {
+// TEST_ADDRESS_ENV names variable with address of MySQL server used by integration tests,
+// the rest of the connection is configured by QONTO_DB_* variables of the application
+const TEST_ADDRESS_ENV = "QONTO_DB_ADDRESS"
+
+// NewTestDatabase creates new migrated database on the server from TEST_ADDRESS_ENV,
+// the test is skipped if the variable is not set
+func NewTestDatabase(ctx context.Context, t *testing.T) (*sqlstorage.Storage, string) {
+	if os.Getenv(TEST_ADDRESS_ENV) == "" {
+		t.Skipf("%s is not set", TEST_ADDRESS_ENV)
+	}
 	once.Do(func() {
 		var err error
 		appConfig, err = app.ConfigurationFromEnv(os.Getenv)
 		if err != nil {
 			t.Fatal(err)
 		}
-		mysqlConfig = NewMysqlConfig()
+		mysqlConfig = NewConfig()
 		mysqlConfig.User = appConfig.DB.User
 		mysqlConfig.Passwd = appConfig.DB.Password
 		mysqlConfig.DBName = appConfig.DB.Name
@@ -38,11 +49,13 @@ func NewTestDatabase(ctx context.Context, t *testing.T) (*mysqlStorage, string)
 
 	dbName := "test-" + tempDBName(10)
 
-	mysqlStorage, err := NewMysqlStorage(mysqlConfig)
+	mysqlStorage, err := NewStorage(mysqlConfig)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if err := mysqlStorage.Wait(TimeoutPingWaiter(ctx, 10*time.Second)); err != nil {
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := storage.WaitAvailable(waitCtx, mysqlStorage, time.Second, nil); err != nil {
 		t.Fatal(err)
 	}
 
@@ -52,7 +65,7 @@ func NewTestDatabase(ctx context.Context, t *testing.T) (*mysqlStorage, string)
 	}
 
 	mysqlConfig.DBName = dbName
-	mysqlStorage, err = NewMysqlStorage(mysqlConfig)
+	mysqlStorage, err = NewStorage(mysqlConfig)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+// PostgreSQL error codes translated to storage errors
+const (
+	UNIQUE_VIOLATION      = "23505"
+	FOREIGN_KEY_VIOLATION = "23503"
+	SERIALIZATION_FAILURE = "40001"
+	DEADLOCK_DETECTED     = "40P01"
+	LOCK_NOT_AVAILABLE    = "55P03"
+)
+
+// RetryReason implements storage.RetryReasonFunc, the whole transaction can be run again
+// after deadlock, serialization failure or lock timeout
+func RetryReason(err error) string {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+	switch pqErr.Code {
+	case DEADLOCK_DETECTED:
+		return storage.RETRY_REASON_DEADLOCK
+	case SERIALIZATION_FAILURE:
+		return storage.RETRY_REASON_SERIALIZATION_FAILURE
+	case LOCK_NOT_AVAILABLE:
+		return storage.RETRY_REASON_LOCK_WAIT_TIMEOUT
+	}
+	return ""
+}
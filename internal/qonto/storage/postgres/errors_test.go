@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/sqlstorage"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errDeadlock            = &pq.Error{Code: DEADLOCK_DETECTED, Message: "deadlock detected"}
+	errSerializationFailed = &pq.Error{Code: SERIALIZATION_FAILURE, Message: "could not serialize access due to concurrent update"}
+	errLockNotAvailable    = &pq.Error{Code: LOCK_NOT_AVAILABLE, Message: "canceling statement due to lock timeout"}
+)
+
+func TestRetryReason(t *testing.T) {
+	assert.Equal(t, storage.RETRY_REASON_DEADLOCK, RetryReason(errDeadlock))
+	assert.Equal(t, storage.RETRY_REASON_SERIALIZATION_FAILURE, RetryReason(errSerializationFailed))
+	assert.Equal(t, storage.RETRY_REASON_LOCK_WAIT_TIMEOUT, RetryReason(fmt.Errorf("update balance: %w", errLockNotAvailable)))
+	assert.Equal(t, "", RetryReason(&pq.Error{Code: UNIQUE_VIOLATION, Message: "duplicate key value violates unique constraint"}))
+	assert.Equal(t, "", RetryReason(errors.New("deadlock")))
+	assert.Equal(t, "", RetryReason(nil))
+}
+
+func TestTranslateError(t *testing.T) {
+	errDBDown := errors.New("connection refused")
+
+	testCases := []struct {
+		name        string
+		err         error
+		notFound    storage.Error
+		expectedErr error
+	}{
+		{"no error", nil, storage.ErrAccountNotFound, nil},
+		{"no rows", sql.ErrNoRows, storage.ErrAccountNotFound, storage.ErrAccountNotFound},
+		{"no rows without not found error", sql.ErrNoRows, "", sql.ErrNoRows},
+		{"duplicate key", &pq.Error{Code: UNIQUE_VIOLATION, Message: "duplicate key value violates unique constraint"}, "", storage.ErrConflict},
+		{"foreign key violation", &pq.Error{Code: FOREIGN_KEY_VIOLATION, Message: "violates foreign key constraint"}, "", storage.ErrConstraintViolation},
+		{"wrapped deadlock", fmt.Errorf("append transactions: %w", errDeadlock), "", storage.ErrConflict},
+		{"serialization failure", errSerializationFailed, "", storage.ErrConflict},
+		{"lock not available", errLockNotAvailable, "", storage.ErrConflict},
+		{"unknown PostgreSQL error", &pq.Error{Code: "42P01", Message: "relation does not exist"}, "", nil},
+		{"other error", errDBDown, storage.ErrAccountNotFound, errDBDown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := sqlstorage.TranslateError(dialect{}, tc.err, tc.notFound)
+			if tc.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			if tc.expectedErr == nil {
+				assert.Equal(t, tc.err, err)
+				return
+			}
+			assert.ErrorIs(t, err, tc.expectedErr)
+			// the original error stays available
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+
+	t.Run("translated error is kept", func(t *testing.T) {
+		err := sqlstorage.TranslateError(dialect{}, storage.Wrap(storage.ErrDuplicateIBAN, &pq.Error{Code: UNIQUE_VIOLATION}), "")
+		assert.ErrorIs(t, err, storage.ErrDuplicateIBAN)
+		assert.NotErrorIs(t, err, storage.ErrConflict)
+	})
+
+	t.Run("translated deadlock is retried", func(t *testing.T) {
+		assert.Equal(t, storage.RETRY_REASON_DEADLOCK, RetryReason(sqlstorage.TranslateError(dialect{}, errDeadlock, "")))
+	})
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/sqlstorage"
+)
+
+// dialect adapts shared SQL storage to PostgreSQL
+type dialect struct{}
+
+// NewStorage creates and initializes new PostgreSQL storage instance
+func NewStorage(dsn string) (*sqlstorage.Storage, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(30)
+	db.SetMaxIdleConns(10)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	return sqlstorage.NewStorage(db, dialect{}), nil
+}
+
+// NewDSN builds PostgreSQL connection URL, address is "host:port"
+func NewDSN(address, user, password, name string) string {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(user, password),
+		Host:     address,
+		Path:     "/" + name,
+		RawQuery: "sslmode=disable",
+	}
+
+	return dsn.String()
+}
+
+// Rebind numbers the placeholders, PostgreSQL uses "$1", "$2"... instead of "?"
+func (dialect) Rebind(query string) string {
+	builder := strings.Builder{}
+	builder.Grow(len(query))
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] != '?' {
+			builder.WriteByte(query[i])
+			continue
+		}
+		n++
+		builder.WriteString("$" + strconv.Itoa(n))
+	}
+
+	return builder.String()
+}
+
+// InsertID reads the id returned by the statement, pq does not support LastInsertId
+func (dialect) InsertID(ctx context.Context, q sqlstorage.Querier, stmt string, args ...interface{}) (int64, error) {
+	var id int64
+	err := q.QueryRowContext(ctx, stmt+" RETURNING id", args...).Scan(&id)
+	return id, err
+}
+
+func (dialect) Upsert(key, columns []string) string {
+	updates := make([]string, 0, len(columns))
+	for _, column := range columns {
+		updates = append(updates, column+" = EXCLUDED."+column)
+	}
+	return "ON CONFLICT (" + strings.Join(key, ", ") + ") DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+func (dialect) RetryReason(err error) string {
+	return RetryReason(err)
+}
+
+func (dialect) ErrorKind(err error) storage.Error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return ""
+	}
+	switch pqErr.Code {
+	case UNIQUE_VIOLATION, DEADLOCK_DETECTED, SERIALIZATION_FAILURE, LOCK_NOT_AVAILABLE:
+		return storage.ErrConflict
+	case FOREIGN_KEY_VIOLATION:
+		return storage.ErrConstraintViolation
+	}
+	return ""
+}
+
+func (dialect) IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == UNIQUE_VIOLATION
+}
@@ -0,0 +1,32 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebind(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		expected string
+	}{
+		{"no placeholders", "SELECT 1", "SELECT 1"},
+		{"single placeholder", "SELECT id FROM bank_accounts WHERE iban = ?", "SELECT id FROM bank_accounts WHERE iban = $1"},
+		{"placeholders are numbered in order", "VALUES (?, ?), (?,?)", "VALUES ($1, $2), ($3,$4)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, dialect{}.Rebind(tc.query))
+		})
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	assert.Equal(t,
+		"ON CONFLICT (bank_account_id, counterparty_iban) DO UPDATE SET max_daily_cents = EXCLUDED.max_daily_cents, max_monthly_cents = EXCLUDED.max_monthly_cents",
+		dialect{}.Upsert([]string{"bank_account_id", "counterparty_iban"}, []string{"max_daily_cents", "max_monthly_cents"}),
+	)
+}
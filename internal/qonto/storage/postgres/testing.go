@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage/sqlstorage"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/utils"
+)
+
+// TEST_DSN_ENV names variable with connection URL of PostgreSQL server used by integration tests
+const TEST_DSN_ENV = "QONTO_TEST_POSTGRES_DSN"
+
+// NewTestDatabase creates new migrated database on the server from TEST_DSN_ENV,
+// the test is skipped if the variable is not set
+func NewTestDatabase(ctx context.Context, t *testing.T) (*sqlstorage.Storage, string) {
+	dsn := os.Getenv(TEST_DSN_ENV)
+	if dsn == "" {
+		t.Skipf("%s is not set", TEST_DSN_ENV)
+	}
+	dsnURL, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dbName := "test-" + tempDBName(10)
+
+	postgresStorage, err := NewStorage(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer postgresStorage.Close()
+	waitCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := storage.WaitAvailable(waitCtx, postgresStorage, time.Second, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = postgresStorage.DB().ExecContext(ctx, `CREATE DATABASE "`+dbName+`"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dsnURL.Path = "/" + dbName
+	postgresStorage, err = NewStorage(dsnURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectRoot := utils.ProjectRootDir()
+	if err := Migrate("file://"+projectRoot+"/migrations/postgres", postgresStorage.DB()); err != nil {
+		t.Fatal(err)
+	}
+
+	return postgresStorage, dbName
+}
+
+func tempDBName(n int) string {
+	builder := strings.Builder{}
+	rand.Seed(time.Now().UnixMicro())
+	for i := 0; i < n; i++ {
+		builder.WriteByte(byte(rand.Intn(26) + 'a'))
+	}
+
+	return builder.String()
+}
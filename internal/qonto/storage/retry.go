@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// reasons of retries reported to RetryObserver
+const (
+	RETRY_REASON_DEADLOCK              = "deadlock"
+	RETRY_REASON_LOCK_WAIT_TIMEOUT     = "lock_wait_timeout"
+	RETRY_REASON_SERIALIZATION_FAILURE = "serialization_failure"
+)
+
+type (
+	// RetryPolicy limits retries of failed transactions
+	RetryPolicy struct {
+		// MaxAttempts is the number of times the transaction is run, 1 or less disables retries
+		MaxAttempts int
+		// BaseDelay is the upper bound of delay before the first retry, it doubles with every next one
+		BaseDelay time.Duration
+		// MaxDelay caps the upper bound of delay before a retry
+		MaxDelay time.Duration
+	}
+
+	// RetryReasonFunc returns reason of retry if err is worth retrying, empty string otherwise
+	RetryReasonFunc func(err error) string
+
+	// RetryObserver is notified about retried transactions
+	RetryObserver interface {
+		// ObserveRetry is called before the transaction is run again
+		ObserveRetry(reason string)
+		// ObserveRetriesExhausted is called when the last attempt fails with retriable error
+		ObserveRetriesExhausted(reason string)
+	}
+)
+
+// DefaultRetryPolicy is used by storage unless another one is set
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 10 * time.Millisecond, MaxDelay: 200 * time.Millisecond}
+
+// jitter picks actual delay up to the upper bound, so concurrent transactions do not collide again
+var jitter = func(upper time.Duration) time.Duration {
+	return time.Duration(rand.Int63n(int64(upper) + 1))
+}
+
+// Retry runs f until it succeeds, fails with error which has no retry reason or attempts of the policy run out.
+// Waiting before the next attempt is aborted once ctx is done, observer may be nil
+func Retry(ctx context.Context, policy RetryPolicy, observer RetryObserver, retryReason RetryReasonFunc, f func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		reason := retryReason(err)
+		if reason == "" {
+			return err
+		}
+		if attempt >= policy.MaxAttempts {
+			if observer != nil {
+				observer.ObserveRetriesExhausted(reason)
+			}
+			return err
+		}
+		if observer != nil {
+			observer.ObserveRetry(reason)
+		}
+
+		timer := time.NewTimer(policy.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: retry aborted after: %v", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns jittered delay before the next attempt after the failed one
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	upper := p.BaseDelay
+	for i := 1; i < attempt && upper < p.MaxDelay; i++ {
+		upper *= 2
+	}
+	if upper > p.MaxDelay {
+		upper = p.MaxDelay
+	}
+	if upper <= 0 {
+		return 0
+	}
+	return jitter(upper)
+}
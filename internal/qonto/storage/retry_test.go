@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	errDeadlock        = errors.New("Deadlock found when trying to get lock")
+	errLockWaitTimeout = errors.New("Lock wait timeout exceeded")
+	errDuplicateKey    = errors.New("Duplicate entry")
+)
+
+// retryReason classifies errors of tests as a backend would do
+func retryReason(err error) string {
+	switch {
+	case errors.Is(err, errDeadlock):
+		return RETRY_REASON_DEADLOCK
+	case errors.Is(err, errLockWaitTimeout):
+		return RETRY_REASON_LOCK_WAIT_TIMEOUT
+	}
+	return ""
+}
+
+type mockRetryObserver struct {
+	retries   []string
+	exhausted []string
+}
+
+func (mo *mockRetryObserver) ObserveRetry(reason string) {
+	mo.retries = append(mo.retries, reason)
+}
+
+func (mo *mockRetryObserver) ObserveRetriesExhausted(reason string) {
+	mo.exhausted = append(mo.exhausted, reason)
+}
+
+// failing returns function failing with errs one by one and succeeding afterwards, calls counts its calls
+func failing(calls *int, errs ...error) func() error {
+	return func() error {
+		*calls++
+		if *calls <= len(errs) {
+			return errs[*calls-1]
+		}
+		return nil
+	}
+}
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+	testCases := []struct {
+		name              string
+		errs              []error
+		expectedErr       error
+		expectedCalls     int
+		expectedRetries   []string
+		expectedExhausted []string
+	}{
+		{
+			name:          "success",
+			expectedCalls: 1,
+		},
+		{
+			name:            "deadlock then success",
+			errs:            []error{errDeadlock},
+			expectedCalls:   2,
+			expectedRetries: []string{RETRY_REASON_DEADLOCK},
+		},
+		{
+			name:            "lock wait timeout then deadlock then success",
+			errs:            []error{errLockWaitTimeout, errDeadlock},
+			expectedCalls:   3,
+			expectedRetries: []string{RETRY_REASON_LOCK_WAIT_TIMEOUT, RETRY_REASON_DEADLOCK},
+		},
+		{
+			name:              "attempts run out",
+			errs:              []error{errDeadlock, errDeadlock, errDeadlock, errDeadlock},
+			expectedErr:       errDeadlock,
+			expectedCalls:     3,
+			expectedRetries:   []string{RETRY_REASON_DEADLOCK, RETRY_REASON_DEADLOCK},
+			expectedExhausted: []string{RETRY_REASON_DEADLOCK},
+		},
+		{
+			name:          "not retriable error",
+			errs:          []error{errDuplicateKey},
+			expectedErr:   errDuplicateKey,
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			observer := &mockRetryObserver{}
+			calls := 0
+			err := Retry(context.Background(), policy, observer, retryReason, failing(&calls, tc.errs...))
+
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedCalls, calls)
+			assert.Equal(t, tc.expectedRetries, observer.retries)
+			assert.Equal(t, tc.expectedExhausted, observer.exhausted)
+		})
+	}
+
+	t.Run("retries are disabled", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), RetryPolicy{MaxAttempts: 1}, nil, retryReason, failing(&calls, errDeadlock))
+		assert.ErrorIs(t, err, errDeadlock)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("cancelled context aborts waiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		f := failing(&calls, errDeadlock, errDeadlock)
+		start := time.Now()
+		err := Retry(ctx, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Minute}, nil, retryReason, func() error {
+			cancel()
+			return f()
+		})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "Deadlock found")
+		assert.Equal(t, 1, calls)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	defer func(original func(time.Duration) time.Duration) { jitter = original }(jitter)
+	jitter = func(upper time.Duration) time.Duration { return upper }
+
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, policy.delay(1))
+	assert.Equal(t, 20*time.Millisecond, policy.delay(2))
+	assert.Equal(t, 40*time.Millisecond, policy.delay(3))
+	assert.Equal(t, 50*time.Millisecond, policy.delay(4))
+	assert.Equal(t, 50*time.Millisecond, policy.delay(9))
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.delay(1))
+}
@@ -0,0 +1,23 @@
+package sqlstorage
+
+import (
+	"context"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+// Dialect covers differences of SQL databases the storage is run on
+type Dialect interface {
+	// Rebind replaces "?" placeholders of the query with the ones of the database
+	Rebind(query string) string
+	// InsertID runs INSERT statement and returns id generated for the inserted row
+	InsertID(ctx context.Context, q Querier, stmt string, args ...interface{}) (int64, error)
+	// Upsert returns the clause of INSERT statement updating columns of the row conflicting by key columns
+	Upsert(key, columns []string) string
+	// RetryReason implements storage.RetryReasonFunc for errors of the database
+	RetryReason(err error) string
+	// ErrorKind returns storage error the database error stands for, empty if the error is not known
+	ErrorKind(err error) storage.Error
+	// IsUniqueViolation reports whether err is caused by duplicated unique key
+	IsUniqueViolation(err error) bool
+}
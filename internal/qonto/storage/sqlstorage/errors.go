@@ -0,0 +1,26 @@
+package sqlstorage
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+// TranslateError wraps database errors known to the dialect in storage errors, notFound is used for sql.ErrNoRows if set
+func TranslateError(dialect Dialect, err error, notFound storage.Error) error {
+	if err == nil || storage.IsWrapped(err) {
+		return err
+	}
+	if notFound != "" && errors.Is(err, sql.ErrNoRows) {
+		return storage.Wrap(notFound, err)
+	}
+	if kind := dialect.ErrorKind(err); kind != "" {
+		return storage.Wrap(kind, err)
+	}
+	return err
+}
+
+func (s *Storage) translateError(err error, notFound storage.Error) error {
+	return TranslateError(s.dialect, err, notFound)
+}
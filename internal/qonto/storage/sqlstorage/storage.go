@@ -0,0 +1,850 @@
+package sqlstorage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+)
+
+type (
+	// Storage implements storage.Storage on top of database/sql, queries are written once with "?" placeholders
+	// and the dialect adapts them and the errors to the database
+	Storage struct {
+		db            *sql.DB
+		querier       Querier
+		inTx          bool
+		dialect       Dialect
+		observer      storage.TransactionObserver
+		retryPolicy   storage.RetryPolicy
+		retryObserver storage.RetryObserver
+	}
+
+	// Querier runs queries either directly on the database or in a transaction
+	Querier interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+		QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	}
+
+	// boundQuerier rebinds placeholders of every query to the dialect
+	boundQuerier struct {
+		querier Querier
+		dialect Dialect
+	}
+)
+
+// NewStorage creates storage running queries on db in the dialect, the caller configures the pool
+func NewStorage(db *sql.DB, dialect Dialect) *Storage {
+	return &Storage{
+		db:          db,
+		querier:     boundQuerier{querier: db, dialect: dialect},
+		dialect:     dialect,
+		retryPolicy: storage.DefaultRetryPolicy,
+	}
+}
+
+func (q boundQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return q.querier.ExecContext(ctx, q.dialect.Rebind(query), args...)
+}
+
+func (q boundQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return q.querier.QueryContext(ctx, q.dialect.Rebind(query), args...)
+}
+
+func (q boundQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return q.querier.QueryRowContext(ctx, q.dialect.Rebind(query), args...)
+}
+
+// forUpdate locks selected rows until the end of the transaction the storage is bound to,
+// so read-modify-write changes of concurrent transactions do not overwrite each other
+func (s *Storage) forUpdate() string {
+	if s.inTx {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+// insert runs INSERT statement and returns id generated for the new row
+func (s *Storage) insert(ctx context.Context, stmt string, args ...interface{}) (int64, error) {
+	id, err := s.dialect.InsertID(ctx, s.querier, stmt, args...)
+	if err != nil {
+		return 0, s.translateError(err, "")
+	}
+	return id, nil
+}
+
+func (s *Storage) CreateAccount(ctx context.Context, name, iban, bic string, initialBalanceCents int64) (int64, error) {
+	stmt := `
+		INSERT INTO bank_accounts ( organization_name, balance_cents, iban, bic)
+		VALUES (?,?,?,?)`
+
+	id, err := s.dialect.InsertID(ctx, s.querier, stmt, name, initialBalanceCents, iban, bic)
+	if s.dialect.IsUniqueViolation(err) {
+		return 0, storage.Wrap(storage.ErrDuplicateIBAN, err)
+	}
+	if err != nil {
+		return 0, s.translateError(err, "")
+	}
+
+	return id, nil
+}
+
+func (s *Storage) FindAccount(ctx context.Context, id int64) (storage.Account, error) {
+	stmt := `
+		SELECT
+			id, organization_name, balance_cents, iban, bic
+		FROM
+			bank_accounts
+		WHERE id = ?
+		` + s.forUpdate()
+
+	row := s.querier.QueryRowContext(ctx, stmt, id)
+	account := storage.Account{}
+	if err := row.Scan(&account.ID, &account.Name, &account.BalanceCents, &account.IBAN, &account.BIC); err != nil {
+		return storage.Account{}, s.translateError(err, storage.ErrAccountNotFound)
+	}
+	return account, nil
+}
+
+func (s *Storage) FindAccountByIBAN(ctx context.Context, iban string) (storage.Account, error) {
+	stmt := `
+		SELECT
+			id, organization_name, balance_cents, iban, bic
+		FROM
+			bank_accounts
+		WHERE iban = ?
+		` + s.forUpdate()
+
+	row := s.querier.QueryRowContext(ctx, stmt, iban)
+	account := storage.Account{}
+	if err := row.Scan(&account.ID, &account.Name, &account.BalanceCents, &account.IBAN, &account.BIC); err != nil {
+		return storage.Account{}, s.translateError(err, storage.ErrAccountNotFound)
+	}
+	return account, nil
+}
+
+func (s *Storage) UpdateAccountBalance(ctx context.Context, id, balance int64) error {
+	stmt := `
+		UPDATE
+			bank_accounts
+		SET
+			balance_cents = ?
+		WHERE id = ?
+		`
+
+	_, err := s.querier.ExecContext(ctx, stmt, balance, id)
+	if err != nil {
+		return s.translateError(err, "")
+	}
+
+	return nil
+}
+
+func (s *Storage) FindAccountTransactions(ctx context.Context, id int64) ([]*storage.Transaction, error) {
+	stmt := `
+		SELECT
+			id,
+			counterparty_name, counterparty_iban, counterparty_bic,
+			amount_cents, amount_currency,
+			bank_account_id,
+			description,
+			created_at,
+			fingerprint, flagged_duplicate
+		FROM
+			transactions
+		WHERE bank_account_id = ?
+		`
+
+	rows, err := s.querier.QueryContext(ctx, stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	return scanTransactions(rows)
+}
+
+func (s *Storage) FilterAccountTransactions(ctx context.Context, accountID int64, filter storage.TransactionFilter) ([]*storage.Transaction, error) {
+	stmt, args := filteredTransactionsQuery(accountID, filter)
+	rows, err := s.querier.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanTransactions(rows)
+}
+
+func (s *Storage) EachAccountTransaction(ctx context.Context, accountID int64, filter storage.TransactionFilter, f func(*storage.Transaction) error) error {
+	stmt, args := filteredTransactionsQuery(accountID, filter)
+	rows, err := s.querier.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return err
+	}
+	return eachTransaction(rows, f)
+}
+
+// filteredTransactionsQuery selects account transactions matching the filter ordered by creation time
+func filteredTransactionsQuery(accountID int64, filter storage.TransactionFilter) (string, []interface{}) {
+	where, args := transactionFilterClause(accountID, filter)
+	stmt := `
+		SELECT
+			id,
+			counterparty_name, counterparty_iban, counterparty_bic,
+			amount_cents, amount_currency,
+			bank_account_id,
+			description,
+			created_at,
+			fingerprint, flagged_duplicate
+		FROM
+			transactions
+		WHERE ` + where + `
+		ORDER BY created_at, id
+		`
+
+	return stmt, args
+}
+
+// scanTransactions reads all transactions from rows and closes them
+func scanTransactions(rows *sql.Rows) ([]*storage.Transaction, error) {
+	result := []*storage.Transaction{}
+	err := eachTransaction(rows, func(tx *storage.Transaction) error {
+		result = append(result, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// eachTransaction calls f for every transaction read from rows and closes them, error of f stops reading
+func eachTransaction(rows *sql.Rows, f func(*storage.Transaction) error) error {
+	defer rows.Close()
+
+	for rows.Next() {
+		tx := storage.Transaction{}
+		if err := rows.Scan(
+			&tx.ID,
+			&tx.CounterpartyName, &tx.CounterpartyIBAN, &tx.CounterpartyBIC,
+			&tx.AmountCents, &tx.AmountCurrency,
+			&tx.BankAccountID,
+			&tx.Description,
+			&tx.CreatedAt,
+			&tx.Fingerprint, &tx.FlaggedDuplicate,
+		); err != nil {
+			return err
+		}
+		tx.CreatedAt = tx.CreatedAt.UTC()
+		if err := f(&tx); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// transactionsChunkSize limits number of rows in a single insert, both MySQL and PostgreSQL allow
+// at most 65535 placeholders per statement and every row takes 10 of them
+const transactionsChunkSize = 1000
+
+// AppendAccountTransactions inserts transactions by chunks, all of them are inserted in the same
+// database transaction, which is started if the storage is not bound to one yet
+func (s *Storage) AppendAccountTransactions(ctx context.Context, transactions []*storage.Transaction) error {
+	if !s.inTx && len(transactions) > transactionsChunkSize {
+		return s.WithTransactionStorage(ctx, func(ctx context.Context, s storage.Storage) error {
+			return s.AppendAccountTransactions(ctx, transactions)
+		})
+	}
+
+	for start := 0; start < len(transactions); start += transactionsChunkSize {
+		end := start + transactionsChunkSize
+		if end > len(transactions) {
+			end = len(transactions)
+		}
+		if err := s.insertTransactions(ctx, transactions[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Storage) insertTransactions(ctx context.Context, transactions []*storage.Transaction) error {
+	stmt := `
+		INSERT INTO
+			transactions
+			(
+				counterparty_name,
+				counterparty_iban,
+				counterparty_bic,
+				amount_cents,
+				amount_currency,
+				bank_account_id,
+				description,
+				created_at,
+				fingerprint,
+				flagged_duplicate
+			)
+		VALUES
+		` + strings.Repeat(", (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", len(transactions))[1:]
+
+	args := make([]interface{}, 0, 10*len(transactions))
+	for _, v := range transactions {
+		args = append(args,
+			v.CounterpartyName,
+			v.CounterpartyIBAN,
+			v.CounterpartyBIC,
+			v.AmountCents,
+			v.AmountCurrency,
+			v.BankAccountID,
+			v.Description,
+			v.CreatedAt,
+			v.Fingerprint,
+			v.FlaggedDuplicate)
+	}
+	_, err := s.querier.ExecContext(ctx, stmt, args...)
+	return s.translateError(err, "")
+}
+
+func (s *Storage) SumAccountTransactions(ctx context.Context, accountID int64, filter storage.TransactionFilter) (int64, error) {
+	where, args := transactionFilterClause(accountID, filter)
+	stmt := `
+		SELECT
+			COALESCE(SUM(amount_cents), 0)
+		FROM
+			transactions
+		WHERE ` + where
+
+	var sum int64
+	if err := s.querier.QueryRowContext(ctx, stmt, args...).Scan(&sum); err != nil {
+		return 0, err
+	}
+	return sum, nil
+}
+
+func (s *Storage) CountAccountTransactions(ctx context.Context, accountID int64, filter storage.TransactionFilter) (int64, error) {
+	where, args := transactionFilterClause(accountID, filter)
+	stmt := `
+		SELECT
+			COUNT(*)
+		FROM
+			transactions
+		WHERE ` + where
+
+	var count int64
+	if err := s.querier.QueryRowContext(ctx, stmt, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// fingerprintsChunkSize limits number of placeholders in a single query
+const fingerprintsChunkSize = 1000
+
+func (s *Storage) FindTransactionFingerprints(ctx context.Context, accountID int64, fingerprints []string, since time.Time) ([]string, error) {
+	result := []string{}
+	for start := 0; start < len(fingerprints); start += fingerprintsChunkSize {
+		end := start + fingerprintsChunkSize
+		if end > len(fingerprints) {
+			end = len(fingerprints)
+		}
+		chunk := fingerprints[start:end]
+
+		stmt := `
+		SELECT DISTINCT
+			fingerprint
+		FROM
+			transactions
+		WHERE bank_account_id = ? AND created_at >= ? AND fingerprint IN (` + strings.Repeat(", ?", len(chunk))[1:] + `)
+		`
+		args := []interface{}{accountID, since}
+		for _, fp := range chunk {
+			args = append(args, fp)
+		}
+
+		rows, err := s.querier.QueryContext(ctx, stmt, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var fp string
+			if err := rows.Scan(&fp); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			result = append(result, fp)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rows.Close()
+	}
+
+	return result, nil
+}
+
+// transactionFilterClause builds WHERE clause and its arguments for transactions of the account
+func transactionFilterClause(accountID int64, filter storage.TransactionFilter) (string, []interface{}) {
+	conditions := []string{"bank_account_id = ?"}
+	args := []interface{}{accountID}
+	if filter.CounterpartyIBAN != "" {
+		conditions = append(conditions, "counterparty_iban = ?")
+		args = append(args, filter.CounterpartyIBAN)
+	}
+	if filter.AmountCents != 0 {
+		conditions = append(conditions, "amount_cents = ?")
+		args = append(args, filter.AmountCents)
+	}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.Until)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func (s *Storage) FindTransferLimits(ctx context.Context, accountID int64) ([]storage.TransferLimit, error) {
+	stmt := `
+		SELECT
+			id, bank_account_id, counterparty_iban,
+			max_single_transfer_cents, max_daily_cents, max_monthly_cents,
+			max_transfers_per_batch
+		FROM
+			transfer_limits
+		WHERE bank_account_id = ?
+		`
+
+	rows, err := s.querier.QueryContext(ctx, stmt, accountID)
+	if err != nil {
+		return nil, err
+	}
+	result := []storage.TransferLimit{}
+	defer rows.Close()
+
+	for rows.Next() {
+		limit := storage.TransferLimit{}
+		if err := rows.Scan(
+			&limit.ID, &limit.BankAccountID, &limit.CounterpartyIBAN,
+			&limit.MaxSingleTransferCents, &limit.MaxDailyCents, &limit.MaxMonthlyCents,
+			&limit.MaxTransfersPerBatch,
+		); err != nil {
+			return nil, err
+		}
+		result = append(result, limit)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) SaveTransferLimit(ctx context.Context, limit storage.TransferLimit) error {
+	stmt := `
+		INSERT INTO
+			transfer_limits
+			(
+				bank_account_id, counterparty_iban,
+				max_single_transfer_cents, max_daily_cents, max_monthly_cents,
+				max_transfers_per_batch
+			)
+		VALUES (?, ?, ?, ?, ?, ?)
+		` + s.dialect.Upsert(
+		[]string{"bank_account_id", "counterparty_iban"},
+		[]string{"max_single_transfer_cents", "max_daily_cents", "max_monthly_cents", "max_transfers_per_batch"},
+	)
+
+	_, err := s.querier.ExecContext(ctx, stmt,
+		limit.BankAccountID, limit.CounterpartyIBAN,
+		limit.MaxSingleTransferCents, limit.MaxDailyCents, limit.MaxMonthlyCents,
+		limit.MaxTransfersPerBatch,
+	)
+	return s.translateError(err, "")
+}
+
+// WithTransactionStorage runs f in transaction, it is run again if the dialect reports the failure as transient
+// (deadlock, lock wait timeout, serialization failure), so f must not keep outcomes of failed attempts
+func (s *Storage) WithTransactionStorage(ctx context.Context, f func(context.Context, storage.Storage) error) error {
+	return s.translateError(storage.Retry(ctx, s.retryPolicy, s.retryObserver, s.dialect.RetryReason, func() error {
+		return s.runTransaction(ctx, f)
+	}), "")
+}
+
+func (s *Storage) runTransaction(ctx context.Context, f func(context.Context, storage.Storage) error) error {
+	start := time.Now()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	txStorage := &Storage{
+		db:            s.db,
+		querier:       boundQuerier{querier: tx, dialect: s.dialect},
+		inTx:          true,
+		dialect:       s.dialect,
+		observer:      s.observer,
+		retryPolicy:   s.retryPolicy,
+		retryObserver: s.retryObserver,
+	}
+	if err := f(ctx, txStorage); err != nil {
+		s.observeTransaction(start, false, err)
+		if errTx := tx.Rollback(); errTx != nil {
+			return fmt.Errorf("%w: cannot rollback transaction: %v", err, errTx)
+		}
+
+		return err
+	}
+
+	err = tx.Commit()
+	s.observeTransaction(start, true, err)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// WithTransactionObserver sets observer notified about every finished transaction
+func (s *Storage) WithTransactionObserver(observer storage.TransactionObserver) *Storage {
+	s.observer = observer
+	return s
+}
+
+// WithRetryPolicy sets limits of transaction retries
+func (s *Storage) WithRetryPolicy(policy storage.RetryPolicy) *Storage {
+	s.retryPolicy = policy
+	return s
+}
+
+// WithRetryObserver sets observer notified about every retried transaction
+func (s *Storage) WithRetryObserver(observer storage.RetryObserver) *Storage {
+	s.retryObserver = observer
+	return s
+}
+
+func (s *Storage) observeTransaction(start time.Time, committed bool, err error) {
+	if s.observer != nil {
+		s.observer.ObserveTransaction(time.Since(start), committed, err)
+	}
+}
+
+func (s *Storage) Close() error {
+	return s.db.Close()
+}
+
+func (s *Storage) DB() *sql.DB {
+	return s.db
+
+}
+
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// MigrationVersion reads the table maintained by migrate tool
+func (s *Storage) MigrationVersion(ctx context.Context) (uint, bool, error) {
+	var (
+		version uint
+		dirty   bool
+	)
+	err := s.querier.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, storage.ErrNoMigrations
+	}
+	return version, dirty, err
+}
+
+func (s *Storage) SaveRiskDecision(ctx context.Context, decision storage.RiskDecision) (int64, error) {
+	stmt := `
+		INSERT INTO risk_decisions ( bank_account_id, decision, reasons, created_at)
+		VALUES (?,?,?,?)`
+
+	reasons, err := json.Marshal(decision.Reasons)
+	if err != nil {
+		return 0, err
+	}
+	return s.insert(ctx, stmt, decision.BankAccountID, decision.Decision, string(reasons), decision.CreatedAt)
+}
+
+func (s *Storage) FindRiskDecisions(ctx context.Context, accountID int64) ([]storage.RiskDecision, error) {
+	stmt := `
+		SELECT
+			id, bank_account_id, decision, reasons, created_at
+		FROM
+			risk_decisions
+		WHERE bank_account_id = ?
+		ORDER BY id
+		`
+
+	rows, err := s.querier.QueryContext(ctx, stmt, accountID)
+	if err != nil {
+		return nil, err
+	}
+	result := []storage.RiskDecision{}
+	defer rows.Close()
+
+	for rows.Next() {
+		decision := storage.RiskDecision{}
+		var reasons []byte
+		if err := rows.Scan(&decision.ID, &decision.BankAccountID, &decision.Decision, &reasons, &decision.CreatedAt); err != nil {
+			return nil, err
+		}
+		decision.CreatedAt = decision.CreatedAt.UTC()
+		if err := json.Unmarshal(reasons, &decision.Reasons); err != nil {
+			return nil, err
+		}
+		result = append(result, decision)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) SaveScreeningHit(ctx context.Context, hit storage.ScreeningHit) (int64, error) {
+	stmt := `
+		INSERT INTO
+			screening_hits
+			(
+				bank_account_id,
+				counterparty_name, counterparty_iban, counterparty_bic,
+				entry_id, entry_name, matched_field, score,
+				status, created_at
+			)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	return s.insert(ctx, stmt,
+		hit.BankAccountID,
+		hit.CounterpartyName, hit.CounterpartyIBAN, hit.CounterpartyBIC,
+		hit.EntryID, hit.EntryName, hit.MatchedField, hit.Score,
+		hit.Status, hit.CreatedAt,
+	)
+}
+
+func (s *Storage) FindScreeningHits(ctx context.Context, filter storage.ScreeningHitFilter) ([]storage.ScreeningHit, error) {
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	if filter.BankAccountID != 0 {
+		conditions = append(conditions, "bank_account_id = ?")
+		args = append(args, filter.BankAccountID)
+	}
+	if filter.CounterpartyIBAN != "" {
+		conditions = append(conditions, "counterparty_iban = ?")
+		args = append(args, filter.CounterpartyIBAN)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	stmt := `
+		SELECT
+			id, bank_account_id,
+			counterparty_name, counterparty_iban, counterparty_bic,
+			entry_id, entry_name, matched_field, score,
+			status, created_at, cleared_at
+		FROM
+			screening_hits
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY id
+		`
+
+	rows, err := s.querier.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	result := []storage.ScreeningHit{}
+	defer rows.Close()
+
+	for rows.Next() {
+		hit := storage.ScreeningHit{}
+		clearedAt := sql.NullTime{}
+		if err := rows.Scan(
+			&hit.ID, &hit.BankAccountID,
+			&hit.CounterpartyName, &hit.CounterpartyIBAN, &hit.CounterpartyBIC,
+			&hit.EntryID, &hit.EntryName, &hit.MatchedField, &hit.Score,
+			&hit.Status, &hit.CreatedAt, &clearedAt,
+		); err != nil {
+			return nil, err
+		}
+		hit.CreatedAt = hit.CreatedAt.UTC()
+		if clearedAt.Valid {
+			hit.ClearedAt = clearedAt.Time.UTC()
+		}
+		result = append(result, hit)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) ClearScreeningHit(ctx context.Context, id int64, clearedAt time.Time) (bool, error) {
+	stmt := `
+		UPDATE
+			screening_hits
+		SET
+			status = ?,
+			cleared_at = ?
+		WHERE id = ? AND status = ?
+		`
+
+	result, err := s.querier.ExecContext(ctx, stmt, storage.ScreeningHitCleared, clearedAt, id, storage.ScreeningHitOpen)
+	if err != nil {
+		return false, s.translateError(err, "")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (s *Storage) FindScreeningHit(ctx context.Context, id int64) (storage.ScreeningHit, error) {
+	stmt := `
+		SELECT
+			id, bank_account_id,
+			counterparty_name, counterparty_iban, counterparty_bic,
+			entry_id, entry_name, matched_field, score,
+			status, created_at, cleared_at
+		FROM
+			screening_hits
+		WHERE id = ?
+		`
+
+	hit := storage.ScreeningHit{}
+	clearedAt := sql.NullTime{}
+	if err := s.querier.QueryRowContext(ctx, stmt, id).Scan(
+		&hit.ID, &hit.BankAccountID,
+		&hit.CounterpartyName, &hit.CounterpartyIBAN, &hit.CounterpartyBIC,
+		&hit.EntryID, &hit.EntryName, &hit.MatchedField, &hit.Score,
+		&hit.Status, &hit.CreatedAt, &clearedAt,
+	); err != nil {
+		return storage.ScreeningHit{}, s.translateError(err, storage.ErrScreeningHitNotFound)
+	}
+	hit.CreatedAt = hit.CreatedAt.UTC()
+	if clearedAt.Valid {
+		hit.ClearedAt = clearedAt.Time.UTC()
+	}
+	return hit, nil
+}
+
+// AppendHeldTransfers inserts transfers one by one, so every transfer gets its id
+func (s *Storage) AppendHeldTransfers(ctx context.Context, transfers []*storage.HeldTransfer) error {
+	stmt := `
+		INSERT INTO
+			held_transfers
+			(
+				bank_account_id,
+				counterparty_name, counterparty_iban, counterparty_bic,
+				amount_cents, amount_currency, description, fingerprint,
+				status, reason, created_at
+			)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	for _, t := range transfers {
+		id, err := s.insert(ctx, stmt,
+			t.BankAccountID,
+			t.CounterpartyName, t.CounterpartyIBAN, t.CounterpartyBIC,
+			t.AmountCents, t.AmountCurrency, t.Description, t.Fingerprint,
+			t.Status, t.Reason, t.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		t.ID = id
+	}
+
+	return nil
+}
+
+func (s *Storage) FindPendingHeldTransfers(ctx context.Context, accountID int64, counterpartyIBAN string) ([]*storage.HeldTransfer, error) {
+	stmt := `
+		SELECT
+			id, bank_account_id,
+			counterparty_name, counterparty_iban, counterparty_bic,
+			amount_cents, amount_currency, description, fingerprint,
+			status, reason, created_at, released_at
+		FROM
+			held_transfers
+		WHERE bank_account_id = ? AND counterparty_iban = ? AND status = ?
+		ORDER BY id
+		` + s.forUpdate()
+
+	rows, err := s.querier.QueryContext(ctx, stmt, accountID, counterpartyIBAN, storage.HeldTransferPending)
+	if err != nil {
+		return nil, err
+	}
+	result := []*storage.HeldTransfer{}
+	defer rows.Close()
+
+	for rows.Next() {
+		t := &storage.HeldTransfer{}
+		releasedAt := sql.NullTime{}
+		if err := rows.Scan(
+			&t.ID, &t.BankAccountID,
+			&t.CounterpartyName, &t.CounterpartyIBAN, &t.CounterpartyBIC,
+			&t.AmountCents, &t.AmountCurrency, &t.Description, &t.Fingerprint,
+			&t.Status, &t.Reason, &t.CreatedAt, &releasedAt,
+		); err != nil {
+			return nil, err
+		}
+		t.CreatedAt = t.CreatedAt.UTC()
+		if releasedAt.Valid {
+			t.ReleasedAt = releasedAt.Time.UTC()
+		}
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *Storage) ReleaseHeldTransfer(ctx context.Context, id int64, status, reason string, releasedAt time.Time) (bool, error) {
+	stmt := `
+		UPDATE
+			held_transfers
+		SET
+			status = ?,
+			reason = ?,
+			released_at = ?
+		WHERE id = ? AND status = ?
+		`
+
+	result, err := s.querier.ExecContext(ctx, stmt, status, reason, releasedAt, id, storage.HeldTransferPending)
+	if err != nil {
+		return false, s.translateError(err, "")
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func (s *Storage) SavePaymentStatusReport(ctx context.Context, report storage.PaymentStatusReport) (int64, error) {
+	stmt := `
+		INSERT INTO payment_status_reports ( message_id, original_message_id, group_status, content, created_at)
+		VALUES (?,?,?,?,?)`
+
+	return s.insert(ctx, stmt, report.MessageID, report.OriginalMessageID, report.GroupStatus, report.Content, report.CreatedAt)
+}
+
+func (s *Storage) FindPaymentStatusReport(ctx context.Context, id int64) (storage.PaymentStatusReport, error) {
+	stmt := `
+		SELECT
+			id, message_id, original_message_id, group_status, content, created_at
+		FROM
+			payment_status_reports
+		WHERE id = ?
+		`
+
+	row := s.querier.QueryRowContext(ctx, stmt, id)
+	report := storage.PaymentStatusReport{}
+	if err := row.Scan(&report.ID, &report.MessageID, &report.OriginalMessageID, &report.GroupStatus, &report.Content, &report.CreatedAt); err != nil {
+		return storage.PaymentStatusReport{}, s.translateError(err, storage.ErrStatusReportNotFound)
+	}
+	report.CreatedAt = report.CreatedAt.UTC()
+	return report, nil
+}
@@ -1,6 +1,9 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type (
 	Account struct {
@@ -20,26 +23,177 @@ type (
 		AmountCurrency   string
 		BankAccountID    int64
 		Description      string
+		CreatedAt        time.Time
+		Fingerprint      string
+		FlaggedDuplicate bool
+	}
+
+	// TransactionFilter narrows down account transactions, zero value fields are ignored
+	TransactionFilter struct {
+		CounterpartyIBAN string
+		AmountCents      int64
+		Since            time.Time
+		// Until excludes transactions created at or after the time
+		Until time.Time
+	}
+
+	// PaymentStatusReport is a generated status report of the processed payment message
+	PaymentStatusReport struct {
+		ID                int64
+		MessageID         string
+		OriginalMessageID string
+		GroupStatus       string
+		Content           []byte
+		CreatedAt         time.Time
+	}
+
+	// RiskDecision is an outcome of risk rules evaluation of a single request
+	RiskDecision struct {
+		ID            int64
+		BankAccountID int64
+		Decision      string
+		Reasons       []string
+		CreatedAt     time.Time
+	}
+
+	// ScreeningHit is a match of transfer counterparty against sanctions list.
+	// ClearedAt is zero until the hit is cleared
+	ScreeningHit struct {
+		ID               int64
+		BankAccountID    int64
+		CounterpartyName string
+		CounterpartyIBAN string
+		CounterpartyBIC  string
+		EntryID          string
+		EntryName        string
+		MatchedField     string
+		Score            float64
+		Status           string
+		CreatedAt        time.Time
+		ClearedAt        time.Time
+	}
+
+	// ScreeningHitFilter narrows down screening hits, zero value fields are ignored
+	ScreeningHitFilter struct {
+		BankAccountID    int64
+		CounterpartyIBAN string
+		Status           string
+	}
+
+	// HeldTransfer is a transfer kept pending until screening hits of its counterparty are cleared.
+	// Reason is set for rejected transfers, ReleasedAt is zero until the transfer is executed or rejected
+	HeldTransfer struct {
+		ID               int64
+		BankAccountID    int64
+		CounterpartyName string
+		CounterpartyIBAN string
+		CounterpartyBIC  string
+		AmountCents      int64
+		AmountCurrency   string
+		Description      string
+		Fingerprint      string
+		Status           string
+		Reason           string
+		CreatedAt        time.Time
+		ReleasedAt       time.Time
+	}
+
+	// TransferLimit defines limits of outgoing transfers of the account.
+	// Empty CounterpartyIBAN means the limit is organization-wide,
+	// zero value of any limit means the limit is not set
+	TransferLimit struct {
+		ID                     int64
+		BankAccountID          int64
+		CounterpartyIBAN       string
+		MaxSingleTransferCents int64
+		MaxDailyCents          int64
+		MaxMonthlyCents        int64
+		MaxTransfersPerBatch   int
+	}
+
+	// TransactionObserver is notified about finished transactions, err is the reason of rollback or failed commit
+	TransactionObserver interface {
+		ObserveTransaction(duration time.Duration, committed bool, err error)
 	}
 
-	// Storage defines interface to be satisfied by concrete storage implementation
+	// Storage defines interface to be satisfied by concrete storage implementation.
+	// Known database errors are returned as storage errors, e.g. ErrConflict, ErrConstraintViolation
 	Storage interface {
-		// WithTransaction wraps functions in transaction and rolls it back if function returns error
-		WithTransaction(context.Context, func(context.Context, Querier) error) error
+		// WithTransactionStorage runs f as a unit of work: changes made with the storage passed to f are applied
+		// all together if f returns nil and discarded otherwise. f may be run again if the storage retries
+		// conflicting changes, so it must not keep outcomes of failed attempts
 		WithTransactionStorage(context.Context, func(context.Context, Storage) error) error
 
+		// CreateAccount returns ErrDuplicateIBAN if account with the IBAN exists
 		CreateAccount(ctx context.Context, name, iban, bic string, initialBalanceCents int64) (int64, error)
+		// FindAccount returns ErrAccountNotFound if there is no account with the id
 		FindAccount(ctx context.Context, id int64) (Account, error)
 		UpdateAccountBalance(ctx context.Context, id, balance int64) error
+		// FindAccountByIBAN returns ErrAccountNotFound if there is no account with the IBAN
 		FindAccountByIBAN(ctx context.Context, iban string) (Account, error)
 
 		FindAccountTransactions(ctx context.Context, id int64) ([]*Transaction, error)
 		AppendAccountTransactions(ctx context.Context, transactions []*Transaction) error
+		// FilterAccountTransactions returns account transactions matching the filter ordered by creation time
+		FilterAccountTransactions(ctx context.Context, accountID int64, filter TransactionFilter) ([]*Transaction, error)
+		// EachAccountTransaction reads account transactions matching the filter ordered by creation time
+		// from database cursor one by one, error returned by f stops reading and is returned as is
+		EachAccountTransaction(ctx context.Context, accountID int64, filter TransactionFilter, f func(*Transaction) error) error
+		// SumAccountTransactions sums amounts of account transactions matching the filter
+		SumAccountTransactions(ctx context.Context, accountID int64, filter TransactionFilter) (int64, error)
+		// CountAccountTransactions counts account transactions matching the filter
+		CountAccountTransactions(ctx context.Context, accountID int64, filter TransactionFilter) (int64, error)
+		// FindTransactionFingerprints returns those of fingerprints which account transactions created since the given time have
+		FindTransactionFingerprints(ctx context.Context, accountID int64, fingerprints []string, since time.Time) ([]string, error)
+
+		FindTransferLimits(ctx context.Context, accountID int64) ([]TransferLimit, error)
+		// SaveTransferLimit creates or replaces limit of the account for the counterparty
+		SaveTransferLimit(ctx context.Context, limit TransferLimit) error
+
+		SaveRiskDecision(ctx context.Context, decision RiskDecision) (int64, error)
+		FindRiskDecisions(ctx context.Context, accountID int64) ([]RiskDecision, error)
 
-		// Wait runs provided wait function until it returns true without error
-		Wait(f WaiterFunc) error
+		SaveScreeningHit(ctx context.Context, hit ScreeningHit) (int64, error)
+		FindScreeningHits(ctx context.Context, filter ScreeningHitFilter) ([]ScreeningHit, error)
+		// ClearScreeningHit marks open hit as cleared, returns false if there is no open hit with the id
+		ClearScreeningHit(ctx context.Context, id int64, clearedAt time.Time) (bool, error)
+		// FindScreeningHit returns ErrScreeningHitNotFound if there is no hit with the id
+		FindScreeningHit(ctx context.Context, id int64) (ScreeningHit, error)
+
+		AppendHeldTransfers(ctx context.Context, transfers []*HeldTransfer) error
+		// FindPendingHeldTransfers returns pending transfers of the account to the counterparty in order of arrival
+		FindPendingHeldTransfers(ctx context.Context, accountID int64, counterpartyIBAN string) ([]*HeldTransfer, error)
+		// ReleaseHeldTransfer sets final status of pending transfer, returns false if there is no pending transfer with the id
+		ReleaseHeldTransfer(ctx context.Context, id int64, status, reason string, releasedAt time.Time) (bool, error)
+
+		SavePaymentStatusReport(ctx context.Context, report PaymentStatusReport) (int64, error)
+		// FindPaymentStatusReport returns ErrStatusReportNotFound if there is no report with the id
+		FindPaymentStatusReport(ctx context.Context, id int64) (PaymentStatusReport, error)
 
 		// Close closes underlying storage connection if supported by concrete implementation
 		// Close() error
 	}
+
+	// Health is implemented by backends reachable over the network and having schema migrations,
+	// it is used by health checks only, so business logic does not depend on it
+	Health interface {
+		// Ping checks that the storage is reachable
+		Ping(ctx context.Context) error
+		// MigrationVersion returns version of the last applied migration, dirty is set if it failed.
+		// ErrNoMigrations is returned if none is applied
+		MigrationVersion(ctx context.Context) (version uint, dirty bool, err error)
+	}
+)
+
+// statuses of screening hits
+const (
+	ScreeningHitOpen    = "open"
+	ScreeningHitCleared = "cleared"
+)
+
+// statuses of held transfers
+const (
+	HeldTransferPending  = "pending"
+	HeldTransferExecuted = "executed"
+	HeldTransferRejected = "rejected"
 )
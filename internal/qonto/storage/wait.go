@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WaitAvailable pings the storage every interval until it responds or ctx is done,
+// failed is called with every ping error if set
+func WaitAvailable(ctx context.Context, s Health, interval time.Duration, failed func(error)) error {
+	var err error
+	for {
+		select {
+		case <-ctx.Done():
+			if err != nil {
+				return fmt.Errorf("%w: %v", ctx.Err(), err)
+			}
+			return ctx.Err()
+		case <-time.After(interval):
+			if err = s.Ping(ctx); err == nil {
+				return nil
+			}
+			if failed != nil {
+				failed(err)
+			}
+		}
+	}
+}
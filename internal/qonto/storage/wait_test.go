@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mockPinger fails the first pings
+type mockPinger struct {
+	Health
+	failures int
+	pings    int
+}
+
+func (mp *mockPinger) Ping(ctx context.Context) error {
+	mp.pings++
+	if mp.pings <= mp.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestWaitAvailable(t *testing.T) {
+	t.Run("storage becomes available", func(t *testing.T) {
+		pinger := &mockPinger{failures: 2}
+		failed := 0
+		err := WaitAvailable(context.Background(), pinger, time.Millisecond, func(error) { failed++ })
+		assert.NoError(t, err)
+		assert.Equal(t, 3, pinger.pings)
+		assert.Equal(t, 2, failed)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := WaitAvailable(ctx, &mockPinger{failures: 1000}, time.Millisecond, nil)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "connection refused")
+	})
+}
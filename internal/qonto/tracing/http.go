@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware starts a server span of every request handled by chi router. The span continues
+// the trace of the caller given by traceparent header and is named by the route pattern
+func Middleware(provider trace.TracerProvider) func(http.Handler) http.Handler {
+	tracer := provider.Tracer(INSTRUMENTATION_NAME)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethodKey.String(r.Method),
+					semconv.HTTPTargetKey.String(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			// callers may correlate the response with the trace
+			Propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(fmt.Sprintf("%s %s", r.Method, rctx.RoutePattern()))
+				span.SetAttributes(semconv.HTTPRouteKey.String(rctx.RoutePattern()))
+			}
+			status := ww.Status()
+			if status == 0 {
+				// nothing is written, so net/http responds with 200
+				status = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPStatusCodeKey.Int(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+		})
+	}
+}
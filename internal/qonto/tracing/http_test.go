@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	provider, recorder := newTestProvider()
+	router := chi.NewRouter()
+	router.Use(Middleware(provider))
+	router.Post("/v1/transfers", func(w http.ResponseWriter, r *http.Request) {
+		_, span := StartSpan(r.Context(), "decode request")
+		span.End()
+		w.WriteHeader(http.StatusCreated)
+	})
+	router.Get("/v1/accounts/{iban}/statements", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	request := httptest.NewRequest(http.MethodPost, "http://localhost/v1/transfers", nil)
+	request.Header.Set("traceparent", traceparent)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, request)
+
+	callerContext := trace.SpanContextFromContext(Propagator.Extract(request.Context(), propagation.HeaderCarrier(request.Header)))
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	decode, server := spans[0], spans[1]
+
+	assert.Equal(t, "POST /v1/transfers", server.Name())
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind())
+	assert.Equal(t, callerContext.TraceID(), server.SpanContext().TraceID())
+	assert.Equal(t, callerContext.SpanID(), server.Parent().SpanID())
+	assert.True(t, server.Parent().IsRemote())
+	assert.Contains(t, server.Attributes(), attribute.Int("http.status_code", http.StatusCreated))
+	assert.Contains(t, server.Attributes(), attribute.String("http.route", "/v1/transfers"))
+	assert.Equal(t, codes.Unset, server.Status().Code)
+
+	assert.Equal(t, "decode request", decode.Name())
+	assert.Equal(t, server.SpanContext().SpanID(), decode.Parent().SpanID())
+
+	responseContext := trace.SpanContextFromContext(Propagator.Extract(request.Context(), propagation.HeaderCarrier(w.Header())))
+	assert.Equal(t, server.SpanContext().TraceID(), responseContext.TraceID())
+	assert.Equal(t, server.SpanContext().SpanID(), responseContext.SpanID())
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost/v1/accounts/FR10474608000002006107XXXXX/statements", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "http://localhost/unknown", nil))
+
+	spans = recorder.Ended()
+	require.Len(t, spans, 4)
+	failed, unmatched := spans[2], spans[3]
+	assert.Equal(t, "GET /v1/accounts/{iban}/statements", failed.Name())
+	assert.False(t, failed.Parent().IsValid(), "request without traceparent starts a new trace")
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	assert.Equal(t, "GET", unmatched.Name())
+	assert.Contains(t, unmatched.Attributes(), attribute.Int("http.status_code", http.StatusNotFound))
+}
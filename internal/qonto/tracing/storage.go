@@ -0,0 +1,196 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedStorage starts a client span for every call of wrapped storage
+type tracedStorage struct {
+	next   storage.Storage
+	tracer trace.Tracer
+	system string
+}
+
+// NewStorage wraps the storage, system names the database in span attributes, e.g. "mysql".
+// Storage bound to a transaction is wrapped too, so its calls are children of the transaction span
+func NewStorage(next storage.Storage, provider trace.TracerProvider, system string) storage.Storage {
+	return &tracedStorage{
+		next:   next,
+		tracer: provider.Tracer(INSTRUMENTATION_NAME),
+		system: system,
+	}
+}
+
+func (ts *tracedStorage) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, semconv.DBSystemKey.String(ts.system), semconv.DBOperationKey.String(operation))
+	return ts.tracer.Start(ctx, "storage."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// end ends the span, missing records are expected outcome of lookups, so they are not errors
+func end(ctx context.Context, span trace.Span, err error) {
+	if errors.Is(err, storage.ErrAccountNotFound) || errors.Is(err, storage.ErrStatusReportNotFound) ||
+		errors.Is(err, storage.ErrScreeningHitNotFound) {
+		span.SetAttributes(attribute.Bool("db.not_found", true))
+		err = nil
+	}
+	End(ctx, span, err)
+}
+
+func (ts *tracedStorage) WithTransactionStorage(ctx context.Context, f func(context.Context, storage.Storage) error) (err error) {
+	ctx, span := ts.start(ctx, "WithTransactionStorage")
+	defer func() { end(ctx, span, err) }()
+	return ts.next.WithTransactionStorage(ctx, func(ctx context.Context, txStorage storage.Storage) error {
+		return f(ctx, &tracedStorage{next: txStorage, tracer: ts.tracer, system: ts.system})
+	})
+}
+
+func (ts *tracedStorage) CreateAccount(ctx context.Context, name, iban, bic string, initialBalanceCents int64) (id int64, err error) {
+	ctx, span := ts.start(ctx, "CreateAccount")
+	defer func() { end(ctx, span, err) }()
+	return ts.next.CreateAccount(ctx, name, iban, bic, initialBalanceCents)
+}
+
+func (ts *tracedStorage) FindAccount(ctx context.Context, id int64) (account storage.Account, err error) {
+	ctx, span := ts.start(ctx, "FindAccount", attribute.Int64("qonto.account_id", id))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindAccount(ctx, id)
+}
+
+func (ts *tracedStorage) UpdateAccountBalance(ctx context.Context, id, balance int64) (err error) {
+	ctx, span := ts.start(ctx, "UpdateAccountBalance", attribute.Int64("qonto.account_id", id))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.UpdateAccountBalance(ctx, id, balance)
+}
+
+func (ts *tracedStorage) FindAccountByIBAN(ctx context.Context, iban string) (account storage.Account, err error) {
+	ctx, span := ts.start(ctx, "FindAccountByIBAN")
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindAccountByIBAN(ctx, iban)
+}
+
+func (ts *tracedStorage) FindAccountTransactions(ctx context.Context, id int64) (transactions []*storage.Transaction, err error) {
+	ctx, span := ts.start(ctx, "FindAccountTransactions", attribute.Int64("qonto.account_id", id))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindAccountTransactions(ctx, id)
+}
+
+func (ts *tracedStorage) AppendAccountTransactions(ctx context.Context, transactions []*storage.Transaction) (err error) {
+	ctx, span := ts.start(ctx, "AppendAccountTransactions", attribute.Int("qonto.transactions", len(transactions)))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.AppendAccountTransactions(ctx, transactions)
+}
+
+func (ts *tracedStorage) FilterAccountTransactions(ctx context.Context, accountID int64, filter storage.TransactionFilter) (transactions []*storage.Transaction, err error) {
+	ctx, span := ts.start(ctx, "FilterAccountTransactions", attribute.Int64("qonto.account_id", accountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FilterAccountTransactions(ctx, accountID, filter)
+}
+
+func (ts *tracedStorage) EachAccountTransaction(ctx context.Context, accountID int64, filter storage.TransactionFilter, f func(*storage.Transaction) error) (err error) {
+	ctx, span := ts.start(ctx, "EachAccountTransaction", attribute.Int64("qonto.account_id", accountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.EachAccountTransaction(ctx, accountID, filter, f)
+}
+
+func (ts *tracedStorage) SumAccountTransactions(ctx context.Context, accountID int64, filter storage.TransactionFilter) (sum int64, err error) {
+	ctx, span := ts.start(ctx, "SumAccountTransactions", attribute.Int64("qonto.account_id", accountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.SumAccountTransactions(ctx, accountID, filter)
+}
+
+func (ts *tracedStorage) CountAccountTransactions(ctx context.Context, accountID int64, filter storage.TransactionFilter) (count int64, err error) {
+	ctx, span := ts.start(ctx, "CountAccountTransactions", attribute.Int64("qonto.account_id", accountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.CountAccountTransactions(ctx, accountID, filter)
+}
+
+func (ts *tracedStorage) FindTransactionFingerprints(ctx context.Context, accountID int64, fingerprints []string, since time.Time) (found []string, err error) {
+	ctx, span := ts.start(ctx, "FindTransactionFingerprints", attribute.Int64("qonto.account_id", accountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindTransactionFingerprints(ctx, accountID, fingerprints, since)
+}
+
+func (ts *tracedStorage) FindTransferLimits(ctx context.Context, accountID int64) (limits []storage.TransferLimit, err error) {
+	ctx, span := ts.start(ctx, "FindTransferLimits", attribute.Int64("qonto.account_id", accountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindTransferLimits(ctx, accountID)
+}
+
+func (ts *tracedStorage) SaveTransferLimit(ctx context.Context, limit storage.TransferLimit) (err error) {
+	ctx, span := ts.start(ctx, "SaveTransferLimit", attribute.Int64("qonto.account_id", limit.BankAccountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.SaveTransferLimit(ctx, limit)
+}
+
+func (ts *tracedStorage) SaveRiskDecision(ctx context.Context, decision storage.RiskDecision) (id int64, err error) {
+	ctx, span := ts.start(ctx, "SaveRiskDecision", attribute.Int64("qonto.account_id", decision.BankAccountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.SaveRiskDecision(ctx, decision)
+}
+
+func (ts *tracedStorage) FindRiskDecisions(ctx context.Context, accountID int64) (decisions []storage.RiskDecision, err error) {
+	ctx, span := ts.start(ctx, "FindRiskDecisions", attribute.Int64("qonto.account_id", accountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindRiskDecisions(ctx, accountID)
+}
+
+func (ts *tracedStorage) SaveScreeningHit(ctx context.Context, hit storage.ScreeningHit) (id int64, err error) {
+	ctx, span := ts.start(ctx, "SaveScreeningHit", attribute.Int64("qonto.account_id", hit.BankAccountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.SaveScreeningHit(ctx, hit)
+}
+
+func (ts *tracedStorage) FindScreeningHits(ctx context.Context, filter storage.ScreeningHitFilter) (hits []storage.ScreeningHit, err error) {
+	ctx, span := ts.start(ctx, "FindScreeningHits")
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindScreeningHits(ctx, filter)
+}
+
+func (ts *tracedStorage) ClearScreeningHit(ctx context.Context, id int64, clearedAt time.Time) (cleared bool, err error) {
+	ctx, span := ts.start(ctx, "ClearScreeningHit")
+	defer func() { end(ctx, span, err) }()
+	return ts.next.ClearScreeningHit(ctx, id, clearedAt)
+}
+
+func (ts *tracedStorage) FindScreeningHit(ctx context.Context, id int64) (hit storage.ScreeningHit, err error) {
+	ctx, span := ts.start(ctx, "FindScreeningHit")
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindScreeningHit(ctx, id)
+}
+
+func (ts *tracedStorage) AppendHeldTransfers(ctx context.Context, transfers []*storage.HeldTransfer) (err error) {
+	ctx, span := ts.start(ctx, "AppendHeldTransfers", attribute.Int("qonto.transfers", len(transfers)))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.AppendHeldTransfers(ctx, transfers)
+}
+
+func (ts *tracedStorage) FindPendingHeldTransfers(ctx context.Context, accountID int64, counterpartyIBAN string) (transfers []*storage.HeldTransfer, err error) {
+	ctx, span := ts.start(ctx, "FindPendingHeldTransfers", attribute.Int64("qonto.account_id", accountID))
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindPendingHeldTransfers(ctx, accountID, counterpartyIBAN)
+}
+
+func (ts *tracedStorage) ReleaseHeldTransfer(ctx context.Context, id int64, status, reason string, releasedAt time.Time) (released bool, err error) {
+	ctx, span := ts.start(ctx, "ReleaseHeldTransfer")
+	defer func() { end(ctx, span, err) }()
+	return ts.next.ReleaseHeldTransfer(ctx, id, status, reason, releasedAt)
+}
+
+func (ts *tracedStorage) SavePaymentStatusReport(ctx context.Context, report storage.PaymentStatusReport) (id int64, err error) {
+	ctx, span := ts.start(ctx, "SavePaymentStatusReport")
+	defer func() { end(ctx, span, err) }()
+	return ts.next.SavePaymentStatusReport(ctx, report)
+}
+
+func (ts *tracedStorage) FindPaymentStatusReport(ctx context.Context, id int64) (report storage.PaymentStatusReport, err error) {
+	ctx, span := ts.start(ctx, "FindPaymentStatusReport")
+	defer func() { end(ctx, span, err) }()
+	return ts.next.FindPaymentStatusReport(ctx, id)
+}
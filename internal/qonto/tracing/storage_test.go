@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// mockStorage implements methods used by transfer manager, others panic
+type mockStorage struct {
+	storage.Storage
+	account   storage.Account
+	findErr   error
+	appendErr error
+}
+
+func (ms *mockStorage) WithTransactionStorage(ctx context.Context, f func(context.Context, storage.Storage) error) error {
+	return f(ctx, ms)
+}
+
+func (ms *mockStorage) FindAccountByIBAN(ctx context.Context, iban string) (storage.Account, error) {
+	return ms.account, ms.findErr
+}
+
+func (ms *mockStorage) FindTransferLimits(ctx context.Context, accountID int64) ([]storage.TransferLimit, error) {
+	return nil, nil
+}
+
+func (ms *mockStorage) UpdateAccountBalance(ctx context.Context, id, balance int64) error {
+	return nil
+}
+
+func (ms *mockStorage) AppendAccountTransactions(ctx context.Context, transactions []*storage.Transaction) error {
+	return ms.appendErr
+}
+
+func TestTracedTransferProcessing(t *testing.T) {
+	request := &core.Request{
+		Party: core.Party{IBAN: "FR10474608000002006107XXXXX"},
+		CreditTransfers: core.TransferList{
+			{Amount: core.Amount{Cents: 1450}, Currency: core.CURRENCY_EURO},
+			{Amount: core.Amount{Cents: 1450}, Currency: core.CURRENCY_EURO},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		storage        *mockStorage
+		expectedSpans  []string
+		expectedStatus map[string]codes.Code
+	}{
+		{
+			name:    "accepted",
+			storage: &mockStorage{account: storage.Account{ID: 1, BalanceCents: 10000}},
+			expectedSpans: []string{
+				"storage.FindAccountByIBAN",
+				"storage.FindTransferLimits",
+				"storage.AppendAccountTransactions",
+				"storage.UpdateAccountBalance",
+				"storage.WithTransactionStorage",
+				"TransferManager.ProcessTransfers",
+			},
+			expectedStatus: map[string]codes.Code{"TransferManager.ProcessTransfers": codes.Unset},
+		},
+		{
+			name:    "unknown account",
+			storage: &mockStorage{findErr: storage.ErrAccountNotFound},
+			expectedSpans: []string{
+				"storage.FindAccountByIBAN",
+				"storage.WithTransactionStorage",
+				"TransferManager.ProcessTransfers",
+			},
+			expectedStatus: map[string]codes.Code{
+				"storage.FindAccountByIBAN":        codes.Unset,
+				"TransferManager.ProcessTransfers": codes.Error,
+			},
+		},
+		{
+			name:    "insert failed",
+			storage: &mockStorage{account: storage.Account{ID: 1, BalanceCents: 10000}, appendErr: errors.New("connection lost")},
+			expectedSpans: []string{
+				"storage.FindAccountByIBAN",
+				"storage.FindTransferLimits",
+				"storage.AppendAccountTransactions",
+				"storage.WithTransactionStorage",
+				"TransferManager.ProcessTransfers",
+			},
+			expectedStatus: map[string]codes.Code{
+				"storage.AppendAccountTransactions": codes.Error,
+				"storage.WithTransactionStorage":    codes.Error,
+				"TransferManager.ProcessTransfers":  codes.Error,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider, recorder := newTestProvider()
+			manager := core.NewQontoTransferManager(NewStorage(tc.storage, provider, "mysql"))
+			_, _ = NewTransferManager(manager, provider).ProcessTransfers(context.Background(), request)
+
+			assert.Equal(t, tc.expectedSpans, spanNames(recorder))
+
+			spans := map[string]sdktrace.ReadOnlySpan{}
+			for _, span := range recorder.Ended() {
+				spans[span.Name()] = span
+			}
+			root := spans["TransferManager.ProcessTransfers"]
+			require.NotNil(t, root)
+			assert.Contains(t, root.Attributes(), attribute.Int("qonto.transfers", 2))
+			// storage calls inside of transaction are its children
+			tx := spans["storage.WithTransactionStorage"]
+			assert.Equal(t, root.SpanContext().SpanID(), tx.Parent().SpanID())
+			assert.Equal(t, tx.SpanContext().SpanID(), spans["storage.FindAccountByIBAN"].Parent().SpanID())
+			assert.Contains(t, tx.Attributes(), attribute.String("db.system", "mysql"))
+
+			for name, code := range tc.expectedStatus {
+				assert.Equal(t, code, spans[name].Status().Code, name)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// INSTRUMENTATION_NAME names the tracer of all spans created by the app
+const INSTRUMENTATION_NAME = "github.com/maxim-nazarenko/qonto-interview"
+
+// Propagator reads and writes W3C traceparent and tracestate headers
+var Propagator propagation.TextMapPropagator = propagation.TraceContext{}
+
+// Output is the destination of exported spans, an empty value disables tracing
+type Output string
+
+// OUTPUT_STDOUT writes spans to standard output, any other non-empty output is a file path
+const OUTPUT_STDOUT Output = "stdout"
+
+// Provider creates tracers and exports finished spans
+type Provider struct {
+	trace.TracerProvider
+	shutdown func(context.Context) error
+}
+
+// NewProvider creates provider writing spans as JSON objects to the output,
+// the file is appended to, so traces of previous runs are kept.
+// Provider of empty output creates spans that are not recorded
+func NewProvider(output Output, serviceName string) (*Provider, error) {
+	if output == "" {
+		return &Provider{
+			TracerProvider: trace.NewNoopTracerProvider(),
+			shutdown:       func(context.Context) error { return nil },
+		}, nil
+	}
+
+	var w io.Writer = os.Stdout
+	var file *os.File
+	if output != OUTPUT_STDOUT {
+		var err error
+		if file, err = os.OpenFile(string(output), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err != nil {
+			return nil, err
+		}
+		w = file
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(w))
+	if err != nil {
+		return nil, err
+	}
+	provider := newSDKProvider(sdktrace.NewBatchSpanProcessor(exporter), serviceName)
+
+	return &Provider{
+		TracerProvider: provider,
+		shutdown: func(ctx context.Context) error {
+			err := provider.Shutdown(ctx)
+			if file != nil {
+				if closeErr := file.Close(); err == nil {
+					err = closeErr
+				}
+			}
+			return err
+		},
+	}, nil
+}
+
+func newSDKProvider(processor sdktrace.SpanProcessor, serviceName string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+}
+
+// Shutdown exports remaining spans and closes the output
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.shutdown(ctx)
+}
+
+// StartSpan starts a child of the span found in the context, it is not recorded if there is no span.
+// It lets packages trace their steps without knowing the provider
+func StartSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return trace.SpanFromContext(ctx).TracerProvider().Tracer(INSTRUMENTATION_NAME).Start(ctx, name, opts...)
+}
+
+// End records the error, if any, and ends the span. IBANs and names known to the redactor
+// of the context are masked, so spans do not leak them to the tracing output
+func End(ctx context.Context, span trace.Span, err error) {
+	if err != nil {
+		message := qonto.RedactorFromContext(ctx).Text(err.Error())
+		span.RecordError(errors.New(message))
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
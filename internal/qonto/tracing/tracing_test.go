@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestProvider() (*sdktrace.TracerProvider, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	return newSDKProvider(recorder, "qonto-test"), recorder
+}
+
+// spanNames returns names of ended spans in order they ended
+func spanNames(recorder *tracetest.SpanRecorder) []string {
+	names := []string{}
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+	}
+	return names
+}
+
+func TestNewProvider(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		provider, err := NewProvider("", "qonto")
+		require.NoError(t, err)
+		_, span := provider.Tracer(INSTRUMENTATION_NAME).Start(context.Background(), "request")
+		assert.False(t, span.IsRecording())
+		assert.NoError(t, provider.Shutdown(context.Background()))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.json")
+		provider, err := NewProvider(Output(path), "qonto")
+		require.NoError(t, err)
+
+		ctx, parent := provider.Tracer(INSTRUMENTATION_NAME).Start(context.Background(), "request")
+		_, child := StartSpan(ctx, "decode request")
+		child.End()
+		parent.End()
+		require.NoError(t, provider.Shutdown(context.Background()))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		names := []string{}
+		for decoder.More() {
+			var span struct {
+				Name        string
+				SpanContext struct{ TraceID string }
+			}
+			require.NoError(t, decoder.Decode(&span))
+			assert.Equal(t, parent.SpanContext().TraceID().String(), span.SpanContext.TraceID)
+			names = append(names, span.Name)
+		}
+		assert.Equal(t, []string{"decode request", "request"}, names)
+	})
+
+	t.Run("unwritable file", func(t *testing.T) {
+		_, err := NewProvider(Output(filepath.Join(t.TempDir(), "missing", "traces.json")), "qonto")
+		assert.Error(t, err)
+	})
+}
+
+func TestStartSpanWithoutParent(t *testing.T) {
+	_, span := StartSpan(context.Background(), "decode request")
+	assert.False(t, span.IsRecording())
+	End(context.Background(), span, nil)
+}
+
+func TestEndRedactsError(t *testing.T) {
+	provider, recorder := newTestProvider()
+	ctx := qonto.ContextWithRedactor(context.Background(), qonto.NewRedactor("Bip Bip"))
+	_, span := provider.Tracer(INSTRUMENTATION_NAME).Start(ctx, "request")
+	End(ctx, span, errors.New("transfer to Bip Bip ee38 3680 9810 2124 5685 failed"))
+
+	require.Len(t, recorder.Ended(), 1)
+	ended := recorder.Ended()[0]
+	expected := "transfer to " + qonto.RedactName("Bip Bip") + " EE****5685 failed"
+	assert.Equal(t, expected, ended.Status().Description)
+	require.Len(t, ended.Events(), 1)
+	for _, attr := range ended.Events()[0].Attributes {
+		if attr.Key == "exception.message" {
+			assert.Equal(t, expected, attr.Value.AsString())
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/maxim-nazarenko/qonto-interview/internal/qonto/core"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// transferManager traces requests processed by wrapped manager
+type transferManager struct {
+	next   core.TransferManager
+	tracer trace.Tracer
+}
+
+// NewTransferManager wraps the manager, its storage calls become children of the request span
+// if the storage is wrapped by NewStorage
+func NewTransferManager(next core.TransferManager, provider trace.TracerProvider) *transferManager {
+	return &transferManager{
+		next:   next,
+		tracer: provider.Tracer(INSTRUMENTATION_NAME),
+	}
+}
+
+// ProcessTransfers implements core.TransferManager interface
+func (tm *transferManager) ProcessTransfers(ctx context.Context, request *core.Request) (*core.Result, error) {
+	ctx, span := tm.tracer.Start(ctx, "TransferManager.ProcessTransfers", trace.WithAttributes(
+		attribute.String("qonto.mode", string(request.Mode)),
+		attribute.Int("qonto.transfers", request.Len()),
+	))
+
+	result, err := tm.next.ProcessTransfers(ctx, request)
+	if err == nil {
+		rejected, held := 0, 0
+		for i := 0; i < result.Len(); i++ {
+			switch result.Transfer(i).Status {
+			case core.TRANSFER_REJECTED:
+				rejected++
+			case core.TRANSFER_HELD:
+				held++
+			}
+		}
+		span.SetAttributes(attribute.Int("qonto.rejected", rejected), attribute.Int("qonto.held", held))
+	}
+	End(ctx, span, err)
+
+	return result, err
+}
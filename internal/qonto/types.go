@@ -1,11 +1,36 @@
 package qonto
 
 type (
+	// Logger writes structured entries, keyvals are alternating keys and values
 	Logger interface {
-		Info(msg string, args ...interface{})
-		Error(msg string, args ...interface{})
+		Debug(msg string, keyvals ...interface{})
+		Info(msg string, keyvals ...interface{})
+		Warn(msg string, keyvals ...interface{})
+		Error(msg string, keyvals ...interface{})
 
-		// SubLogger creates new sublogger with provided instance name
+		// With creates new logger adding the fields to every entry
+		With(keyvals ...interface{}) Logger
+		// SubLogger creates new sublogger with provided instance name, it is written as "logger" field
 		SubLogger(name string) Logger
 	}
+
+	// Level is the severity of log entry, entries below the level of the logger are dropped
+	Level int8
+
+	// Format defines how entries are encoded
+	Format string
+)
+
+const (
+	LEVEL_DEBUG Level = iota
+	LEVEL_INFO
+	LEVEL_WARN
+	LEVEL_ERROR
+)
+
+const (
+	// FORMAT_TEXT writes entries as logfmt lines
+	FORMAT_TEXT Format = "text"
+	// FORMAT_JSON writes entries as JSON objects, one per line
+	FORMAT_JSON Format = "json"
 )
@@ -0,0 +1,26 @@
+-- ------------------------
+-- Transfer limits
+-- ------------------------
+
+-- transactions must be dated to calculate daily/monthly volumes
+ALTER TABLE `transactions`
+    ADD COLUMN created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    ADD INDEX idx_account_created (bank_account_id, created_at);
+
+-- empty counterparty_iban defines organization-wide limits of the account,
+-- otherwise limits apply to transfers to the given counterparty only.
+-- Zero value of any limit means "no limit".
+CREATE TABLE IF NOT EXISTS `transfer_limits` (
+    id INT NOT NULL AUTO_INCREMENT,
+    bank_account_id INTEGER NOT NULL,
+    counterparty_iban VARCHAR(34) NOT NULL DEFAULT '',
+    max_single_transfer_cents BIGINT NOT NULL DEFAULT 0,
+    max_daily_cents BIGINT NOT NULL DEFAULT 0,
+    max_monthly_cents BIGINT NOT NULL DEFAULT 0,
+    max_transfers_per_batch INTEGER NOT NULL DEFAULT 0,
+
+    PRIMARY KEY(id),
+    UNIQUE INDEX idx_account_counterparty (bank_account_id, counterparty_iban),
+    FOREIGN KEY (bank_account_id)
+        REFERENCES bank_accounts (id)
+) ENGINE=InnoDB DEFAULT CHARACTER SET=utf8mb4;
@@ -0,0 +1,21 @@
+-- ------------------------
+-- Risk rules decisions
+-- ------------------------
+
+-- reasons contains JSON array of human-readable reasons of the decision
+CREATE TABLE IF NOT EXISTS `risk_decisions` (
+    id INT NOT NULL AUTO_INCREMENT,
+    bank_account_id INTEGER NOT NULL,
+    decision VARCHAR(16) NOT NULL,
+    reasons TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+
+    PRIMARY KEY(id),
+    INDEX idx_account_created (bank_account_id, created_at),
+    FOREIGN KEY (bank_account_id)
+        REFERENCES bank_accounts (id)
+) ENGINE=InnoDB DEFAULT CHARACTER SET=utf8mb4;
+
+-- speeds up lookups of previous payments to the same counterparty
+ALTER TABLE `transactions`
+    ADD INDEX idx_account_counterparty (bank_account_id, counterparty_iban);
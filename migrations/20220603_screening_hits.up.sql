@@ -0,0 +1,25 @@
+-- ------------------------
+-- Sanctions screening hits
+-- ------------------------
+
+-- status is either "open" (transfer is on hold) or "cleared" (false positive confirmed)
+CREATE TABLE IF NOT EXISTS `screening_hits` (
+    id INT NOT NULL AUTO_INCREMENT,
+    bank_account_id INTEGER NOT NULL,
+    counterparty_name TEXT NOT NULL,
+    counterparty_iban VARCHAR(34) NOT NULL,
+    counterparty_bic TEXT NOT NULL,
+    entry_id VARCHAR(64) NOT NULL,
+    entry_name TEXT NOT NULL,
+    matched_field VARCHAR(16) NOT NULL,
+    score DOUBLE NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    cleared_at DATETIME(6) NULL,
+
+    PRIMARY KEY(id),
+    INDEX idx_status (status),
+    INDEX idx_account_counterparty (bank_account_id, counterparty_iban),
+    FOREIGN KEY (bank_account_id)
+        REFERENCES bank_accounts (id)
+) ENGINE=InnoDB DEFAULT CHARACTER SET=utf8mb4;
@@ -0,0 +1,17 @@
+-- ---------------------------------------------
+-- ISO 20022 pain.002 payment status reports
+-- ---------------------------------------------
+
+-- group_status is the status of the original message as a whole: ACSC, PART or RJCT
+CREATE TABLE IF NOT EXISTS `payment_status_reports` (
+    id INT NOT NULL AUTO_INCREMENT,
+    message_id VARCHAR(35) NOT NULL,
+    original_message_id VARCHAR(35) NOT NULL,
+    group_status VARCHAR(4) NOT NULL,
+    content MEDIUMBLOB NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+
+    PRIMARY KEY(id),
+    UNIQUE KEY uniq_message_id (message_id),
+    INDEX idx_original_message_id (original_message_id)
+) ENGINE=InnoDB DEFAULT CHARACTER SET=utf8mb4;
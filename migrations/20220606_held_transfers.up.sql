@@ -0,0 +1,26 @@
+-- -----------------------------------------------
+-- Transfers held by open sanctions screening hits
+-- -----------------------------------------------
+
+-- status is "pending" until hits of the counterparty are cleared, then "executed" or "rejected";
+-- reason explains rejection of the released transfer
+CREATE TABLE IF NOT EXISTS `held_transfers` (
+    id INT NOT NULL AUTO_INCREMENT,
+    bank_account_id INTEGER NOT NULL,
+    counterparty_name TEXT NOT NULL,
+    counterparty_iban VARCHAR(34) NOT NULL,
+    counterparty_bic TEXT NOT NULL,
+    amount_cents BIGINT NOT NULL,
+    amount_currency CHAR(3) NOT NULL,
+    description TEXT NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    released_at DATETIME(6) NULL,
+
+    PRIMARY KEY(id),
+    INDEX idx_account_status (bank_account_id, status),
+    FOREIGN KEY (bank_account_id)
+        REFERENCES bank_accounts (id)
+) ENGINE=InnoDB DEFAULT CHARACTER SET=utf8mb4;
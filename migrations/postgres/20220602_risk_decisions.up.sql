@@ -0,0 +1,20 @@
+-- ------------------------
+-- Risk rules decisions
+-- ------------------------
+
+-- reasons contains JSON array of human-readable reasons of the decision
+CREATE TABLE IF NOT EXISTS risk_decisions (
+    id SERIAL NOT NULL,
+    bank_account_id INTEGER NOT NULL,
+    decision VARCHAR(16) NOT NULL,
+    reasons TEXT NOT NULL,
+    created_at TIMESTAMPTZ(6) NOT NULL,
+
+    PRIMARY KEY(id),
+    FOREIGN KEY (bank_account_id)
+        REFERENCES bank_accounts (id)
+);
+CREATE INDEX idx_risk_decisions_account_created ON risk_decisions (bank_account_id, created_at);
+
+-- speeds up lookups of previous payments to the same counterparty
+CREATE INDEX idx_transactions_account_counterparty ON transactions (bank_account_id, counterparty_iban);
@@ -0,0 +1,25 @@
+-- ------------------------
+-- Sanctions screening hits
+-- ------------------------
+
+-- status is either "open" (transfer is on hold) or "cleared" (false positive confirmed)
+CREATE TABLE IF NOT EXISTS screening_hits (
+    id SERIAL NOT NULL,
+    bank_account_id INTEGER NOT NULL,
+    counterparty_name TEXT NOT NULL,
+    counterparty_iban VARCHAR(34) NOT NULL,
+    counterparty_bic TEXT NOT NULL,
+    entry_id VARCHAR(64) NOT NULL,
+    entry_name TEXT NOT NULL,
+    matched_field VARCHAR(16) NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    created_at TIMESTAMPTZ(6) NOT NULL,
+    cleared_at TIMESTAMPTZ(6) NULL,
+
+    PRIMARY KEY(id),
+    FOREIGN KEY (bank_account_id)
+        REFERENCES bank_accounts (id)
+);
+CREATE INDEX idx_screening_hits_status ON screening_hits (status);
+CREATE INDEX idx_screening_hits_account_counterparty ON screening_hits (bank_account_id, counterparty_iban);
@@ -0,0 +1,10 @@
+-- ------------------------
+-- Duplicate transfers detection
+-- ------------------------
+
+-- fingerprint is SHA-256 of counterparty IBAN, amount and description of the transfer,
+-- flagged_duplicate marks transactions executed while a duplicate was detected
+ALTER TABLE transactions
+    ADD COLUMN fingerprint CHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN flagged_duplicate BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX idx_transactions_account_fingerprint ON transactions (bank_account_id, fingerprint, created_at);
@@ -0,0 +1,17 @@
+-- ---------------------------------------------
+-- ISO 20022 pain.002 payment status reports
+-- ---------------------------------------------
+
+-- group_status is the status of the original message as a whole: ACSC, PART or RJCT
+CREATE TABLE IF NOT EXISTS payment_status_reports (
+    id SERIAL NOT NULL,
+    message_id VARCHAR(35) NOT NULL,
+    original_message_id VARCHAR(35) NOT NULL,
+    group_status VARCHAR(4) NOT NULL,
+    content BYTEA NOT NULL,
+    created_at TIMESTAMPTZ(6) NOT NULL,
+
+    PRIMARY KEY(id),
+    CONSTRAINT uniq_message_id UNIQUE (message_id)
+);
+CREATE INDEX idx_payment_status_reports_original_message_id ON payment_status_reports (original_message_id);